@@ -62,6 +62,8 @@ func init() {
 	pflag.String("elastic_username", "elastic", "Username for access to elastic cluster")
 	pflag.String("elastic_password", "", "Password for access to elastic")
 	pflag.String("md_index_name", "", "The elastic index name for metadata.")
+	pflag.Bool("md_index_org_aliases", false, "Whether to search the metadata of each org through the org's alias. "+
+		"This must only be enabled once the indexer creates the org aliases.")
 	pflag.String("allowed_origins", "", "The allowed origins for CORS")
 
 	pflag.String("auth_connector_name", "", "If any, the name of the auth connector to be used with Pixie")
@@ -231,6 +233,9 @@ func main() {
 	if err != nil {
 		log.WithError(err).Fatal("Failed to start elastic suggester")
 	}
+	if viper.GetBool("md_index_org_aliases") {
+		esSuggester.UseOrgAliases()
+	}
 
 	var br *script.BundleManager
 	var bundleErr error
@@ -47,7 +47,7 @@ func (a *AutocompleteServer) Autocomplete(ctx context.Context, req *cloudpb.Auto
 		return nil, err
 	}
 
-	fmtString, executable, suggestions, err := autocomplete.Autocomplete(req.Input, int(req.CursorPos), req.Action, a.Suggester, orgID, req.ClusterUID)
+	fmtString, executable, suggestions, err := autocomplete.Autocomplete(req.Input, int(req.CursorPos), req.Action, a.Suggester, orgID, req.ClusterUID, req.Namespace)
 	if err != nil {
 		return nil, err
 	}
@@ -83,6 +83,7 @@ func (a *AutocompleteServer) AutocompleteField(ctx context.Context, req *cloudpb
 			AllowedKinds: []cloudpb.AutocompleteEntityKind{req.FieldType},
 			AllowedArgs:  allowedArgs,
 			ClusterUID:   req.ClusterUID,
+			Namespace:    req.Namespace,
 		},
 	}
 	suggestions, err := a.Suggester.GetSuggestions(suggestionReq)
@@ -67,6 +67,7 @@ func TestAutocompleteService_Autocomplete(t *testing.T) {
 						Input:        "px/svc_info",
 						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT},
 						AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
+						Namespace:    "pl",
 					},
 					{
 						OrgID:        orgID,
@@ -74,6 +75,7 @@ func TestAutocompleteService_Autocomplete(t *testing.T) {
 						Input:        "pl/test",
 						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT},
 						AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
+						Namespace:    "pl",
 					},
 				},
 			}
@@ -123,6 +125,7 @@ func TestAutocompleteService_Autocomplete(t *testing.T) {
 				CursorPos:  0,
 				Action:     cloudpb.AAT_EDIT,
 				ClusterUID: "test",
+				Namespace:  "pl",
 			})
 			require.NoError(t, err)
 			assert.NotNil(t, resp)
@@ -167,6 +170,7 @@ func TestAutocompleteService_AutocompleteField(t *testing.T) {
 						Input:        "px/svc_info",
 						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SVC},
 						AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
+						Namespace:    "pl",
 					},
 				},
 			}
@@ -204,6 +208,7 @@ func TestAutocompleteService_AutocompleteField(t *testing.T) {
 				Input:      "px/svc_info",
 				FieldType:  cloudpb.AEK_SVC,
 				ClusterUID: "test",
+				Namespace:  "pl",
 			})
 			require.NoError(t, err)
 			assert.NotNil(t, resp)
@@ -82,20 +82,20 @@ var protoToKindLabelMap = map[cloudpb.AutocompleteEntityKind]string{
 }
 
 // Autocomplete returns a formatted string and suggestions for the given input.
-func Autocomplete(input string, cursorPos int, action cloudpb.AutocompleteActionType, s Suggester, orgID uuid.UUID, clusterUID string) (string, bool, []*cloudpb.TabSuggestion, error) {
+func Autocomplete(input string, cursorPos int, action cloudpb.AutocompleteActionType, s Suggester, orgID uuid.UUID, clusterUID string, namespace string) (string, bool, []*cloudpb.TabSuggestion, error) {
 	inputWithCursor := input[:cursorPos] + "$0" + input[cursorPos:]
-	cmd, err := ParseIntoCommand(inputWithCursor, s, orgID, clusterUID)
+	cmd, err := ParseIntoCommand(inputWithCursor, s, orgID, clusterUID, namespace)
 	if err != nil {
 		return "", false, nil, err
 	}
 
-	fmtOutput, suggestions := cmd.ToFormatString(action, s, orgID, clusterUID)
+	fmtOutput, suggestions := cmd.ToFormatString(action, s, orgID, clusterUID, namespace)
 
 	return fmtOutput, cmd.Executable, suggestions, nil
 }
 
 // ParseIntoCommand takes user input and attempts to parse it into a valid command with suggestions.
-func ParseIntoCommand(input string, s Suggester, orgID uuid.UUID, clusterUID string) (*Command, error) {
+func ParseIntoCommand(input string, s Suggester, orgID uuid.UUID, clusterUID string, namespace string) (*Command, error) {
 	parsedCmd, err := ebnf.ParseInput(input)
 	if err != nil {
 		return nil, err
@@ -118,7 +118,7 @@ func ParseIntoCommand(input string, s Suggester, orgID uuid.UUID, clusterUID str
 	if action == "go" {
 		err = parseGoCommand(parsedCmd, cmd, s)
 	} else {
-		err = parseRunCommand(parsedCmd, cmd, s, orgID, clusterUID)
+		err = parseRunCommand(parsedCmd, cmd, s, orgID, clusterUID, namespace)
 	}
 
 	if err != nil {
@@ -132,7 +132,7 @@ func parseGoCommand(parsedCmd *ebnf.ParsedCmd, cmd *Command, s Suggester) error
 	return errors.New("Not yet implemented")
 }
 
-func parseRunScript(parsedCmd *ebnf.ParsedCmd, cmd *Command, s Suggester, orgID uuid.UUID, clusterUID string) (int, []string, []cloudpb.AutocompleteEntityKind, error) {
+func parseRunScript(parsedCmd *ebnf.ParsedCmd, cmd *Command, s Suggester, orgID uuid.UUID, clusterUID string, namespace string) (int, []string, []cloudpb.AutocompleteEntityKind, error) {
 	// The TabStop after the action should be the script. Check if there are any scripts defined.
 	argNames := make([]string, 0)
 	argTypes := make([]cloudpb.AutocompleteEntityKind, 0)
@@ -150,7 +150,14 @@ func parseRunScript(parsedCmd *ebnf.ParsedCmd, cmd *Command, s Suggester, orgID
 				searchTerm = strings.Replace(searchTerm, CursorMarker, "", 1)
 			}
 
-			res, err := s.GetSuggestions([]*SuggestionRequest{{orgID, clusterUID, searchTerm, []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT}, []cloudpb.AutocompleteEntityKind{}}})
+			res, err := s.GetSuggestions([]*SuggestionRequest{{
+				OrgID:        orgID,
+				ClusterUID:   clusterUID,
+				Input:        searchTerm,
+				AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT},
+				AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
+				Namespace:    namespace,
+			}})
 			if err != nil {
 				return -1, nil, nil, err
 			}
@@ -347,12 +354,12 @@ func validateCommand(scriptDefined bool, cmd *Command) {
 	}
 }
 
-func parseRunCommand(parsedCmd *ebnf.ParsedCmd, cmd *Command, s Suggester, orgID uuid.UUID, clusterUID string) error {
+func parseRunCommand(parsedCmd *ebnf.ParsedCmd, cmd *Command, s Suggester, orgID uuid.UUID, clusterUID string, namespace string) error {
 	if parsedCmd.Args == nil {
 		return nil
 	}
 
-	scriptTabIndex, argNames, argTypes, err := parseRunScript(parsedCmd, cmd, s, orgID, clusterUID)
+	scriptTabIndex, argNames, argTypes, err := parseRunScript(parsedCmd, cmd, s, orgID, clusterUID, namespace)
 	if err != nil {
 		return err
 	}
@@ -382,7 +389,14 @@ func parseRunCommand(parsedCmd *ebnf.ParsedCmd, cmd *Command, s Suggester, orgID
 		if a.ContainsCursor {
 			searchTerm = strings.Replace(searchTerm, CursorMarker, "", 1)
 		}
-		reqs = append(reqs, &SuggestionRequest{orgID, clusterUID, searchTerm, ak, specifiedEntities})
+		reqs = append(reqs, &SuggestionRequest{
+			OrgID:        orgID,
+			ClusterUID:   clusterUID,
+			Input:        searchTerm,
+			AllowedKinds: ak,
+			AllowedArgs:  specifiedEntities,
+			Namespace:    namespace,
+		})
 	}
 
 	res, err := s.GetSuggestions(reqs)
@@ -403,7 +417,7 @@ func parseRunCommand(parsedCmd *ebnf.ParsedCmd, cmd *Command, s Suggester, orgID
 }
 
 // ToFormatString converts a command to a formatted string with tab indexes, such as: ${1:run} ${2: px/svc_info}
-func (cmd *Command) ToFormatString(action cloudpb.AutocompleteActionType, s Suggester, orgID uuid.UUID, clusterUID string) (string, []*cloudpb.TabSuggestion) {
+func (cmd *Command) ToFormatString(action cloudpb.AutocompleteActionType, s Suggester, orgID uuid.UUID, clusterUID string, namespace string) (string, []*cloudpb.TabSuggestion) {
 	curTabStop, nextInvalidTabStop, invalidTabs := cmd.processTabStops()
 
 	// Move the cursor according to the action that was taken.
@@ -436,9 +450,13 @@ func (cmd *Command) ToFormatString(action cloudpb.AutocompleteActionType, s Sugg
 			for k := range knownTypes {
 				scriptTypes = append(scriptTypes, k)
 			}
-			res, err := s.GetSuggestions([]*SuggestionRequest{{orgID, clusterUID, "",
-				[]cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT},
-				scriptTypes}})
+			res, err := s.GetSuggestions([]*SuggestionRequest{{
+				OrgID:        orgID,
+				ClusterUID:   clusterUID,
+				AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT},
+				AllowedArgs:  scriptTypes,
+				Namespace:    namespace,
+			}})
 			if err == nil {
 				cmd.TabStops[curTabStop].Suggestions = res[0].Suggestions
 			}
@@ -804,7 +804,7 @@ func TestParseIntoCommand(t *testing.T) {
 				}).
 				Times(len(test.requests))
 
-			cmd, err := autocomplete.ParseIntoCommand(test.input, s, orgID, "test")
+			cmd, err := autocomplete.ParseIntoCommand(test.input, s, orgID, "test", "")
 			require.NoError(t, err)
 			assert.NotNil(t, cmd)
 
@@ -1280,9 +1280,10 @@ func TestToFormatString(t *testing.T) {
 				s.EXPECT().
 					GetSuggestions([]*autocomplete.SuggestionRequest{
 						{
-							orgID, "test", "",
-							[]cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT},
-							test.suggestionScriptTypes,
+							OrgID:        orgID,
+							ClusterUID:   "test",
+							AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT},
+							AllowedArgs:  test.suggestionScriptTypes,
 						},
 					}).Return([]*autocomplete.SuggestionResult{
 					{
@@ -1297,7 +1298,7 @@ func TestToFormatString(t *testing.T) {
 				}, nil)
 			}
 
-			output, suggestions := test.cmd.ToFormatString(test.action, s, orgID, "test")
+			output, suggestions := test.cmd.ToFormatString(test.action, s, orgID, "test", "")
 			assert.Equal(t, test.expectedStr, output)
 			assert.ElementsMatch(t, test.expectedSuggestions, suggestions)
 		})
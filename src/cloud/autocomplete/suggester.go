@@ -22,6 +22,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
+	"strings"
 
 	"github.com/gofrs/uuid"
 	"github.com/olivere/elastic/v7"
@@ -39,7 +41,9 @@ type ElasticSuggester struct {
 	client          *elastic.Client
 	mdIndexName     string
 	scriptIndexName string
-	pc              profilepb.ProfileServiceClient
+	// Whether to search each org's entities through the org's alias, rather than across every index.
+	orgAliases bool
+	pc         profilepb.ProfileServiceClient
 	// This is temporary, and will be removed once we start indexing scripts.
 	br *script.BundleManager
 }
@@ -86,6 +90,12 @@ func NewElasticSuggester(client *elastic.Client, mdIndex, scriptIndex string, pc
 	}, nil
 }
 
+// UseOrgAliases searches the entities of each org through the org's alias, which the indexer creates when
+// org aliases are enabled on its index manager. This only searches the shards which contain the org's entities.
+func (e *ElasticSuggester) UseOrgAliases() {
+	e.orgAliases = true
+}
+
 // SuggestionRequest is a request for autocomplete suggestions.
 type SuggestionRequest struct {
 	OrgID        uuid.UUID
@@ -93,6 +103,8 @@ type SuggestionRequest struct {
 	Input        string
 	AllowedKinds []cloudpb.AutocompleteEntityKind
 	AllowedArgs  []cloudpb.AutocompleteEntityKind
+	// Namespace restricts the suggestions to entities in the namespace. If empty, all namespaces are searched.
+	Namespace string
 }
 
 // SuggestionResult contains results for an autocomplete request.
@@ -152,9 +164,13 @@ func (e *ElasticSuggester) GetSuggestions(reqs []*SuggestionRequest) ([]*Suggest
 	coll := elastic.NewCollapseBuilder("name.keyword").InnerHit(elastic.NewInnerHit().Size(1).Name("collapse").Sort("updateVersion", false))
 
 	for _, r := range reqs {
-		ms.Add(elastic.NewSearchRequest().
+		req := elastic.NewSearchRequest().
 			Highlight(highlight).
-			Query(e.getQueryForRequest(r.OrgID, r.ClusterUID, r.Input, r.AllowedKinds, r.AllowedArgs)).FetchSourceIncludeExclude([]string{"kind", "name", "ns", "state", "updateVersion"}, []string{}).Collapse(coll).Size(searchLimit))
+			Query(e.getQueryForRequest(r.OrgID, r.ClusterUID, r.Namespace, r.Input, r.AllowedKinds, r.AllowedArgs)).FetchSourceIncludeExclude([]string{"kind", "name", "ns", "state", "updateVersion"}, []string{}).Collapse(coll).Size(searchLimit)
+		if e.orgAliases {
+			req = req.Index(md.OrgAliasName(e.mdIndexName, r.OrgID)).Routing(r.OrgID.String())
+		}
+		ms.Add(req)
 	}
 
 	resp, err := ms.Do(context.Background())
@@ -270,10 +286,9 @@ func (e *ElasticSuggester) GetSuggestions(reqs []*SuggestionRequest) ([]*Suggest
 				matchedIndexes = append(matchedIndexes, parseHighlightIndexes(h.Highlight["name"][0], 0)...)
 			}
 
-			// TODO(michellenguyen): Remove namespace handling when we create a new index and ensure there are no more
-			// documents with namespace.
+			// Older documents only have the namespace in the NS field, rather than also prefixed to the name.
 			resName := res.Name
-			if res.NS != "" && !(md.EsMDType(res.Kind) == md.EsMDTypeNamespace || md.EsMDType(res.Kind) == md.EsMDTypeNode) {
+			if res.NS != "" && !strings.HasPrefix(res.Name, res.NS+"/") && !(md.EsMDType(res.Kind) == md.EsMDTypeNamespace || md.EsMDType(res.Kind) == md.EsMDTypeNode) {
 				resName = fmt.Sprintf("%s/%s", res.NS, res.Name)
 			}
 
@@ -312,16 +327,16 @@ func (e *ElasticSuggester) GetSuggestions(reqs []*SuggestionRequest) ([]*Suggest
 	return resps, nil
 }
 
-func (e *ElasticSuggester) getQueryForRequest(orgID uuid.UUID, clusterUID string, input string, allowedKinds []cloudpb.AutocompleteEntityKind, allowedArgs []cloudpb.AutocompleteEntityKind) *elastic.BoolQuery {
+func (e *ElasticSuggester) getQueryForRequest(orgID uuid.UUID, clusterUID string, namespace string, input string, allowedKinds []cloudpb.AutocompleteEntityKind, allowedArgs []cloudpb.AutocompleteEntityKind) *elastic.BoolQuery {
 	q := elastic.NewBoolQuery()
 
-	q.Should(e.getMDEntityQuery(orgID, clusterUID, input, allowedKinds))
+	q.Should(e.getMDEntityQuery(orgID, clusterUID, namespace, input, allowedKinds))
 
 	// Once script indexing is in, we should also query the scripts: q.Should(e.getScriptQuery(orgID, input, allowedArgs))
 	return q
 }
 
-func (e *ElasticSuggester) getMDEntityQuery(orgID uuid.UUID, clusterUID string, input string, allowedKinds []cloudpb.AutocompleteEntityKind) *elastic.BoolQuery {
+func (e *ElasticSuggester) getMDEntityQuery(orgID uuid.UUID, clusterUID string, namespace string, input string, allowedKinds []cloudpb.AutocompleteEntityKind) *elastic.BoolQuery {
 	entityQuery := elastic.NewBoolQuery()
 	entityQuery.Must(elastic.NewTermQuery("_index", e.mdIndexName))
 
@@ -333,7 +348,13 @@ func (e *ElasticSuggester) getMDEntityQuery(orgID uuid.UUID, clusterUID string,
 	entityQuery.Should(elastic.NewBoostingQuery().Positive(positiveQuery).Negative(negativeQuery).NegativeBoost(0.7))
 
 	// If the user hasn't provided any input string, don't run bother running a match query.
-	if len(input) >= 1 {
+	if net.ParseIP(input) != nil {
+		// An IP finds the pod or service which it is assigned to.
+		entityQuery.Must(elastic.NewBoolQuery().Should(elastic.NewTermQuery("podIP", input)).Should(elastic.NewTermQuery("clusterIP", input)))
+	} else if strings.Contains(input, "=") {
+		// A key=value pair finds the entities with the label.
+		entityQuery.Must(elastic.NewTermQuery("labels", input))
+	} else if len(input) >= 1 {
 		entityQuery.Must(elastic.NewMatchQuery("name", input))
 	}
 
@@ -344,6 +365,10 @@ func (e *ElasticSuggester) getMDEntityQuery(orgID uuid.UUID, clusterUID string,
 		entityQuery.Must(elastic.NewTermQuery("clusterUID", clusterUID))
 	}
 
+	if namespace != "" {
+		entityQuery.Filter(elastic.NewTermQuery("ns", namespace))
+	}
+
 	// Only search for allowed kinds.
 	kindsQuery := elastic.NewBoolQuery()
 	for _, k := range allowedKinds {
@@ -81,6 +81,40 @@ var mdEntities = []md.EsMDEntity{
 		RelatedEntityNames: []string{},
 		State:              md.ESMDEntityStateTerminated,
 	},
+	{
+		OrgID:              org1.String(),
+		UID:                "svc4",
+		NS:                 "scoped",
+		Name:               "scoped/frontend",
+		Kind:               "service",
+		TimeStartedNS:      1,
+		TimeStoppedNS:      0,
+		RelatedEntityNames: []string{},
+	},
+	{
+		OrgID:              org1.String(),
+		UID:                "svc5",
+		NS:                 "other",
+		Name:               "other/frontend",
+		Kind:               "service",
+		TimeStartedNS:      1,
+		TimeStoppedNS:      0,
+		RelatedEntityNames: []string{},
+	},
+	{
+		OrgID:              org1.String(),
+		UID:                "pod2",
+		NS:                 "pl",
+		Name:               "pl/checkout-5d4f8",
+		Kind:               "pod",
+		TimeStartedNS:      1,
+		TimeStoppedNS:      0,
+		RelatedEntityNames: []string{},
+		State:              md.ESMDEntityStateRunning,
+		PodIP:              "10.16.1.12",
+		HostIP:             "10.128.0.3",
+		Labels:             []string{"app=checkout", "team=payments"},
+	},
 	{
 		OrgID:              org1.String(),
 		UID:                "ns1",
@@ -166,7 +200,7 @@ func TestMain(m *testing.M) {
 	elasticClient = es
 
 	// Set up elastic indexes.
-	err = md.InitializeMapping(es, indexName, 1)
+	err = md.InitializeMapping(es, indexName, 1, nil)
 	if err != nil {
 		cleanup()
 		log.Fatal(err)
@@ -260,6 +294,84 @@ func TestGetSuggestions(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "namespace filter",
+			reqs: []*autocomplete.SuggestionRequest{
+				{
+					Input:     "frontend",
+					OrgID:     org1,
+					Namespace: "scoped",
+					AllowedKinds: []cloudpb.AutocompleteEntityKind{
+						cloudpb.AEK_SVC,
+					},
+					AllowedArgs: []cloudpb.AutocompleteEntityKind{},
+				},
+			},
+			expectedResults: []*autocomplete.SuggestionResult{
+				{
+					ExactMatch:           false,
+					HasAdditionalMatches: false,
+					Suggestions: []*autocomplete.Suggestion{
+						{
+							Name: "scoped/frontend",
+							Kind: cloudpb.AEK_SVC,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "ip",
+			reqs: []*autocomplete.SuggestionRequest{
+				{
+					Input: "10.16.1.12",
+					OrgID: org1,
+					AllowedKinds: []cloudpb.AutocompleteEntityKind{
+						cloudpb.AEK_SVC, cloudpb.AEK_POD,
+					},
+					AllowedArgs: []cloudpb.AutocompleteEntityKind{},
+				},
+			},
+			expectedResults: []*autocomplete.SuggestionResult{
+				{
+					ExactMatch:           false,
+					HasAdditionalMatches: false,
+					Suggestions: []*autocomplete.Suggestion{
+						{
+							Name:  "pl/checkout-5d4f8",
+							Kind:  cloudpb.AEK_POD,
+							State: cloudpb.AES_RUNNING,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "label",
+			reqs: []*autocomplete.SuggestionRequest{
+				{
+					Input: "team=payments",
+					OrgID: org1,
+					AllowedKinds: []cloudpb.AutocompleteEntityKind{
+						cloudpb.AEK_SVC, cloudpb.AEK_POD,
+					},
+					AllowedArgs: []cloudpb.AutocompleteEntityKind{},
+				},
+			},
+			expectedResults: []*autocomplete.SuggestionResult{
+				{
+					ExactMatch:           false,
+					HasAdditionalMatches: false,
+					Suggestions: []*autocomplete.Suggestion{
+						{
+							Name:  "pl/checkout-5d4f8",
+							Kind:  cloudpb.AEK_POD,
+							State: cloudpb.AES_RUNNING,
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "additional_matches",
 			reqs: []*autocomplete.SuggestionRequest{
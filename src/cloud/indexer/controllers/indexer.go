@@ -21,6 +21,7 @@ package controllers
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/nats-io/nats.go"
@@ -36,6 +37,11 @@ import (
 // The topic on which updates are written to.
 const indexerMetadataTopic = "MetadataIndex"
 
+// MetadataTopic returns the topic on which the metadata updates of the cluster with the given UID are written.
+func MetadataTopic(k8sUID string) string {
+	return fmt.Sprintf("%s.%s", indexerMetadataTopic, k8sUID)
+}
+
 type concurrentIndexersMap struct {
 	unsafeMap map[string]*md.VizierIndexer
 	mapMu     sync.RWMutex
@@ -69,27 +75,39 @@ func (c *concurrentIndexersMap) values() []*md.VizierIndexer {
 type Indexer struct {
 	clusters *concurrentIndexersMap // Map from cluster UID->indexer.
 
-	st        msgbus.Streamer
-	es        *elastic.Client
-	indexName string
+	st      msgbus.Streamer
+	es      *elastic.Client
+	indices *md.IndexManager
+
+	// When the updates of each cluster are flushed to elastic.
+	actionsPerBatch    int
+	batchFlushInterval time.Duration
+	// The number of workers which index the updates of each cluster.
+	workers int
 
 	watcher *vzutils.Watcher
 }
 
 // NewIndexer creates a new Vizier indexer. This is a wrapper around the Vizier Watcher, which starts the indexer
-// for any active viziers.
-func NewIndexer(nc *nats.Conn, vzmgrClient vzmgrpb.VZMgrServiceClient, st msgbus.Streamer, es *elastic.Client, indexName, fromShardID, toShardID string) (*Indexer, error) {
+// for any active viziers. The updates of each vizier are flushed to elastic once there are actionsPerBatch of them,
+// or batchFlushInterval has passed since they were last flushed, by each of the vizier's workers.
+func NewIndexer(nc *nats.Conn, vzmgrClient vzmgrpb.VZMgrServiceClient, st msgbus.Streamer, es *elastic.Client, indices *md.IndexManager,
+	actionsPerBatch int, batchFlushInterval time.Duration, workers int, fromShardID, toShardID string) (*Indexer, error) {
 	watcher, err := vzutils.NewWatcher(nc, vzmgrClient, fromShardID, toShardID)
 	if err != nil {
 		return nil, err
 	}
 
 	i := &Indexer{
-		clusters:  &concurrentIndexersMap{unsafeMap: make(map[string]*md.VizierIndexer)},
-		watcher:   watcher,
-		st:        st,
-		es:        es,
-		indexName: indexName,
+		clusters: &concurrentIndexersMap{unsafeMap: make(map[string]*md.VizierIndexer)},
+		watcher:  watcher,
+		st:       st,
+		es:       es,
+		indices:  indices,
+
+		actionsPerBatch:    actionsPerBatch,
+		batchFlushInterval: batchFlushInterval,
+		workers:            workers,
 	}
 
 	err = watcher.RegisterVizierHandler(i.handleVizier)
@@ -117,8 +135,8 @@ func (i *Indexer) handleVizier(id uuid.UUID, orgID uuid.UUID, uid string) error
 	}
 
 	// Start indexer.
-	vzIndexer := md.NewVizierIndexer(id, orgID, uid, i.indexName, i.st, i.es)
-	err := vzIndexer.Start(fmt.Sprintf("%s.%s", indexerMetadataTopic, uid))
+	vzIndexer := md.NewVizierIndexerWithBulkSettings(id, orgID, uid, i.indices, i.st, i.es, i.actionsPerBatch, i.batchFlushInterval, i.workers)
+	err := vzIndexer.Start(MetadataTopic(uid))
 	if err != nil {
 		log.WithField("UID", uid).WithError(err).Error("Could not set up Vizier watcher for metadata updates")
 		return err
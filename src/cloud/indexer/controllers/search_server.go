@@ -0,0 +1,98 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/cloud/indexer/indexerpb"
+	"px.dev/pixie/src/cloud/indexer/md"
+	"px.dev/pixie/src/utils"
+)
+
+// SearchServer is the gRPC server which searches the indexed metadata entities.
+type SearchServer struct {
+	indices *md.IndexManager
+}
+
+// NewSearchServer creates a server which searches the entities in the managed indices.
+func NewSearchServer(indices *md.IndexManager) *SearchServer {
+	return &SearchServer{
+		indices: indices,
+	}
+}
+
+// SearchEntities searches the entities of an org.
+func (s *SearchServer) SearchEntities(ctx context.Context, req *indexerpb.SearchEntitiesRequest) (*indexerpb.SearchEntitiesResponse, error) {
+	orgID, err := utils.UUIDFromProto(req.OrgID)
+	if err != nil || utils.IsNilUUID(orgID) {
+		return nil, status.Error(codes.InvalidArgument, "org ID is required")
+	}
+	if req.StartTimeNS > 0 && req.EndTimeNS > 0 && req.StartTimeNS > req.EndTimeNS {
+		return nil, status.Error(codes.InvalidArgument, "the start of the time range must not be after its end")
+	}
+
+	search := &md.EntitySearch{
+		OrgID:       orgID,
+		ClusterUID:  req.ClusterUID,
+		NamePrefix:  req.NamePrefix,
+		Namespace:   req.Namespace,
+		StartTimeNS: req.StartTimeNS,
+		EndTimeNS:   req.EndTimeNS,
+		Limit:       int(req.Limit),
+	}
+	for _, k := range req.Kinds {
+		search.Kinds = append(search.Kinds, md.EsMDType(k))
+	}
+	// The entity states of the API are numbered the same as the states in the index.
+	for _, state := range req.States {
+		search.States = append(search.States, md.ESMDEntityState(state))
+	}
+
+	entities, hasMore, err := s.indices.SearchEntities(ctx, search)
+	if err != nil {
+		log.WithError(err).Error("Failed to search metadata entities")
+		return nil, status.Error(codes.Internal, "failed to search metadata entities")
+	}
+
+	resp := &indexerpb.SearchEntitiesResponse{
+		Entities: make([]*indexerpb.Entity, len(entities)),
+		HasMore:  hasMore,
+	}
+	for i, e := range entities {
+		resp.Entities[i] = &indexerpb.Entity{
+			UID:               e.UID,
+			Name:              e.Name,
+			Namespace:         e.NS,
+			Kind:              e.Kind,
+			State:             indexerpb.EntityState(e.State),
+			VizierID:          utils.ProtoFromUUIDStrOrNil(e.VizierID),
+			ClusterUID:        e.ClusterUID,
+			StartTimeNS:       e.TimeStartedNS,
+			StopTimeNS:        e.TimeStoppedNS,
+			Labels:            e.Labels,
+			RelatedEntityUIDs: e.RelatedEntityNames,
+		}
+	}
+	return resp, nil
+}
@@ -31,6 +31,7 @@ import (
 	"google.golang.org/grpc"
 
 	"px.dev/pixie/src/cloud/indexer/controllers"
+	"px.dev/pixie/src/cloud/indexer/indexerpb"
 	"px.dev/pixie/src/cloud/indexer/md"
 	"px.dev/pixie/src/cloud/shared/esutils"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
@@ -44,14 +45,36 @@ import (
 
 func init() {
 	pflag.String("es_url", "https://pl-elastic-es-http:9200", "The URL for the elastic cluster")
-	pflag.String("es_ca_cert", "/es-certs/tls.crt", "The CA cert for elastic")
+	pflag.String("es_ca_cert", "/es-certs/tls.crt", "The CA cert for elastic. If empty, the system's root CAs are used")
+	pflag.String("es_client_cert", "", "The client cert for mTLS with elastic")
+	pflag.String("es_client_key", "", "The client key for mTLS with elastic")
 	pflag.String("es_user", "elastic", "The user for elastic")
 	pflag.String("es_passwd", "elastic", "The password for elastic")
+	pflag.String("es_api_key", "", "The API key for elastic. If specified, it is used instead of es_user and es_passwd")
+	pflag.String("es_distribution", string(esutils.DistributionElasticsearch), "The distribution of the elastic cluster, either elasticsearch or opensearch")
 	pflag.String("vzmgr_service", "kubernetes:///vzmgr-service.plc:51800", "The profile service url (load balancer/list is ok)")
 	pflag.String("domain_name", "dev.withpixie.dev", "The domain name of Pixie Cloud")
 
 	pflag.String("md_index_name", "", "The elastic index name for metadata.")
+	pflag.String("md_index_name_template", "", "An optional template for date-based metadata index names, ex: md-{org}-{yyyy.MM}. "+
+		"If specified, indices are created on demand and md_index_name is used as the alias that spans them.")
+	pflag.Bool("md_index_org_aliases", false, "Whether to route the metadata of each org to a single shard and add it to a per-org alias. "+
+		"Requires md_index_name_template, ex: md-{shard:8}-{yyyy.MM}.")
 	pflag.Int("md_index_replicas", 4, "The number of replicas to setup for the metadata index.")
+	pflag.String("md_index_max_size", "", "The size at which the metadata index is rolled over to a new index, ex: 50gb. "+
+		"If rollover is enabled, md_index_name is the alias that the rolled over indices are written through.")
+	pflag.String("md_index_max_age", "", "The age at which the metadata index is rolled over to a new index, ex: 7d.")
+	pflag.String("md_index_delete_after", "", "How long after rollover, or after creation for templated indices, to delete "+
+		"metadata indices, ex: 30d. If empty, metadata indices are never deleted.")
+	pflag.Int("md_bulk_max_actions", md.DefaultMaxActionsPerBatch, "The number of metadata updates of a cluster after which they are flushed to elastic.")
+	pflag.Duration("md_bulk_flush_interval", md.DefaultMaxActionBatchFlushInterval, "The time after which the metadata updates of a cluster are flushed to elastic.")
+	pflag.Int("md_workers", md.DefaultWorkers, "The number of workers which index the metadata updates of a cluster concurrently. "+
+		"The updates of each entity are indexed in order by the same worker.")
+	pflag.String("md_jetstream_stream", "", "The JetStream stream which stores the metadata updates. If empty, the updates are consumed from STAN.")
+	pflag.Duration("md_jetstream_ack_wait", msgbus.DefaultJetStreamStreamerConfig.AckWait, "The time after which a metadata update which was not acked is redelivered by JetStream.")
+	pflag.Int("md_jetstream_max_inflight", md.DefaultMaxActionsPerBatch, "The number of unacked metadata updates of a cluster which are delivered by JetStream at a time.")
+	pflag.Duration("md_entity_retention", 0, "How long to keep terminated entities in the metadata index, ex: 720h. If zero, terminated entities are never deleted.")
+	pflag.Duration("md_entity_gc_interval", md.DefaultEntityGCInterval, "The time between deletions of the terminated entities which are older than md_entity_retention.")
 }
 
 func newVZMgrClient() (vzmgrpb.VZMgrServiceClient, error) {
@@ -72,10 +95,14 @@ func mustConnectElastic() *elastic.Client {
 	esURL := viper.GetString("es_url")
 
 	es, err := esutils.NewEsClient(&esutils.Config{
-		URL:        []string{esURL},
-		User:       viper.GetString("es_user"),
-		Passwd:     viper.GetString("es_passwd"),
-		CaCertFile: viper.GetString("es_ca_cert"),
+		URL:            []string{esURL},
+		User:           viper.GetString("es_user"),
+		Passwd:         viper.GetString("es_passwd"),
+		APIKey:         viper.GetString("es_api_key"),
+		CaCertFile:     viper.GetString("es_ca_cert"),
+		ClientCertFile: viper.GetString("es_client_cert"),
+		ClientKeyFile:  viper.GetString("es_client_key"),
+		Distribution:   esutils.Distribution(viper.GetString("es_distribution")),
 	})
 
 	if err != nil {
@@ -84,6 +111,29 @@ func mustConnectElastic() *elastic.Client {
 	return es
 }
 
+// mustConnectStreamer connects to the streamer which the metadata updates are consumed from, which is JetStream if
+// a stream is specified, and STAN otherwise.
+func mustConnectStreamer(nc *nats.Conn) msgbus.Streamer {
+	if stream := viper.GetString("md_jetstream_stream"); stream != "" {
+		strmr, err := msgbus.NewJetStreamStreamerWithConfig(nc, msgbus.JetStreamStreamerConfig{
+			Stream:      stream,
+			AckWait:     viper.GetDuration("md_jetstream_ack_wait"),
+			MaxInflight: viper.GetInt("md_jetstream_max_inflight"),
+		})
+		if err != nil {
+			log.WithError(err).Fatal("Could not connect to JetStream")
+		}
+		return strmr
+	}
+
+	sc := msgbus.MustConnectSTAN(nc, uuid.Must(uuid.NewV4()).String())
+	strmr, err := msgbus.NewSTANStreamer(sc)
+	if err != nil {
+		log.Fatal("Could not connect to streamer")
+	}
+	return strmr
+}
+
 func main() {
 	services.SetupService("indexer-service", 51800)
 	services.PostFlagSetupAndParse()
@@ -101,12 +151,7 @@ func main() {
 
 	s := server.NewPLServer(env.New(viper.GetString("domain_name")), mux)
 	nc := msgbus.MustConnectNATS()
-	sc := msgbus.MustConnectSTAN(nc, uuid.Must(uuid.NewV4()).String())
-
-	strmr, err := msgbus.NewSTANStreamer(sc)
-	if err != nil {
-		log.Fatal("Could not connect to streamer")
-	}
+	strmr := mustConnectStreamer(nc)
 
 	nc.SetErrorHandler(func(conn *nats.Conn, subscription *nats.Subscription, err error) {
 		log.WithError(err).
@@ -122,9 +167,33 @@ func main() {
 	}
 	replicas := viper.GetInt("md_index_replicas")
 
-	err = md.InitializeMapping(es, indexName, replicas)
+	indexNameTemplate := md.IndexNameTemplate(viper.GetString("md_index_name_template"))
+	lifecycle := &md.IndexLifecycle{
+		MaxIndexSize: viper.GetString("md_index_max_size"),
+		MaxIndexAge:  viper.GetString("md_index_max_age"),
+		DeleteAfter:  viper.GetString("md_index_delete_after"),
+	}
+	err := lifecycle.Validate(indexNameTemplate)
 	if err != nil {
-		log.WithError(err).Fatal("Could not initialize elastic mapping")
+		log.WithError(err).Fatal("Invalid metadata index lifecycle")
+	}
+	if lifecycle.Managed() && !esutils.Distribution(viper.GetString("es_distribution")).SupportsILM() {
+		log.Fatal("The metadata index lifecycle is not supported by the elastic distribution.")
+	}
+	if indexNameTemplate == "" {
+		err = md.InitializeMapping(es, indexName, replicas, lifecycle)
+		if err != nil {
+			log.WithError(err).Fatal("Could not initialize elastic mapping")
+		}
+	} else if err = indexNameTemplate.Validate(); err != nil {
+		log.WithError(err).Fatal("Invalid metadata index name template")
+	}
+	indices := md.NewIndexManager(es, indexName, indexNameTemplate, replicas, lifecycle)
+	if viper.GetBool("md_index_org_aliases") {
+		err = indices.EnableOrgAliases()
+		if err != nil {
+			log.WithError(err).Fatal("Could not enable metadata org aliases")
+		}
 	}
 
 	vzmgrClient, err := newVZMgrClient()
@@ -132,13 +201,35 @@ func main() {
 		log.WithError(err).Fatal("Could not connect to vzmgr")
 	}
 
-	indexer, err := controllers.NewIndexer(nc, vzmgrClient, strmr, es, indexName, "00", "ff")
+	actionsPerBatch := viper.GetInt("md_bulk_max_actions")
+	batchFlushInterval := viper.GetDuration("md_bulk_flush_interval")
+	if actionsPerBatch <= 0 || batchFlushInterval <= 0 {
+		log.Fatal("The metadata bulk settings must be positive.")
+	}
+	workers := viper.GetInt("md_workers")
+	if workers <= 0 {
+		log.Fatal("The number of metadata workers must be positive.")
+	}
+
+	indexer, err := controllers.NewIndexer(nc, vzmgrClient, strmr, es, indices, actionsPerBatch, batchFlushInterval, workers, "00", "ff")
 	if err != nil {
 		log.WithError(err).Fatal("Could not start indexer")
 	}
 
 	defer indexer.Stop()
 
+	if retention := viper.GetDuration("md_entity_retention"); retention > 0 {
+		gcInterval := viper.GetDuration("md_entity_gc_interval")
+		if gcInterval <= 0 {
+			log.Fatal("The metadata entity GC interval must be positive.")
+		}
+		collector := md.NewEntityCollector(es, indices, retention, gcInterval)
+		collector.Start()
+		defer collector.Stop()
+	}
+
+	indexerpb.RegisterMetadataSearchServiceServer(s.GRPCServer(), controllers.NewSearchServer(indices))
+
 	s.Start()
 	s.StopOnInterrupt()
 }
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package md
+
+import (
+	"reflect"
+	"sync"
+
+	"px.dev/pixie/src/shared/k8s/metadatapb"
+)
+
+// EntityConverter converts a kind of resource update to the entity which is indexed for it.
+type EntityConverter interface {
+	// Convert returns the entity for the update, or nil if the update should not be indexed. The org, vizier and
+	// cluster of the entity, along with its update and doc versions, are set by the indexer.
+	Convert(update *metadatapb.ResourceUpdate) *EsMDEntity
+}
+
+// EntityConverterFunc is an EntityConverter which is implemented by a function.
+type EntityConverterFunc func(update *metadatapb.ResourceUpdate) *EsMDEntity
+
+// Convert converts the update by calling the function.
+func (f EntityConverterFunc) Convert(update *metadatapb.ResourceUpdate) *EsMDEntity {
+	return f(update)
+}
+
+var (
+	convertersMu sync.RWMutex
+	// The converters are keyed by the type of the update in the resource update, ex: *metadatapb.ResourceUpdate_PodUpdate.
+	converters = map[reflect.Type]EntityConverter{
+		reflect.TypeOf(&metadatapb.ResourceUpdate_NamespaceUpdate{}):  EntityConverterFunc(nsUpdateToEMD),
+		reflect.TypeOf(&metadatapb.ResourceUpdate_PodUpdate{}):        EntityConverterFunc(podUpdateToEMD),
+		reflect.TypeOf(&metadatapb.ResourceUpdate_ServiceUpdate{}):    EntityConverterFunc(serviceUpdateToEMD),
+		reflect.TypeOf(&metadatapb.ResourceUpdate_NodeUpdate{}):       EntityConverterFunc(nodeUpdateToEMD),
+		reflect.TypeOf(&metadatapb.ResourceUpdate_ContainerUpdate{}):  EntityConverterFunc(containerUpdateToEMD),
+		reflect.TypeOf(&metadatapb.ResourceUpdate_DeploymentUpdate{}): EntityConverterFunc(deploymentUpdateToEMD),
+		reflect.TypeOf(&metadatapb.ResourceUpdate_ReplicaSetUpdate{}): EntityConverterFunc(replicaSetUpdateToEMD),
+	}
+)
+
+// RegisterEntityConverter registers the converter for the resource updates whose update has the same type as the
+// given one, ex: &metadatapb.ResourceUpdate_PodUpdate{}. This allows additional kinds of entities to be indexed, and
+// is expected to be called before any indexers are started. The converter which is replaced, if any, is returned,
+// so that the new converter can extend it.
+func RegisterEntityConverter(update interface{}, c EntityConverter) EntityConverter {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	t := reflect.TypeOf(update)
+	prev := converters[t]
+	converters[t] = c
+	return prev
+}
+
+// entityConverter returns the converter for the resource update, or nil if its kind is not indexed.
+func entityConverter(update *metadatapb.ResourceUpdate) EntityConverter {
+	if update.Update == nil {
+		return nil
+	}
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+	return converters[reflect.TypeOf(update.Update)]
+}
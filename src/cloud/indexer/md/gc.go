@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package md
+
+import (
+	"context"
+	"time"
+
+	"github.com/olivere/elastic/v7"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultEntityGCInterval is the default time between deletions of the terminated entities.
+const DefaultEntityGCInterval = time.Hour
+
+// EntityCollector periodically deletes the entities which were terminated longer than the retention period ago,
+// so that the index does not grow without bound as pods and namespaces come and go.
+type EntityCollector struct {
+	es        *elastic.Client
+	indices   *IndexManager
+	retention time.Duration
+	interval  time.Duration
+
+	quitCh chan bool
+}
+
+// NewEntityCollector creates a new collector for the entities in the managed indices.
+func NewEntityCollector(es *elastic.Client, indices *IndexManager, retention time.Duration, interval time.Duration) *EntityCollector {
+	return &EntityCollector{
+		es:        es,
+		indices:   indices,
+		retention: retention,
+		interval:  interval,
+		quitCh:    make(chan bool),
+	}
+}
+
+// Start starts deleting the terminated entities in the background.
+func (c *EntityCollector) Start() {
+	go func() {
+		t := time.NewTicker(c.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-c.quitCh:
+				return
+			case <-t.C:
+				deleted, err := c.Collect(time.Now())
+				if err != nil {
+					log.WithError(err).Error("Failed to delete terminated entities")
+					continue
+				}
+				log.WithField("deleted", deleted).Info("Deleted terminated entities")
+			}
+		}
+	}()
+}
+
+// Stop stops the collector.
+func (c *EntityCollector) Stop() {
+	close(c.quitCh)
+}
+
+// Collect deletes the entities which were terminated longer than the retention period before now, and returns the
+// number of deleted entities.
+func (c *EntityCollector) Collect(now time.Time) (int64, error) {
+	cutoff := now.Add(-c.retention).UnixNano()
+	q := elastic.NewBoolQuery().Filter(elastic.NewRangeQuery("timeStoppedNS").Gt(0).Lt(cutoff))
+	// Entities which are updated while being deleted are skipped, and are deleted by the next collection if they
+	// are still terminated.
+	resp, err := c.es.DeleteByQuery(c.indices.Alias()).
+		Query(q).
+		Conflicts("proceed").
+		Do(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	return resp.Deleted, nil
+}
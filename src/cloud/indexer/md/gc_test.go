@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package md_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/indexer/md"
+)
+
+func TestEntityCollector_Collect(t *testing.T) {
+	ctx := context.Background()
+	alias := "test_md_gc"
+	require.NoError(t, md.InitializeMapping(elasticClient, alias, 1, nil))
+
+	now := time.Now()
+	entities := []*md.EsMDEntity{
+		{
+			UID:   "running",
+			Kind:  "pod",
+			State: md.ESMDEntityStateRunning,
+		},
+		{
+			UID:           "recently-terminated",
+			Kind:          "pod",
+			TimeStoppedNS: now.Add(-time.Minute).UnixNano(),
+			State:         md.ESMDEntityStateTerminated,
+		},
+		{
+			UID:           "terminated",
+			Kind:          "pod",
+			TimeStoppedNS: now.Add(-2 * time.Hour).UnixNano(),
+			State:         md.ESMDEntityStateTerminated,
+		},
+	}
+	for _, e := range entities {
+		e.OrgID = orgID.String()
+		e.RelatedEntityNames = []string{}
+		_, err := elasticClient.Index().Index(alias).Id(e.UID).BodyJson(e).Refresh("true").Do(ctx)
+		require.NoError(t, err)
+	}
+
+	c := md.NewEntityCollector(elasticClient, md.NewIndexManager(elasticClient, alias, "", 1, nil), time.Hour, md.DefaultEntityGCInterval)
+	deleted, err := c.Collect(now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	_, err = elasticClient.Refresh(alias).Do(ctx)
+	require.NoError(t, err)
+	for _, e := range entities {
+		exists, err := elasticClient.Exists().Index(alias).Id(e.UID).Do(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, e.UID != "terminated", exists, e.UID)
+	}
+}
@@ -0,0 +1,357 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package md
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/olivere/elastic/v7"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	orgPlaceholder = "org"
+	// How often the alias is checked for a rollover to a new index, when the indices are rolled over.
+	rolloverCheckInterval = time.Minute
+)
+
+var (
+	templatePlaceholderRegex = regexp.MustCompile(`\{([^{}]*)\}`)
+	shardPlaceholderRegex    = regexp.MustCompile(`^shard:([1-9][0-9]*)$`)
+	datePlaceholderRegex     = regexp.MustCompile(`^(yyyy|MM|dd|HH|[._-])+$`)
+	// Converts the date tokens accepted in a template into the equivalent go time layout.
+	dateLayoutReplacer = strings.NewReplacer("yyyy", "2006", "MM", "01", "dd", "02", "HH", "15")
+)
+
+// IndexNameTemplate is a template for the name of the elastic index that an entity is written to.
+// The template may contain an {org} placeholder, which is replaced by the org ID of the entity, a {shard:N}
+// placeholder, which is replaced by one of N shards that the org ID hashes to, and date placeholders made up of
+// yyyy, MM, dd and HH, which are replaced by the UTC time of the write.
+// For example, "md-{org}-{yyyy.MM}" creates a new index per org every month, and "md-{shard:8}" spreads the orgs
+// across 8 indices.
+type IndexNameTemplate string
+
+// Validate checks that the template only contains supported placeholders.
+func (t IndexNameTemplate) Validate() error {
+	if t == "" {
+		return fmt.Errorf("index name template must not be empty")
+	}
+	for _, m := range templatePlaceholderRegex.FindAllStringSubmatch(string(t), -1) {
+		if m[1] == orgPlaceholder || shardPlaceholderRegex.MatchString(m[1]) || datePlaceholderRegex.MatchString(m[1]) {
+			continue
+		}
+		return fmt.Errorf("unsupported placeholder %s in index name template %s", m[0], t)
+	}
+	return nil
+}
+
+// IndexName returns the name of the index for the given org at the given time.
+func (t IndexNameTemplate) IndexName(orgID uuid.UUID, ts time.Time) string {
+	return templatePlaceholderRegex.ReplaceAllStringFunc(string(t), func(p string) string {
+		placeholder := p[1 : len(p)-1]
+		if placeholder == orgPlaceholder {
+			return orgID.String()
+		}
+		if m := shardPlaceholderRegex.FindStringSubmatch(placeholder); m != nil {
+			return orgShard(orgID, m[1])
+		}
+		return ts.UTC().Format(dateLayoutReplacer.Replace(placeholder))
+	})
+}
+
+// orgShard returns the shard, out of the given number of shards, which the org hashes to. Shards are zero padded,
+// so that the index names of the shards sort in order.
+func orgShard(orgID uuid.UUID, shards string) string {
+	n, _ := strconv.Atoi(shards)
+	h := fnv.New32a()
+	h.Write(orgID.Bytes())
+	return fmt.Sprintf("%0*d", len(strconv.Itoa(n-1)), h.Sum32()%uint32(n))
+}
+
+// orgIndexPattern returns the pattern which matches the indices of the org, if the template names indices per org.
+func (t IndexNameTemplate) orgIndexPattern(orgID uuid.UUID) (string, bool) {
+	perOrg := false
+	pattern := templatePlaceholderRegex.ReplaceAllStringFunc(string(t), func(p string) string {
+		if p[1:len(p)-1] == orgPlaceholder {
+			perOrg = true
+			return orgID.String()
+		}
+		return "*"
+	})
+	return pattern, perOrg
+}
+
+// previousIndexPattern returns the pattern which matches the indices that entities of the org were written to at
+// other times, and whether the template names indices by time at all.
+func (t IndexNameTemplate) previousIndexPattern(orgID uuid.UUID) (string, bool) {
+	byTime := false
+	pattern := templatePlaceholderRegex.ReplaceAllStringFunc(string(t), func(p string) string {
+		placeholder := p[1 : len(p)-1]
+		if placeholder == orgPlaceholder {
+			return orgID.String()
+		}
+		if m := shardPlaceholderRegex.FindStringSubmatch(placeholder); m != nil {
+			return orgShard(orgID, m[1])
+		}
+		byTime = true
+		return "*"
+	})
+	return pattern, byTime
+}
+
+// OrgAliasName returns the name of the filtered alias which spans the entities of the org in the indices behind the
+// given alias.
+func OrgAliasName(alias string, orgID uuid.UUID) string {
+	return fmt.Sprintf("%s_%s", alias, orgID.String())
+}
+
+// IndexManager determines which index metadata entities should be written to. When configured with an
+// IndexNameTemplate, indices are created from the IndexMapping on first use and added to the alias that
+// readers query, so that old indices can be cheaply deleted once they are no longer needed.
+type IndexManager struct {
+	es        *elastic.Client
+	alias     string
+	template  IndexNameTemplate
+	replicas  int
+	lifecycle *IndexLifecycle
+
+	// Whether each org's entities are routed to a single shard of their index, and can be searched through the org's
+	// alias.
+	orgAliases bool
+
+	// The set of indices which are known to exist and belong to the alias.
+	mu      sync.Mutex
+	created map[string]bool
+	// The set of indices which are known to belong to the alias of an org, keyed by the index and org.
+	orgAliased map[string]bool
+	// Whether the ILM policy which ages out the indices has been created.
+	policyCreated bool
+	// The index which the alias last wrote to, and when it was last checked for a rollover.
+	writeIndex        string
+	lastRolloverCheck time.Time
+}
+
+// NewIndexManager creates a new index manager. If the template is empty, all entities are written through the
+// alias, which is expected to have already been initialized with InitializeMapping.
+// Otherwise, the indices are deleted once they are older than the lifecycle's DeleteAfter, if specified.
+func NewIndexManager(es *elastic.Client, alias string, template IndexNameTemplate, replicas int, lifecycle *IndexLifecycle) *IndexManager {
+	return &IndexManager{
+		es:         es,
+		alias:      alias,
+		template:   template,
+		replicas:   replicas,
+		lifecycle:  lifecycle,
+		created:    make(map[string]bool),
+		orgAliased: make(map[string]bool),
+	}
+}
+
+// EnableOrgAliases routes the entities of each org to a single shard of their index, and adds the indices which
+// contain an org's entities to the org's filtered alias, named by OrgAliasName, so that an org's entities can be
+// searched without querying every shard. Since existing entities are not routed, this must only be enabled for a
+// template which names new indices.
+func (m *IndexManager) EnableOrgAliases() error {
+	if m.template == "" {
+		return fmt.Errorf("org aliases require an index name template")
+	}
+	m.orgAliases = true
+	return nil
+}
+
+// RoutesByOrg returns whether entities must be written with their org ID as the routing key.
+func (m *IndexManager) RoutesByOrg() bool {
+	return m.orgAliases
+}
+
+// Alias returns the name that should be used to read across all of the managed indices.
+func (m *IndexManager) Alias() string {
+	return m.alias
+}
+
+// IndexFor returns the index that entities for the given org should be written to at the given time, creating
+// the index if it does not exist yet.
+// When entities start being written to a new index, the live entities are moved into it from the previous indices,
+// so that they are not deleted along with the previous indices once those age out.
+func (m *IndexManager) IndexFor(orgID uuid.UUID, ts time.Time) (string, error) {
+	if m.template == "" {
+		if m.lifecycle.rollsOver() {
+			err := m.checkRollover(ts)
+			if err != nil {
+				return "", err
+			}
+		}
+		return m.alias, nil
+	}
+
+	name := m.template.IndexName(orgID, ts)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.created[name] {
+		err := m.createIndex(name)
+		if err != nil {
+			return "", err
+		}
+		if pattern, byTime := m.template.previousIndexPattern(orgID); byTime {
+			err = m.moveFromPreviousIndices(name, func(index string) bool {
+				matched, _ := path.Match(pattern, index)
+				return matched
+			})
+			if err != nil {
+				return "", err
+			}
+		}
+		m.created[name] = true
+	}
+
+	if m.orgAliases {
+		key := fmt.Sprintf("%s/%s", name, orgID)
+		if !m.orgAliased[key] {
+			err := m.addOrgAlias(name, orgID)
+			if err != nil {
+				return "", err
+			}
+			m.orgAliased[key] = true
+		}
+	}
+	return name, nil
+}
+
+// checkRollover moves the live entities into the index which the alias writes to, once the alias was rolled over to
+// a new index. The alias is checked at most once per rolloverCheckInterval.
+func (m *IndexManager) checkRollover(ts time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ts.Sub(m.lastRolloverCheck) < rolloverCheckInterval {
+		return nil
+	}
+	m.lastRolloverCheck = ts
+
+	aliases, err := m.es.Aliases().Alias(m.alias).Do(context.Background())
+	if err != nil {
+		return err
+	}
+	writeIndex := ""
+	for index, res := range aliases.Indices {
+		for _, a := range res.Aliases {
+			if a.AliasName == m.alias && a.IsWriteIndex {
+				writeIndex = index
+			}
+		}
+	}
+	if writeIndex == "" || writeIndex == m.writeIndex {
+		return nil
+	}
+
+	err = m.moveFromPreviousIndices(writeIndex, func(string) bool { return true })
+	if err != nil {
+		return err
+	}
+	m.writeIndex = writeIndex
+	return nil
+}
+
+// moveFromPreviousIndices moves the live entities into the index from the indices behind the alias which match the
+// filter. Only the indices which sort before the index are previous indices, so that live entities are never moved
+// into an index which ages out sooner.
+func (m *IndexManager) moveFromPreviousIndices(index string, filter func(string) bool) error {
+	aliases, err := m.es.Aliases().Alias(m.alias).Do(context.Background())
+	if err != nil {
+		return err
+	}
+	var previous []string
+	for _, name := range aliases.IndicesByAlias(m.alias) {
+		if name < index && filter(name) {
+			previous = append(previous, name)
+		}
+	}
+	return moveLiveEntities(m.es, previous, index)
+}
+
+// createIndex creates the index from the IndexMapping and adds it to the alias.
+func (m *IndexManager) createIndex(name string) error {
+	// All of the indices share the policy of the alias.
+	policyName := ""
+	if m.lifecycle.deletes() {
+		if !m.policyCreated {
+			err := initializeLifecyclePolicy(m.es, m.alias, m.lifecycle)
+			if err != nil {
+				return err
+			}
+			m.policyCreated = true
+		}
+		policyName = lifecyclePolicyName(m.alias)
+	}
+	err := initializeIndex(m.es, name, m.replicas, policyName)
+	if err != nil {
+		return err
+	}
+	// Adding an index to an alias is idempotent, so this is safe even if another indexer already added it.
+	_, err = m.es.Alias().Add(name, m.alias).Do(context.Background())
+	if err != nil {
+		return err
+	}
+
+	log.WithField("index", name).WithField("alias", m.alias).Info("Initialized metadata index")
+	return nil
+}
+
+// addOrgAlias adds the index to the org's alias, which only matches the org's entities and is routed to their shard.
+func (m *IndexManager) addOrgAlias(name string, orgID uuid.UUID) error {
+	action := elastic.NewAliasAddAction(OrgAliasName(m.alias, orgID)).
+		Index(name).
+		Filter(elastic.NewTermQuery("orgID", orgID.String())).
+		Routing(orgID.String())
+	// Adding an index to an alias is idempotent, so this is safe even if another indexer already added it.
+	_, err := m.es.Alias().Action(action).Do(context.Background())
+	return err
+}
+
+// DeleteOrg deletes the entities of the org. If the indices are named per org, the org's indices are deleted.
+// Otherwise, the org's entities are deleted from the indices which they share with other orgs.
+func (m *IndexManager) DeleteOrg(orgID uuid.UUID) error {
+	ctx := context.Background()
+	if pattern, perOrg := m.template.orgIndexPattern(orgID); perOrg {
+		_, err := m.es.DeleteIndex(pattern).Do(ctx)
+		if err != nil && !elastic.IsNotFound(err) {
+			return err
+		}
+		m.mu.Lock()
+		// The org's indices are recreated if further entities are written for the org.
+		m.created = make(map[string]bool)
+		m.orgAliased = make(map[string]bool)
+		m.mu.Unlock()
+		return nil
+	}
+
+	_, err := m.es.DeleteByQuery(m.alias).
+		Query(elastic.NewTermQuery("orgID", orgID.String())).
+		Conflicts("proceed").
+		Do(ctx)
+	return err
+}
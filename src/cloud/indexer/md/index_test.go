@@ -0,0 +1,226 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package md_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/indexer/md"
+)
+
+func TestIndexNameTemplate_IndexName(t *testing.T) {
+	org := uuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	ts := time.Date(2022, time.March, 7, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		template md.IndexNameTemplate
+		expected string
+	}{
+		{
+			name:     "static",
+			template: "md_entities",
+			expected: "md_entities",
+		},
+		{
+			name:     "org and month",
+			template: "md-{org}-{yyyy.MM}",
+			expected: "md-6ba7b810-9dad-11d1-80b4-00c04fd430c8-2022.03",
+		},
+		{
+			name:     "day",
+			template: "md-{yyyy.MM.dd}",
+			expected: "md-2022.03.07",
+		},
+		{
+			name:     "shard",
+			template: "md-{shard:8}",
+			expected: "md-4",
+		},
+		{
+			name:     "padded shard and month",
+			template: "md-{shard:100}-{yyyy.MM}",
+			expected: "md-00-2022.03",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.NoError(t, test.template.Validate())
+			assert.Equal(t, test.expected, test.template.IndexName(org, ts))
+		})
+	}
+}
+
+func TestIndexNameTemplate_ValidateInvalid(t *testing.T) {
+	assert.Error(t, md.IndexNameTemplate("").Validate())
+	assert.Error(t, md.IndexNameTemplate("md-{cluster}").Validate())
+	assert.Error(t, md.IndexNameTemplate("md-{yyyy.mm}").Validate())
+	assert.Error(t, md.IndexNameTemplate("md-{shard}").Validate())
+	assert.Error(t, md.IndexNameTemplate("md-{shard:0}").Validate())
+}
+
+func TestIndexManager_IndexFor(t *testing.T) {
+	alias := "test_md_alias"
+	indices := md.NewIndexManager(elasticClient, alias, "test_md-{org}-{yyyy.MM}", 1, nil)
+
+	ts := time.Date(2022, time.March, 7, 15, 0, 0, 0, time.UTC)
+	index, err := indices.IndexFor(orgID, ts)
+	require.NoError(t, err)
+	assert.Equal(t, "test_md-"+orgID.String()+"-2022.03", index)
+
+	nextIndex, err := indices.IndexFor(orgID, ts.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "test_md-"+orgID.String()+"-2022.04", nextIndex)
+
+	aliases, err := elasticClient.Aliases().Alias(alias).Do(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{index, nextIndex}, aliases.IndicesByAlias(alias))
+}
+
+func TestIndexManager_MovesLiveEntities(t *testing.T) {
+	ctx := context.Background()
+	alias := "test_md_moved"
+	indices := md.NewIndexManager(elasticClient, alias, "test_md_moved-{yyyy.MM}", 1, &md.IndexLifecycle{DeleteAfter: "30d"})
+
+	ts := time.Date(2022, time.March, 7, 15, 0, 0, 0, time.UTC)
+	index, err := indices.IndexFor(orgID, ts)
+	require.NoError(t, err)
+	for _, e := range []*md.EsMDEntity{
+		{OrgID: orgID.String(), UID: "live", Kind: "namespace", RelatedEntityNames: []string{}},
+		{OrgID: orgID.String(), UID: "stopped", Kind: "pod", TimeStoppedNS: 1, RelatedEntityNames: []string{}},
+	} {
+		_, err = elasticClient.Index().Index(index).Id(e.UID).BodyJson(e).Refresh("true").Do(ctx)
+		require.NoError(t, err)
+	}
+
+	nextIndex, err := indices.IndexFor(orgID, ts.AddDate(0, 1, 0))
+	require.NoError(t, err)
+
+	// The live entity outlives the index it was written to, once that index ages out.
+	_, err = elasticClient.DeleteIndex(index).Do(ctx)
+	require.NoError(t, err)
+	resp, err := elasticClient.Search().Index(alias).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), resp.TotalHits())
+	assert.Equal(t, "live", resp.Hits.Hits[0].Id)
+	assert.Equal(t, nextIndex, resp.Hits.Hits[0].Index)
+}
+
+func TestIndexManager_MovesLiveEntitiesOnRollover(t *testing.T) {
+	ctx := context.Background()
+	alias := "test_md_moved_rollover"
+	lifecycle := &md.IndexLifecycle{MaxIndexSize: "1gb", DeleteAfter: "1d"}
+	require.NoError(t, md.InitializeMapping(elasticClient, alias, 1, lifecycle))
+	indices := md.NewIndexManager(elasticClient, alias, "", 1, lifecycle)
+
+	ts := time.Now()
+	index, err := indices.IndexFor(orgID, ts)
+	require.NoError(t, err)
+	e := &md.EsMDEntity{OrgID: orgID.String(), UID: "live", Kind: "namespace", RelatedEntityNames: []string{}}
+	_, err = elasticClient.Index().Index(index).Id(e.UID).BodyJson(e).Refresh("true").Do(ctx)
+	require.NoError(t, err)
+
+	rollover, err := elasticClient.RolloverIndex(alias).Do(ctx)
+	require.NoError(t, err)
+	_, err = indices.IndexFor(orgID, ts.Add(2*time.Minute))
+	require.NoError(t, err)
+
+	// The entity is no longer in the rolled over index, which is deleted once it ages out.
+	_, err = elasticClient.DeleteIndex(rollover.OldIndex).Do(ctx)
+	require.NoError(t, err)
+	resp, err := elasticClient.Search().Index(alias).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), resp.TotalHits())
+	assert.Equal(t, rollover.NewIndex, resp.Hits.Hits[0].Index)
+}
+
+func TestIndexManager_OrgAliases(t *testing.T) {
+	ctx := context.Background()
+	alias := "test_md_sharded"
+	indices := md.NewIndexManager(elasticClient, alias, "test_md_sharded-{shard:4}", 1, nil)
+	require.NoError(t, indices.EnableOrgAliases())
+	assert.True(t, indices.RoutesByOrg())
+
+	otherOrgID := uuid.Must(uuid.NewV4())
+	for _, org := range []uuid.UUID{orgID, otherOrgID} {
+		index, err := indices.IndexFor(org, time.Now())
+		require.NoError(t, err)
+		e := &md.EsMDEntity{
+			OrgID:              org.String(),
+			UID:                "sharded-" + org.String(),
+			Kind:               "pod",
+			RelatedEntityNames: []string{},
+		}
+		_, err = elasticClient.Index().Index(index).Id(e.UID).Routing(org.String()).BodyJson(e).Refresh("true").Do(ctx)
+		require.NoError(t, err)
+	}
+
+	// The org's alias only matches the org's entities, even if they share an index with other orgs.
+	resp, err := elasticClient.Search().Index(md.OrgAliasName(alias, orgID)).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), resp.TotalHits())
+	assert.Equal(t, "sharded-"+orgID.String(), resp.Hits.Hits[0].Id)
+
+	// Deleting the org only deletes its entities from the shared indices.
+	require.NoError(t, indices.DeleteOrg(orgID))
+	_, err = elasticClient.Refresh(alias).Do(ctx)
+	require.NoError(t, err)
+	resp, err = elasticClient.Search().Index(alias).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), resp.TotalHits())
+	assert.Equal(t, "sharded-"+otherOrgID.String(), resp.Hits.Hits[0].Id)
+}
+
+func TestIndexManager_EnableOrgAliasesWithoutTemplate(t *testing.T) {
+	indices := md.NewIndexManager(elasticClient, "test_md_no_template", "", 1, nil)
+	assert.Error(t, indices.EnableOrgAliases())
+	assert.False(t, indices.RoutesByOrg())
+}
+
+func TestIndexManager_DeleteOrgIndices(t *testing.T) {
+	ctx := context.Background()
+	alias := "test_md_per_org"
+	indices := md.NewIndexManager(elasticClient, alias, "test_md_per_org-{org}-{yyyy.MM}", 1, nil)
+
+	otherOrgID := uuid.Must(uuid.NewV4())
+	ts := time.Date(2022, time.March, 7, 15, 0, 0, 0, time.UTC)
+	var otherIndex string
+	for _, org := range []uuid.UUID{orgID, otherOrgID} {
+		for _, month := range []int{0, 1} {
+			index, err := indices.IndexFor(org, ts.AddDate(0, month, 0))
+			require.NoError(t, err)
+			if org == otherOrgID && month == 0 {
+				otherIndex = index
+			}
+		}
+	}
+
+	require.NoError(t, indices.DeleteOrg(orgID))
+
+	aliases, err := elasticClient.Aliases().Alias(alias).Do(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{otherIndex, "test_md_per_org-" + otherOrgID.String() + "-2022.04"}, aliases.IndicesByAlias(alias))
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package md
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/olivere/elastic/v7"
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/cloud/shared/esutils"
+)
+
+// IndexLifecycle configures the elastic ILM policy which rolls over the metadata indices and ages them out.
+type IndexLifecycle struct {
+	// MaxIndexSize rolls the index over to a new index once it reaches the given size, eg. "50gb".
+	MaxIndexSize string
+	// MaxIndexAge rolls the index over to a new index once it reaches the given age, eg. "7d". An index which is
+	// rolled over by age is still rolled over once it reaches esutils.DefaultMaxIndexSize.
+	MaxIndexAge string
+	// DeleteAfter deletes an index once the given time has passed since it was rolled over, or since it was created
+	// for indices which are not rolled over, eg. "30d". If empty, indices are never deleted. Live entities are moved
+	// into each new index, so only the terminated entities are deleted along with an index.
+	DeleteAfter string
+}
+
+func (l *IndexLifecycle) rollsOver() bool {
+	return l != nil && (l.MaxIndexSize != "" || l.MaxIndexAge != "")
+}
+
+func (l *IndexLifecycle) deletes() bool {
+	return l != nil && l.DeleteAfter != ""
+}
+
+// Managed returns whether the indices are managed by an ILM policy, which requires a distribution which supports ILM.
+func (l *IndexLifecycle) Managed() bool {
+	return l.rollsOver() || l.deletes()
+}
+
+// Validate checks that the lifecycle can be applied to the indices named by the template. Rolled over indices are
+// written through a single alias, so rollover is not supported for templated index names, which are instead aged
+// out with DeleteAfter.
+func (l *IndexLifecycle) Validate(template IndexNameTemplate) error {
+	if !l.rollsOver() {
+		return nil
+	}
+	if template != "" {
+		return errors.New("index rollover is not supported with an index name template")
+	}
+	// Without a delete phase, the managed index's default policy deletes indices as soon as they are rolled over.
+	if !l.deletes() {
+		return errors.New("index rollover requires the time after which rolled over indices are deleted")
+	}
+	return nil
+}
+
+// lifecyclePolicyName returns the name of the ILM policy for the indices behind the alias. This matches the name of
+// the policy created by esutils.ManagedIndex.
+func lifecyclePolicyName(alias string) string {
+	return fmt.Sprintf("%s_policy", alias)
+}
+
+// initializeLifecyclePolicy creates or updates the policy which deletes the indices behind the alias once they are
+// older than DeleteAfter.
+func initializeLifecyclePolicy(es *elastic.Client, alias string, l *IndexLifecycle) error {
+	// The phases replace those of the default policy, which rolls indices over. Rollover fails for indices which are
+	// not written through a rollover alias, which would stop the policy from ever deleting them.
+	policy := fmt.Sprintf(`{"policy": {"phases": {"delete": {"min_age": %q, "actions": {"delete": {}}}}}}`, l.DeleteAfter)
+	return esutils.NewILMPolicy(es, lifecyclePolicyName(alias)).FromJSONString(policy).Migrate(context.Background())
+}
+
+// initializeManagedIndex creates the index, which is rolled over and deleted by its ILM policy, behind the alias of
+// the given name.
+func initializeManagedIndex(es *elastic.Client, alias string, replicas int, l *IndexLifecycle) error {
+	index := esutils.NewManagedIndex(es, alias).IndexFromJSONString(IndexMapping).TimeBeforeDelete(l.DeleteAfter)
+	var maxSize, maxAge *string
+	if l.MaxIndexSize != "" {
+		maxSize = &l.MaxIndexSize
+	}
+	if l.MaxIndexAge != "" {
+		maxAge = &l.MaxIndexAge
+	}
+	index.ILMPolicy().Rollover(maxSize, nil, maxAge)
+	// Indices created by rollover take their settings from the template.
+	index.IndexTemplate().AddIndexSettings(map[string]interface{}{"number_of_replicas": replicas})
+	err := index.Migrate(context.Background())
+	if err != nil {
+		return err
+	}
+	return setIndexSettings(es, alias, replicas, "")
+}
+
+// moveLiveEntities moves the entities which have not been terminated from the source indices into the target index.
+// Entities which were already written to the target index are newer than their copies in the source indices, so they
+// are not overwritten. The copies are then deleted from the source indices, so that they do not linger as stale
+// duplicates until the source indices are deleted.
+func moveLiveEntities(es *elastic.Client, sources []string, target string) error {
+	if len(sources) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	live := elastic.NewTermQuery("timeStoppedNS", 0)
+	resp, err := es.Reindex().
+		Source(elastic.NewReindexSource().Index(sources...).Query(live)).
+		Destination(elastic.NewReindexDestination().Index(target).OpType("create")).
+		Conflicts("proceed").
+		Refresh("true").
+		Do(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = es.DeleteByQuery(sources...).Query(live).Conflicts("proceed").Refresh("true").Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.WithField("index", target).WithField("sources", sources).WithField("entities", resp.Created).
+		Info("Moved live entities into metadata index")
+	return nil
+}
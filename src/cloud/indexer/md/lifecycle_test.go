@@ -0,0 +1,71 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package md_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/indexer/md"
+)
+
+func TestIndexLifecycle_Validate(t *testing.T) {
+	var noLifecycle *md.IndexLifecycle
+	assert.NoError(t, noLifecycle.Validate(""))
+	assert.NoError(t, (&md.IndexLifecycle{DeleteAfter: "30d"}).Validate("md-{yyyy.MM}"))
+	assert.NoError(t, (&md.IndexLifecycle{MaxIndexSize: "50gb", DeleteAfter: "30d"}).Validate(""))
+	assert.Error(t, (&md.IndexLifecycle{MaxIndexSize: "50gb", DeleteAfter: "30d"}).Validate("md-{yyyy.MM}"))
+	assert.Error(t, (&md.IndexLifecycle{MaxIndexAge: "7d"}).Validate(""))
+}
+
+func TestIndexLifecycle_Managed(t *testing.T) {
+	var noLifecycle *md.IndexLifecycle
+	assert.False(t, noLifecycle.Managed())
+	assert.False(t, (&md.IndexLifecycle{}).Managed())
+	assert.True(t, (&md.IndexLifecycle{DeleteAfter: "30d"}).Managed())
+	assert.True(t, (&md.IndexLifecycle{MaxIndexAge: "7d", DeleteAfter: "30d"}).Managed())
+}
+
+func TestInitializeMapping_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	err := md.InitializeMapping(elasticClient, "test_md_retention", 1, &md.IndexLifecycle{DeleteAfter: "30d"})
+	require.NoError(t, err)
+	policies, err := elasticClient.XPackIlmGetLifecycle().Policy("test_md_retention_policy").Do(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, policies, "test_md_retention_policy")
+	settings, err := elasticClient.IndexGetSettings("test_md_retention").Do(ctx)
+	require.NoError(t, err)
+	lifecycle := settings[fmt.Sprintf("test_md_retention_v%d", md.IndexMappingVersion)].Settings["index"].(map[string]interface{})["lifecycle"]
+	assert.Equal(t, map[string]interface{}{"name": "test_md_retention_policy"}, lifecycle)
+
+	// Rolled over indices are written through the alias.
+	err = md.InitializeMapping(elasticClient, "test_md_rollover", 1, &md.IndexLifecycle{MaxIndexSize: "1gb", DeleteAfter: "1d"})
+	require.NoError(t, err)
+	aliases, err := elasticClient.Aliases().Alias("test_md_rollover").Do(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test_md_rollover-000000"}, aliases.IndicesByAlias("test_md_rollover"))
+	policies, err = elasticClient.XPackIlmGetLifecycle().Policy("test_md_rollover_policy").Do(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, policies, "test_md_rollover_policy")
+}
@@ -20,7 +20,6 @@ package md
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/olivere/elastic/v7"
 )
@@ -55,6 +54,12 @@ const (
 	EsMDTypeScript EsMDType = "script"
 	// EsMDTypeNode is for node entities.
 	EsMDTypeNode EsMDType = "node"
+	// EsMDTypeContainer is for container entities.
+	EsMDTypeContainer EsMDType = "container"
+	// EsMDTypeDeployment is for deployment entities.
+	EsMDTypeDeployment EsMDType = "deployment"
+	// EsMDTypeReplicaSet is for replica set entities.
+	EsMDTypeReplicaSet EsMDType = "replicaset"
 )
 
 // EsMDEntity is the struct that is stored in elastic.
@@ -75,10 +80,26 @@ type EsMDEntity struct {
 	UpdateVersion int64 `json:"updateVersion"`
 
 	State ESMDEntityState `json:"state"`
+
+	// The IPs of pods and services, which are omitted when unassigned since elastic does not accept empty IPs.
+	PodIP     string `json:"podIP,omitempty"`
+	HostIP    string `json:"hostIP,omitempty"`
+	ClusterIP string `json:"clusterIP,omitempty"`
+
+	// Labels are formatted as key=value, so that they can be matched exactly.
+	Labels []string `json:"labels,omitempty"`
+
+	// DocVersion is the version of the shape of the document, which is EsMDEntityDocVersion for documents written by
+	// this version of the indexer. Older documents are upgraded by the update script when they are next written.
+	DocVersion int `json:"docVersion"`
 }
 
+// EsMDEntityDocVersion is the current version of the shape of EsMDEntity documents. It must be incremented whenever a
+// field is added that the update script relies on, along with a step in the update script which upgrades documents
+// of the previous version.
+const EsMDEntityDocVersion = 1
+
 // IndexMapping is the index structure for metadata entities.
-// TODO(michellenguyen): Remove namespace from the index once we stop writing and reading from it.
 const IndexMapping = `
 {
   "settings": {
@@ -179,6 +200,9 @@ const IndexMapping = `
           }
         }
       },
+      "ns": {
+        "type": "keyword"
+      },
       "kind": {
         "type": "text",
         "eager_global_ordinals": true
@@ -195,27 +219,75 @@ const IndexMapping = `
       "updateVersion": {
         "type": "long"
       },
+      "docVersion": {
+        "type": "integer"
+      },
       "state": {
         "type": "integer"
+      },
+      "podIP": {
+        "type": "ip"
+      },
+      "hostIP": {
+        "type": "ip"
+      },
+      "clusterIP": {
+        "type": "ip"
+      },
+      "labels": {
+        "type": "keyword"
       }
     }
   }
 }
 `
 
-// InitializeMapping creates the index in elastic.
-func InitializeMapping(es *elastic.Client, indexName string, replicas int) error {
+// InitializeMapping creates the index in elastic, along with the ILM policy of its lifecycle if there is one. Entities
+// are written through indexName, which is an alias for either the rolled over indices, if the lifecycle rolls the
+// index over, or for the index with the current IndexMappingVersion, which is migrated from the index with the
+// previous mapping if there is one.
+func InitializeMapping(es *elastic.Client, indexName string, replicas int, lifecycle *IndexLifecycle) error {
+	if lifecycle.rollsOver() {
+		return initializeManagedIndex(es, indexName, replicas, lifecycle)
+	}
+	policyName := ""
+	if lifecycle.deletes() {
+		err := initializeLifecyclePolicy(es, indexName, lifecycle)
+		if err != nil {
+			return err
+		}
+		policyName = lifecyclePolicyName(indexName)
+	}
+	return initializeVersionedIndex(es, indexName, replicas, policyName)
+}
+
+// initializeIndex creates the index, which is managed by the named ILM policy if it is not empty.
+func initializeIndex(es *elastic.Client, indexName string, replicas int, policyName string) error {
 	exists, err := es.IndexExists(indexName).Do(context.Background())
 	if err != nil {
 		return err
 	}
 	if !exists {
 		_, err = es.CreateIndex(indexName).Body(IndexMapping).Do(context.Background())
-		if err != nil {
+		// Another indexer may have created the index in the meantime.
+		if err != nil && !isIndexExistsError(err) {
 			return err
 		}
 	}
-	replicaSetting := fmt.Sprintf("{\"index\": {\"number_of_replicas\": %d}}", replicas)
-	_, err = es.IndexPutSettings(indexName).BodyString(replicaSetting).Do(context.Background())
+	return setIndexSettings(es, indexName, replicas, policyName)
+}
+
+// setIndexSettings updates the number of replicas of the index, and its ILM policy if the policy name is not empty.
+func setIndexSettings(es *elastic.Client, indexName string, replicas int, policyName string) error {
+	settings := map[string]interface{}{"number_of_replicas": replicas}
+	if policyName != "" {
+		settings["lifecycle.name"] = policyName
+	}
+	_, err := es.IndexPutSettings(indexName).BodyJson(map[string]interface{}{"index": settings}).Do(context.Background())
 	return err
 }
+
+func isIndexExistsError(err error) bool {
+	e, ok := err.(*elastic.Error)
+	return ok && e.Details != nil && e.Details.Type == "resource_already_exists_exception"
+}
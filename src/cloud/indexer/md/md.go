@@ -20,7 +20,14 @@ package md
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cenkalti/backoff/v3"
@@ -34,65 +41,156 @@ import (
 )
 
 const (
-	maxActionsPerBatch          = 256
-	maxActionBatchFlushInterval = time.Second * 30
-	maxElasticBackoffInterval   = time.Second * 60
+	// DefaultMaxActionsPerBatch is the default number of updates after which they are flushed to elastic.
+	DefaultMaxActionsPerBatch = 256
+	// DefaultMaxActionBatchFlushInterval is the default time after which updates are flushed to elastic.
+	DefaultMaxActionBatchFlushInterval = time.Second * 30
+	maxElasticBackoffInterval          = time.Second * 60
+	// How long a batch of updates is retried for before elastic is considered unavailable.
+	maxElasticRetryTime = time.Minute * 5
+	// How long updates are rejected for once elastic is considered unavailable, before the batch is retried.
+	elasticCircuitBreakerCooldown = time.Minute
+	// How long the pending updates are flushed for when the indexer is stopped.
+	stopFlushTimeout = time.Second * 10
+	// DefaultWorkers is the default number of workers which index the updates of a vizier.
+	DefaultWorkers = 1
+	// The number of updates which are queued for each worker.
+	workQueueSize = 64
 )
 
+// errElasticUnavailable is returned while updates are rejected because the last batch could not be flushed to elastic.
+var errElasticUnavailable = errors.New("elastic is unavailable")
+
 var (
 	elasticRetriesCollector = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "elastic_index_retries",
 		Help: "The number of retries for this particular index",
 	}, []string{"vizier_id"})
+	elasticCircuitBreakerOpenCollector = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "elastic_circuit_breaker_open",
+		Help: "Whether updates for this particular vizier are rejected because elastic is unavailable",
+	}, []string{"vizier_id"})
+	elasticFlushDurationCollector = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "elastic_bulk_flush_duration_seconds",
+		Help: "The time taken to flush a batch of updates to elastic, including retries",
+		// Flushes which are retried can take up to maxElasticRetryTime.
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 10),
+	}, []string{"vizier_id"})
+	elasticBatchSizeCollector = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "elastic_bulk_batch_size",
+		Help:    "The number of updates in each batch flushed to elastic",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+	}, []string{"vizier_id"})
+	elasticFlushFailuresCollector = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "elastic_bulk_flush_failures",
+		Help: "The number of batches which could not be flushed to elastic within the retry budget",
+	}, []string{"vizier_id"})
+	elasticItemFailuresCollector = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "elastic_bulk_item_failures",
+		Help: "The number of updates which elastic failed to apply in otherwise successful batches",
+	}, []string{"vizier_id"})
+	indexerPausedCollector = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "elastic_indexer_paused",
+		Help: "Whether the subscription to the updates of this particular vizier is paused because elastic is failing",
+	}, []string{"vizier_id"})
 )
 
 func init() {
 	prometheus.MustRegister(elasticRetriesCollector)
+	prometheus.MustRegister(elasticCircuitBreakerOpenCollector)
+	prometheus.MustRegister(elasticFlushDurationCollector)
+	prometheus.MustRegister(elasticBatchSizeCollector)
+	prometheus.MustRegister(elasticFlushFailuresCollector)
+	prometheus.MustRegister(elasticItemFailuresCollector)
+	prometheus.MustRegister(indexerPausedCollector)
 }
 
 // VizierIndexer run the indexer for a single vizier index.
 type VizierIndexer struct {
-	st        msgbus.Streamer
-	es        *elastic.Client
-	bulk      *elastic.BulkService
-	vizierID  uuid.UUID
-	orgID     uuid.UUID
-	k8sUID    string
-	indexName string
-
-	sub    msgbus.PersistentSub
+	st       msgbus.Streamer
+	es       *elastic.Client
+	vizierID uuid.UUID
+	orgID    uuid.UUID
+	k8sUID   string
+	indices  *IndexManager
+
+	// The subscription is paused while elastic is failing, and resumed once a batch is flushed again. Together with
+	// the in-flight limit of the streamer, this bounds the updates which are received but not yet indexed.
+	sub    *pausableSub
 	quitCh chan bool
 	errCh  chan error
+	// The topic to which the updates that elastic fails to apply are published.
+	deadLetterTopic string
 
 	// Specification for when to flush updates to Elastic using the bulk API.
 	maxActionsPerBatch          int
 	maxActionBatchFlushInterval time.Duration
-	lastFlushTime               time.Time
+	// The updates are split across the batches by entity UID, so that the batches are flushed concurrently while the
+	// updates of each entity are applied in order.
+	batches []*updateBatch
+
+	// The queues of the workers which index the updates from the stream, one for each batch.
+	workQueues  []chan *streamUpdate
+	workersDone sync.WaitGroup
+	// Guards the work queues from being closed while updates are queued on them.
+	dispatchMu sync.RWMutex
+	stopped    bool
+
+	// Guards the circuit breaker, which is shared by the batches.
+	mu sync.Mutex
+	// When a batch fails to flush, updates are rejected until this time, after which the batch is retried once.
+	circuitOpenUntil time.Time
+}
+
+// updateBatch is a batch of updates which are flushed to elastic together using the bulk API.
+type updateBatch struct {
+	mu   sync.Mutex
+	bulk *elastic.BulkService
+	// The updates in the bulk service, in the order of its requests.
+	pending       []*metadatapb.ResourceUpdate
+	lastFlushTime time.Time
 }
 
-// NewVizierIndexerWithBulkSettings creates a new Vizier indexer with bulk settings.
-func NewVizierIndexerWithBulkSettings(vizierID uuid.UUID, orgID uuid.UUID, k8sUID, indexName string, st msgbus.Streamer,
-	es *elastic.Client, actionsPerBatch int, batchFlushInterval time.Duration) *VizierIndexer {
+// streamUpdate is an update received from the stream, which is acked once a worker has handled it.
+type streamUpdate struct {
+	msg    msgbus.Msg
+	update *metadatapb.ResourceUpdate
+	entity *EsMDEntity
+}
+
+// NewVizierIndexerWithBulkSettings creates a new Vizier indexer with bulk settings. The updates from the stream are
+// indexed by the given number of workers, each of which flushes its own batch of updates.
+func NewVizierIndexerWithBulkSettings(vizierID uuid.UUID, orgID uuid.UUID, k8sUID string, indices *IndexManager, st msgbus.Streamer,
+	es *elastic.Client, actionsPerBatch int, batchFlushInterval time.Duration, workers int) *VizierIndexer {
+	if workers < 1 {
+		workers = 1
+	}
+	batches := make([]*updateBatch, workers)
+	for i := range batches {
+		batches[i] = &updateBatch{
+			// This will get automatically reset for reuse after every call to `bulk.Do`.
+			bulk:          es.Bulk(),
+			lastFlushTime: time.Now(),
+		}
+	}
 	return &VizierIndexer{
-		st: st,
-		es: es,
-		// This will get automatically reset for reuse after every call to `bulk.Do`.
-		bulk:                        es.Bulk().Index(indexName),
+		st:                          st,
+		es:                          es,
 		vizierID:                    vizierID,
 		orgID:                       orgID,
 		k8sUID:                      k8sUID,
-		indexName:                   indexName,
+		indices:                     indices,
 		quitCh:                      make(chan bool),
 		errCh:                       make(chan error),
 		maxActionsPerBatch:          actionsPerBatch,
 		maxActionBatchFlushInterval: batchFlushInterval,
-		lastFlushTime:               time.Now(),
+		batches:                     batches,
 	}
 }
 
 // NewVizierIndexer creates a new Vizier indexer.
-func NewVizierIndexer(vizierID uuid.UUID, orgID uuid.UUID, k8sUID, indexName string, st msgbus.Streamer, es *elastic.Client) *VizierIndexer {
-	return NewVizierIndexerWithBulkSettings(vizierID, orgID, k8sUID, indexName, st, es, maxActionsPerBatch, maxActionBatchFlushInterval)
+func NewVizierIndexer(vizierID uuid.UUID, orgID uuid.UUID, k8sUID string, indices *IndexManager, st msgbus.Streamer, es *elastic.Client) *VizierIndexer {
+	return NewVizierIndexerWithBulkSettings(vizierID, orgID, k8sUID, indices, st, es, DefaultMaxActionsPerBatch, DefaultMaxActionBatchFlushInterval, DefaultWorkers)
 }
 
 // Start starts the indexer.
@@ -102,32 +200,199 @@ func (v *VizierIndexer) Start(topic string) error {
 		WithField("ClusterUID", v.k8sUID).
 		Info("Starting Indexer")
 
-	sub, err := v.st.PersistentSubscribe(topic, "indexer"+v.indexName, v.streamHandler)
+	v.deadLetterTopic = deadLetterTopic(topic)
+	v.workQueues = make([]chan *streamUpdate, len(v.batches))
+	for i, b := range v.batches {
+		v.workQueues[i] = make(chan *streamUpdate, workQueueSize)
+		v.workersDone.Add(1)
+		go v.runWorker(b, v.workQueues[i])
+	}
+
+	v.sub = newPausableSub(v.st, topic, "indexer"+v.indices.Alias(), v.streamHandler, v.vizierID.String())
+	err := v.sub.subscribe()
 	if err != nil {
+		v.stopWorkers()
 		return fmt.Errorf("Failed to subscribe to topic %s: %s", topic, err.Error())
 	}
-	v.sub = sub
 
 	go func() {
 		for {
 			select {
 			case <-v.quitCh:
 				return
-			case err = <-v.errCh:
+			case err := <-v.errCh:
 				log.WithField("vizier", v.vizierID.String()).WithError(err).Error("Error during indexing")
 			}
 		}
 	}()
+	go v.runFlusher()
+	return nil
+}
+
+// Replay indexes the updates which are retained on the topic, from the oldest one onwards, and returns the number of
+// indexed updates once no further updates have been received for the idle timeout. This rebuilds the entities in
+// the indices, eg. after they were lost or reindexed with a broken mapping. Replay uses its own subscription, so it
+// does not affect an indexer which is running on the same topic.
+func (v *VizierIndexer) Replay(topic string, idleTimeout time.Duration) (int64, error) {
+	v.deadLetterTopic = deadLetterTopic(topic)
+	var indexed int64
+	received := make(chan bool, 1)
+	sub, err := v.st.PersistentSubscribe(topic, "replay"+v.indices.Alias(), func(msg msgbus.Msg) {
+		ru := metadatapb.ResourceUpdate{}
+		err := ru.Unmarshal(msg.Data())
+		if err == nil {
+			err = v.HandleResourceUpdate(&ru)
+		}
+		if errors.Is(err, errElasticUnavailable) {
+			// The update is not acked, so that it is redelivered once elastic is available again.
+			log.WithError(err).Warn("Could not index replayed resource update")
+			return
+		}
+		if err != nil {
+			log.WithError(err).Error("Error handling replayed resource update")
+		} else {
+			atomic.AddInt64(&indexed, 1)
+		}
+		err = msg.Ack()
+		if err != nil {
+			log.WithError(err).Error("Failed to ack stan msg")
+		}
+
+		select {
+		case received <- true:
+		default:
+		}
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Failed to subscribe to topic %s: %s", topic, err.Error())
+	}
+
+	idle := false
+	for !idle {
+		select {
+		case <-received:
+		case <-time.After(idleTimeout):
+			idle = true
+		}
+	}
+
+	// The subscription is destroyed rather than closed, so that a later replay starts from the oldest update again.
+	err = sub.Destroy()
+	if err != nil {
+		log.WithError(err).Error("Failed to un-subscribe from channel")
+	}
+
+	for _, b := range v.batches {
+		b.mu.Lock()
+		if b.bulk.NumberOfActions() > 0 {
+			err = v.flush(b)
+		}
+		b.mu.Unlock()
+		if err != nil {
+			return atomic.LoadInt64(&indexed), err
+		}
+	}
+	return atomic.LoadInt64(&indexed), nil
+}
+
+// runFlusher periodically flushes the pending updates, so that they are indexed within the flush interval even if no
+// further updates arrive to trigger a flush.
+func (v *VizierIndexer) runFlusher() {
+	t := time.NewTicker(v.maxActionBatchFlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-v.quitCh:
+			return
+		case <-t.C:
+			err := v.flushPending()
+			if err != nil {
+				log.WithField("vizier", v.vizierID.String()).WithError(err).Error("Failed to flush pending updates")
+				continue
+			}
+			// A successful flush resumes the subscription, but resubscribing may have failed since.
+			if !v.circuitOpen() {
+				v.resumeSub()
+			}
+		}
+	}
+}
+
+// flushPending flushes the pending updates, unless updates are being rejected because elastic is unavailable.
+func (v *VizierIndexer) flushPending() error {
+	for _, b := range v.batches {
+		if v.circuitOpen() {
+			return nil
+		}
+		var err error
+		b.mu.Lock()
+		if b.bulk.NumberOfActions() > 0 {
+			err = v.flush(b)
+		}
+		b.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// Stop stops the indexer.
+// Stop stops the indexer, and flushes any pending updates to elastic.
 func (v *VizierIndexer) Stop() {
-	close(v.quitCh)
-	err := v.sub.Close()
+	// Unsubscribe first, so that no further updates are added to the batches while they are being flushed.
+	err := v.sub.close()
 	if err != nil {
 		log.WithError(err).Error("Failed to un-subscribe from channel")
 	}
+	v.stopWorkers()
+	close(v.quitCh)
+
+	err = v.flushOnStop()
+	if err != nil {
+		log.WithField("vizier", v.vizierID.String()).WithError(err).Error("Failed to flush pending updates on stop")
+	}
+}
+
+// stopWorkers stops the workers once they have indexed the updates which were already queued.
+func (v *VizierIndexer) stopWorkers() {
+	v.dispatchMu.Lock()
+	v.stopped = true
+	for _, q := range v.workQueues {
+		close(q)
+	}
+	v.dispatchMu.Unlock()
+	v.workersDone.Wait()
+}
+
+// flushOnStop makes a single attempt at flushing the pending updates, without the retries of a regular flush, so that
+// shutdown is not held up by an unavailable elastic.
+func (v *VizierIndexer) flushOnStop() error {
+	var flushErr error
+	for _, b := range v.batches {
+		err := v.flushBatchOnStop(b)
+		if err != nil {
+			flushErr = err
+		}
+	}
+	return flushErr
+}
+
+func (v *VizierIndexer) flushBatchOnStop(b *updateBatch) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.bulk.NumberOfActions() == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), stopFlushTimeout)
+	defer cancel()
+	resp, err := b.bulk.Refresh("wait_for").Do(ctx)
+	if err != nil {
+		return err
+	}
+	v.deadLetter(resp, b.pending)
+	b.pending = nil
+	return nil
 }
 
 func namespacedName(namespace string, name string) string {
@@ -137,18 +402,18 @@ func namespacedName(namespace string, name string) string {
 	return fmt.Sprintf("%s/%s", namespace, name)
 }
 
-func (v *VizierIndexer) nsUpdateToEMD(u *metadatapb.ResourceUpdate, nsUpdate *metadatapb.NamespaceUpdate) *EsMDEntity {
+// nsUpdateToEMD converts the namespace update. A namespace is scoped to itself, so that filtering by namespace also
+// finds the namespace.
+func nsUpdateToEMD(u *metadatapb.ResourceUpdate) *EsMDEntity {
+	nsUpdate := u.GetNamespaceUpdate()
 	return &EsMDEntity{
-		OrgID:              v.orgID.String(),
-		VizierID:           v.vizierID.String(),
-		ClusterUID:         v.k8sUID,
 		UID:                nsUpdate.UID,
+		NS:                 nsUpdate.Name,
 		Name:               nsUpdate.Name,
 		Kind:               string(EsMDTypeNamespace),
 		TimeStartedNS:      nsUpdate.StartTimestampNS,
 		TimeStoppedNS:      nsUpdate.StopTimestampNS,
 		RelatedEntityNames: []string{},
-		UpdateVersion:      u.UpdateVersion,
 		State:              getStateFromTimestamps(nsUpdate.StopTimestampNS),
 	}
 }
@@ -177,57 +442,165 @@ func getStateFromTimestamps(stopTimestamp int64) ESMDEntityState {
 	return ESMDEntityStateRunning
 }
 
-func (v *VizierIndexer) podUpdateToEMD(u *metadatapb.ResourceUpdate, podUpdate *metadatapb.PodUpdate) *EsMDEntity {
+// getLabels converts the JSON object of labels to key=value pairs, sorted by key.
+func getLabels(labelsJSON string) []string {
+	labels := make([]string, 0)
+	if labelsJSON == "" {
+		return labels
+	}
+	var labelMap map[string]string
+	err := json.Unmarshal([]byte(labelsJSON), &labelMap)
+	if err != nil {
+		log.WithError(err).Error("Failed to parse labels")
+		return labels
+	}
+	for k, v := range labelMap {
+		labels = append(labels, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+func podUpdateToEMD(u *metadatapb.ResourceUpdate) *EsMDEntity {
+	podUpdate := u.GetPodUpdate()
 	return &EsMDEntity{
-		OrgID:              v.orgID.String(),
-		VizierID:           v.vizierID.String(),
-		ClusterUID:         v.k8sUID,
 		UID:                podUpdate.UID,
+		NS:                 podUpdate.Namespace,
 		Name:               namespacedName(podUpdate.Namespace, podUpdate.Name),
 		Kind:               string(EsMDTypePod),
 		TimeStartedNS:      podUpdate.StartTimestampNS,
 		TimeStoppedNS:      podUpdate.StopTimestampNS,
 		RelatedEntityNames: []string{},
-		UpdateVersion:      u.UpdateVersion,
 		State:              podPhaseToState(podUpdate),
+		PodIP:              podUpdate.PodIP,
+		HostIP:             podUpdate.HostIP,
+		Labels:             getLabels(podUpdate.Labels),
+	}
+}
+
+// getClusterIP returns the service's cluster IP, if it has one. Headless services have a cluster IP of "None".
+func getClusterIP(serviceUpdate *metadatapb.ServiceUpdate) string {
+	if net.ParseIP(serviceUpdate.ClusterIP) == nil {
+		return ""
 	}
+	return serviceUpdate.ClusterIP
 }
 
-func (v *VizierIndexer) serviceUpdateToEMD(u *metadatapb.ResourceUpdate, serviceUpdate *metadatapb.ServiceUpdate) *EsMDEntity {
+func serviceUpdateToEMD(u *metadatapb.ResourceUpdate) *EsMDEntity {
+	serviceUpdate := u.GetServiceUpdate()
 	if serviceUpdate.PodIDs == nil {
 		serviceUpdate.PodIDs = make([]string, 0)
 	}
 	return &EsMDEntity{
-		OrgID:              v.orgID.String(),
-		VizierID:           v.vizierID.String(),
-		ClusterUID:         v.k8sUID,
 		UID:                serviceUpdate.UID,
+		NS:                 serviceUpdate.Namespace,
 		Name:               namespacedName(serviceUpdate.Namespace, serviceUpdate.Name),
 		Kind:               string(EsMDTypeService),
 		TimeStartedNS:      serviceUpdate.StartTimestampNS,
 		TimeStoppedNS:      serviceUpdate.StopTimestampNS,
 		RelatedEntityNames: serviceUpdate.PodIDs,
-		UpdateVersion:      u.UpdateVersion,
 		State:              getStateFromTimestamps(serviceUpdate.StopTimestampNS),
+		ClusterIP:          getClusterIP(serviceUpdate),
 	}
 }
 
-func (v *VizierIndexer) nodeUpdateToEMD(u *metadatapb.ResourceUpdate, nodeUpdate *metadatapb.NodeUpdate) *EsMDEntity {
+func nodeUpdateToEMD(u *metadatapb.ResourceUpdate) *EsMDEntity {
+	nodeUpdate := u.GetNodeUpdate()
 	return &EsMDEntity{
-		OrgID:              v.orgID.String(),
-		VizierID:           v.vizierID.String(),
-		ClusterUID:         v.k8sUID,
 		UID:                nodeUpdate.UID,
 		Name:               nodeUpdate.Name,
 		Kind:               string(EsMDTypeNode),
 		TimeStartedNS:      nodeUpdate.StartTimestampNS,
 		TimeStoppedNS:      nodeUpdate.StopTimestampNS,
 		RelatedEntityNames: []string{},
-		UpdateVersion:      u.UpdateVersion,
 		State:              nodeConditionToState(nodeUpdate),
 	}
 }
 
+func containerStateToState(containerUpdate *metadatapb.ContainerUpdate) ESMDEntityState {
+	if containerUpdate.StopTimestampNS > 0 {
+		return ESMDEntityStateTerminated
+	}
+	switch containerUpdate.ContainerState {
+	case metadatapb.CONTAINER_STATE_WAITING:
+		return ESMDEntityStatePending
+	case metadatapb.CONTAINER_STATE_RUNNING:
+		return ESMDEntityStateRunning
+	case metadatapb.CONTAINER_STATE_TERMINATED:
+		return ESMDEntityStateTerminated
+	default:
+		return ESMDEntityStateUnknown
+	}
+}
+
+func containerUpdateToEMD(u *metadatapb.ResourceUpdate) *EsMDEntity {
+	containerUpdate := u.GetContainerUpdate()
+	// Containers which have not been created yet have no ID to index them by.
+	if containerUpdate.CID == "" {
+		return nil
+	}
+	relatedEntities := []string{}
+	if containerUpdate.PodID != "" {
+		relatedEntities = append(relatedEntities, containerUpdate.PodID)
+	}
+	return &EsMDEntity{
+		UID: containerUpdate.CID,
+		NS:  containerUpdate.Namespace,
+		// Container names are only unique within their pod.
+		Name:               namespacedName(containerUpdate.Namespace, fmt.Sprintf("%s/%s", containerUpdate.PodName, containerUpdate.Name)),
+		Kind:               string(EsMDTypeContainer),
+		TimeStartedNS:      containerUpdate.StartTimestampNS,
+		TimeStoppedNS:      containerUpdate.StopTimestampNS,
+		RelatedEntityNames: relatedEntities,
+		State:              containerStateToState(containerUpdate),
+	}
+}
+
+// workloadState returns the state of a workload controller, which is pending until all of its requested replicas are
+// ready.
+func workloadState(stopTimestamp int64, readyReplicas int32, requestedReplicas int32) ESMDEntityState {
+	if stopTimestamp > 0 {
+		return ESMDEntityStateTerminated
+	}
+	if readyReplicas < requestedReplicas {
+		return ESMDEntityStatePending
+	}
+	return ESMDEntityStateRunning
+}
+
+func deploymentUpdateToEMD(u *metadatapb.ResourceUpdate) *EsMDEntity {
+	deploymentUpdate := u.GetDeploymentUpdate()
+	return &EsMDEntity{
+		UID:                deploymentUpdate.UID,
+		NS:                 deploymentUpdate.Namespace,
+		Name:               namespacedName(deploymentUpdate.Namespace, deploymentUpdate.Name),
+		Kind:               string(EsMDTypeDeployment),
+		TimeStartedNS:      deploymentUpdate.StartTimestampNS,
+		TimeStoppedNS:      deploymentUpdate.StopTimestampNS,
+		RelatedEntityNames: []string{},
+		State:              workloadState(deploymentUpdate.StopTimestampNS, deploymentUpdate.ReadyReplicas, deploymentUpdate.RequestedReplicas),
+	}
+}
+
+func replicaSetUpdateToEMD(u *metadatapb.ResourceUpdate) *EsMDEntity {
+	rsUpdate := u.GetReplicaSetUpdate()
+	// A replica set is related to the deployment which owns it.
+	owners := make([]string, 0, len(rsUpdate.OwnerReferences))
+	for _, o := range rsUpdate.OwnerReferences {
+		owners = append(owners, o.UID)
+	}
+	return &EsMDEntity{
+		UID:                rsUpdate.UID,
+		NS:                 rsUpdate.Namespace,
+		Name:               namespacedName(rsUpdate.Namespace, rsUpdate.Name),
+		Kind:               string(EsMDTypeReplicaSet),
+		TimeStartedNS:      rsUpdate.StartTimestampNS,
+		TimeStoppedNS:      rsUpdate.StopTimestampNS,
+		RelatedEntityNames: owners,
+		State:              workloadState(rsUpdate.StopTimestampNS, rsUpdate.ReadyReplicas, rsUpdate.RequestedReplicas),
+	}
+}
+
 func nodeConditionToState(node *metadatapb.NodeUpdate) ESMDEntityState {
 	if node.StopTimestampNS != 0 {
 		return ESMDEntityStateTerminated
@@ -248,24 +621,41 @@ func nodeConditionToState(node *metadatapb.NodeUpdate) ESMDEntityState {
 	return ESMDEntityStatePending
 }
 
+// resourceUpdateToEMD converts the resource update with the converter which is registered for its kind, and returns
+// nil if the update is not indexed.
 func (v *VizierIndexer) resourceUpdateToEMD(update *metadatapb.ResourceUpdate) *EsMDEntity {
-	switch update.Update.(type) {
-	case *metadatapb.ResourceUpdate_NamespaceUpdate:
-		return v.nsUpdateToEMD(update, update.GetNamespaceUpdate())
-	case *metadatapb.ResourceUpdate_PodUpdate:
-		return v.podUpdateToEMD(update, update.GetPodUpdate())
-	case *metadatapb.ResourceUpdate_ServiceUpdate:
-		return v.serviceUpdateToEMD(update, update.GetServiceUpdate())
-	case *metadatapb.ResourceUpdate_NodeUpdate:
-		return v.nodeUpdateToEMD(update, update.GetNodeUpdate())
-	default:
+	c := entityConverter(update)
+	if c == nil {
 		// We don't care about any other update types.
-		// Notably containerUpdates and nodeUpdates.
 		return nil
 	}
+	e := c.Convert(update)
+	if e == nil {
+		return nil
+	}
+	e.OrgID = v.orgID.String()
+	e.VizierID = v.vizierID.String()
+	e.ClusterUID = v.k8sUID
+	e.UpdateVersion = update.UpdateVersion
+	e.DocVersion = EsMDEntityDocVersion
+	// The update script adds the related entities to those of the existing entity, so they must not be null.
+	if e.RelatedEntityNames == nil {
+		e.RelatedEntityNames = []string{}
+	}
+	return e
 }
 
 const elasticUpdateScript = `
+// Documents are upgraded one version at a time, so that each step can rely on the shape of the previous version.
+if (ctx._source.docVersion == null) {
+  ctx._source.docVersion = 0;
+}
+if (ctx._source.docVersion < 1) {
+  if (ctx._source.relatedEntityNames == null) {
+    ctx._source.relatedEntityNames = new ArrayList();
+  }
+  ctx._source.docVersion = 1;
+}
 if (params.updateVersion <= ctx._source.updateVersion)  {
   ctx.op = 'noop';
 }
@@ -274,10 +664,23 @@ ctx._source.relatedEntityNames = ctx._source.relatedEntityNames.stream().distinc
 ctx._source.timeStoppedNS = params.timeStoppedNS;
 ctx._source.updateVersion = params.updateVersion;
 ctx._source.state = params.state;
+ctx._source.ns = params.ns;
+if (params.podIP != '') {
+  ctx._source.podIP = params.podIP;
+}
+if (params.hostIP != '') {
+  ctx._source.hostIP = params.hostIP;
+}
+if (params.clusterIP != '') {
+  ctx._source.clusterIP = params.clusterIP;
+}
+if (params.labels != null) {
+  ctx._source.labels = params.labels;
+}
 `
 
 func (v *VizierIndexer) streamHandler(msg msgbus.Msg) {
-	ru := metadatapb.ResourceUpdate{}
+	ru := &metadatapb.ResourceUpdate{}
 	err := ru.Unmarshal(msg.Data())
 	if err != nil { // We received an invalid message through stan.
 		log.WithError(err).Error("Could not unmarshal message from stan")
@@ -289,21 +692,85 @@ func (v *VizierIndexer) streamHandler(msg msgbus.Msg) {
 		return
 	}
 
-	err = v.HandleResourceUpdate(&ru)
-	if err != nil {
-		log.WithError(err).Error("Error handling resource update")
-		v.errCh <- err
+	esEntity := v.resourceUpdateToEMD(ru)
+	if esEntity == nil { // We are not handling this resource yet.
 		err = msg.Ack()
 		if err != nil {
 			log.WithError(err).Error("Failed to ack stan msg")
 		}
+		return
+	}
 
+	v.dispatchMu.RLock()
+	defer v.dispatchMu.RUnlock()
+	if v.stopped {
+		// The update is not acked, so that it is redelivered once the indexer is restarted.
 		return
 	}
+	// The updates of an entity are always handled by the same worker, so that they are applied in order.
+	v.workQueues[v.batchIndex(esEntity.UID)] <- &streamUpdate{msg: msg, update: ru, entity: esEntity}
+}
 
-	err = msg.Ack()
+// runWorker indexes the queued updates of the batch's entities, in the order that they were received.
+func (v *VizierIndexer) runWorker(b *updateBatch, queue <-chan *streamUpdate) {
+	defer v.workersDone.Done()
+	for u := range queue {
+		if v.Paused() {
+			// The update is not acked, so that it is redelivered once the subscription is resumed.
+			continue
+		}
+		err := v.indexEntity(b, u.update, u.entity)
+		if errors.Is(err, errElasticUnavailable) {
+			// The update is not acked, so that it is redelivered once elastic is available again.
+			log.WithError(err).Warn("Could not index resource update")
+			continue
+		}
+		if err != nil {
+			log.WithError(err).Error("Error handling resource update")
+			v.errCh <- err
+		}
+
+		err = u.msg.Ack()
+		if err != nil {
+			log.WithError(err).Error("Failed to ack stan msg")
+		}
+	}
+}
+
+// batchIndex returns the index of the batch, and of the worker, which handles the updates of the entity.
+func (v *VizierIndexer) batchIndex(uid string) int {
+	h := fnv.New32a()
+	h.Write([]byte(uid))
+	return int(h.Sum32() % uint32(len(v.batches)))
+}
+
+// circuitOpen returns whether updates are being rejected because elastic is unavailable.
+func (v *VizierIndexer) circuitOpen() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return time.Now().Before(v.circuitOpenUntil)
+}
+
+// Paused returns whether the indexer has stopped receiving updates because elastic is failing.
+func (v *VizierIndexer) Paused() bool {
+	return v.sub != nil && v.sub.isPaused()
+}
+
+// pauseSub pauses the subscription of the indexer, if it has one.
+func (v *VizierIndexer) pauseSub() {
+	if v.sub != nil {
+		v.sub.pause()
+	}
+}
+
+// resumeSub resumes the subscription of the indexer, if it was paused.
+func (v *VizierIndexer) resumeSub() {
+	if v.sub == nil {
+		return
+	}
+	err := v.sub.resume()
 	if err != nil {
-		log.WithError(err).Error("Failed to ack stan msg")
+		log.WithField("vizier", v.vizierID.String()).WithError(err).Error("Failed to resume subscription")
 	}
 }
 
@@ -313,9 +780,23 @@ func (v *VizierIndexer) HandleResourceUpdate(update *metadatapb.ResourceUpdate)
 	if esEntity == nil { // We are not handling this resource yet.
 		return nil
 	}
+	return v.indexEntity(v.batches[v.batchIndex(esEntity.UID)], update, esEntity)
+}
+
+// indexEntity adds the entity of the update to the batch, and flushes the batch if it is due.
+func (v *VizierIndexer) indexEntity(b *updateBatch, update *metadatapb.ResourceUpdate, esEntity *EsMDEntity) error {
+	if v.circuitOpen() {
+		return errElasticUnavailable
+	}
+
+	index, err := v.indices.IndexFor(v.orgID, time.Now())
+	if err != nil {
+		return err
+	}
 
 	id := fmt.Sprintf("%s-%s-%s", v.vizierID, v.k8sUID, esEntity.UID)
 	req := elastic.NewBulkUpdateRequest().
+		Index(index).
 		Id(id).
 		Script(
 			elastic.NewScript(elasticUpdateScript).
@@ -323,27 +804,121 @@ func (v *VizierIndexer) HandleResourceUpdate(update *metadatapb.ResourceUpdate)
 				Param("timeStoppedNS", esEntity.TimeStoppedNS).
 				Param("updateVersion", esEntity.UpdateVersion).
 				Param("state", esEntity.State).
+				Param("ns", esEntity.NS).
+				Param("podIP", esEntity.PodIP).
+				Param("hostIP", esEntity.HostIP).
+				Param("clusterIP", esEntity.ClusterIP).
+				Param("labels", esEntity.Labels).
 				Lang("painless")).
 		Upsert(esEntity)
-	v.bulk.Add(req)
-
-	if v.bulk.NumberOfActions() >= v.maxActionsPerBatch || time.Since(v.lastFlushTime) > v.maxActionBatchFlushInterval {
-		bo := backoff.NewExponentialBackOff()
-		// We never want this to return for now and are hoping
-		// that elastic should start to respond after enough time.
-		bo.MaxElapsedTime = 0
-		bo.MaxInterval = maxElasticBackoffInterval
-
-		retryCount := 0.0
-		retryErr := backoff.Retry(func() error {
-			_, err := v.bulk.Refresh("wait_for").Do(context.Background())
-			elasticRetriesCollector.WithLabelValues(v.vizierID.String()).Set(retryCount)
-			retryCount++
-			return err
-		}, bo)
-		v.lastFlushTime = time.Now()
-		return retryErr
+	if v.indices.RoutesByOrg() {
+		req.Routing(v.orgID.String())
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bulk.Add(req)
+	b.pending = append(b.pending, update)
+
+	if b.bulk.NumberOfActions() >= v.maxActionsPerBatch || time.Since(b.lastFlushTime) > v.maxActionBatchFlushInterval {
+		return v.flush(b)
 	}
 
 	return nil
 }
+
+// flush writes the batch of updates to elastic, and must be called with the batch's lock held. The subscription is
+// paused as soon as an attempt fails, and resumed once the batch is flushed. If elastic is unavailable for longer
+// than the retry budget, the batch is kept to be retried, and further updates are rejected until the circuit
+// breaker's cooldown has passed.
+func (v *VizierIndexer) flush(b *updateBatch) error {
+	bo := backoff.NewExponentialBackOff()
+	bo.MaxElapsedTime = maxElasticRetryTime
+	bo.MaxInterval = maxElasticBackoffInterval
+	var retry backoff.BackOff = bo
+	// A batch which has already exhausted its retries is only retried once, so that updates are not held up for
+	// another retry budget if elastic is still unavailable.
+	v.mu.Lock()
+	if !v.circuitOpenUntil.IsZero() {
+		retry = &backoff.StopBackOff{}
+	}
+	v.mu.Unlock()
+
+	vizierID := v.vizierID.String()
+	batchSize := b.bulk.NumberOfActions()
+	start := time.Now()
+	var resp *elastic.BulkResponse
+	retryCount := 0.0
+	retryErr := backoff.RetryNotify(func() error {
+		var err error
+		resp, err = b.bulk.Refresh("wait_for").Do(context.Background())
+		elasticRetriesCollector.WithLabelValues(vizierID).Set(retryCount)
+		retryCount++
+		return err
+	}, retry, func(error, time.Duration) {
+		// Stop receiving updates while the batch is retried, rather than queueing them behind it or having them
+		// redelivered once they have been in flight for too long.
+		v.pauseSub()
+	})
+	b.lastFlushTime = time.Now()
+	elasticFlushDurationCollector.WithLabelValues(vizierID).Observe(b.lastFlushTime.Sub(start).Seconds())
+
+	if retryErr != nil {
+		elasticFlushFailuresCollector.WithLabelValues(vizierID).Inc()
+		v.mu.Lock()
+		// The bulk service is only reset once its requests succeed, so the batch is retried by the next flush.
+		v.circuitOpenUntil = time.Now().Add(elasticCircuitBreakerCooldown)
+		v.mu.Unlock()
+		elasticCircuitBreakerOpenCollector.WithLabelValues(vizierID).Set(1)
+		// A batch which is only attempted once is not paused by the retries.
+		v.pauseSub()
+		return fmt.Errorf("%w: %v", errElasticUnavailable, retryErr)
+	}
+	v.mu.Lock()
+	v.circuitOpenUntil = time.Time{}
+	v.mu.Unlock()
+	elasticCircuitBreakerOpenCollector.WithLabelValues(vizierID).Set(0)
+	v.resumeSub()
+	elasticBatchSizeCollector.WithLabelValues(vizierID).Observe(float64(batchSize))
+	elasticItemFailuresCollector.WithLabelValues(vizierID).Add(float64(len(resp.Failed())))
+	v.deadLetter(resp, b.pending)
+	b.pending = nil
+	return nil
+}
+
+// deadLetterTopic returns the topic to which the updates from the given topic are published when elastic fails to
+// apply them.
+func deadLetterTopic(topic string) string {
+	return topic + ".DeadLetter"
+}
+
+// deadLetter logs the updates which elastic failed to apply in an otherwise successful batch, eg. because of mapping
+// conflicts or script errors, and publishes them to the dead-letter topic, so that they can be inspected and replayed
+// once the cause is fixed. Retrying these updates would fail again, so they are not retried.
+func (v *VizierIndexer) deadLetter(resp *elastic.BulkResponse, pending []*metadatapb.ResourceUpdate) {
+	for i, items := range resp.Items {
+		for _, item := range items {
+			if item.Status >= 200 && item.Status <= 299 {
+				continue
+			}
+			l := log.WithField("vizier", v.vizierID.String()).WithField("id", item.Id).WithField("status", item.Status)
+			if item.Error != nil {
+				l = l.WithField("type", item.Error.Type).WithField("reason", item.Error.Reason)
+			}
+			l.Error("Elastic failed to apply resource update")
+
+			if v.deadLetterTopic == "" || i >= len(pending) {
+				continue
+			}
+			b, err := pending[i].Marshal()
+			if err != nil {
+				l.WithError(err).Error("Failed to marshal resource update for the dead-letter topic")
+				continue
+			}
+			err = v.st.Publish(v.deadLetterTopic, b)
+			if err != nil {
+				l.WithError(err).Error("Failed to publish resource update to the dead-letter topic")
+			}
+		}
+	}
+}
@@ -21,7 +21,14 @@ package md_test
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -33,6 +40,7 @@ import (
 
 	"px.dev/pixie/src/cloud/indexer/md"
 	"px.dev/pixie/src/shared/k8s/metadatapb"
+	"px.dev/pixie/src/shared/services/msgbus"
 	"px.dev/pixie/src/utils/testingutils"
 )
 
@@ -52,7 +60,7 @@ func TestMain(m *testing.M) {
 	vzID = uuid.Must(uuid.NewV4())
 	orgID = uuid.Must(uuid.NewV4())
 
-	err = md.InitializeMapping(es, indexName, 1)
+	err = md.InitializeMapping(es, indexName, 1, nil)
 	if err != nil {
 		cleanup()
 		log.WithError(err).Fatal("Could not initialize indexes in elastic")
@@ -112,6 +120,7 @@ func TestVizierIndexer_ResourceUpdate(t *testing.T) {
 					TimeStoppedNS:      int64(0),
 					RelatedEntityNames: []string{},
 					UpdateVersion:      1,
+					DocVersion:         md.EsMDEntityDocVersion,
 					State:              md.ESMDEntityStateRunning,
 				},
 			},
@@ -156,6 +165,7 @@ func TestVizierIndexer_ResourceUpdate(t *testing.T) {
 					TimeStoppedNS:      int64(0),
 					RelatedEntityNames: []string{},
 					UpdateVersion:      2,
+					DocVersion:         md.EsMDEntityDocVersion,
 					State:              md.ESMDEntityStatePending,
 				},
 			},
@@ -182,13 +192,14 @@ func TestVizierIndexer_ResourceUpdate(t *testing.T) {
 					VizierID:           vzID.String(),
 					ClusterUID:         "test",
 					UID:                "100",
-					NS:                 "",
+					NS:                 "testns",
 					Name:               "testns",
 					Kind:               "namespace",
 					TimeStartedNS:      int64(1000),
 					TimeStoppedNS:      int64(0),
 					RelatedEntityNames: []string{},
 					UpdateVersion:      0,
+					DocVersion:         md.EsMDEntityDocVersion,
 					State:              md.ESMDEntityStateRunning,
 				},
 			},
@@ -205,6 +216,9 @@ func TestVizierIndexer_ResourceUpdate(t *testing.T) {
 							StartTimestampNS: 1000,
 							StopTimestampNS:  0,
 							Phase:            metadatapb.PENDING,
+							PodIP:            "10.16.1.12",
+							HostIP:           "10.128.0.3",
+							Labels:           `{"team":"payments","app":"checkout"}`,
 						},
 					},
 					UpdateVersion:     2,
@@ -218,14 +232,150 @@ func TestVizierIndexer_ResourceUpdate(t *testing.T) {
 					VizierID:           vzID.String(),
 					ClusterUID:         "test",
 					UID:                "300",
-					NS:                 "",
+					NS:                 "pl",
 					Name:               "pl/test-pod",
 					Kind:               "pod",
 					TimeStartedNS:      int64(1000),
 					TimeStoppedNS:      int64(0),
 					RelatedEntityNames: []string{},
 					UpdateVersion:      2,
+					DocVersion:         md.EsMDEntityDocVersion,
 					State:              md.ESMDEntityStatePending,
+					PodIP:              "10.16.1.12",
+					HostIP:             "10.128.0.3",
+					Labels:             []string{"app=checkout", "team=payments"},
+				},
+			},
+		},
+		{
+			name: "container update",
+			updates: []*metadatapb.ResourceUpdate{
+				{
+					Update: &metadatapb.ResourceUpdate_ContainerUpdate{
+						ContainerUpdate: &metadatapb.ContainerUpdate{
+							CID:              "500",
+							Name:             "test-container",
+							Namespace:        "pl",
+							PodID:            "300",
+							PodName:          "test-pod",
+							StartTimestampNS: 1000,
+							StopTimestampNS:  0,
+							ContainerState:   metadatapb.CONTAINER_STATE_RUNNING,
+						},
+					},
+					UpdateVersion:     3,
+					PrevUpdateVersion: 2,
+				},
+				{
+					// Containers without an ID are not indexed.
+					Update: &metadatapb.ResourceUpdate_ContainerUpdate{
+						ContainerUpdate: &metadatapb.ContainerUpdate{
+							Name:           "waiting-container",
+							Namespace:      "pl",
+							PodID:          "300",
+							PodName:        "test-pod",
+							ContainerState: metadatapb.CONTAINER_STATE_WAITING,
+						},
+					},
+					UpdateVersion:     4,
+					PrevUpdateVersion: 3,
+				},
+			},
+			updateKind: "container",
+			expectedResults: []*md.EsMDEntity{
+				{
+					OrgID:              orgID.String(),
+					VizierID:           vzID.String(),
+					ClusterUID:         "test",
+					UID:                "500",
+					NS:                 "pl",
+					Name:               "pl/test-pod/test-container",
+					Kind:               "container",
+					TimeStartedNS:      int64(1000),
+					TimeStoppedNS:      int64(0),
+					RelatedEntityNames: []string{"300"},
+					UpdateVersion:      3,
+					DocVersion:         md.EsMDEntityDocVersion,
+					State:              md.ESMDEntityStateRunning,
+				},
+			},
+		},
+		{
+			name: "deployment update",
+			updates: []*metadatapb.ResourceUpdate{
+				{
+					Update: &metadatapb.ResourceUpdate_DeploymentUpdate{
+						DeploymentUpdate: &metadatapb.DeploymentUpdate{
+							UID:               "600",
+							Name:              "checkout-deployment",
+							Namespace:         "pl",
+							StartTimestampNS:  1000,
+							StopTimestampNS:   0,
+							ReadyReplicas:     1,
+							RequestedReplicas: 2,
+						},
+					},
+					UpdateVersion:     1,
+					PrevUpdateVersion: 0,
+				},
+			},
+			updateKind: "deployment",
+			expectedResults: []*md.EsMDEntity{
+				{
+					OrgID:              orgID.String(),
+					VizierID:           vzID.String(),
+					ClusterUID:         "test",
+					UID:                "600",
+					NS:                 "pl",
+					Name:               "pl/checkout-deployment",
+					Kind:               "deployment",
+					TimeStartedNS:      int64(1000),
+					TimeStoppedNS:      int64(0),
+					RelatedEntityNames: []string{},
+					UpdateVersion:      1,
+					DocVersion:         md.EsMDEntityDocVersion,
+					State:              md.ESMDEntityStatePending,
+				},
+			},
+		},
+		{
+			name: "replicaset update",
+			updates: []*metadatapb.ResourceUpdate{
+				{
+					Update: &metadatapb.ResourceUpdate_ReplicaSetUpdate{
+						ReplicaSetUpdate: &metadatapb.ReplicaSetUpdate{
+							UID:               "700",
+							Name:              "checkout-deployment-5d4f8",
+							Namespace:         "pl",
+							StartTimestampNS:  1000,
+							StopTimestampNS:   0,
+							ReadyReplicas:     2,
+							RequestedReplicas: 2,
+							OwnerReferences: []*metadatapb.OwnerReference{
+								{Kind: "Deployment", Name: "checkout-deployment", UID: "600"},
+							},
+						},
+					},
+					UpdateVersion:     2,
+					PrevUpdateVersion: 1,
+				},
+			},
+			updateKind: "replicaset",
+			expectedResults: []*md.EsMDEntity{
+				{
+					OrgID:              orgID.String(),
+					VizierID:           vzID.String(),
+					ClusterUID:         "test",
+					UID:                "700",
+					NS:                 "pl",
+					Name:               "pl/checkout-deployment-5d4f8",
+					Kind:               "replicaset",
+					TimeStartedNS:      int64(1000),
+					TimeStoppedNS:      int64(0),
+					RelatedEntityNames: []string{"600"},
+					UpdateVersion:      2,
+					DocVersion:         md.EsMDEntityDocVersion,
+					State:              md.ESMDEntityStateRunning,
 				},
 			},
 		},
@@ -239,6 +389,7 @@ func TestVizierIndexer_ResourceUpdate(t *testing.T) {
 							Name:             "test-service",
 							StartTimestampNS: 1000,
 							StopTimestampNS:  0,
+							ClusterIP:        "10.20.0.1",
 						},
 					},
 					UpdateVersion:     1,
@@ -259,7 +410,9 @@ func TestVizierIndexer_ResourceUpdate(t *testing.T) {
 					TimeStoppedNS:      int64(0),
 					RelatedEntityNames: []string{},
 					UpdateVersion:      1,
+					DocVersion:         md.EsMDEntityDocVersion,
 					State:              md.ESMDEntityStateRunning,
+					ClusterIP:          "10.20.0.1",
 				},
 			},
 		},
@@ -305,6 +458,7 @@ func TestVizierIndexer_ResourceUpdate(t *testing.T) {
 					TimeStoppedNS:      int64(0),
 					RelatedEntityNames: []string{},
 					UpdateVersion:      2,
+					DocVersion:         md.EsMDEntityDocVersion,
 					State:              md.ESMDEntityStateRunning,
 				},
 			},
@@ -365,6 +519,7 @@ func TestVizierIndexer_ResourceUpdate(t *testing.T) {
 					TimeStoppedNS:      int64(1200),
 					RelatedEntityNames: []string{"abcd", "efgh"},
 					UpdateVersion:      4,
+					DocVersion:         md.EsMDEntityDocVersion,
 					State:              md.ESMDEntityStateTerminated,
 				},
 			},
@@ -372,7 +527,7 @@ func TestVizierIndexer_ResourceUpdate(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			indexer := md.NewVizierIndexerWithBulkSettings(vzID, orgID, "test", indexName, nil, elasticClient, 1, time.Second*1)
+			indexer := md.NewVizierIndexerWithBulkSettings(vzID, orgID, "test", md.NewIndexManager(elasticClient, indexName, "", 1, nil), nil, elasticClient, 1, time.Second*1, 1)
 
 			for _, u := range test.updates {
 				err := indexer.HandleResourceUpdate(u)
@@ -396,3 +551,368 @@ func TestVizierIndexer_ResourceUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestVizierIndexer_FlushesPendingUpdates(t *testing.T) {
+	_, sc, cleanup := testingutils.MustStartTestStan(t, "stan", "test-client")
+	defer cleanup()
+
+	st, err := msgbus.NewSTANStreamer(sc)
+	require.NoError(t, err)
+
+	// The batch is never full, so the update is only indexed by the background flush.
+	indexer := md.NewVizierIndexerWithBulkSettings(vzID, orgID, "test", md.NewIndexManager(elasticClient, indexName, "", 1, nil), st, elasticClient, 100, time.Millisecond*500, 1)
+	topic := "MetadataIndex.flushtest"
+	require.NoError(t, indexer.Start(topic))
+	defer indexer.Stop()
+
+	update := &metadatapb.ResourceUpdate{
+		Update: &metadatapb.ResourceUpdate_NamespaceUpdate{
+			NamespaceUpdate: &metadatapb.NamespaceUpdate{
+				UID:              "flush-ns-uid",
+				Name:             "flush-ns",
+				StartTimestampNS: 1000,
+			},
+		},
+		UpdateVersion: 1,
+	}
+	b, err := update.Marshal()
+	require.NoError(t, err)
+	require.NoError(t, st.Publish(topic, b))
+
+	require.Eventually(t, func() bool {
+		_, err := elasticClient.Refresh(indexName).Do(context.Background())
+		if err != nil {
+			return false
+		}
+		resp, err := elasticClient.Search().
+			Index(indexName).
+			Query(elastic.NewMatchPhraseQuery("uid", "flush-ns-uid")).
+			Do(context.Background())
+		return err == nil && resp.TotalHits() == 1
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestVizierIndexer_FlushesPendingUpdatesOnStop(t *testing.T) {
+	_, sc, cleanup := testingutils.MustStartTestStan(t, "stan", "test-client")
+	defer cleanup()
+
+	st, err := msgbus.NewSTANStreamer(sc)
+	require.NoError(t, err)
+
+	// Neither the batch size nor the flush interval is reached before the indexer is stopped.
+	indexer := md.NewVizierIndexerWithBulkSettings(vzID, orgID, "test", md.NewIndexManager(elasticClient, indexName, "", 1, nil), st, elasticClient, 100, time.Hour, 1)
+	require.NoError(t, indexer.Start("MetadataIndex.stoptest"))
+
+	err = indexer.HandleResourceUpdate(&metadatapb.ResourceUpdate{
+		Update: &metadatapb.ResourceUpdate_NamespaceUpdate{
+			NamespaceUpdate: &metadatapb.NamespaceUpdate{
+				UID:              "stop-ns-uid",
+				Name:             "stop-ns",
+				StartTimestampNS: 1000,
+			},
+		},
+		UpdateVersion: 1,
+	})
+	require.NoError(t, err)
+	indexer.Stop()
+
+	resp, err := elasticClient.Search().
+		Index(indexName).
+		Query(elastic.NewMatchPhraseQuery("uid", "stop-ns-uid")).
+		Do(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.TotalHits())
+}
+
+func TestVizierIndexer_Replay(t *testing.T) {
+	_, sc, cleanup := testingutils.MustStartTestStan(t, "stan", "test-client")
+	defer cleanup()
+
+	st, err := msgbus.NewSTANStreamer(sc)
+	require.NoError(t, err)
+
+	topic := "MetadataIndex.replaytest"
+	for _, uid := range []string{"replay-ns-1", "replay-ns-2"} {
+		update := &metadatapb.ResourceUpdate{
+			Update: &metadatapb.ResourceUpdate_NamespaceUpdate{
+				NamespaceUpdate: &metadatapb.NamespaceUpdate{
+					UID:              uid,
+					Name:             uid,
+					StartTimestampNS: 1000,
+				},
+			},
+			UpdateVersion: 1,
+		}
+		b, err := update.Marshal()
+		require.NoError(t, err)
+		require.NoError(t, st.Publish(topic, b))
+	}
+
+	alias := "test_md_replay"
+	require.NoError(t, md.InitializeMapping(elasticClient, alias, 1, nil))
+	// The updates are replayed into a fresh index, and the batch is flushed once the replay is done.
+	indexer := md.NewVizierIndexerWithBulkSettings(vzID, orgID, "test", md.NewIndexManager(elasticClient, alias, "", 1, nil), st, elasticClient, 100, time.Hour, 1)
+	indexed, err := indexer.Replay(topic, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), indexed)
+
+	resp, err := elasticClient.Search().Index(alias).Do(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resp.TotalHits())
+}
+
+func TestVizierIndexer_DeadLettersFailedUpdates(t *testing.T) {
+	_, sc, cleanup := testingutils.MustStartTestStan(t, "stan", "test-client")
+	defer cleanup()
+
+	st, err := msgbus.NewSTANStreamer(sc)
+	require.NoError(t, err)
+
+	topic := "MetadataIndex.deadlettertest"
+	indexer := md.NewVizierIndexerWithBulkSettings(vzID, orgID, "test", md.NewIndexManager(elasticClient, indexName, "", 1, nil), st, elasticClient, 1, time.Hour, 1)
+	require.NoError(t, indexer.Start(topic))
+	defer indexer.Stop()
+
+	deadLetters := make(chan msgbus.Msg, 1)
+	sub, err := st.PersistentSubscribe(topic+".DeadLetter", "test", func(m msgbus.Msg) {
+		deadLetters <- m
+		require.NoError(t, m.Ack())
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	// The pod IP does not match the ip mapping, so elastic fails to apply the update while the batch succeeds.
+	update := &metadatapb.ResourceUpdate{
+		Update: &metadatapb.ResourceUpdate_PodUpdate{
+			PodUpdate: &metadatapb.PodUpdate{
+				UID:              "dead-letter-pod",
+				Name:             "dead-letter-pod",
+				Namespace:        "pl",
+				StartTimestampNS: 1000,
+				Phase:            metadatapb.RUNNING,
+				PodIP:            "not-an-ip",
+			},
+		},
+		UpdateVersion: 1,
+	}
+	require.NoError(t, indexer.HandleResourceUpdate(update))
+
+	select {
+	case m := <-deadLetters:
+		ru := &metadatapb.ResourceUpdate{}
+		require.NoError(t, ru.Unmarshal(m.Data()))
+		assert.Equal(t, update, ru)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the dead-lettered update")
+	}
+}
+
+func TestVizierIndexer_Workers(t *testing.T) {
+	_, sc, cleanup := testingutils.MustStartTestStan(t, "stan", "test-client")
+	defer cleanup()
+
+	st, err := msgbus.NewSTANStreamer(sc)
+	require.NoError(t, err)
+
+	alias := "test_md_workers"
+	require.NoError(t, md.InitializeMapping(elasticClient, alias, 1, nil))
+	indexer := md.NewVizierIndexerWithBulkSettings(vzID, orgID, "test", md.NewIndexManager(elasticClient, alias, "", 1, nil), st, elasticClient, 1, time.Hour, 4)
+	topic := "MetadataIndex.workerstest"
+	require.NoError(t, indexer.Start(topic))
+	defer indexer.Stop()
+
+	// Each namespace is started and then stopped, so it is only terminated if its updates are applied in order.
+	numNamespaces := 8
+	for i := 0; i < numNamespaces; i++ {
+		for version, stopTimestamp := range []int64{0, 2000} {
+			update := &metadatapb.ResourceUpdate{
+				Update: &metadatapb.ResourceUpdate_NamespaceUpdate{
+					NamespaceUpdate: &metadatapb.NamespaceUpdate{
+						UID:              fmt.Sprintf("workers-ns-%d", i),
+						Name:             fmt.Sprintf("workers-ns-%d", i),
+						StartTimestampNS: 1000,
+						StopTimestampNS:  stopTimestamp,
+					},
+				},
+				UpdateVersion: int64(version + 1),
+			}
+			b, err := update.Marshal()
+			require.NoError(t, err)
+			require.NoError(t, st.Publish(topic, b))
+		}
+	}
+
+	require.Eventually(t, func() bool {
+		_, err := elasticClient.Refresh(alias).Do(context.Background())
+		if err != nil {
+			return false
+		}
+		resp, err := elasticClient.Search().
+			Index(alias).
+			Query(elastic.NewTermQuery("state", md.ESMDEntityStateTerminated)).
+			Do(context.Background())
+		return err == nil && resp.TotalHits() == int64(numNamespaces)
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestVizierIndexer_UpgradesDocVersion(t *testing.T) {
+	ctx := context.Background()
+	alias := "test_md_doc_version"
+	require.NoError(t, md.InitializeMapping(elasticClient, alias, 1, nil))
+
+	// A document which was written before documents were versioned, and without related entities.
+	id := fmt.Sprintf("%s-%s-%s", vzID, "test", "legacy-pod")
+	legacy := map[string]interface{}{
+		"orgID":         orgID.String(),
+		"vizierID":      vzID.String(),
+		"clusterUID":    "test",
+		"uid":           "legacy-pod",
+		"name":          "pl/legacy-pod",
+		"kind":          "pod",
+		"timeStartedNS": 1000,
+		"updateVersion": 1,
+	}
+	_, err := elasticClient.Index().Index(alias).Id(id).BodyJson(legacy).Refresh("true").Do(ctx)
+	require.NoError(t, err)
+
+	indexer := md.NewVizierIndexerWithBulkSettings(vzID, orgID, "test", md.NewIndexManager(elasticClient, alias, "", 1, nil), nil, elasticClient, 1, time.Hour, 1)
+	err = indexer.HandleResourceUpdate(&metadatapb.ResourceUpdate{
+		Update: &metadatapb.ResourceUpdate_PodUpdate{
+			PodUpdate: &metadatapb.PodUpdate{
+				UID:              "legacy-pod",
+				Name:             "legacy-pod",
+				Namespace:        "pl",
+				StartTimestampNS: 1000,
+				Phase:            metadatapb.RUNNING,
+			},
+		},
+		UpdateVersion: 2,
+	})
+	require.NoError(t, err)
+
+	resp, err := elasticClient.Get().Index(alias).Id(id).Do(ctx)
+	require.NoError(t, err)
+	res := &md.EsMDEntity{}
+	require.NoError(t, json.Unmarshal(resp.Source, res))
+	assert.Equal(t, md.EsMDEntityDocVersion, res.DocVersion)
+	assert.Equal(t, int64(2), res.UpdateVersion)
+}
+
+func TestVizierIndexer_PausesWhileElasticFails(t *testing.T) {
+	_, sc, cleanup := testingutils.MustStartTestStan(t, "stan", "test-client")
+	defer cleanup()
+
+	st, err := msgbus.NewSTANStreamer(sc)
+	require.NoError(t, err)
+
+	// A fake elastic which fails the bulk requests until it is made available.
+	var available int32
+	var mu sync.Mutex
+	var bulkBodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&available) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		bulkBodies = append(bulkBodies, string(body))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"took":1,"errors":false,"items":[]}`))
+	}))
+	defer srv.Close()
+
+	es, err := elastic.NewClient(elastic.SetURL(srv.URL), elastic.SetSniff(false), elastic.SetHealthcheck(false))
+	require.NoError(t, err)
+
+	indexer := md.NewVizierIndexerWithBulkSettings(vzID, orgID, "test", md.NewIndexManager(es, "test_md_paused", "", 1, nil), st, es, 1, time.Hour, 1)
+	topic := "MetadataIndex.pausetest"
+	require.NoError(t, indexer.Start(topic))
+	defer indexer.Stop()
+
+	publish := func(uid string) {
+		update := &metadatapb.ResourceUpdate{
+			Update: &metadatapb.ResourceUpdate_NamespaceUpdate{
+				NamespaceUpdate: &metadatapb.NamespaceUpdate{
+					UID:              uid,
+					Name:             uid,
+					StartTimestampNS: 1000,
+				},
+			},
+			UpdateVersion: 1,
+		}
+		b, err := update.Marshal()
+		require.NoError(t, err)
+		require.NoError(t, st.Publish(topic, b))
+	}
+
+	publish("paused-ns-1")
+	require.Eventually(t, indexer.Paused, 10*time.Second, 50*time.Millisecond)
+
+	// The update which is published while the indexer is paused is received once it resumes.
+	publish("paused-ns-2")
+	atomic.StoreInt32(&available, 1)
+	require.Eventually(t, func() bool {
+		return !indexer.Paused()
+	}, 10*time.Second, 50*time.Millisecond)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		bodies := strings.Join(bulkBodies, "")
+		return strings.Contains(bodies, "paused-ns-1") && strings.Contains(bodies, "paused-ns-2")
+	}, 10*time.Second, 50*time.Millisecond)
+}
+
+func TestVizierIndexer_RegisteredEntityConverter(t *testing.T) {
+	ctx := context.Background()
+	alias := "test_md_converter"
+	require.NoError(t, md.InitializeMapping(elasticClient, alias, 1, nil))
+
+	// The registered converter extends the built-in one, which is restored afterwards.
+	var builtin md.EntityConverter
+	builtin = md.RegisterEntityConverter(&metadatapb.ResourceUpdate_NamespaceUpdate{}, md.EntityConverterFunc(func(u *metadatapb.ResourceUpdate) *md.EsMDEntity {
+		e := builtin.Convert(u)
+		e.Labels = []string{"converted=true"}
+		return e
+	}))
+	require.NotNil(t, builtin)
+	defer md.RegisterEntityConverter(&metadatapb.ResourceUpdate_NamespaceUpdate{}, builtin)
+
+	indexer := md.NewVizierIndexerWithBulkSettings(vzID, orgID, "test", md.NewIndexManager(elasticClient, alias, "", 1, nil), nil, elasticClient, 1, time.Hour, 1)
+	err := indexer.HandleResourceUpdate(&metadatapb.ResourceUpdate{
+		Update: &metadatapb.ResourceUpdate_NamespaceUpdate{
+			NamespaceUpdate: &metadatapb.NamespaceUpdate{
+				UID:              "converted-ns-uid",
+				Name:             "converted-ns",
+				StartTimestampNS: 1000,
+			},
+		},
+		UpdateVersion: 1,
+	})
+	require.NoError(t, err)
+
+	resp, err := elasticClient.Get().Index(alias).Id(fmt.Sprintf("%s-%s-%s", vzID, "test", "converted-ns-uid")).Do(ctx)
+	require.NoError(t, err)
+	res := &md.EsMDEntity{}
+	require.NoError(t, json.Unmarshal(resp.Source, res))
+	assert.Equal(t, &md.EsMDEntity{
+		OrgID:              orgID.String(),
+		VizierID:           vzID.String(),
+		ClusterUID:         "test",
+		UID:                "converted-ns-uid",
+		Name:               "converted-ns",
+		NS:                 "converted-ns",
+		Kind:               "namespace",
+		TimeStartedNS:      1000,
+		RelatedEntityNames: []string{},
+		UpdateVersion:      1,
+		State:              md.ESMDEntityStateRunning,
+		Labels:             []string{"converted=true"},
+		DocVersion:         md.EsMDEntityDocVersion,
+	}, res)
+}
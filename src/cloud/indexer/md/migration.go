@@ -0,0 +1,149 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package md
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/olivere/elastic/v7"
+	log "github.com/sirupsen/logrus"
+)
+
+// IndexMappingVersion is the version of the IndexMapping. It must be incremented whenever the IndexMapping changes, so
+// that the entities are reindexed into an index with the new mapping.
+const IndexMappingVersion = 2
+
+// versionedIndexName returns the name of the index behind the alias for the given version of the IndexMapping.
+func versionedIndexName(alias string, version int) string {
+	return fmt.Sprintf("%s_v%d", alias, version)
+}
+
+// getAliasedIndex returns the index which the alias points to, and whether the alias is instead the name of an
+// unversioned index, which were created before entities were written through an alias. The index is empty if there
+// is neither.
+func getAliasedIndex(ctx context.Context, es *elastic.Client, alias string) (string, bool, error) {
+	resp, err := es.Aliases().Alias(alias).Do(ctx)
+	if err == nil {
+		indices := resp.IndicesByAlias(alias)
+		if len(indices) != 1 {
+			return "", false, fmt.Errorf("alias %s must point to a single index, found %v", alias, indices)
+		}
+		return indices[0], false, nil
+	}
+	if !elastic.IsNotFound(err) {
+		return "", false, err
+	}
+	exists, err := es.IndexExists(alias).Do(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	if exists {
+		return alias, true, nil
+	}
+	return "", false, nil
+}
+
+// getMappingVersion returns the version of the IndexMapping of the index behind the alias, or 0 if it is unversioned.
+func getMappingVersion(alias string, index string) int {
+	version, err := strconv.Atoi(strings.TrimPrefix(index, alias+"_v"))
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// initializeVersionedIndex creates the index for the current IndexMappingVersion behind the alias. If the alias
+// points to an index with an older mapping, its entities are reindexed into the new index before the alias is
+// swapped to it, so that entities keep being read and written through the alias while the mapping is migrated.
+func initializeVersionedIndex(es *elastic.Client, alias string, replicas int, policyName string) error {
+	ctx := context.Background()
+	prev, unversioned, err := getAliasedIndex(ctx, es, alias)
+	if err != nil {
+		return err
+	}
+	target := versionedIndexName(alias, IndexMappingVersion)
+	if prev == target {
+		return setIndexSettings(es, target, replicas, policyName)
+	}
+	// An indexer which has not been upgraded yet must not downgrade the mapping of an upgraded index.
+	if prev != "" && getMappingVersion(alias, prev) > IndexMappingVersion {
+		log.WithField("index", prev).Warn("Metadata index has a newer mapping, not migrating it")
+		return nil
+	}
+
+	err = initializeIndex(es, target, replicas, policyName)
+	if err != nil {
+		return err
+	}
+	if prev == "" {
+		_, err = es.Alias().Add(target, alias).Do(ctx)
+		return err
+	}
+
+	log.WithField("from", prev).WithField("to", target).Info("Migrating metadata index")
+	// Writes to the previous index are blocked while it is reindexed, so that none of them are lost before the alias
+	// is swapped. The indexers retry the blocked writes, which go to the new index once the alias has been swapped.
+	err = setWriteBlock(ctx, es, prev, true)
+	if err != nil {
+		return err
+	}
+	err = swapVersionedIndex(ctx, es, alias, prev, target, unversioned)
+	if err != nil {
+		// Another indexer may have migrated the index in the meantime.
+		if current, _, aliasErr := getAliasedIndex(ctx, es, alias); aliasErr == nil && current == target {
+			return nil
+		}
+		if unblockErr := setWriteBlock(ctx, es, prev, false); unblockErr != nil {
+			log.WithError(unblockErr).WithField("index", prev).Error("Failed to unblock writes to metadata index")
+		}
+		return err
+	}
+	// An unversioned index has the name of the alias, so it was already deleted along with adding the alias.
+	if unversioned {
+		return nil
+	}
+	_, err = es.DeleteIndex(prev).Do(ctx)
+	return err
+}
+
+// swapVersionedIndex reindexes the entities of the previous index into the target index, and then swaps the alias
+// over to the target index.
+func swapVersionedIndex(ctx context.Context, es *elastic.Client, alias, prev, target string, unversioned bool) error {
+	_, err := es.Reindex().SourceIndex(prev).DestinationIndex(target).Refresh("true").WaitForCompletion(true).Do(ctx)
+	if err != nil {
+		return err
+	}
+	remove := elastic.AliasAction(elastic.NewAliasRemoveAction(alias).Index(prev))
+	if unversioned {
+		remove = elastic.NewAliasRemoveIndexAction(prev)
+	}
+	_, err = es.Alias().Action(elastic.NewAliasAddAction(alias).Index(target), remove).Do(ctx)
+	return err
+}
+
+// setWriteBlock blocks or unblocks writes to the index. Reads, and changes to the index's aliases, are unaffected.
+func setWriteBlock(ctx context.Context, es *elastic.Client, index string, blocked bool) error {
+	_, err := es.IndexPutSettings(index).BodyJson(map[string]interface{}{
+		"index.blocks.write": blocked,
+	}).Do(ctx)
+	return err
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package md_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/olivere/elastic/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/indexer/md"
+)
+
+func TestInitializeMapping_Migration(t *testing.T) {
+	ctx := context.Background()
+	entity := &md.EsMDEntity{
+		OrgID:              orgID.String(),
+		UID:                "100",
+		Name:               "pl/test-pod",
+		Kind:               "pod",
+		RelatedEntityNames: []string{},
+	}
+
+	tests := []struct {
+		name  string
+		alias string
+		// The index which the alias points to before the migration.
+		prevIndex string
+	}{
+		{
+			name:      "unversioned index",
+			alias:     "test_md_unversioned",
+			prevIndex: "test_md_unversioned",
+		},
+		{
+			name:      "previous version",
+			alias:     "test_md_versioned",
+			prevIndex: fmt.Sprintf("test_md_versioned_v%d", md.IndexMappingVersion-1),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := elasticClient.CreateIndex(test.prevIndex).Body(md.IndexMapping).Do(ctx)
+			require.NoError(t, err)
+			if test.prevIndex != test.alias {
+				_, err = elasticClient.Alias().Add(test.prevIndex, test.alias).Do(ctx)
+				require.NoError(t, err)
+			}
+			_, err = elasticClient.Index().Index(test.prevIndex).Id(entity.UID).BodyJson(entity).Refresh("true").Do(ctx)
+			require.NoError(t, err)
+
+			require.NoError(t, md.InitializeMapping(elasticClient, test.alias, 1, nil))
+			// Migrating is a no-op once the alias points to the current version.
+			require.NoError(t, md.InitializeMapping(elasticClient, test.alias, 1, nil))
+
+			aliases, err := elasticClient.Aliases().Alias(test.alias).Do(ctx)
+			require.NoError(t, err)
+			target := fmt.Sprintf("%s_v%d", test.alias, md.IndexMappingVersion)
+			assert.Equal(t, []string{target}, aliases.IndicesByAlias(test.alias))
+
+			exists, err := elasticClient.IndexExists(test.prevIndex).Do(ctx)
+			require.NoError(t, err)
+			assert.False(t, exists)
+
+			resp, err := elasticClient.Search().Index(test.alias).Query(elastic.NewTermQuery("uid", entity.UID)).Do(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), resp.TotalHits())
+
+			// Writes were only blocked on the previous index, so the migrated index accepts them.
+			_, err = elasticClient.Index().Index(test.alias).Id("101").BodyJson(entity).Refresh("true").Do(ctx)
+			require.NoError(t, err)
+		})
+	}
+}
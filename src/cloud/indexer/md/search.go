@@ -0,0 +1,170 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package md
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gofrs/uuid"
+	"github.com/olivere/elastic/v7"
+)
+
+const (
+	// DefaultSearchLimit is the number of entities which are returned by a search that does not specify a limit.
+	DefaultSearchLimit = 100
+	// MaxSearchLimit is the maximum number of entities which are returned by a search.
+	MaxSearchLimit = 1000
+	// The maximum number of documents which elastic returns for a search, by default.
+	maxSearchWindow = 10000
+)
+
+// EntitySearch is a search for the entities of an org. The filters which are left empty are ignored.
+type EntitySearch struct {
+	OrgID      uuid.UUID
+	ClusterUID string
+	// NamePrefix matches the start of the entities' names, which include their namespace.
+	NamePrefix string
+	Kinds      []EsMDType
+	Namespace  string
+	States     []ESMDEntityState
+	// The entities which existed at some point within the time range are matched. Either end of the range may be
+	// left open by leaving it as 0.
+	StartTimeNS int64
+	EndTimeNS   int64
+	// Limit is the maximum number of entities to return, which defaults to DefaultSearchLimit.
+	Limit int
+}
+
+func (s *EntitySearch) query() elastic.Query {
+	q := elastic.NewBoolQuery().Filter(elastic.NewTermQuery("orgID", s.OrgID.String()))
+	if s.ClusterUID != "" {
+		q.Filter(elastic.NewTermQuery("clusterUID", s.ClusterUID))
+	}
+	if s.NamePrefix != "" {
+		q.Filter(elastic.NewPrefixQuery("name.keyword", s.NamePrefix))
+	}
+	if len(s.Kinds) > 0 {
+		kinds := make([]interface{}, len(s.Kinds))
+		for i, k := range s.Kinds {
+			kinds[i] = string(k)
+		}
+		q.Filter(elastic.NewTermsQuery("kind", kinds...))
+	}
+	if s.Namespace != "" {
+		q.Filter(elastic.NewTermQuery("ns", s.Namespace))
+	}
+	if len(s.States) > 0 {
+		states := make([]interface{}, len(s.States))
+		for i, state := range s.States {
+			states[i] = state
+		}
+		q.Filter(elastic.NewTermsQuery("state", states...))
+	}
+	if s.EndTimeNS > 0 {
+		q.Filter(elastic.NewRangeQuery("timeStartedNS").Lte(s.EndTimeNS))
+	}
+	if s.StartTimeNS > 0 {
+		// Entities which have not stopped have a stop time of 0.
+		q.Filter(elastic.NewBoolQuery().
+			Should(elastic.NewTermQuery("timeStoppedNS", 0)).
+			Should(elastic.NewRangeQuery("timeStoppedNS").Gte(s.StartTimeNS)).
+			MinimumNumberShouldMatch(1))
+	}
+	return q
+}
+
+// SearchEntities returns the entities which match the search, sorted by name, and whether more entities matched the
+// search than were returned.
+func (m *IndexManager) SearchEntities(ctx context.Context, s *EntitySearch) ([]*EsMDEntity, bool, error) {
+	limit := s.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	// An entity has a document in each of the templated indices that it was updated in, so the documents are
+	// de-duplicated before the limit is applied. Since the documents of an entity share its name, they are adjacent in
+	// the results, and more documents are fetched until every document of the returned entities has been seen.
+	size := limit + 1
+	for {
+		search := m.es.Search().Index(m.alias)
+		if m.orgAliases {
+			search = m.es.Search().Index(OrgAliasName(m.alias, s.OrgID)).Routing(s.OrgID.String())
+		}
+		resp, err := search.
+			Query(s.query()).
+			Sort("name.keyword", true).
+			Size(size).
+			Do(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		entities, err := dedupEntityHits(resp.Hits.Hits)
+		if err != nil {
+			return nil, false, err
+		}
+
+		done := len(resp.Hits.Hits) < size || size >= maxSearchWindow
+		if !done && len(entities) > limit {
+			// Documents with the name of the last returned entity may still follow the fetched ones.
+			lastHit := &EsMDEntity{}
+			err = json.Unmarshal(resp.Hits.Hits[len(resp.Hits.Hits)-1].Source, lastHit)
+			if err != nil {
+				return nil, false, err
+			}
+			done = lastHit.Name != entities[limit-1].Name
+		}
+		if done {
+			if len(entities) > limit {
+				return entities[:limit], true, nil
+			}
+			return entities, false, nil
+		}
+
+		size *= 2
+		if size > maxSearchWindow {
+			size = maxSearchWindow
+		}
+	}
+}
+
+// dedupEntityHits returns the latest version of each entity in the hits, in the order that the entities first appear.
+func dedupEntityHits(hits []*elastic.SearchHit) ([]*EsMDEntity, error) {
+	entities := make([]*EsMDEntity, 0, len(hits))
+	byID := make(map[string]int)
+	for _, hit := range hits {
+		e := &EsMDEntity{}
+		err := json.Unmarshal(hit.Source, e)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := byID[hit.Id]; ok {
+			if e.UpdateVersion > entities[i].UpdateVersion {
+				entities[i] = e
+			}
+			continue
+		}
+		byID[hit.Id] = len(entities)
+		entities = append(entities, e)
+	}
+	return entities, nil
+}
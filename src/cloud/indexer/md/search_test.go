@@ -0,0 +1,193 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package md_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/indexer/md"
+	"px.dev/pixie/src/shared/k8s/metadatapb"
+)
+
+func TestIndexManager_SearchEntities(t *testing.T) {
+	alias := "test_md_search"
+	require.NoError(t, md.InitializeMapping(elasticClient, alias, 1, nil))
+	indices := md.NewIndexManager(elasticClient, alias, "", 1, nil)
+
+	searchOrgID := uuid.Must(uuid.NewV4())
+	indexer := md.NewVizierIndexerWithBulkSettings(vzID, searchOrgID, "search-cluster", indices, nil, elasticClient, 1, time.Hour, 1)
+	updates := []*metadatapb.ResourceUpdate{
+		{
+			Update: &metadatapb.ResourceUpdate_NamespaceUpdate{
+				NamespaceUpdate: &metadatapb.NamespaceUpdate{
+					UID:              "search-ns",
+					Name:             "pl",
+					StartTimestampNS: 1000,
+				},
+			},
+			UpdateVersion: 1,
+		},
+		{
+			Update: &metadatapb.ResourceUpdate_PodUpdate{
+				PodUpdate: &metadatapb.PodUpdate{
+					UID:              "search-pod-1",
+					Name:             "vizier-pem-1",
+					Namespace:        "pl",
+					StartTimestampNS: 1000,
+					Phase:            metadatapb.RUNNING,
+				},
+			},
+			UpdateVersion: 2,
+		},
+		{
+			Update: &metadatapb.ResourceUpdate_PodUpdate{
+				PodUpdate: &metadatapb.PodUpdate{
+					UID:              "search-pod-2",
+					Name:             "vizier-pem-2",
+					Namespace:        "pl",
+					StartTimestampNS: 1000,
+					StopTimestampNS:  2000,
+					Phase:            metadatapb.TERMINATED,
+				},
+			},
+			UpdateVersion: 3,
+		},
+		{
+			Update: &metadatapb.ResourceUpdate_ServiceUpdate{
+				ServiceUpdate: &metadatapb.ServiceUpdate{
+					UID:              "search-svc",
+					Name:             "vizier-query-broker",
+					Namespace:        "pl",
+					StartTimestampNS: 3000,
+					PodIDs:           []string{"search-pod-1"},
+				},
+			},
+			UpdateVersion: 4,
+		},
+	}
+	for _, u := range updates {
+		require.NoError(t, indexer.HandleResourceUpdate(u))
+	}
+
+	tests := []struct {
+		name         string
+		search       *md.EntitySearch
+		expectedUIDs []string
+		hasMore      bool
+	}{
+		{
+			name:         "org",
+			search:       &md.EntitySearch{},
+			expectedUIDs: []string{"search-ns", "search-pod-1", "search-pod-2", "search-svc"},
+		},
+		{
+			name:         "name prefix",
+			search:       &md.EntitySearch{NamePrefix: "pl/vizier-pem"},
+			expectedUIDs: []string{"search-pod-1", "search-pod-2"},
+		},
+		{
+			name:         "kind and state",
+			search:       &md.EntitySearch{Kinds: []md.EsMDType{md.EsMDTypePod}, States: []md.ESMDEntityState{md.ESMDEntityStateRunning}},
+			expectedUIDs: []string{"search-pod-1"},
+		},
+		{
+			name:         "cluster and namespace",
+			search:       &md.EntitySearch{ClusterUID: "search-cluster", Namespace: "pl", Kinds: []md.EsMDType{md.EsMDTypeService}},
+			expectedUIDs: []string{"search-svc"},
+		},
+		{
+			name:         "time range",
+			search:       &md.EntitySearch{StartTimeNS: 2500, EndTimeNS: 2800},
+			expectedUIDs: []string{"search-ns", "search-pod-1"},
+		},
+		{
+			name:         "limit",
+			search:       &md.EntitySearch{Limit: 2},
+			expectedUIDs: []string{"search-ns", "search-pod-1"},
+			hasMore:      true,
+		},
+		{
+			name:         "other cluster",
+			search:       &md.EntitySearch{ClusterUID: "other-cluster"},
+			expectedUIDs: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.search.OrgID = searchOrgID
+			entities, hasMore, err := indices.SearchEntities(context.Background(), test.search)
+			require.NoError(t, err)
+			uids := make([]string, len(entities))
+			for i, e := range entities {
+				uids[i] = e.UID
+			}
+			assert.Equal(t, test.expectedUIDs, uids)
+			assert.Equal(t, test.hasMore, hasMore)
+		})
+	}
+}
+
+func TestIndexManager_SearchEntitiesAcrossIndices(t *testing.T) {
+	ctx := context.Background()
+	alias := "test_md_search_templated"
+	indices := md.NewIndexManager(elasticClient, alias, "test_md_search_templated-{yyyy.MM}", 1, nil)
+
+	searchOrgID := uuid.Must(uuid.NewV4())
+	ts := time.Date(2022, time.March, 7, 15, 0, 0, 0, time.UTC)
+	// The namespace was updated in both months, so it has a document in each month's index.
+	docs := []struct {
+		ts     time.Time
+		entity *md.EsMDEntity
+	}{
+		{ts, &md.EsMDEntity{UID: "templated-ns", Name: "pl", Kind: "namespace", UpdateVersion: 1}},
+		{ts.AddDate(0, 1, 0), &md.EsMDEntity{UID: "templated-ns", Name: "pl", Kind: "namespace", UpdateVersion: 3}},
+		{ts, &md.EsMDEntity{UID: "templated-pod", Name: "pl/vizier-pem", Kind: "pod", UpdateVersion: 2}},
+	}
+	for _, d := range docs {
+		index, err := indices.IndexFor(searchOrgID, d.ts)
+		require.NoError(t, err)
+		d.entity.OrgID = searchOrgID.String()
+		d.entity.RelatedEntityNames = []string{}
+		_, err = elasticClient.Index().Index(index).Id(d.entity.UID).BodyJson(d.entity).Refresh("true").Do(ctx)
+		require.NoError(t, err)
+	}
+
+	entities, hasMore, err := indices.SearchEntities(ctx, &md.EntitySearch{OrgID: searchOrgID})
+	require.NoError(t, err)
+	require.Len(t, entities, 2)
+	assert.Equal(t, "templated-ns", entities[0].UID)
+	assert.Equal(t, int64(3), entities[0].UpdateVersion)
+	assert.Equal(t, "templated-pod", entities[1].UID)
+	assert.False(t, hasMore)
+
+	// The limit applies to the entities rather than their documents, so a full page of the latest versions is returned.
+	entities, hasMore, err = indices.SearchEntities(ctx, &md.EntitySearch{OrgID: searchOrgID, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, entities, 1)
+	assert.Equal(t, "templated-ns", entities[0].UID)
+	assert.Equal(t, int64(3), entities[0].UpdateVersion)
+	assert.True(t, hasMore)
+}
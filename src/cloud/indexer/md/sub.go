@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package md
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/shared/services/msgbus"
+)
+
+// pausableSub is a persistent subscription which is paused while its updates cannot be indexed, so that they are
+// neither buffered by the indexer nor redelivered over and over by the streamer. Since the subscription is durable,
+// it resumes from the oldest update which was not acked.
+type pausableSub struct {
+	st       msgbus.Streamer
+	subject  string
+	name     string
+	cb       msgbus.MsgHandler
+	vizierID string
+
+	mu  sync.Mutex
+	sub msgbus.PersistentSub
+	// Whether the subscription was closed by pause, and should be resumed.
+	paused bool
+	// Whether the subscription was closed for good, and must not be resumed.
+	closed bool
+}
+
+func newPausableSub(st msgbus.Streamer, subject, name string, cb msgbus.MsgHandler, vizierID string) *pausableSub {
+	return &pausableSub{
+		st:       st,
+		subject:  subject,
+		name:     name,
+		cb:       cb,
+		vizierID: vizierID,
+	}
+}
+
+// subscribe starts the subscription.
+func (s *pausableSub) subscribe() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, err := s.st.PersistentSubscribe(s.subject, s.name, s.cb)
+	if err != nil {
+		return err
+	}
+	s.sub = sub
+	return nil
+}
+
+// pause closes the subscription, so that no further updates are delivered until it is resumed.
+func (s *pausableSub) pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused || s.closed || s.sub == nil {
+		return
+	}
+	err := s.sub.Close()
+	if err != nil {
+		log.WithField("vizier", s.vizierID).WithError(err).Error("Failed to pause subscription")
+	}
+	s.sub = nil
+	s.paused = true
+	indexerPausedCollector.WithLabelValues(s.vizierID).Set(1)
+	log.WithField("vizier", s.vizierID).Warn("Paused metadata updates until elastic is available")
+}
+
+// resume resubscribes, if the subscription is paused.
+func (s *pausableSub) resume() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.paused || s.closed {
+		return nil
+	}
+	sub, err := s.st.PersistentSubscribe(s.subject, s.name, s.cb)
+	if err != nil {
+		return err
+	}
+	s.sub = sub
+	s.paused = false
+	indexerPausedCollector.WithLabelValues(s.vizierID).Set(0)
+	log.WithField("vizier", s.vizierID).Info("Resumed metadata updates")
+	return nil
+}
+
+// isPaused returns whether the subscription is paused.
+func (s *pausableSub) isPaused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// close closes the subscription for good, so that it is not resumed.
+func (s *pausableSub) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.sub == nil {
+		return nil
+	}
+	err := s.sub.Close()
+	s.sub = nil
+	return err
+}
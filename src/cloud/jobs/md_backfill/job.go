@@ -0,0 +1,126 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/cloud/indexer/controllers"
+	"px.dev/pixie/src/cloud/indexer/md"
+	"px.dev/pixie/src/cloud/shared/esutils"
+	"px.dev/pixie/src/shared/services"
+	"px.dev/pixie/src/shared/services/msgbus"
+)
+
+func init() {
+	pflag.String("vizier_id", "", "The ID of the vizier whose metadata updates are replayed.")
+	pflag.String("org_id", "", "The ID of the org that the vizier belongs to.")
+	pflag.String("cluster_uid", "", "The UID of the vizier's k8s cluster.")
+	pflag.String("md_index_name", "", "The elastic index name, or alias, that the metadata updates are replayed into.")
+	pflag.Int("md_index_replicas", 4, "The number of replicas to setup for the metadata index.")
+	pflag.Duration("idle_timeout", 30*time.Second, "The time without new updates after which the replay is considered done.")
+	pflag.String("es_url", "https://pl-elastic-es-http:9200", "The URL for the elastic cluster.")
+	pflag.String("es_ca_cert", "/es-certs/tls.crt", "The CA cert for elastic.")
+	pflag.String("es_user", "elastic", "The user for elastic.")
+	pflag.String("es_passwd", "elastic", "The password for elastic.")
+	pflag.String("es_api_key", "", "The API key for elastic. If specified, it is used instead of es_user and es_passwd.")
+	pflag.String("md_jetstream_stream", "", "The JetStream stream which retains the metadata updates. If empty, the updates are replayed from STAN.")
+}
+
+// The job replays the metadata updates of a vizier which are retained by STAN, or JetStream, into the given index, which is created
+// if it does not exist. This rebuilds the vizier's entities after the index was lost or written with a broken mapping.
+func main() {
+	services.SetupCommonFlags()
+	services.SetupSSLClientFlags()
+	services.PostFlagSetupAndParse()
+	services.SetupServiceLogging()
+
+	vizierID, err := uuid.FromString(viper.GetString("vizier_id"))
+	if err != nil {
+		log.WithError(err).Fatal("Invalid vizier ID")
+	}
+	orgID, err := uuid.FromString(viper.GetString("org_id"))
+	if err != nil {
+		log.WithError(err).Fatal("Invalid org ID")
+	}
+	clusterUID := viper.GetString("cluster_uid")
+	if clusterUID == "" {
+		log.Fatal("Must specify the UID of the vizier's cluster.")
+	}
+	indexName := viper.GetString("md_index_name")
+	if indexName == "" {
+		log.Fatal("Must specify a name for the elastic index.")
+	}
+	replicas := viper.GetInt("md_index_replicas")
+
+	elasticURL := viper.GetString("es_url")
+	es, err := esutils.NewEsClient(&esutils.Config{
+		URL:        []string{elasticURL},
+		User:       viper.GetString("es_user"),
+		Passwd:     viper.GetString("es_passwd"),
+		APIKey:     viper.GetString("es_api_key"),
+		CaCertFile: viper.GetString("es_ca_cert"),
+	})
+	if err != nil {
+		log.WithError(err).Fatalf("Failed to connect to %s", elasticURL)
+	}
+	err = md.InitializeMapping(es, indexName, replicas, nil)
+	if err != nil {
+		log.WithError(err).Fatal("Could not initialize elastic mapping")
+	}
+
+	nc := msgbus.MustConnectNATS()
+	strmr := mustConnectStreamer(nc)
+
+	indices := md.NewIndexManager(es, indexName, "", replicas, nil)
+	indexer := md.NewVizierIndexer(vizierID, orgID, clusterUID, indices, strmr, es)
+	indexed, err := indexer.Replay(controllers.MetadataTopic(clusterUID), viper.GetDuration("idle_timeout"))
+	if err != nil {
+		log.WithError(err).Fatal("Failed to replay metadata updates")
+	}
+
+	log.WithField("updates", indexed).Infof("Replayed metadata updates into '%s'", indexName)
+}
+
+// mustConnectStreamer connects to the streamer which the metadata updates are replayed from, which is JetStream if
+// a stream is specified, and STAN otherwise.
+func mustConnectStreamer(nc *nats.Conn) msgbus.Streamer {
+	if stream := viper.GetString("md_jetstream_stream"); stream != "" {
+		cfg := msgbus.DefaultJetStreamStreamerConfig
+		cfg.Stream = stream
+		strmr, err := msgbus.NewJetStreamStreamerWithConfig(nc, cfg)
+		if err != nil {
+			log.WithError(err).Fatal("Could not connect to JetStream")
+		}
+		return strmr
+	}
+
+	sc := msgbus.MustConnectSTAN(nc, uuid.Must(uuid.NewV4()).String())
+	strmr, err := msgbus.NewSTANStreamer(sc)
+	if err != nil {
+		log.WithError(err).Fatal("Could not connect to streamer")
+	}
+	return strmr
+}
@@ -31,67 +31,142 @@ import (
 	"github.com/olivere/elastic/v7"
 )
 
+// Distribution is the search engine which serves the elastic API.
+type Distribution string
+
+const (
+	// DistributionElasticsearch is Elasticsearch, which is the default distribution.
+	DistributionElasticsearch Distribution = "elasticsearch"
+	// DistributionOpenSearch is OpenSearch, eg. AWS OpenSearch Service.
+	DistributionOpenSearch Distribution = "opensearch"
+)
+
+// Validate checks that the distribution is supported. An empty distribution is Elasticsearch.
+func (d Distribution) Validate() error {
+	switch d {
+	case "", DistributionElasticsearch, DistributionOpenSearch:
+		return nil
+	default:
+		return fmt.Errorf("unsupported elastic distribution %q", d)
+	}
+}
+
+// SupportsILM returns whether the distribution supports index lifecycle management, ie. ILMPolicy and ManagedIndex.
+// OpenSearch manages indices with ISM instead, which has an incompatible API.
+func (d Distribution) SupportsILM() bool {
+	return d != DistributionOpenSearch
+}
+
 // Config describes the underlying config for elastic.
 type Config struct {
-	URL        []string `json:"url"`
-	User       string   `json:"user"`
-	Passwd     string   `json:"passwd"`
-	CaCertFile string   `json:"ca_cert_file"`
+	URL    []string `json:"url"`
+	User   string   `json:"user"`
+	Passwd string   `json:"passwd"`
+	// APIKey is the base64 encoded elastic API key, which is used instead of basic auth if specified.
+	APIKey string `json:"api_key"`
+	// CaCertFile verifies the elastic server's certificate. If empty, the system's root CAs are used.
+	CaCertFile string `json:"ca_cert_file"`
+	// ClientCertFile and ClientKeyFile are the certificate and key which the client presents for mTLS.
+	ClientCertFile string       `json:"client_cert_file"`
+	ClientKeyFile  string       `json:"client_key_file"`
+	Distribution   Distribution `json:"distribution"`
 }
 
-func getESHTTPSClient(config *Config) (*http.Client, error) {
-	caCert, err := os.ReadFile(config.CaCertFile)
-	if err != nil {
-		return nil, err
-	}
-	caCertPool := x509.NewCertPool()
-	ok := caCertPool.AppendCertsFromPEM(caCert)
-	if !ok {
-		return nil, fmt.Errorf("failed to append caCert to pool")
+// apiKeyTransport authenticates the requests to elastic with an API key.
+type apiKeyTransport struct {
+	apiKey string
+	next   http.RoundTripper
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request it is given.
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "ApiKey "+t.apiKey)
+	return t.next.RoundTrip(req)
+}
+
+func getESTLSConfig(config *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	if config.CaCertFile != "" {
+		caCert, err := os.ReadFile(config.CaCertFile)
+		if err != nil {
+			return nil, err
+		}
+		caCertPool := x509.NewCertPool()
+		ok := caCertPool.AppendCertsFromPEM(caCert)
+		if !ok {
+			return nil, fmt.Errorf("failed to append caCert to pool")
+		}
+		tlsConfig.RootCAs = caCertPool
 	}
-	tlsConfig := &tls.Config{
-		RootCAs: caCertPool,
+
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		if config.ClientCertFile == "" || config.ClientKeyFile == "" {
+			return nil, fmt.Errorf("both a client cert and key are required for mTLS")
+		}
+		cert, err := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
+	return tlsConfig, nil
+}
 
+func getESHTTPClient(config *Config, useTLS bool) (*http.Client, error) {
 	tr := http.DefaultTransport.(*http.Transport).Clone()
-	tr.TLSClientConfig = tlsConfig
+	if useTLS {
+		tlsConfig, err := getESTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		tr.TLSClientConfig = tlsConfig
+	}
 	tr.MaxIdleConnsPerHost = 100 // Default is 2.
 	tr.DialContext = (&net.Dialer{KeepAlive: 30 * time.Second}).DialContext
 
+	var rt http.RoundTripper = tr
+	if config.APIKey != "" {
+		rt = &apiKeyTransport{apiKey: config.APIKey, next: tr}
+	}
 	httpClient := &http.Client{
-		Transport: tr,
+		Transport: rt,
 	}
 	return httpClient, nil
 }
 
-func setupHTTPS(config *Config) ([]elastic.ClientOptionFunc, error) {
-	var opts []elastic.ClientOptionFunc
-	httpClient, err := getESHTTPSClient(config)
-	if err != nil {
-		return opts, err
-	}
-	opts = append(opts,
-		elastic.SetHttpClient(httpClient))
-	return opts, nil
-}
-
 // NewEsClient creates an elastic search client from the config.
 func NewEsClient(config *Config) (*elastic.Client, error) {
+	if err := config.Distribution.Validate(); err != nil {
+		return nil, err
+	}
 	var opts []elastic.ClientOptionFunc
 
 	// Sniffer should look for HTTPS URLs if at-least-one initial URL is HTTPS
+	useTLS := false
 	for _, url := range config.URL {
 		if strings.HasPrefix(url, "https:") {
-			httpsOpts, err := setupHTTPS(config)
-			if err != nil {
-				return nil, err
-			}
-			opts = append(opts, httpsOpts...)
+			useTLS = true
 			break
 		}
 	}
+	if useTLS || config.APIKey != "" {
+		httpClient, err := getESHTTPClient(config, useTLS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, elastic.SetHttpClient(httpClient))
+	}
 
-	opts = append(opts, elastic.SetURL(config.URL...), elastic.SetBasicAuth(config.User, config.Passwd), elastic.SetSniff(false))
+	opts = append(opts, elastic.SetURL(config.URL...), elastic.SetSniff(false))
+	if config.APIKey == "" {
+		opts = append(opts, elastic.SetBasicAuth(config.User, config.Passwd))
+	}
+	// The startup healthcheck expects an Elasticsearch version, which OpenSearch does not report unless its
+	// compatibility mode is enabled. The connection is instead checked by the first request.
+	if config.Distribution == DistributionOpenSearch {
+		opts = append(opts, elastic.SetHealthcheck(false))
+	}
 
 	return elastic.NewClient(opts...)
 }
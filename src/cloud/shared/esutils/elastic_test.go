@@ -20,11 +20,16 @@ package esutils_test
 
 import (
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 
 	"github.com/olivere/elastic/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"px.dev/pixie/src/cloud/shared/esutils"
 	"px.dev/pixie/src/utils/testingutils"
 )
 
@@ -42,3 +47,53 @@ func TestMain(m *testing.M) {
 	cleanup()
 	os.Exit(code)
 }
+
+func TestDistribution_Validate(t *testing.T) {
+	assert.NoError(t, esutils.Distribution("").Validate())
+	assert.NoError(t, esutils.DistributionElasticsearch.Validate())
+	assert.NoError(t, esutils.DistributionOpenSearch.Validate())
+	assert.Error(t, esutils.Distribution("solr").Validate())
+}
+
+func TestDistribution_SupportsILM(t *testing.T) {
+	assert.True(t, esutils.Distribution("").SupportsILM())
+	assert.True(t, esutils.DistributionElasticsearch.SupportsILM())
+	assert.False(t, esutils.DistributionOpenSearch.SupportsILM())
+}
+
+func TestNewEsClient_UnsupportedDistribution(t *testing.T) {
+	_, err := esutils.NewEsClient(&esutils.Config{
+		URL:          []string{"http://localhost:9200"},
+		Distribution: "solr",
+	})
+	assert.Error(t, err)
+}
+
+func TestNewEsClient_APIKey(t *testing.T) {
+	var authHeaders []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	_, err := esutils.NewEsClient(&esutils.Config{
+		URL:    []string{s.URL},
+		User:   "elastic",
+		Passwd: "elastic",
+		APIKey: "abc123",
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, authHeaders)
+	for _, h := range authHeaders {
+		assert.Equal(t, "ApiKey abc123", h)
+	}
+}
+
+func TestNewEsClient_ClientCertWithoutKey(t *testing.T) {
+	_, err := esutils.NewEsClient(&esutils.Config{
+		URL:            []string{"https://localhost:9200"},
+		ClientCertFile: "/certs/client.crt",
+	})
+	assert.Error(t, err)
+}
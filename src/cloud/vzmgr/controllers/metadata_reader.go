@@ -105,7 +105,9 @@ type MetadataReader struct {
 	db *sqlx.DB
 
 	st msgbus.Streamer
-	nc *nats.Conn
+	// The streamer which the metadata updates for the indexer are published to.
+	indexSt msgbus.Streamer
+	nc      *nats.Conn
 
 	viziers *concurrentViziersMap // Map of Vizier ID to it's state.
 
@@ -113,11 +115,12 @@ type MetadataReader struct {
 	once   sync.Once
 }
 
-// NewMetadataReader creates a new MetadataReader.
-func NewMetadataReader(db *sqlx.DB, st msgbus.Streamer, nc *nats.Conn) (*MetadataReader, error) {
+// NewMetadataReader creates a new MetadataReader, which reads the metadata updates of Viziers from st and publishes
+// them for the indexer to indexSt.
+func NewMetadataReader(db *sqlx.DB, st msgbus.Streamer, indexSt msgbus.Streamer, nc *nats.Conn) (*MetadataReader, error) {
 	viziers := &concurrentViziersMap{unsafeMap: make(map[uuid.UUID]*VizierState)}
 
-	m := &MetadataReader{db: db, st: st, nc: nc, viziers: viziers, quitCh: make(chan struct{})}
+	m := &MetadataReader{db: db, st: st, indexSt: indexSt, nc: nc, viziers: viziers, quitCh: make(chan struct{})}
 	err := m.loadState()
 	if err != nil {
 		m.Stop()
@@ -212,7 +215,7 @@ func (m *MetadataReader) loadVizierState(id uuid.UUID, k8sUID string) (*VizierSt
 		quitCh: make(chan struct{}),
 	}
 	subject := fmt.Sprintf("%s.%s", indexerMetadataTopic, vzState.k8sUID)
-	msg, err := m.st.PeekLatestMessage(subject)
+	msg, err := m.indexSt.PeekLatestMessage(subject)
 	if err != nil {
 		return nil, err
 	}
@@ -499,7 +502,7 @@ func (m *MetadataReader) applyMetadataUpdate(vzState *VizierState, update *metad
 		WithField("rv", update.UpdateVersion).
 		Trace("Publishing metadata update to indexer")
 
-	err = m.st.Publish(fmt.Sprintf("%s.%s", indexerMetadataTopic, vzState.k8sUID), b)
+	err = m.indexSt.Publish(fmt.Sprintf("%s.%s", indexerMetadataTopic, vzState.k8sUID), b)
 	if err != nil {
 		return err
 	}
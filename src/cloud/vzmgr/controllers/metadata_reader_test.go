@@ -417,7 +417,7 @@ func TestMetadataReader_ProcessVizierUpdate(t *testing.T) {
 				}
 			}()
 
-			mdr, err := controllers.NewMetadataReader(db, st, nc)
+			mdr, err := controllers.NewMetadataReader(db, st, st, nc)
 			require.NoError(t, err)
 
 			numUpdates := 0
@@ -440,7 +440,7 @@ func TestMetadataReader_ProcessVizierUpdate(t *testing.T) {
 			mdr.Stop()
 
 			// On restart, we shouldn't receive any updates.
-			mdr, err = controllers.NewMetadataReader(db, st, nc)
+			mdr, err = controllers.NewMetadataReader(db, st, st, nc)
 			require.NoError(t, err)
 			defer mdr.Stop()
 
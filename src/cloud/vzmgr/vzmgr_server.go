@@ -61,6 +61,7 @@ var (
 func init() {
 	pflag.String("database_key", "", "The encryption key to use for the database")
 	pflag.String("domain_name", "dev.withpixie.dev", "The domain name of Pixie Cloud")
+	pflag.String("md_jetstream_stream", "", "The JetStream stream which the metadata updates for the indexer are published to. If empty, the updates are published to STAN.")
 
 	prometheus.MustRegister(natsErrorCount)
 }
@@ -104,6 +105,22 @@ func extractMessageInfo(subject string) (string, string, string) {
 	return "", "", ""
 }
 
+// mustConnectIndexStreamer connects to the streamer which the metadata updates for the indexer are published to,
+// which is JetStream if a stream is specified, and the given STAN streamer otherwise.
+func mustConnectIndexStreamer(nc *nats.Conn, stanStrmr msgbus.Streamer) msgbus.Streamer {
+	stream := viper.GetString("md_jetstream_stream")
+	if stream == "" {
+		return stanStrmr
+	}
+	cfg := msgbus.DefaultJetStreamStreamerConfig
+	cfg.Stream = stream
+	strmr, err := msgbus.NewJetStreamStreamerWithConfig(nc, cfg)
+	if err != nil {
+		log.WithError(err).Fatal("Could not connect to JetStream")
+	}
+	return strmr
+}
+
 func mustSetupNATSAndSTAN() (*nats.Conn, stan.Conn, msgbus.Streamer) {
 	nc := msgbus.MustConnectNATS()
 	stc := msgbus.MustConnectSTAN(nc, uuid.Must(uuid.NewV4()).String())
@@ -194,9 +211,10 @@ func main() {
 	vzmgrpb.RegisterVZDeploymentKeyServiceServer(s.GRPCServer(), dks)
 	vzmgrpb.RegisterVZDeploymentServiceServer(s.GRPCServer(), ds)
 
+	indexStrmr := mustConnectIndexStreamer(nc, strmr)
 	var mdr *controllers.MetadataReader
 	go func() {
-		mdr, err = controllers.NewMetadataReader(db, strmr, nc)
+		mdr, err = controllers.NewMetadataReader(db, strmr, indexStrmr, nc)
 		if err != nil {
 			log.WithError(err).Fatal("Could not start metadata listener")
 		}
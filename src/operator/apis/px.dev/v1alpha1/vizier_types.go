@@ -27,7 +27,9 @@ package v1alpha1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // VizierSpec defines the desired state of Vizier
@@ -39,10 +41,38 @@ type VizierSpec struct {
 	DeployKey string `json:"deployKey,omitempty"`
 	// CustomDeployKeySecret is the name of the secret where the deploy key is stored.
 	CustomDeployKeySecret string `json:"customDeployKeySecret,omitempty"`
+	// DeployKeySource specifies an external secret manager from which the deploy key is read on each deploy, so that
+	// the deploy key is never stored in the Vizier's spec. This takes precedence over DeployKey.
+	DeployKeySource *DeployKeySource `json:"deployKeySource,omitempty"`
+	// CustomTLSCertsSecret is the name of a secret in the Vizier's namespace which contains the certs that Vizier
+	// services use to communicate, such as certs issued by an internal PKI. The secret must contain the PEM-encoded
+	// "ca.crt", "server.crt", "server.key", "client.crt" and "client.key". The server cert must be valid for the
+	// Vizier's services, such as "*.<namespace>.svc" and "*.<namespace>.svc.cluster.local". If not specified, the
+	// operator generates a self-signed CA and certs.
+	CustomTLSCertsSecret string `json:"customTLSCertsSecret,omitempty"`
+	// CertManager specifies that the certs which Vizier services use to communicate should be issued by
+	// cert-manager, which must already be installed in the cluster. This is ignored if CustomTLSCertsSecret is set.
+	CertManager *CertManagerParams `json:"certManager,omitempty"`
+	// JWTSigningKeyRotationPeriod is how often the JWT signing key which Vizier services use to authenticate each
+	// other is rotated, for example: "720h". The Vizier pods are restarted after each rotation to pick up the new key,
+	// and tokens signed with the previous key remain valid until the next rotation. If not specified, the key is not
+	// rotated.
+	JWTSigningKeyRotationPeriod *metav1.Duration `json:"jwtSigningKeyRotationPeriod,omitempty"`
+	// RestartPeriod is how often the Vizier pods are restarted with a rolling restart, for example: "168h" to restart
+	// them weekly. This picks up refreshed certs and secrets, and releases memory which has built up over time. If
+	// not specified, the pods are not restarted on a schedule.
+	RestartPeriod *metav1.Duration `json:"restartPeriod,omitempty"`
 	// DisableAutoUpdate specifies whether auto update should be enabled for the Vizier instance.
 	DisableAutoUpdate bool `json:"disableAutoUpdate,omitempty"`
 	// UseEtcdOperator specifies whether the metadata service should use etcd for storage.
 	UseEtcdOperator bool `json:"useEtcdOperator,omitempty"`
+	// StorageClassName is the name of the StorageClass to use for the metadata PVC. If not specified, the cluster's
+	// default StorageClass is used, and the metadata service falls back to the etcd operator when the cluster does
+	// not have exactly one default StorageClass.
+	StorageClassName string `json:"storageClassName,omitempty"`
+	// MetadataStorageSize is the size of the metadata PVC, for example: "16Gi". If not specified, the size from the
+	// Vizier YAMLs is used. Once the PVC is created, it can only be expanded if its StorageClass allows expansion.
+	MetadataStorageSize string `json:"metadataStorageSize,omitempty"`
 	// ClusterName is a name for the Vizier instance, usually specifying which cluster the Vizier is
 	// deployed to. If not specified, a random name will be generated.
 	ClusterName string `json:"clusterName,omitempty"`
@@ -52,12 +82,19 @@ type VizierSpec struct {
 	// redirect traffic to the correct service. The DevCloudNamespace is the namespace that the dev Pixie cloud is
 	// running on, for example: "plc-dev".
 	DevCloudNamespace string `json:"devCloudNamespace,omitempty"`
+	// NamespaceLabels are labels which the operator sets on the Vizier's namespace, such as Pod Security Standard
+	// labels which allow the privileged PEMs to run. If the namespace does not exist, the operator creates it with
+	// these labels. Other labels on the namespace are left unchanged.
+	NamespaceLabels map[string]string `json:"namespaceLabels,omitempty"`
 	// PemMemoryLimit is a memory limit applied specifically to PEM pods.
 	PemMemoryLimit string `json:"pemMemoryLimit,omitempty"`
 	// PemMemoryRequest is a memory request applied specifically
 	// to PEM pods. It will automatically use the value of pemMemoryLimit
 	// if not specified.
 	PemMemoryRequest string `json:"pemMemoryRequest,omitempty"`
+	// PEMMemoryAutoSize sizes the memory of PEMs from the allocatable memory of the nodes which they are scheduled on,
+	// each time the Vizier is deployed. This takes precedence over PemMemoryLimit and PemMemoryRequest.
+	PEMMemoryAutoSize *PEMMemoryAutoSizeParams `json:"pemMemoryAutoSize,omitempty"`
 	// ClockConverter specifies which routine to use for converting timestamps to a synced reference time.
 	ClockConverter ClockConverterType `json:"clockConverter,omitempty"`
 	// Pod defines the policy for creating Vizier pods.
@@ -66,6 +103,10 @@ type VizierSpec struct {
 	// The key of the patch should be the name of the resource that is patched. The value of the patch is the patch,
 	// encoded as a string which follow the "strategic merge patch" rules for K8s.
 	Patches map[string]string `json:"patches,omitempty"`
+	// JSONPatches defines RFC 6902 JSON patches that should be applied to Vizier resources. Each patch is applied to
+	// every resource matched by its target, after the operator has applied the rest of the spec to the resource.
+	// Unlike Patches, JSON patches can add, replace or remove any field, such as adding volumes or sidecar containers.
+	JSONPatches []JSONPatch `json:"jsonPatches,omitempty"`
 	// DataAccess defines the level of data that may be accesssed when executing a script on the cluster. If none specified,
 	// assumes full data access.
 	DataAccess DataAccessLevel `json:"dataAccess,omitempty"`
@@ -73,6 +114,83 @@ type VizierSpec struct {
 	DataCollectorParams *DataCollectorParams `json:"dataCollectorParams,omitempty"`
 	// LeadershipElectionParams specifies configurable values for the K8s leaderships elections which Vizier uses manage pod leadership.
 	LeadershipElectionParams *LeadershipElectionParams `json:"leadershipElectionParams,omitempty"`
+	// Registry specifies a custom registry to pull all Vizier images from, such as a private mirror. The registry
+	// host of each image is replaced with this registry, and the rest of the image reference is left unchanged. For
+	// example, with the registry "registry.internal/mirror", the image "gcr.io/pixie-oss/pixie-prod/vizier-pem_image:0.10.0"
+	// is pulled from "registry.internal/mirror/pixie-oss/pixie-prod/vizier-pem_image:0.10.0".
+	Registry string `json:"registry,omitempty"`
+	// Architecture specifies the CPU architectures supported by the images of Vizier components, for clusters which
+	// have nodes of multiple architectures, such as mixed amd64 and arm64 clusters.
+	Architecture *ArchitectureParams `json:"architecture,omitempty"`
+	// ImageDigests pins the images of all Vizier containers to immutable digests. Before each deploy, the operator
+	// resolves the tag of each image to the digest it currently points to in the image's registry, and deploys the
+	// image by its digest. The resolved images are recorded in the status.
+	ImageDigests *ImageDigestParams `json:"imageDigests,omitempty"`
+	// YAMLConfigMapName is the name of a ConfigMap or Secret in the Vizier's namespace which contains the YAMLs to
+	// deploy, keyed by YAML name. If specified, the operator deploys these YAMLs rather than fetching them from Pixie
+	// Cloud, which allows deploying to clusters without outbound connectivity. The version must also be specified.
+	YAMLConfigMapName string `json:"yamlConfigMapName,omitempty"`
+	// Proxy specifies the HTTP(S) proxy which Vizier containers should use for outbound connections.
+	Proxy *ProxyParams `json:"proxy,omitempty"`
+	// Logging specifies the log level and format of all Vizier components. If not specified, the components log at
+	// the info level in text format.
+	Logging *LoggingParams `json:"logging,omitempty"`
+	// ServiceMesh specifies how Vizier pods interact with a service mesh, such as Istio or Linkerd, which injects
+	// sidecars into pods.
+	ServiceMesh *ServiceMeshParams `json:"serviceMesh,omitempty"`
+	// PEMUpgradeStrategy specifies how updates to the PEM daemonset are rolled out. If specified, updated PEMs are
+	// first rolled out to a subset of canary nodes, and are only rolled out to the remaining nodes once the canary
+	// PEMs are healthy. Otherwise, the PEM daemonset's rolling update is used.
+	PEMUpgradeStrategy *PEMUpgradeStrategy `json:"pemUpgradeStrategy,omitempty"`
+	// MetadataBackup specifies where the metadata store is backed up to before each Vizier update, and which backup
+	// it should be restored from, if any.
+	MetadataBackup *MetadataBackupParams `json:"metadataBackup,omitempty"`
+	// ExternalNATS specifies an externally managed NATS cluster for Vizier to use. If specified, the operator does not
+	// deploy NATS in the Vizier's namespace, and checks that the external cluster is reachable before reporting the
+	// Vizier as healthy.
+	ExternalNATS *ExternalNATSParams `json:"externalNATS,omitempty"`
+	// NATS specifies the configuration of the NATS cluster deployed with Vizier. This is ignored when using an
+	// external NATS cluster.
+	NATS *NATSParams `json:"nats,omitempty"`
+	// Kelvin specifies the scaling of Kelvin, which executes the non-data-local parts of queries. Increase this when
+	// heavy query load saturates a single Kelvin.
+	Kelvin *KelvinParams `json:"kelvin,omitempty"`
+	// ResyncInterval is how often the operator fully reconciles the Vizier, redeploying its resources even if the
+	// spec has not changed, for example: "30m". This overrides the operator's --resync-interval flag. A value of 0
+	// disables periodic resyncs.
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
+	// DryRun specifies that the operator should not deploy the Vizier, and should instead write the resources which it
+	// would deploy to the "vizier-dry-run" ConfigMap in the Vizier's namespace, so that they can be reviewed. The
+	// values of secrets are redacted. Once DryRun is disabled, the Vizier is deployed as usual.
+	DryRun bool `json:"dryRun,omitempty"`
+	// OpenShift specifies that Vizier is deployed to an OpenShift cluster. The pods which require host access, such
+	// as the PEM, are allowed to use the privileged SecurityContextConstraint, and the fixed user and group IDs are
+	// removed from all other pods so that they run under the restricted SecurityContextConstraint. If not set, the
+	// operator sets this when it detects an OpenShift cluster.
+	OpenShift bool `json:"openShift,omitempty"`
+	// IPFamilies are the IP families of the Vizier's services, in order of preference: a single family for an
+	// IPv4-only or IPv6-only cluster, or both families for a dual-stack cluster. If the first family is IPv6, the
+	// Vizier pods bind to IPv6 addresses. If not set, the operator sets this when it detects an IPv6-only or
+	// dual-stack cluster.
+	// +kubebuilder:validation:MaxItems=2
+	IPFamilies []v1.IPFamily `json:"ipFamilies,omitempty"`
+	// PreventDeletion protects the Vizier from being deleted. Deleting a protected Vizier is rejected, and if the
+	// Vizier is deleted regardless, its resources and metadata are kept until PreventDeletion is set to false.
+	PreventDeletion bool `json:"preventDeletion,omitempty"`
+	// ForceRedeployGeneration can be incremented to redeploy all of the Vizier's resources, even if nothing else in
+	// its spec has changed.
+	ForceRedeployGeneration int64 `json:"forceRedeployGeneration,omitempty"`
+	// AutoRemediation enables the operator to restart Vizier pods which are stuck in CrashLoopBackOff or
+	// ImagePullBackOff, instead of only reporting them in the status. Each restart is recorded as an event on the
+	// Vizier.
+	AutoRemediation *AutoRemediationParams `json:"autoRemediation,omitempty"`
+	// Monitor configures how often the operator checks the health of the Vizier, how many consecutive checks must
+	// agree before the Vizier's status changes, and which checks are run. This avoids a flapping status on large
+	// clusters, where some checks fail intermittently.
+	Monitor *MonitorParams `json:"monitor,omitempty"`
+	// Webhooks are notified whenever the Vizier's phase changes between Healthy, Degraded, Unhealthy and
+	// Disconnected, as observed by the operator.
+	Webhooks []WebhookParams `json:"webhooks,omitempty"`
 }
 
 // DataAccessLevel defines the levels of data access that can be used when executing a script on a cluster.
@@ -112,6 +230,11 @@ type VizierStatus struct {
 	// VizierReason is a short, machine understandable string that gives the reason
 	// for the transition into the Vizier's current status.
 	VizierReason string `json:"vizierReason,omitempty"`
+	// LastVizierReasonTime is the last time that the VizierReason changed, which is how long the Vizier has been in its
+	// current state.
+	LastVizierReasonTime *metav1.Time `json:"lastVizierReasonTime,omitempty"`
+	// UnhealthyPod is the name of the pod which caused the VizierReason, if it was caused by a single pod.
+	UnhealthyPod string `json:"unhealthyPod,omitempty"`
 	// ReconciliationPhase describes the state the Reconciler is in for this Vizier. See the
 	// documentation above the ReconciliationPhase type for more information.
 	ReconciliationPhase ReconciliationPhase `json:"reconciliationPhase,omitempty"`
@@ -124,6 +247,25 @@ type VizierStatus struct {
 	// A checksum of the last reconciled Vizier spec. If this checksum does not match the checksum
 	// of the current vizier spec, reconciliation should be performed.
 	Checksum []byte `json:"checksum,omitempty"`
+	// LastMetadataBackup is the name of the most recent backup of the metadata store.
+	LastMetadataBackup string `json:"lastMetadataBackup,omitempty"`
+	// LastMetadataRestore is the name of the backup which the metadata store was most recently restored from.
+	LastMetadataRestore string `json:"lastMetadataRestore,omitempty"`
+	// ImageDigests are the images pinned to their digests in the last deploy, keyed by the image they were resolved
+	// from. This is only set if image digest pinning is enabled in the spec.
+	ImageDigests map[string]string `json:"imageDigests,omitempty"`
+	// ResourceChecksums are the checksums of the core Vizier resources which were applied in the last deploy, keyed
+	// by the kind and name of the resource. Updates only apply the resources whose checksum has changed.
+	ResourceChecksums map[string]string `json:"resourceChecksums,omitempty"`
+	// LastScheduledRestartTime is the last time that the Vizier pods were restarted according to the RestartPeriod.
+	LastScheduledRestartTime *metav1.Time `json:"lastScheduledRestartTime,omitempty"`
+	// Components summarizes the health of each of the Vizier's components, as last observed by the operator.
+	Components *ComponentsStatus `json:"components,omitempty"`
+	// Conditions are the latest observations of the Vizier's state. See the VizierCondition constants
+	// for the types of conditions which are reported.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // VizierPhase is a high-level summary of where the Vizier is in its lifecycle.
@@ -163,6 +305,49 @@ const (
 	ReconciliationPhaseFailed ReconciliationPhase = "Failed"
 )
 
+// The types of conditions which are reported in the Vizier status.
+const (
+	// VizierConditionCloudConnected indicates whether the Vizier's cloud connector is connected to Pixie Cloud, and
+	// is sending heartbeats to it.
+	VizierConditionCloudConnected = "CloudConnected"
+	// VizierConditionResourcesApplied indicates whether the resources for the current Vizier spec have been applied.
+	VizierConditionResourcesApplied = "ResourcesApplied"
+	// VizierConditionPodsHealthy indicates whether the Vizier's pods are running and healthy.
+	VizierConditionPodsHealthy = "PodsHealthy"
+	// VizierConditionMetadataStorageHealthy indicates whether the metadata PVC is bound and has space left, or if the
+	// metadata is stored in etcd, whether all of the etcd members are healthy.
+	VizierConditionMetadataStorageHealthy = "MetadataStorageHealthy"
+	// VizierConditionUpdateInProgress indicates whether the Reconciler is currently deploying or updating the Vizier.
+	VizierConditionUpdateInProgress = "UpdateInProgress"
+	// VizierConditionSpecValid indicates whether the Vizier's spec passed validation. Viziers with an invalid spec are
+	// not deployed, and the condition's message describes each invalid field.
+	VizierConditionSpecValid = "SpecValid"
+)
+
+// ComponentsStatus summarizes the health of each of the Vizier's components.
+type ComponentsStatus struct {
+	// PEM is the number of PEMs which are ready.
+	PEM ComponentStatus `json:"pem"`
+	// Kelvin is the number of Kelvin pods which are ready.
+	Kelvin ComponentStatus `json:"kelvin"`
+	// Metadata is the number of metadata pods which are ready.
+	Metadata ComponentStatus `json:"metadata"`
+	// NATS is the number of NATS pods which are ready. This is empty if an external NATS cluster is used.
+	NATS ComponentStatus `json:"nats"`
+	// CloudConnector is the number of cloud connector pods which are ready.
+	CloudConnector ComponentStatus `json:"cloudConnector"`
+	// LastCloudHeartbeatTime is the last time that the cloud connector reported that it was connected to Pixie Cloud.
+	LastCloudHeartbeatTime *metav1.Time `json:"lastCloudHeartbeatTime,omitempty"`
+}
+
+// ComponentStatus is the readiness of the pods of a Vizier component.
+type ComponentStatus struct {
+	// Ready is the number of the component's pods which are ready.
+	Ready int32 `json:"ready"`
+	// Total is the number of the component's pods which are running or pending.
+	Total int32 `json:"total"`
+}
+
 // PodPolicy defines the policy for creating Vizier pods.
 type PodPolicy struct {
 	// Labels specifies the labels to attach to pods the operator creates.
@@ -180,6 +365,68 @@ type PodPolicy struct {
 	// The securityContext which should be set on non-privileged pods. All pods which require privileged permissions
 	// will still require a privileged securityContext.
 	SecurityContext *PodSecurityContext `json:"securityContext,omitempty"`
+	// RestrictedPodSecurity specifies that the pods which do not require host access should comply with the
+	// "restricted" Pod Security Standard, so that they are admitted to namespaces which enforce it. These pods run
+	// as a non-root user with the runtime's default seccomp profile, a read-only root filesystem and no capabilities.
+	// The PEM and Kelvin require host access, so must still be admitted by a privileged policy.
+	// More info: https://kubernetes.io/docs/concepts/security/pod-security-standards/#restricted
+	RestrictedPodSecurity bool `json:"restrictedPodSecurity,omitempty"`
+	// PEMHostNetwork specifies whether the PEM daemonset should run in the host's network namespace. Some CNI
+	// configurations require this for PEMs to correctly capture traffic. When enabled, the PEM's DNS policy is set to
+	// ClusterFirstWithHostNet so that the PEM can still resolve in-cluster services.
+	PEMHostNetwork bool `json:"pemHostNetwork,omitempty"`
+	// PEMHostPID specifies whether the PEM daemonset should run in the host's PID namespace, which the PEM requires to
+	// trace processes outside of its own pod. If not specified, the PEM runs in the host's PID namespace. Disabling this
+	// allows PEMs to be deployed on clusters which forbid it, but limits the processes which the PEM can trace.
+	PEMHostPID *bool `json:"pemHostPID,omitempty"`
+	// PEMExcludeNodeSelector prevents the PEM daemonset from running on nodes which have any of the given labels,
+	// such as Windows nodes or nodes whose kernel does not support eBPF. An empty value excludes all nodes which have
+	// the label, regardless of its value. Unlike NodeSelector, this only applies to the PEMs.
+	PEMExcludeNodeSelector map[string]string `json:"pemExcludeNodeSelector,omitempty"`
+	// Tolerations allow pods to be scheduled onto nodes with matching taints, such as dedicated node pools.
+	// These are added to any tolerations already specified on the pods.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration/
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+	// Affinity specifies node affinity, pod affinity and pod anti-affinity scheduling rules for pods. These are
+	// combined with the rules already specified on the pods: required node selector terms must be satisfied in
+	// addition to the existing terms, and all other rules are added to the existing ones.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node/#affinity-and-anti-affinity
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+	// PriorityClassName is the name of the PriorityClass to assign to pods, which determines their priority during
+	// scheduling and eviction. The PriorityClass must already exist in the cluster.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/pod-priority-preemption/
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// DNSPolicy is the DNS policy for pods, such as "ClusterFirst" or "None". Pods in the host network which are given
+	// the "ClusterFirst" policy use "ClusterFirstWithHostNet" instead, so that they can still resolve cluster services.
+	// More info: https://kubernetes.io/docs/concepts/services-networking/dns-pod-service/#pod-s-dns-policy
+	DNSPolicy v1.DNSPolicy `json:"dnsPolicy,omitempty"`
+	// DNSConfig specifies DNS parameters for pods, such as nameservers and search domains, which are merged with
+	// the configuration generated from the DNS policy.
+	// More info: https://kubernetes.io/docs/concepts/services-networking/dns-pod-service/#pod-dns-config
+	DNSConfig *v1.PodDNSConfig `json:"dnsConfig,omitempty"`
+	// Components specifies pod policies for individual Vizier components, keyed by the name of the component's
+	// workload, such as "kelvin", "vizier-metadata", "vizier-query-broker" or "vizier-pem". For example, the Kelvin,
+	// metadata and query broker pods can be restricted to a dedicated node pool, while the PEMs still run on every
+	// node.
+	Components map[string]ComponentPodPolicy `json:"components,omitempty"`
+}
+
+// ComponentPodPolicy specifies the policy for the pods of a single Vizier component, in addition to the policy for
+// all pods.
+type ComponentPodPolicy struct {
+	// NodeSelector is merged with the NodeSelector for all pods. Where both specify the same label, the component's
+	// value is used.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations are added to the Tolerations for all pods.
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+	// TerminationGracePeriodSeconds is how long the component's pods are given to shut down gracefully before they are
+	// killed, such as when their node is drained. This overrides the grace period in the Vizier YAMLs, which may be
+	// too short for PEMs and stateful components to flush their in-flight data.
+	// +kubebuilder:validation:Minimum=0
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+	// PreStop is a hook which runs in the component's main container before the container is stopped. The
+	// termination grace period includes the time taken by the hook.
+	PreStop *v1.LifecycleHandler `json:"preStop,omitempty"`
 }
 
 // PodSecurityContext describes the desired security context for non-privileged pods. This may be required for some
@@ -215,6 +462,361 @@ type LeadershipElectionParams struct {
 	ElectionPeriodMs int64 `json:"electionPeriodMs,omitempty"`
 }
 
+// ProxyParams specifies the HTTP(S) proxy settings which are set as environment variables in all Vizier containers.
+type ProxyParams struct {
+	// HTTPProxy is the proxy to use for HTTP requests, set as HTTP_PROXY.
+	HTTPProxy string `json:"httpProxy,omitempty"`
+	// HTTPSProxy is the proxy to use for HTTPS and gRPC requests, set as HTTPS_PROXY.
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	// NoProxy is a comma-separated list of hosts, domains and CIDRs which should not be proxied, set as NO_PROXY.
+	// Localhost, cluster-local domains and the cluster's pod and service CIDRs are always excluded. Any other
+	// addresses which Vizier must reach without the proxy should be added here.
+	NoProxy string `json:"noProxy,omitempty"`
+	// PodCIDRs are the cluster's pod CIDRs, which are not proxied so that Vizier components can reach each other by
+	// pod IP. They are detected from the nodes' pod CIDRs when unset, which misses the CIDRs of nodes added later, so
+	// the cluster's pod CIDR should be set on clusters which scale their nodes.
+	PodCIDRs []string `json:"podCIDRs,omitempty"`
+	// ServiceCIDRs are the cluster's service CIDRs, which are not proxied. The service CIDR can't be detected from the
+	// cluster, so the cluster IPs of the API server's service are used when unset.
+	ServiceCIDRs []string `json:"serviceCIDRs,omitempty"`
+}
+
+// CertManagerParams specifies the cert-manager issuer of the Vizier service certs. The operator creates the
+// "vizier-server-tls" and "vizier-client-tls" Certificates in the Vizier's namespace, and copies the issued certs
+// into the secrets mounted by Vizier pods. Renewed certs are copied when the Vizier is next updated.
+type CertManagerParams struct {
+	// IssuerName is the name of the cert-manager Issuer or ClusterIssuer. The issuer must provide the CA in the
+	// issued secrets, as CA and Vault issuers do, since Vizier services use it to verify each other.
+	IssuerName string `json:"issuerName"`
+	// IssuerKind is the kind of the issuer. Defaults to Issuer.
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	IssuerKind string `json:"issuerKind,omitempty"`
+	// Duration is the requested lifetime of the certs, for example: "2160h". If not specified, the issuer's
+	// default is used.
+	Duration *metav1.Duration `json:"duration,omitempty"`
+}
+
+// ArchitectureParams specifies the CPU architectures supported by the images of Vizier components. Components which
+// are not listed are assumed to have multi-arch images, and are scheduled on nodes of any architecture.
+type ArchitectureParams struct {
+	// Components maps the name of a component's workload, such as "kelvin" or "vizier-pem", to the CPU architectures
+	// which its images support, such as "amd64" or "arm64". The component's pods are only scheduled on nodes of these
+	// architectures, according to the "kubernetes.io/arch" node label.
+	Components map[string][]string `json:"components,omitempty"`
+	// ImageTagSuffixes maps a CPU architecture to the suffix of the image tags which are built only for that
+	// architecture, such as "-arm64". When a component supports a single architecture which has a suffix, the suffix
+	// is appended to the tags of the component's images. For example, the image "vizier-pem_image:0.10.0" of a
+	// component which only supports arm64 is replaced with "vizier-pem_image:0.10.0-arm64".
+	ImageTagSuffixes map[string]string `json:"imageTagSuffixes,omitempty"`
+}
+
+// ImageDigestParams specifies how the digests of Vizier images are resolved.
+type ImageDigestParams struct {
+	// PullSecretName is the name of a Secret of type kubernetes.io/dockerconfigjson in the Vizier's namespace, which
+	// contains the credentials for registries that do not allow anonymous pulls. Images in other registries are
+	// resolved anonymously.
+	PullSecretName string `json:"pullSecretName,omitempty"`
+}
+
+// PEMMemoryAutoSizeParams specifies how the memory of PEMs is sized from the nodes which they are scheduled on. Since
+// all PEMs are deployed by the same daemonset, the memory is sized from the smallest of these nodes, and both the
+// memory request and limit are set to it.
+type PEMMemoryAutoSizeParams struct {
+	// NodeMemoryPercent is the percentage of the node's allocatable memory which PEMs may use. Defaults to 25.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	NodeMemoryPercent int32 `json:"nodeMemoryPercent,omitempty"`
+	// Min is the smallest memory which PEMs are given, regardless of the size of the nodes. Defaults to 1Gi.
+	Min *resource.Quantity `json:"min,omitempty"`
+	// Max is the largest memory which PEMs are given, regardless of the size of the nodes. If not specified, the
+	// memory is not capped.
+	Max *resource.Quantity `json:"max,omitempty"`
+}
+
+// DeployKeySource specifies the external secret manager which stores the deploy key. Exactly one source should be
+// specified.
+type DeployKeySource struct {
+	// Vault reads the deploy key from a HashiCorp Vault secret.
+	Vault *VaultDeployKeySource `json:"vault,omitempty"`
+	// AWSSecretsManager reads the deploy key from an AWS Secrets Manager secret.
+	AWSSecretsManager *AWSSecretsManagerDeployKeySource `json:"awsSecretsManager,omitempty"`
+	// GCPSecretManager reads the deploy key from a GCP Secret Manager secret version.
+	GCPSecretManager *GCPSecretManagerDeployKeySource `json:"gcpSecretManager,omitempty"`
+}
+
+// VaultDeployKeySource specifies a HashiCorp Vault secret containing the deploy key. The operator logs in to Vault
+// with its Kubernetes service account token, using Vault's Kubernetes auth method.
+type VaultDeployKeySource struct {
+	// Address is the address of the Vault server, for example: "https://vault.example.com:8200".
+	Address string `json:"address"`
+	// Path is the path of the secret, including its mount, for example: "secret/data/pixie" for a KV version 2 secret.
+	Path string `json:"path"`
+	// Key is the key in the secret which contains the deploy key. Defaults to "deploy-key".
+	Key string `json:"key,omitempty"`
+	// Role is the Vault role which the operator logs in as.
+	Role string `json:"role"`
+	// AuthMountPath is the path where the Kubernetes auth method is mounted. Defaults to "kubernetes".
+	AuthMountPath string `json:"authMountPath,omitempty"`
+}
+
+// AWSSecretsManagerDeployKeySource specifies an AWS Secrets Manager secret containing the deploy key. The operator
+// authenticates with the credentials in its environment, either static credentials or a web identity token, such as
+// the one provided by IAM roles for service accounts.
+type AWSSecretsManagerDeployKeySource struct {
+	// Region is the AWS region of the secret.
+	Region string `json:"region"`
+	// SecretID is the name or ARN of the secret.
+	SecretID string `json:"secretID"`
+	// Key is the key in the secret's JSON value which contains the deploy key. If not specified, the entire value of
+	// the secret is the deploy key.
+	Key string `json:"key,omitempty"`
+}
+
+// GCPSecretManagerDeployKeySource specifies a GCP Secret Manager secret version containing the deploy key. The
+// operator authenticates with its application default credentials, such as those provided by Workload Identity.
+type GCPSecretManagerDeployKeySource struct {
+	// Name is the resource name of the secret version, for example:
+	// "projects/my-project/secrets/pixie-deploy-key/versions/latest".
+	Name string `json:"name"`
+}
+
+// LogLevel is the minimum severity of the messages which Vizier components log.
+// +kubebuilder:validation:Enum=debug;info;warn;error
+type LogLevel string
+
+const (
+	// LogLevelDebug logs debug messages, in addition to all messages logged at the info level.
+	LogLevelDebug LogLevel = "debug"
+	// LogLevelInfo logs informational messages, warnings and errors.
+	LogLevelInfo LogLevel = "info"
+	// LogLevelWarn logs warnings and errors.
+	LogLevelWarn LogLevel = "warn"
+	// LogLevelError logs errors only.
+	LogLevelError LogLevel = "error"
+)
+
+// LogFormat is the format which Vizier components write their logs in.
+// +kubebuilder:validation:Enum=text;json
+type LogFormat string
+
+const (
+	// LogFormatText writes logs as human-readable text.
+	LogFormatText LogFormat = "text"
+	// LogFormatJSON writes each log message as a JSON object, for log pipelines which parse structured logs.
+	LogFormatJSON LogFormat = "json"
+)
+
+// LoggingParams specifies the logging settings which are set as environment variables in all Vizier containers.
+type LoggingParams struct {
+	// Level is the minimum severity of the messages which are logged. Defaults to info.
+	Level LogLevel `json:"level,omitempty"`
+	// Format is the format of the logs. The JSON format is only supported by the Go services, so the PEM and Kelvin
+	// always log as text. Defaults to text.
+	Format LogFormat `json:"format,omitempty"`
+}
+
+// ServiceMeshParams specifies the Istio and Linkerd annotations which are added to all Vizier pods. Sidecars injected
+// into PEMs break tracing, since the PEMs then trace the sidecar's traffic, and use resources on every node.
+type ServiceMeshParams struct {
+	// DisableSidecarInjection prevents Istio and Linkerd from injecting sidecars into Vizier pods.
+	DisableSidecarInjection bool `json:"disableSidecarInjection,omitempty"`
+	// ExcludeInboundPorts are the ports which injected sidecars do not intercept inbound traffic on, such as ports
+	// which Vizier already secures with its own mTLS. This only applies if sidecar injection is not disabled.
+	ExcludeInboundPorts []int32 `json:"excludeInboundPorts,omitempty"`
+	// ExcludeOutboundPorts are the ports which injected sidecars do not intercept outbound traffic to. This only
+	// applies if sidecar injection is not disabled.
+	ExcludeOutboundPorts []int32 `json:"excludeOutboundPorts,omitempty"`
+}
+
+// PEMUpgradeStrategy specifies a staged rollout of updated PEMs, in which a set of canary nodes is upgraded first.
+type PEMUpgradeStrategy struct {
+	// CanaryNodes is the number of nodes, or the percentage of nodes running PEMs, which are upgraded first, for
+	// example: 5 or "10%". Percentages are rounded up. Defaults to 1.
+	CanaryNodes *intstr.IntOrString `json:"canaryNodes,omitempty"`
+	// CanaryNodeSelector restricts the canaries to nodes whose labels match the selector. If not specified, any node
+	// running a PEM may be chosen as a canary.
+	CanaryNodeSelector map[string]string `json:"canaryNodeSelector,omitempty"`
+	// VerificationPeriodSeconds is how long the canary PEMs must remain healthy before the upgrade proceeds to the
+	// remaining nodes. Defaults to 300.
+	VerificationPeriodSeconds int32 `json:"verificationPeriodSeconds,omitempty"`
+	// MaxUnavailable is the number or percentage of the remaining PEMs which may be upgraded at a time once the
+	// canary PEMs have been verified. Defaults to 1.
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// MetadataBackupParams specifies the backup and restore of the metadata store. When the metadata service uses the
+// etcd operator, backups are etcd snapshots. Otherwise, backups are archives of the metadata PVC's contents.
+type MetadataBackupParams struct {
+	// ClaimName is the name of a PersistentVolumeClaim in the Vizier's namespace which backups are stored in. The
+	// claim must already exist. Backups are not removed from the claim by the operator.
+	ClaimName string `json:"claimName"`
+	// RestoreFrom is the name of a backup to restore the metadata store from, as reported in the status'
+	// lastMetadataBackup. The metadata store is restored once, during the next update of the Vizier. Restoring is
+	// only supported when the metadata service does not use the etcd operator.
+	RestoreFrom string `json:"restoreFrom,omitempty"`
+}
+
+// ExternalNATSParams specifies how Vizier connects to an externally managed NATS cluster.
+type ExternalNATSParams struct {
+	// URL is the address of the NATS cluster, for example: "tls://nats.nats-system.svc:4222".
+	URL string `json:"url"`
+	// TLSSecretName is the name of a secret in the Vizier's namespace which contains the "ca.crt", "client.crt" and
+	// "client.key" that Vizier uses to connect to NATS. If not specified, the NATS cluster must accept Vizier's
+	// service certificates, or TLS must be disabled for Vizier.
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+	// CredentialsSecretName is the name of a secret in the Vizier's namespace which contains the "nats.creds" file that
+	// Vizier uses to authenticate to NATS. If not specified, Vizier connects without user credentials.
+	CredentialsSecretName string `json:"credentialsSecretName,omitempty"`
+	// Streaming is the persistent streaming layer that Vizier uses on top of the NATS cluster. Defaults to STAN.
+	Streaming MessageBusStreaming `json:"streaming,omitempty"`
+	// STANClusterID is the cluster ID of the STAN streaming server. This is ignored unless streaming is STAN, and
+	// defaults to "pl-stan".
+	STANClusterID string `json:"stanClusterID,omitempty"`
+}
+
+// MessageBusStreaming is the persistent streaming layer used on top of an external NATS cluster.
+// +kubebuilder:validation:Enum=STAN;JetStream
+type MessageBusStreaming string
+
+const (
+	// MessageBusStreamingSTAN uses a NATS Streaming (STAN) server for persistent streams.
+	MessageBusStreamingSTAN MessageBusStreaming = "STAN"
+	// MessageBusStreamingJetStream uses NATS JetStream for persistent streams.
+	MessageBusStreamingJetStream MessageBusStreaming = "JetStream"
+)
+
+// NATSParams specifies the configuration of the NATS cluster deployed with Vizier. The NATS cluster does not persist
+// any messages, so it does not require storage.
+type NATSParams struct {
+	// Replicas is the number of NATS servers to run. When more than one server is run, the servers are clustered.
+	// +kubebuilder:validation:Minimum=1
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Resources are the resource requirements for the NATS servers. These take precedence over the pod policy's
+	// resource requirements.
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// KelvinParams specifies the scaling of the Kelvin deployment.
+type KelvinParams struct {
+	// Replicas is the number of Kelvins to run. This is ignored when autoscaling is enabled.
+	// +kubebuilder:validation:Minimum=1
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Autoscaling specifies that the number of Kelvins should be scaled with their CPU usage by a
+	// HorizontalPodAutoscaler. Kelvin must have a CPU request, which can be set in the pod policy's resources.
+	Autoscaling *KelvinAutoscalingParams `json:"autoscaling,omitempty"`
+}
+
+// KelvinAutoscalingParams specifies the bounds and target of the Kelvin HorizontalPodAutoscaler.
+type KelvinAutoscalingParams struct {
+	// MinReplicas is the minimum number of Kelvins. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// MaxReplicas is the maximum number of Kelvins.
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+	// TargetCPUUtilizationPercentage is the average CPU utilization, as a percentage of the requested CPU, which
+	// the autoscaler maintains. Defaults to 80.
+	// +kubebuilder:validation:Minimum=1
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+}
+
+// AutoRemediationParams specifies how often the operator may restart stuck Vizier pods.
+type AutoRemediationParams struct {
+	// MinBackoff is how long the operator waits before restarting a component again after restarting it once. The
+	// backoff doubles with each further restart of the component, up to an hour. Defaults to 5m.
+	MinBackoff *metav1.Duration `json:"minBackoff,omitempty"`
+	// MaxRestartsPerHour is the number of pods which the operator may restart within an hour, across all of the
+	// Vizier's components. Defaults to 5.
+	// +kubebuilder:validation:Minimum=1
+	MaxRestartsPerHour int32 `json:"maxRestartsPerHour,omitempty"`
+}
+
+// MonitorCheck is a health check of the Vizier which can be disabled.
+// +kubebuilder:validation:Enum=VizierVersion;NATSConnectivity;MetadataStorage;PEMResources;PEMCrashing
+type MonitorCheck string
+
+const (
+	// MonitorCheckVizierVersion checks that the Vizier is at most one minor version older than the latest version.
+	MonitorCheckVizierVersion MonitorCheck = "VizierVersion"
+	// MonitorCheckNATSConnectivity checks that a message can be round-tripped through NATS.
+	MonitorCheckNATSConnectivity MonitorCheck = "NATSConnectivity"
+	// MonitorCheckMetadataStorage checks the usage of the metadata PVC, or the health of the etcd cluster if the
+	// metadata is stored in etcd.
+	MonitorCheckMetadataStorage MonitorCheck = "MetadataStorage"
+	// MonitorCheckPEMResources checks that the PEMs can be scheduled with their memory requests.
+	MonitorCheckPEMResources MonitorCheck = "PEMResources"
+	// MonitorCheckPEMCrashing checks how many of the PEMs are crashing.
+	MonitorCheckPEMCrashing MonitorCheck = "PEMCrashing"
+)
+
+// MonitorParams specifies how the operator checks the health of the Vizier.
+type MonitorParams struct {
+	// Interval is how often the health of the Vizier is checked, for example: "1m". It must be at least 5s.
+	// Defaults to 20s.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// FailureThreshold is the number of consecutive checks which must observe a new state before the Vizier's status
+	// changes to it, both when the Vizier fails and when it recovers. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+	// PEMCrashingPercent is the percentage of PEMs which must be crashing for the Vizier to be degraded. Defaults
+	// to 25.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	PEMCrashingPercent int32 `json:"pemCrashingPercent,omitempty"`
+	// DisabledChecks are the health checks which are not run.
+	DisabledChecks []MonitorCheck `json:"disabledChecks,omitempty"`
+}
+
+// WebhookFormat is the format of the JSON payload which is POSTed to a webhook.
+// +kubebuilder:validation:Enum=Generic;Slack;PagerDuty
+type WebhookFormat string
+
+const (
+	// WebhookFormatGeneric posts the Vizier's name, namespace, previous and current phase, reason and message.
+	WebhookFormatGeneric WebhookFormat = "Generic"
+	// WebhookFormatSlack posts a message to a Slack incoming webhook.
+	WebhookFormatSlack WebhookFormat = "Slack"
+	// WebhookFormatPagerDuty posts an event to the PagerDuty Events API v2, which triggers an alert when the Vizier
+	// becomes unhealthy and resolves it once the Vizier is healthy.
+	WebhookFormatPagerDuty WebhookFormat = "PagerDuty"
+)
+
+// WebhookParams specifies a webhook which is notified of the Vizier's phase transitions.
+type WebhookParams struct {
+	// URL is where the notifications are POSTed. For PagerDuty, this defaults to the PagerDuty Events API v2.
+	URL string `json:"url,omitempty"`
+	// SecretName is the name of a secret in the Vizier's namespace which contains the "url" of the webhook, for
+	// webhooks whose URL is a credential, such as Slack's. For PagerDuty, the secret must contain the "routingKey"
+	// of the integration instead.
+	SecretName string `json:"secretName,omitempty"`
+	// Format is the format of the payload. Defaults to Generic.
+	Format WebhookFormat `json:"format,omitempty"`
+}
+
+// JSONPatch is an RFC 6902 JSON patch which is applied to the Vizier resources matched by its target.
+type JSONPatch struct {
+	// Target selects the resources which the patch is applied to.
+	Target PatchTarget `json:"target"`
+	// Patch is the list of patch operations, encoded as either JSON or YAML. For example:
+	// [{"op": "add", "path": "/spec/template/spec/volumes/-", "value": {"name": "tmp", "emptyDir": {}}}]
+	Patch string `json:"patch"`
+}
+
+// PatchTarget selects resources in the same way as a Kustomize patch target. A resource must match all of the
+// specified fields, and fields which are not specified match any resource.
+type PatchTarget struct {
+	// Group is the API group of the resource, for example: "apps". The core API group is the empty string.
+	Group string `json:"group,omitempty"`
+	// Version is the API version of the resource, for example: "v1".
+	Version string `json:"version,omitempty"`
+	// Kind is the kind of the resource, for example: "Deployment".
+	Kind string `json:"kind,omitempty"`
+	// Name is the name of the resource.
+	Name string `json:"name,omitempty"`
+	// LabelSelector is a label selector which the resource's labels must match, for example: "app=pl-monitoring".
+	LabelSelector string `json:"labelSelector,omitempty"`
+}
+
 // Vizier is the Schema for the viziers API
 // +genclient
 // +genclient:noStatus
@@ -6,9 +6,182 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSSecretsManagerDeployKeySource) DeepCopyInto(out *AWSSecretsManagerDeployKeySource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSSecretsManagerDeployKeySource.
+func (in *AWSSecretsManagerDeployKeySource) DeepCopy() *AWSSecretsManagerDeployKeySource {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSSecretsManagerDeployKeySource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArchitectureParams) DeepCopyInto(out *ArchitectureParams) {
+	*out = *in
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.ImageTagSuffixes != nil {
+		in, out := &in.ImageTagSuffixes, &out.ImageTagSuffixes
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArchitectureParams.
+func (in *ArchitectureParams) DeepCopy() *ArchitectureParams {
+	if in == nil {
+		return nil
+	}
+	out := new(ArchitectureParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoRemediationParams) DeepCopyInto(out *AutoRemediationParams) {
+	*out = *in
+	if in.MinBackoff != nil {
+		in, out := &in.MinBackoff, &out.MinBackoff
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoRemediationParams.
+func (in *AutoRemediationParams) DeepCopy() *AutoRemediationParams {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoRemediationParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertManagerParams) DeepCopyInto(out *CertManagerParams) {
+	*out = *in
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManagerParams.
+func (in *CertManagerParams) DeepCopy() *CertManagerParams {
+	if in == nil {
+		return nil
+	}
+	out := new(CertManagerParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentPodPolicy) DeepCopyInto(out *ComponentPodPolicy) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.PreStop != nil {
+		in, out := &in.PreStop, &out.PreStop
+		*out = new(corev1.LifecycleHandler)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentPodPolicy.
+func (in *ComponentPodPolicy) DeepCopy() *ComponentPodPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentPodPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentStatus) DeepCopyInto(out *ComponentStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentStatus.
+func (in *ComponentStatus) DeepCopy() *ComponentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentsStatus) DeepCopyInto(out *ComponentsStatus) {
+	*out = *in
+	out.PEM = in.PEM
+	out.Kelvin = in.Kelvin
+	out.Metadata = in.Metadata
+	out.NATS = in.NATS
+	out.CloudConnector = in.CloudConnector
+	if in.LastCloudHeartbeatTime != nil {
+		in, out := &in.LastCloudHeartbeatTime, &out.LastCloudHeartbeatTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentsStatus.
+func (in *ComponentsStatus) DeepCopy() *ComponentsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataCollectorParams) DeepCopyInto(out *DataCollectorParams) {
 	*out = *in
@@ -31,6 +204,147 @@ func (in *DataCollectorParams) DeepCopy() *DataCollectorParams {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeployKeySource) DeepCopyInto(out *DeployKeySource) {
+	*out = *in
+	if in.Vault != nil {
+		in, out := &in.Vault, &out.Vault
+		*out = new(VaultDeployKeySource)
+		**out = **in
+	}
+	if in.AWSSecretsManager != nil {
+		in, out := &in.AWSSecretsManager, &out.AWSSecretsManager
+		*out = new(AWSSecretsManagerDeployKeySource)
+		**out = **in
+	}
+	if in.GCPSecretManager != nil {
+		in, out := &in.GCPSecretManager, &out.GCPSecretManager
+		*out = new(GCPSecretManagerDeployKeySource)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeployKeySource.
+func (in *DeployKeySource) DeepCopy() *DeployKeySource {
+	if in == nil {
+		return nil
+	}
+	out := new(DeployKeySource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalNATSParams) DeepCopyInto(out *ExternalNATSParams) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalNATSParams.
+func (in *ExternalNATSParams) DeepCopy() *ExternalNATSParams {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalNATSParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GCPSecretManagerDeployKeySource) DeepCopyInto(out *GCPSecretManagerDeployKeySource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCPSecretManagerDeployKeySource.
+func (in *GCPSecretManagerDeployKeySource) DeepCopy() *GCPSecretManagerDeployKeySource {
+	if in == nil {
+		return nil
+	}
+	out := new(GCPSecretManagerDeployKeySource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageDigestParams) DeepCopyInto(out *ImageDigestParams) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageDigestParams.
+func (in *ImageDigestParams) DeepCopy() *ImageDigestParams {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageDigestParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JSONPatch) DeepCopyInto(out *JSONPatch) {
+	*out = *in
+	out.Target = in.Target
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JSONPatch.
+func (in *JSONPatch) DeepCopy() *JSONPatch {
+	if in == nil {
+		return nil
+	}
+	out := new(JSONPatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KelvinAutoscalingParams) DeepCopyInto(out *KelvinAutoscalingParams) {
+	*out = *in
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetCPUUtilizationPercentage != nil {
+		in, out := &in.TargetCPUUtilizationPercentage, &out.TargetCPUUtilizationPercentage
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KelvinAutoscalingParams.
+func (in *KelvinAutoscalingParams) DeepCopy() *KelvinAutoscalingParams {
+	if in == nil {
+		return nil
+	}
+	out := new(KelvinAutoscalingParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KelvinParams) DeepCopyInto(out *KelvinParams) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(KelvinAutoscalingParams)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KelvinParams.
+func (in *KelvinParams) DeepCopy() *KelvinParams {
+	if in == nil {
+		return nil
+	}
+	out := new(KelvinParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LeadershipElectionParams) DeepCopyInto(out *LeadershipElectionParams) {
 	*out = *in
@@ -46,6 +360,158 @@ func (in *LeadershipElectionParams) DeepCopy() *LeadershipElectionParams {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoggingParams) DeepCopyInto(out *LoggingParams) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoggingParams.
+func (in *LoggingParams) DeepCopy() *LoggingParams {
+	if in == nil {
+		return nil
+	}
+	out := new(LoggingParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetadataBackupParams) DeepCopyInto(out *MetadataBackupParams) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetadataBackupParams.
+func (in *MetadataBackupParams) DeepCopy() *MetadataBackupParams {
+	if in == nil {
+		return nil
+	}
+	out := new(MetadataBackupParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitorParams) DeepCopyInto(out *MonitorParams) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.DisabledChecks != nil {
+		in, out := &in.DisabledChecks, &out.DisabledChecks
+		*out = make([]MonitorCheck, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitorParams.
+func (in *MonitorParams) DeepCopy() *MonitorParams {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitorParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NATSParams) DeepCopyInto(out *NATSParams) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NATSParams.
+func (in *NATSParams) DeepCopy() *NATSParams {
+	if in == nil {
+		return nil
+	}
+	out := new(NATSParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PEMMemoryAutoSizeParams) DeepCopyInto(out *PEMMemoryAutoSizeParams) {
+	*out = *in
+	if in.Min != nil {
+		in, out := &in.Min, &out.Min
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Max != nil {
+		in, out := &in.Max, &out.Max
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PEMMemoryAutoSizeParams.
+func (in *PEMMemoryAutoSizeParams) DeepCopy() *PEMMemoryAutoSizeParams {
+	if in == nil {
+		return nil
+	}
+	out := new(PEMMemoryAutoSizeParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PEMUpgradeStrategy) DeepCopyInto(out *PEMUpgradeStrategy) {
+	*out = *in
+	if in.CanaryNodes != nil {
+		in, out := &in.CanaryNodes, &out.CanaryNodes
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.CanaryNodeSelector != nil {
+		in, out := &in.CanaryNodeSelector, &out.CanaryNodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PEMUpgradeStrategy.
+func (in *PEMUpgradeStrategy) DeepCopy() *PEMUpgradeStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(PEMUpgradeStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatchTarget) DeepCopyInto(out *PatchTarget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatchTarget.
+func (in *PatchTarget) DeepCopy() *PatchTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(PatchTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodPolicy) DeepCopyInto(out *PodPolicy) {
 	*out = *in
@@ -76,6 +542,42 @@ func (in *PodPolicy) DeepCopyInto(out *PodPolicy) {
 		*out = new(PodSecurityContext)
 		**out = **in
 	}
+	if in.PEMHostPID != nil {
+		in, out := &in.PEMHostPID, &out.PEMHostPID
+		*out = new(bool)
+		**out = **in
+	}
+	if in.PEMExcludeNodeSelector != nil {
+		in, out := &in.PEMExcludeNodeSelector, &out.PEMExcludeNodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DNSConfig != nil {
+		in, out := &in.DNSConfig, &out.DNSConfig
+		*out = new(corev1.PodDNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make(map[string]ComponentPodPolicy, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodPolicy.
@@ -103,6 +605,71 @@ func (in *PodSecurityContext) DeepCopy() *PodSecurityContext {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxyParams) DeepCopyInto(out *ProxyParams) {
+	*out = *in
+	if in.PodCIDRs != nil {
+		in, out := &in.PodCIDRs, &out.PodCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServiceCIDRs != nil {
+		in, out := &in.ServiceCIDRs, &out.ServiceCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyParams.
+func (in *ProxyParams) DeepCopy() *ProxyParams {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxyParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceMeshParams) DeepCopyInto(out *ServiceMeshParams) {
+	*out = *in
+	if in.ExcludeInboundPorts != nil {
+		in, out := &in.ExcludeInboundPorts, &out.ExcludeInboundPorts
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeOutboundPorts != nil {
+		in, out := &in.ExcludeOutboundPorts, &out.ExcludeOutboundPorts
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceMeshParams.
+func (in *ServiceMeshParams) DeepCopy() *ServiceMeshParams {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceMeshParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultDeployKeySource) DeepCopyInto(out *VaultDeployKeySource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultDeployKeySource.
+func (in *VaultDeployKeySource) DeepCopy() *VaultDeployKeySource {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultDeployKeySource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Vizier) DeepCopyInto(out *Vizier) {
 	*out = *in
@@ -165,6 +732,38 @@ func (in *VizierList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VizierSpec) DeepCopyInto(out *VizierSpec) {
 	*out = *in
+	if in.DeployKeySource != nil {
+		in, out := &in.DeployKeySource, &out.DeployKeySource
+		*out = new(DeployKeySource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CertManager != nil {
+		in, out := &in.CertManager, &out.CertManager
+		*out = new(CertManagerParams)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.JWTSigningKeyRotationPeriod != nil {
+		in, out := &in.JWTSigningKeyRotationPeriod, &out.JWTSigningKeyRotationPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.RestartPeriod != nil {
+		in, out := &in.RestartPeriod, &out.RestartPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.NamespaceLabels != nil {
+		in, out := &in.NamespaceLabels, &out.NamespaceLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PEMMemoryAutoSize != nil {
+		in, out := &in.PEMMemoryAutoSize, &out.PEMMemoryAutoSize
+		*out = new(PEMMemoryAutoSizeParams)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Pod != nil {
 		in, out := &in.Pod, &out.Pod
 		*out = new(PodPolicy)
@@ -177,6 +776,11 @@ func (in *VizierSpec) DeepCopyInto(out *VizierSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.JSONPatches != nil {
+		in, out := &in.JSONPatches, &out.JSONPatches
+		*out = make([]JSONPatch, len(*in))
+		copy(*out, *in)
+	}
 	if in.DataCollectorParams != nil {
 		in, out := &in.DataCollectorParams, &out.DataCollectorParams
 		*out = new(DataCollectorParams)
@@ -187,6 +791,81 @@ func (in *VizierSpec) DeepCopyInto(out *VizierSpec) {
 		*out = new(LeadershipElectionParams)
 		**out = **in
 	}
+	if in.Architecture != nil {
+		in, out := &in.Architecture, &out.Architecture
+		*out = new(ArchitectureParams)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageDigests != nil {
+		in, out := &in.ImageDigests, &out.ImageDigests
+		*out = new(ImageDigestParams)
+		**out = **in
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(ProxyParams)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Logging != nil {
+		in, out := &in.Logging, &out.Logging
+		*out = new(LoggingParams)
+		**out = **in
+	}
+	if in.ServiceMesh != nil {
+		in, out := &in.ServiceMesh, &out.ServiceMesh
+		*out = new(ServiceMeshParams)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PEMUpgradeStrategy != nil {
+		in, out := &in.PEMUpgradeStrategy, &out.PEMUpgradeStrategy
+		*out = new(PEMUpgradeStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MetadataBackup != nil {
+		in, out := &in.MetadataBackup, &out.MetadataBackup
+		*out = new(MetadataBackupParams)
+		**out = **in
+	}
+	if in.ExternalNATS != nil {
+		in, out := &in.ExternalNATS, &out.ExternalNATS
+		*out = new(ExternalNATSParams)
+		**out = **in
+	}
+	if in.NATS != nil {
+		in, out := &in.NATS, &out.NATS
+		*out = new(NATSParams)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Kelvin != nil {
+		in, out := &in.Kelvin, &out.Kelvin
+		*out = new(KelvinParams)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResyncInterval != nil {
+		in, out := &in.ResyncInterval, &out.ResyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.IPFamilies != nil {
+		in, out := &in.IPFamilies, &out.IPFamilies
+		*out = make([]corev1.IPFamily, len(*in))
+		copy(*out, *in)
+	}
+	if in.AutoRemediation != nil {
+		in, out := &in.AutoRemediation, &out.AutoRemediation
+		*out = new(AutoRemediationParams)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Monitor != nil {
+		in, out := &in.Monitor, &out.Monitor
+		*out = new(MonitorParams)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Webhooks != nil {
+		in, out := &in.Webhooks, &out.Webhooks
+		*out = make([]WebhookParams, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VizierSpec.
@@ -202,6 +881,10 @@ func (in *VizierSpec) DeepCopy() *VizierSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VizierStatus) DeepCopyInto(out *VizierStatus) {
 	*out = *in
+	if in.LastVizierReasonTime != nil {
+		in, out := &in.LastVizierReasonTime, &out.LastVizierReasonTime
+		*out = (*in).DeepCopy()
+	}
 	if in.LastReconciliationPhaseTime != nil {
 		in, out := &in.LastReconciliationPhaseTime, &out.LastReconciliationPhaseTime
 		*out = (*in).DeepCopy()
@@ -211,6 +894,36 @@ func (in *VizierStatus) DeepCopyInto(out *VizierStatus) {
 		*out = make([]byte, len(*in))
 		copy(*out, *in)
 	}
+	if in.ImageDigests != nil {
+		in, out := &in.ImageDigests, &out.ImageDigests
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ResourceChecksums != nil {
+		in, out := &in.ResourceChecksums, &out.ResourceChecksums
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LastScheduledRestartTime != nil {
+		in, out := &in.LastScheduledRestartTime, &out.LastScheduledRestartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = new(ComponentsStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VizierStatus.
@@ -222,3 +935,18 @@ func (in *VizierStatus) DeepCopy() *VizierStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookParams) DeepCopyInto(out *WebhookParams) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookParams.
+func (in *WebhookParams) DeepCopy() *WebhookParams {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookParams)
+	in.DeepCopyInto(out)
+	return out
+}
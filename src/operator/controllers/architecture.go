@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+// updateArchitecture schedules the pods of a component whose images do not support every architecture only on nodes
+// of the architectures which they support. If the component supports a single architecture with an image tag suffix,
+// its images are replaced with the images built for that architecture.
+func updateArchitecture(arch *v1alpha1.ArchitectureParams, res map[string]interface{}) error {
+	archs := arch.Components[getComponentName(res)]
+	if len(archs) == 0 {
+		return nil
+	}
+	md, ok, err := unstructured.NestedFieldNoCopy(res, "spec", "template", "spec")
+	if !ok || err != nil {
+		return err
+	}
+	podSpec, ok := md.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	err = updateAffinity(getArchitectureAffinity(archs), podSpec)
+	if err != nil {
+		return err
+	}
+
+	if len(archs) > 1 || arch.ImageTagSuffixes[archs[0]] == "" {
+		return nil
+	}
+	suffix := arch.ImageTagSuffixes[archs[0]]
+	for _, field := range []string{"containers", "initContainers"} {
+		containers, ok := podSpec[field].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, c := range containers {
+			castedContainer, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if image, ok := castedContainer["image"].(string); ok && image != "" {
+				castedContainer["image"] = addImageTagSuffix(image, suffix)
+			}
+		}
+	}
+	return nil
+}
+
+// getArchitectureAffinity returns a node affinity which only allows nodes of the given architectures.
+func getArchitectureAffinity(archs []string) *v1.Affinity {
+	return &v1.Affinity{
+		NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{
+					{
+						MatchExpressions: []v1.NodeSelectorRequirement{
+							{
+								Key:      v1.LabelArchStable,
+								Operator: v1.NodeSelectorOpIn,
+								Values:   archs,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// addImageTagSuffix appends the suffix to the tag of the image. Images without a tag are treated as the "latest" tag,
+// and images which are pinned to a digest are left unchanged, since the digest already identifies a single image.
+func addImageTagSuffix(image string, suffix string) string {
+	if strings.Contains(image, "@") {
+		return image
+	}
+	// The registry host may contain a port, so the tag is only the part after a ":" in the last path component.
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		if strings.HasSuffix(image, suffix) {
+			return image
+		}
+		return image + suffix
+	}
+	return image + ":latest" + suffix
+}
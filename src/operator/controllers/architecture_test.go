@@ -0,0 +1,111 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func newTestArchResource(name string, image string) map[string]interface{} {
+	res := newTestPodResource(map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "app", "image": image},
+		},
+	})
+	res["metadata"] = map[string]interface{}{"name": name}
+	return res
+}
+
+func TestUpdateArchitecture(t *testing.T) {
+	arch := &v1alpha1.ArchitectureParams{
+		Components: map[string][]string{
+			"kelvin":      {"amd64"},
+			"vizier-cert": {"amd64", "arm64"},
+		},
+		ImageTagSuffixes: map[string]string{"amd64": "-amd64"},
+	}
+
+	kelvin := newTestArchResource("kelvin", "gcr.io/pixie-oss/pixie-prod/vizier-kelvin_image:0.10.0")
+	require.NoError(t, updateArchitecture(arch, kelvin))
+	assert.Equal(t, map[string]interface{}{
+		"nodeAffinity": map[string]interface{}{
+			"requiredDuringSchedulingIgnoredDuringExecution": map[string]interface{}{
+				"nodeSelectorTerms": []interface{}{
+					map[string]interface{}{
+						"matchExpressions": []interface{}{
+							map[string]interface{}{"key": "kubernetes.io/arch", "operator": "In", "values": []interface{}{"amd64"}},
+						},
+					},
+				},
+			},
+		},
+	}, testPodSpec(kelvin)["affinity"])
+	assert.Equal(t, "gcr.io/pixie-oss/pixie-prod/vizier-kelvin_image:0.10.0-amd64",
+		testPodSpec(kelvin)["containers"].([]interface{})[0].(map[string]interface{})["image"])
+
+	// Components which support multiple architectures keep their images.
+	cert := newTestArchResource("vizier-cert", "gcr.io/pixie-oss/pixie-prod/vizier-cert_image:0.10.0")
+	require.NoError(t, updateArchitecture(arch, cert))
+	assert.NotNil(t, testPodSpec(cert)["affinity"])
+	assert.Equal(t, "gcr.io/pixie-oss/pixie-prod/vizier-cert_image:0.10.0",
+		testPodSpec(cert)["containers"].([]interface{})[0].(map[string]interface{})["image"])
+
+	// Components which are not listed are assumed to have multi-arch images.
+	pem := newTestArchResource("vizier-pem", "gcr.io/pixie-oss/pixie-prod/vizier-pem_image:0.10.0")
+	require.NoError(t, updateArchitecture(arch, pem))
+	assert.Nil(t, testPodSpec(pem)["affinity"])
+	assert.Equal(t, "gcr.io/pixie-oss/pixie-prod/vizier-pem_image:0.10.0",
+		testPodSpec(pem)["containers"].([]interface{})[0].(map[string]interface{})["image"])
+}
+
+func TestUpdateArchitecture_NoPodSpec(t *testing.T) {
+	arch := &v1alpha1.ArchitectureParams{
+		Components: map[string][]string{"kelvin": {"amd64"}},
+	}
+	service := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "kelvin"},
+		"spec":     map[string]interface{}{"type": "ClusterIP"},
+	}
+	require.NoError(t, updateArchitecture(arch, service))
+	assert.Equal(t, map[string]interface{}{"type": "ClusterIP"}, service["spec"])
+}
+
+func TestAddImageTagSuffix(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{"gcr.io/pixie-oss/vizier-pem_image:0.10.0", "gcr.io/pixie-oss/vizier-pem_image:0.10.0-arm64"},
+		{"gcr.io/pixie-oss/vizier-pem_image:0.10.0-arm64", "gcr.io/pixie-oss/vizier-pem_image:0.10.0-arm64"},
+		{"localhost:5000/vizier-pem_image", "localhost:5000/vizier-pem_image:latest-arm64"},
+		{"nats:2.9", "nats:2.9-arm64"},
+		{"gcr.io/pixie-oss/vizier-pem_image@sha256:abcd", "gcr.io/pixie-oss/vizier-pem_image@sha256:abcd"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.image, func(t *testing.T) {
+			assert.Equal(t, tc.expected, addImageTagSuffix(tc.image, "-arm64"))
+		})
+	}
+}
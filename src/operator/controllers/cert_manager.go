@@ -0,0 +1,131 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/certs"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+const (
+	// The name of the cert-manager Certificate, and the secret it is issued to, for the Vizier services' server cert.
+	certManagerServerCertName = "vizier-server-tls"
+	// The name of the cert-manager Certificate, and the secret it is issued to, for the Vizier services' client cert.
+	certManagerClientCertName = "vizier-client-tls"
+	// The default kind of the cert-manager issuer.
+	defaultCertManagerIssuerKind = "Issuer"
+	// How long to wait for cert-manager to issue the Vizier certs.
+	certManagerIssueTimeout = 5 * time.Minute
+)
+
+var certManagerCertificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+// getCertManagerCertificate returns the cert-manager Certificate which issues a Vizier service cert to the secret of
+// the same name.
+func getCertManagerCertificate(namespace string, vizierName string, name string, params *v1alpha1.CertManagerParams) *k8s.Resource {
+	issuerKind := params.IssuerKind
+	if issuerKind == "" {
+		issuerKind = defaultCertManagerIssuerKind
+	}
+	dnsNames := make([]interface{}, 0)
+	for _, n := range certs.GetVizierDNSNamesForNamespace(namespace) {
+		dnsNames = append(dnsNames, n)
+	}
+
+	spec := map[string]interface{}{
+		"secretName": name,
+		"commonName": name,
+		"dnsNames":   dnsNames,
+		"usages":     []interface{}{"server auth", "client auth"},
+		"issuerRef": map[string]interface{}{
+			"name":  params.IssuerName,
+			"kind":  issuerKind,
+			"group": certManagerCertificateGVK.Group,
+		},
+	}
+	if params.Duration != nil {
+		spec["duration"] = params.Duration.Duration.String()
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	obj.SetGroupVersionKind(certManagerCertificateGVK)
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	obj.SetLabels(map[string]string{operatorAnnotation: vizierName})
+	return &k8s.Resource{Object: obj, GVK: &certManagerCertificateGVK}
+}
+
+// getCertManagerVizierCertYAMLs creates the cert-manager Certificates for the Vizier services, and returns the YAMLs
+// for the Vizier cert secrets once the certs have been issued.
+func (r *VizierReconciler) getCertManagerVizierCertYAMLs(ctx context.Context, namespace string, vz *v1alpha1.Vizier) (string, error) {
+	resources := []*k8s.Resource{
+		getCertManagerCertificate(namespace, vz.Name, certManagerServerCertName, vz.Spec.CertManager),
+		getCertManagerCertificate(namespace, vz.Name, certManagerClientCertName, vz.Spec.CertManager),
+	}
+	err := k8s.ApplyResources(r.Clientset, r.RestConfig, resources, namespace, nil, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to create cert-manager certificates: %w", err)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(r.RestConfig)
+	if err != nil {
+		return "", err
+	}
+	return readCertManagerVizierCerts(ctx, r.Clientset, dynamicClient, namespace)
+}
+
+// readCertManagerVizierCerts waits for cert-manager to issue the Vizier certs, and returns the YAMLs for the Vizier
+// cert secrets containing the issued certs.
+func readCertManagerVizierCerts(ctx context.Context, clientset kubernetes.Interface, dynamicClient dynamic.Interface, namespace string) (string, error) {
+	gvr := certManagerCertificateGVK.GroupVersion().WithResource("certificates")
+	data := make(map[string]map[string][]byte)
+	for _, name := range []string{certManagerServerCertName, certManagerClientCertName} {
+		err := k8s.WaitForCondition(ctx, dynamicClient, gvr, namespace, name,
+			&k8s.Condition{JSONPath: `{.status.conditions[?(@.type=="Ready")].status}`, Value: "True"}, certManagerIssueTimeout)
+		if err != nil {
+			return "", err
+		}
+		s, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		// Some issuers, such as ACME issuers, do not provide the CA, which Vizier services require to verify each other.
+		for _, key := range []string{"ca.crt", "tls.crt", "tls.key"} {
+			if len(s.Data[key]) == 0 {
+				return "", fmt.Errorf("cert-manager secret %s is missing %s", name, key)
+			}
+		}
+		data[name] = s.Data
+	}
+
+	server := data[certManagerServerCertName]
+	client := data[certManagerClientCertName]
+	return certs.VizierCertYAMLs(namespace, server["ca.crt"], server["tls.crt"], server["tls.key"],
+		client["tls.crt"], client["tls.key"])
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func TestGetCertManagerCertificate(t *testing.T) {
+	cert := getCertManagerCertificate("pl", "vizier", certManagerServerCertName, &v1alpha1.CertManagerParams{
+		IssuerName: "internal-ca",
+		Duration:   &metav1.Duration{Duration: 90 * 24 * time.Hour},
+	})
+
+	assert.Equal(t, "Certificate", cert.GVK.Kind)
+	assert.Equal(t, "pl", cert.Object.GetNamespace())
+	assert.Equal(t, "vizier", cert.Object.GetLabels()[operatorAnnotation])
+
+	spec := cert.Object.Object["spec"].(map[string]interface{})
+	assert.Equal(t, certManagerServerCertName, spec["secretName"])
+	assert.Equal(t, "2160h0m0s", spec["duration"])
+	assert.Equal(t, map[string]interface{}{
+		"name":  "internal-ca",
+		"kind":  "Issuer",
+		"group": "cert-manager.io",
+	}, spec["issuerRef"])
+	assert.Contains(t, spec["dnsNames"], "*.pl.svc")
+}
+
+func newTestCertificate(name string, ready string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Certificate",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "pl"},
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": ready},
+			},
+		},
+	}}
+}
+
+func newTestCertManagerSecret(name string, ca string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "pl"},
+		Data: map[string][]byte{
+			"ca.crt":  []byte(ca),
+			"tls.crt": []byte(name + " cert"),
+			"tls.key": []byte(name + " key"),
+		},
+	}
+}
+
+func TestReadCertManagerVizierCerts(t *testing.T) {
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newTestCertificate(certManagerServerCertName, "True"),
+		newTestCertificate(certManagerClientCertName, "True"),
+	)
+
+	t.Run("issued", func(t *testing.T) {
+		clientset := fake.NewSimpleClientset(
+			newTestCertManagerSecret(certManagerServerCertName, "ca"),
+			newTestCertManagerSecret(certManagerClientCertName, "ca"),
+		)
+		yamls, err := readCertManagerVizierCerts(context.Background(), clientset, dynamicClient, "pl")
+		require.NoError(t, err)
+		resources, err := k8s.GetResourcesFromYAML(strings.NewReader(yamls))
+		require.NoError(t, err)
+		assert.NotEmpty(t, resources)
+	})
+
+	t.Run("missing CA", func(t *testing.T) {
+		clientset := fake.NewSimpleClientset(
+			newTestCertManagerSecret(certManagerServerCertName, ""),
+			newTestCertManagerSecret(certManagerClientCertName, ""),
+		)
+		_, err := readCertManagerVizierCerts(context.Background(), clientset, dynamicClient, "pl")
+		assert.Error(t, err)
+	})
+}
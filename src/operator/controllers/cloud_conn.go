@@ -0,0 +1,226 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+
+	"px.dev/pixie/src/shared/services"
+)
+
+// CloudConnConfig configures the operator's connection to Pixie Cloud.
+type CloudConnConfig struct {
+	// ProxyURL is the URL of the HTTP proxy through which Pixie Cloud is reached. If empty, the proxy is taken from
+	// the HTTPS_PROXY and NO_PROXY environment variables.
+	ProxyURL string
+	// CACertFile is the path to a PEM file of CA certificates which are trusted to sign the certificate of Pixie
+	// Cloud, in addition to the system's trusted CAs. This is needed for self-hosted clouds behind an internal CA.
+	CACertFile string
+}
+
+// getTLSConfig returns the TLS config with which the certificate of Pixie Cloud is validated.
+func (c CloudConnConfig) getTLSConfig() (*tls.Config, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		log.WithError(err).Warn("Failed to load the system's trusted CAs, only trusting the cloud CA")
+		pool = x509.NewCertPool()
+	}
+	ca, err := os.ReadFile(c.CACertFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cloud CA cert: %w", err)
+	}
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in cloud CA cert %s", c.CACertFile)
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
+
+// getProxyURL returns the URL of the proxy through which the given cloud address should be reached, or nil if it
+// should be reached directly.
+func (c CloudConnConfig) getProxyURL(addr string) (*url.URL, error) {
+	if c.ProxyURL != "" {
+		return url.Parse(c.ProxyURL)
+	}
+	// The gRPC connection to Pixie Cloud is always over TLS, so the HTTPS proxy applies.
+	return http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: "https", Host: addr}})
+}
+
+func getCloudClientConnection(conf CloudConnConfig, cloudAddr string, devCloudNS string) (*grpc.ClientConn, error) {
+	isInternal := false
+
+	if devCloudNS != "" {
+		cloudAddr = fmt.Sprintf("api-service.%s.svc.cluster.local:51200", devCloudNS)
+		isInternal = true
+	}
+
+	dialOpts, err := services.GetGRPCClientDialOptsServerSideTLS(isInternal)
+	if err != nil {
+		return nil, err
+	}
+
+	// A cloud in the cluster is neither validated against the CA nor reached through the proxy.
+	if !isInternal && conf.CACertFile != "" {
+		tlsConfig, err := conf.getTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		// This replaces the transport credentials in the default dial options.
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+	if !isInternal {
+		proxyURL, err := conf.getProxyURL(cloudAddr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cloud proxy: %w", err)
+		}
+		if proxyURL != nil {
+			dialOpts = append(dialOpts, grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+				return dialThroughProxy(ctx, proxyURL, addr)
+			}))
+		}
+	}
+
+	c, err := grpc.Dial(cloudAddr, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// cloudConnPool caches a connection to each cloud address, so that the connection is shared by all reconciles rather
+// than dialed for each of them. gRPC reconnects the cached connections by itself if they break.
+type cloudConnPool struct {
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// get returns the cached connection to the given cloud, or dials one if there is none or it has been shut down. The
+// returned connection is shared, and must not be closed by the caller.
+func (p *cloudConnPool) get(conf CloudConnConfig, cloudAddr string, devCloudNS string) (*grpc.ClientConn, error) {
+	key := cloudAddr
+	if devCloudNS != "" {
+		key = "dev/" + devCloudNS
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.conns[key]; ok {
+		switch c.GetState() {
+		case connectivity.Shutdown:
+			delete(p.conns, key)
+		case connectivity.TransientFailure:
+			// Retry right away rather than waiting out the reconnect backoff, since the connection is needed now.
+			c.ResetConnectBackoff()
+			return c, nil
+		default:
+			return c, nil
+		}
+	}
+
+	c, err := getCloudClientConnection(conf, cloudAddr, devCloudNS)
+	if err != nil {
+		return nil, err
+	}
+	if p.conns == nil {
+		p.conns = make(map[string]*grpc.ClientConn)
+	}
+	p.conns[key] = c
+	return c, nil
+}
+
+// bufferedConn is a connection whose reads are first served from a reader, which may have buffered data read from
+// the connection.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// dialThroughProxy opens a tunnel to the given address through the HTTP proxy, using the CONNECT method.
+func dialThroughProxy(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	if proxyURL.Scheme != "http" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q, only http proxies are supported", proxyURL.Scheme)
+	}
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "80")
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to proxy %s: %w", proxyAddr, err)
+	}
+
+	req := (&http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Host: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}).WithContext(ctx)
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		creds := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+creds)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer func() { _ = conn.SetDeadline(time.Time{}) }()
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to write CONNECT request to proxy: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read CONNECT response from proxy: %w", err)
+	}
+	// The body of a successful response is the tunnel itself, so it must not be closed.
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy refused to connect to %s: %s", addr, resp.Status)
+	}
+
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// startTestProxy starts an HTTP proxy which only supports CONNECT, and echoes back everything written to the tunnel.
+// It records the target and the Proxy-Authorization header of each CONNECT request.
+func startTestProxy(t *testing.T, status int) (*url.URL, chan *http.Request) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	reqs := make(chan *http.Request, 1)
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil {
+					return
+				}
+				reqs <- req
+				resp := &http.Response{StatusCode: status, ProtoMajor: 1, ProtoMinor: 1}
+				if err := resp.Write(conn); err != nil || status != http.StatusOK {
+					return
+				}
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return &url.URL{Scheme: "http", Host: lis.Addr().String()}, reqs
+}
+
+func TestDialThroughProxy(t *testing.T) {
+	proxyURL, reqs := startTestProxy(t, http.StatusOK)
+	proxyURL.User = url.UserPassword("pixie", "secret")
+
+	conn, err := dialThroughProxy(context.Background(), proxyURL, "withpixie.ai:443")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	req := <-reqs
+	assert.Equal(t, http.MethodConnect, req.Method)
+	assert.Equal(t, "withpixie.ai:443", req.Host)
+	assert.Equal(t, "Basic cGl4aWU6c2VjcmV0", req.Header.Get("Proxy-Authorization"))
+
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+}
+
+func TestDialThroughProxy_Refused(t *testing.T) {
+	proxyURL, _ := startTestProxy(t, http.StatusForbidden)
+
+	_, err := dialThroughProxy(context.Background(), proxyURL, "withpixie.ai:443")
+	assert.Error(t, err)
+}
+
+func TestDialThroughProxy_UnsupportedScheme(t *testing.T) {
+	_, err := dialThroughProxy(context.Background(), &url.URL{Scheme: "socks5", Host: "proxy:1080"}, "withpixie.ai:443")
+	assert.Error(t, err)
+}
+
+func TestCloudConnConfig_GetProxyURL(t *testing.T) {
+	conf := CloudConnConfig{ProxyURL: "http://proxy.corp:3128"}
+	proxyURL, err := conf.getProxyURL("withpixie.ai:443")
+	require.NoError(t, err)
+	assert.Equal(t, "proxy.corp:3128", proxyURL.Host)
+}
+
+func TestCloudConnConfig_GetTLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0o600))
+
+	// The server's certificate is only trusted with the CA cert.
+	_, err := tls.Dial("tcp", srv.Listener.Addr().String(), &tls.Config{})
+	assert.Error(t, err)
+
+	tlsConfig, err := CloudConnConfig{CACertFile: caFile}.getTLSConfig()
+	require.NoError(t, err)
+	conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), tlsConfig)
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestCloudConnConfig_GetTLSConfig_InvalidCA(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, []byte("not a cert"), 0o600))
+
+	_, err := CloudConnConfig{CACertFile: caFile}.getTLSConfig()
+	assert.Error(t, err)
+
+	_, err = CloudConnConfig{CACertFile: filepath.Join(t.TempDir(), "missing.crt")}.getTLSConfig()
+	assert.Error(t, err)
+}
+
+func TestCloudConnPool(t *testing.T) {
+	var pool cloudConnPool
+	conf := CloudConnConfig{}
+
+	c1, err := pool.get(conf, "withpixie.ai:443", "")
+	require.NoError(t, err)
+	c2, err := pool.get(conf, "withpixie.ai:443", "")
+	require.NoError(t, err)
+	assert.Same(t, c1, c2)
+
+	other, err := pool.get(conf, "pixie.internal:443", "")
+	require.NoError(t, err)
+	assert.NotSame(t, c1, other)
+
+	// Connections which were shut down are replaced.
+	require.NoError(t, c1.Close())
+	c3, err := pool.get(conf, "withpixie.ai:443", "")
+	require.NoError(t, err)
+	assert.NotSame(t, c1, c3)
+
+	for _, c := range []*grpc.ClientConn{c3, other} {
+		c.Close()
+	}
+}
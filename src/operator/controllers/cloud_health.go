@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// How long to wait for the cloud connector to become healthy after a deploy.
+	cloudConnHealthTimeout = 10 * time.Minute
+	// How often the cloud connector's health is checked while waiting for it to become healthy.
+	cloudConnHealthPollInterval = 5 * time.Second
+	// The annotation which the cloud connector sets on its pod once it has registered with Pixie Cloud.
+	cloudConnClusterIDAnnotation = "cluster-id"
+)
+
+// newStatuszHTTPClient returns the HTTP client used to query the statusz endpoints of Vizier pods. Pods serve
+// statusz with self-signed certs, so these are not verified.
+func newStatuszHTTPClient() *http.Client {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	return &http.Client{Transport: tr}
+}
+
+// waitForCloudConnHealthy waits for the cloud connector to run the deployed version and to report that it is
+// connected to Pixie Cloud.
+func waitForCloudConnHealthy(ctx context.Context, clientset kubernetes.Interface, httpClient HTTPClient, namespace string) error {
+	var lastErr error
+	err := wait.PollImmediateWithContext(ctx, cloudConnHealthPollInterval, cloudConnHealthTimeout, func(ctx context.Context) (bool, error) {
+		lastErr = checkCloudConnHealth(ctx, clientset, httpClient, namespace)
+		return lastErr == nil, nil
+	})
+	if err != nil && lastErr != nil {
+		return fmt.Errorf("cloud connector did not become healthy: %w", lastErr)
+	}
+	return err
+}
+
+// checkCloudConnHealth returns an error if the cloud connector is unhealthy. The cloud connector is healthy once its
+// deployment has rolled out, so that it runs the deployed version, and each of its pods has registered with Pixie
+// Cloud and reports a healthy connection on its statusz endpoint.
+func checkCloudConnHealth(ctx context.Context, clientset kubernetes.Interface, httpClient HTTPClient, namespace string) error {
+	d, err := clientset.AppsV1().Deployments(namespace).Get(ctx, cloudConnName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if !isDeploymentRolledOut(d) {
+		return errors.New("cloud connector has not finished rolling out")
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "name=" + cloudConnName})
+	if err != nil {
+		return err
+	}
+	healthy := 0
+	for i := range pods.Items {
+		p := &pods.Items[i]
+		// Pods of the previous version may still be terminating.
+		if p.DeletionTimestamp != nil {
+			continue
+		}
+		if p.Status.Phase != v1.PodRunning {
+			return fmt.Errorf("cloud connector pod %s is %s", p.Name, p.Status.Phase)
+		}
+		if p.Annotations[cloudConnClusterIDAnnotation] == "" {
+			return fmt.Errorf("cloud connector pod %s has not registered with Pixie Cloud", p.Name)
+		}
+		ok, reason := queryPodStatusz(httpClient, p)
+		if !ok {
+			return fmt.Errorf("cloud connector pod %s is unhealthy: %s", p.Name, reason)
+		}
+		healthy++
+	}
+	if healthy == 0 {
+		return errors.New("no cloud connector pods are running")
+	}
+	return nil
+}
+
+// isDeploymentRolledOut returns whether all replicas of the deployment run its latest pod template and are available.
+func isDeploymentRolledOut(d *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	return d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedReplicas == replicas &&
+		d.Status.Replicas == replicas &&
+		d.Status.AvailableReplicas == replicas
+}
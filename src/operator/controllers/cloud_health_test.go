@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestCloudConnDeployment(updatedReplicas int32) *appsv1.Deployment {
+	replicas := int32(1)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: cloudConnName, Namespace: "pl", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           1,
+			UpdatedReplicas:    updatedReplicas,
+			AvailableReplicas:  1,
+		},
+	}
+}
+
+func newTestCloudConnPod(clusterID string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "vizier-cloud-connector-abc",
+			Namespace:   "pl",
+			Labels:      map[string]string{"name": cloudConnName},
+			Annotations: map[string]string{cloudConnClusterIDAnnotation: clusterID},
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{Ports: []v1.ContainerPort{{ContainerPort: 50800}}},
+			},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning, PodIP: "127.0.0.1"},
+	}
+}
+
+func TestCheckCloudConnHealth(t *testing.T) {
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		pod        *v1.Pod
+		statusz    string
+		healthy    bool
+	}{
+		{
+			name:       "healthy",
+			deployment: newTestCloudConnDeployment(1),
+			pod:        newTestCloudConnPod("test-cluster"),
+			healthy:    true,
+		},
+		{
+			name:       "rolling out",
+			deployment: newTestCloudConnDeployment(0),
+			pod:        newTestCloudConnPod("test-cluster"),
+		},
+		{
+			name:       "not registered",
+			deployment: newTestCloudConnDeployment(1),
+			pod:        newTestCloudConnPod(""),
+		},
+		{
+			name:       "unhealthy statusz",
+			deployment: newTestCloudConnDeployment(1),
+			pod:        newTestCloudConnPod("test-cluster"),
+			statusz:    "CloudConnectorFailedToConnect",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(tc.deployment, tc.pod)
+			httpClient := &FakeHTTPClient{
+				responses: map[string]string{"https://127.0.0.1:50800/statusz": tc.statusz},
+			}
+
+			err := checkCloudConnHealth(context.Background(), clientset, httpClient, "pl")
+			if tc.healthy {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckCloudConnHealth_NoPods(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newTestCloudConnDeployment(1))
+	err := checkCloudConnHealth(context.Background(), clientset, &FakeHTTPClient{}, "pl")
+	assert.Error(t, err)
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"time"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// clusterScopedResourceTypes are the cluster-scoped resource types which are deployed for a Vizier. Only these types
+// are deleted, so that a mislabeled resource of any other type is never removed along with the Vizier.
+var clusterScopedResourceTypes = []string{
+	"clusterroles.rbac.authorization.k8s.io",
+	"clusterrolebindings.rbac.authorization.k8s.io",
+	"mutatingwebhookconfigurations.admissionregistration.k8s.io",
+	"validatingwebhookconfigurations.admissionregistration.k8s.io",
+}
+
+// deleteClusterScopedResources deletes the cluster-scoped resources which are labeled as belonging to the Vizier, such
+// as the ClusterRoles and ClusterRoleBindings deployed for it. These are not deleted along with the Vizier's
+// namespace, and would otherwise be leaked.
+func (r *VizierReconciler) deleteClusterScopedResources(namespace string, vizierName string) error {
+	od := k8s.ObjectDeleter{
+		Clientset:  r.Clientset,
+		RestConfig: r.RestConfig,
+		Timeout:    2 * time.Minute,
+	}
+	_, err := od.DeleteByLabel(getClusterScopedSelector(namespace, vizierName), clusterScopedResourceTypes...)
+	return err
+}
+
+// getClusterScopedSelector returns the label selector for the cluster-scoped resources of a Vizier. The selector
+// includes the Vizier's namespace, since Viziers of the same name may be deployed to different namespaces.
+func getClusterScopedSelector(namespace string, vizierName string) string {
+	return operatorAnnotation + "=" + vizierName + "," + vizierNamespaceLabel + "=" + namespace
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+// getComponentName returns the name of the Vizier component which the resource belongs to, which is the name of
+// its workload.
+func getComponentName(res map[string]interface{}) string {
+	name, _, _ := unstructured.NestedString(res, "metadata", "name")
+	return name
+}
+
+// getComponentNodeSelector returns the node selector for the pods of the given component, which is the node selector
+// for all pods merged with the component's node selector.
+func getComponentNodeSelector(pod *v1alpha1.PodPolicy, component string) map[string]string {
+	componentPolicy, ok := pod.Components[component]
+	if !ok || len(componentPolicy.NodeSelector) == 0 {
+		return pod.NodeSelector
+	}
+	nodeSelector := make(map[string]string, len(pod.NodeSelector)+len(componentPolicy.NodeSelector))
+	for k, v := range pod.NodeSelector {
+		nodeSelector[k] = v
+	}
+	for k, v := range componentPolicy.NodeSelector {
+		nodeSelector[k] = v
+	}
+	return nodeSelector
+}
+
+// getComponentTolerations returns the tolerations for the pods of the given component, which are the tolerations for
+// all pods followed by the component's tolerations.
+func getComponentTolerations(pod *v1alpha1.PodPolicy, component string) []v1.Toleration {
+	componentPolicy, ok := pod.Components[component]
+	if !ok || len(componentPolicy.Tolerations) == 0 {
+		return pod.Tolerations
+	}
+	tolerations := make([]v1.Toleration, 0, len(pod.Tolerations)+len(componentPolicy.Tolerations))
+	tolerations = append(tolerations, pod.Tolerations...)
+	return append(tolerations, componentPolicy.Tolerations...)
+}
+
+// updateComponentTermination sets the termination grace period of the component's pods, and adds the component's
+// preStop hook to the first container in the pod spec, which is the component's main container.
+func updateComponentTermination(pod *v1alpha1.PodPolicy, component string, podSpec map[string]interface{}) {
+	componentPolicy, ok := pod.Components[component]
+	if !ok {
+		return
+	}
+	if componentPolicy.TerminationGracePeriodSeconds != nil {
+		podSpec["terminationGracePeriodSeconds"] = *componentPolicy.TerminationGracePeriodSeconds
+	}
+	if componentPolicy.PreStop == nil {
+		return
+	}
+
+	containers, ok := podSpec["containers"].([]interface{})
+	if !ok || len(containers) == 0 {
+		return
+	}
+	container, ok := containers[0].(map[string]interface{})
+	if !ok {
+		return
+	}
+	preStop, err := runtime.DefaultUnstructuredConverter.ToUnstructured(componentPolicy.PreStop)
+	if err != nil {
+		log.WithError(err).Error("Failed to convert preStop hook")
+		return
+	}
+	lifecycle, ok := container["lifecycle"].(map[string]interface{})
+	if !ok {
+		lifecycle = make(map[string]interface{})
+	}
+	lifecycle["preStop"] = preStop
+	container["lifecycle"] = lifecycle
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestGetComponentNodeSelector(t *testing.T) {
+	pod := &v1alpha1.PodPolicy{
+		NodeSelector: map[string]string{"kubernetes.io/os": "linux", "pool": "default"},
+		Components: map[string]v1alpha1.ComponentPodPolicy{
+			"kelvin": {NodeSelector: map[string]string{"pool": "observability"}},
+		},
+	}
+
+	assert.Equal(t, map[string]string{"kubernetes.io/os": "linux", "pool": "observability"}, getComponentNodeSelector(pod, "kelvin"))
+	assert.Equal(t, map[string]string{"kubernetes.io/os": "linux", "pool": "default"}, getComponentNodeSelector(pod, "vizier-pem"))
+	// The component's selector must not modify the selector for all pods.
+	assert.Equal(t, "default", pod.NodeSelector["pool"])
+}
+
+func TestGetComponentTolerations(t *testing.T) {
+	all := v1.Toleration{Key: "all", Operator: v1.TolerationOpExists}
+	observability := v1.Toleration{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "observability", Effect: v1.TaintEffectNoSchedule}
+	pod := &v1alpha1.PodPolicy{
+		Tolerations: []v1.Toleration{all},
+		Components: map[string]v1alpha1.ComponentPodPolicy{
+			"vizier-query-broker": {Tolerations: []v1.Toleration{observability}},
+		},
+	}
+
+	assert.Equal(t, []v1.Toleration{all, observability}, getComponentTolerations(pod, "vizier-query-broker"))
+	assert.Equal(t, []v1.Toleration{all}, getComponentTolerations(pod, "vizier-pem"))
+}
+
+func TestUpdateComponentTermination(t *testing.T) {
+	gracePeriod := int64(120)
+	pod := &v1alpha1.PodPolicy{
+		Components: map[string]v1alpha1.ComponentPodPolicy{
+			"vizier-pem": {
+				TerminationGracePeriodSeconds: &gracePeriod,
+				PreStop: &v1.LifecycleHandler{
+					Exec: &v1.ExecAction{Command: []string{"sleep", "30"}},
+				},
+			},
+		},
+	}
+
+	podSpec := map[string]interface{}{
+		"terminationGracePeriodSeconds": int64(10),
+		"containers": []interface{}{
+			map[string]interface{}{"name": "pem"},
+			map[string]interface{}{"name": "sidecar"},
+		},
+	}
+	updateComponentTermination(pod, "vizier-pem", podSpec)
+	assert.Equal(t, int64(120), podSpec["terminationGracePeriodSeconds"])
+	assert.Equal(t, map[string]interface{}{
+		"name": "pem",
+		"lifecycle": map[string]interface{}{
+			"preStop": map[string]interface{}{
+				"exec": map[string]interface{}{"command": []interface{}{"sleep", "30"}},
+			},
+		},
+	}, podSpec["containers"].([]interface{})[0])
+	assert.Equal(t, map[string]interface{}{"name": "sidecar"}, podSpec["containers"].([]interface{})[1])
+
+	// Components without a policy keep the grace period from the YAMLs.
+	podSpec = map[string]interface{}{"terminationGracePeriodSeconds": int64(30)}
+	updateComponentTermination(pod, "kelvin", podSpec)
+	assert.Equal(t, int64(30), podSpec["terminationGracePeriodSeconds"])
+}
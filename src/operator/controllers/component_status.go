@@ -0,0 +1,62 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+// getComponentStatus counts the ready pods of the component with the given name label.
+func getComponentStatus(pods *concurrentPodMap, nameLabel string) v1alpha1.ComponentStatus {
+	pods.mapMu.Lock()
+	defer pods.mapMu.Unlock()
+
+	var s v1alpha1.ComponentStatus
+	for _, p := range pods.unsafeMap[nameLabel] {
+		s.Total++
+		if isPodReady(p.pod) {
+			s.Ready++
+		}
+	}
+	return s
+}
+
+// getComponentsStatus summarizes the health of each of the Vizier's components. The last cloud heartbeat is carried
+// over from the previous summary, unless the cloud connector is currently connected.
+func getComponentsStatus(pods *concurrentPodMap, prev *v1alpha1.ComponentsStatus, cloudConnState *vizierState, now time.Time) *v1alpha1.ComponentsStatus {
+	s := &v1alpha1.ComponentsStatus{
+		PEM:            getComponentStatus(pods, vizierPemLabel),
+		Kelvin:         getComponentStatus(pods, kelvinName),
+		Metadata:       getComponentStatus(pods, vizierMetadataLabel),
+		NATS:           getComponentStatus(pods, natsLabel),
+		CloudConnector: getComponentStatus(pods, cloudConnName),
+	}
+	if prev != nil {
+		s.LastCloudHeartbeatTime = prev.LastCloudHeartbeatTime
+	}
+	if isOk(cloudConnState) {
+		t := metav1.NewTime(now)
+		s.LastCloudHeartbeatTime = &t
+	}
+	return s
+}
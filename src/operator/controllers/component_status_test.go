@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/shared/status"
+)
+
+func TestGetComponentsStatus(t *testing.T) {
+	pods := &concurrentPodMap{unsafeMap: make(map[string]map[string]*podWrapper)}
+	addPod := func(nameLabel string, podName string, ready bool) {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: podName, Labels: map[string]string{"name": nameLabel}}}
+		if ready {
+			pod.Status.Conditions = healthyConditions
+		}
+		pods.write(nameLabel, podName, &podWrapper{pod: pod})
+	}
+	addPod(vizierPemLabel, "vizier-pem-1", true)
+	addPod(vizierPemLabel, "vizier-pem-2", false)
+	addPod(vizierPemLabel, "vizier-pem-3", true)
+	addPod(kelvinName, "kelvin-1", true)
+	addPod(vizierMetadataLabel, "vizier-metadata-0", false)
+	addPod(cloudConnName, "vizier-cloud-connector-1", true)
+
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := getComponentsStatus(pods, nil, okState(), now)
+	assert.Equal(t, v1alpha1.ComponentStatus{Ready: 2, Total: 3}, s.PEM)
+	assert.Equal(t, v1alpha1.ComponentStatus{Ready: 1, Total: 1}, s.Kelvin)
+	assert.Equal(t, v1alpha1.ComponentStatus{Ready: 0, Total: 1}, s.Metadata)
+	assert.Equal(t, v1alpha1.ComponentStatus{}, s.NATS)
+	assert.Equal(t, v1alpha1.ComponentStatus{Ready: 1, Total: 1}, s.CloudConnector)
+	require.NotNil(t, s.LastCloudHeartbeatTime)
+	assert.True(t, now.Equal(s.LastCloudHeartbeatTime.Time))
+
+	// The last heartbeat is kept while the cloud connector is disconnected.
+	s = getComponentsStatus(pods, s, &vizierState{Reason: status.CloudConnectorPodFailed}, now.Add(time.Minute))
+	require.NotNil(t, s.LastCloudHeartbeatTime)
+	assert.True(t, now.Equal(s.LastCloudHeartbeatTime.Time))
+
+	s = getComponentsStatus(pods, nil, &vizierState{Reason: status.CloudConnectorMissing}, now)
+	assert.Nil(t, s.LastCloudHeartbeatTime)
+}
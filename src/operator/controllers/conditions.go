@@ -0,0 +1,113 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/shared/status"
+)
+
+// Condition reasons must match this format, or the status update is rejected by the API server.
+var conditionReasonRe = regexp.MustCompile(`^[A-Za-z]([A-Za-z0-9_,:]*[A-Za-z0-9_])?$`)
+
+// setCondition sets the condition of the given type in the Vizier status. The transition time is only updated
+// if the status of the condition changes.
+func setCondition(vz *v1alpha1.Vizier, condType string, condStatus metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&vz.Status.Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             condStatus,
+		ObservedGeneration: vz.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// setReconciliationConditions sets the conditions which are maintained by the Reconciler, based on the
+// reconciliation phase.
+func setReconciliationConditions(vz *v1alpha1.Vizier, rp v1alpha1.ReconciliationPhase) {
+	switch rp {
+	case v1alpha1.ReconciliationPhaseUpdating:
+		setCondition(vz, v1alpha1.VizierConditionUpdateInProgress, metav1.ConditionTrue, "Updating",
+			"Deploying Vizier version "+vz.Spec.Version)
+	case v1alpha1.ReconciliationPhaseReady:
+		setCondition(vz, v1alpha1.VizierConditionUpdateInProgress, metav1.ConditionFalse, "UpdateComplete", "")
+		setCondition(vz, v1alpha1.VizierConditionResourcesApplied, metav1.ConditionTrue, "ResourcesApplied",
+			"Deployed Vizier version "+vz.Spec.Version)
+	case v1alpha1.ReconciliationPhaseFailed:
+		setCondition(vz, v1alpha1.VizierConditionUpdateInProgress, metav1.ConditionFalse, "UpdateFailed", "")
+		setCondition(vz, v1alpha1.VizierConditionResourcesApplied, metav1.ConditionFalse, "UpdateFailed",
+			"Timed out deploying Vizier version "+vz.Spec.Version)
+	}
+}
+
+// setSpecValidCondition sets the SpecValid condition from the errors returned by validateVizierSpec, and returns
+// whether the condition changed, so that the status only needs to be updated when it did.
+func setSpecValidCondition(vz *v1alpha1.Vizier, errs []error) bool {
+	condStatus, reason, message := metav1.ConditionTrue, "SpecValid", ""
+	if len(errs) > 0 {
+		condStatus, reason, message = metav1.ConditionFalse, "InvalidSpec", joinValidationErrors(errs).Error()
+	}
+	cond := meta.FindStatusCondition(vz.Status.Conditions, v1alpha1.VizierConditionSpecValid)
+	if cond != nil && cond.Status == condStatus && cond.Reason == reason && cond.Message == message &&
+		cond.ObservedGeneration == vz.Generation {
+		return false
+	}
+	setCondition(vz, v1alpha1.VizierConditionSpecValid, condStatus, reason, message)
+	return true
+}
+
+// setMetadataStorageCondition sets the MetadataStorageHealthy condition from the state of the metadata PVC or etcd
+// cluster. The condition is removed if the state is nil.
+func setMetadataStorageCondition(vz *v1alpha1.Vizier, state *vizierState) {
+	switch {
+	case state == nil:
+		meta.RemoveStatusCondition(&vz.Status.Conditions, v1alpha1.VizierConditionMetadataStorageHealthy)
+	case isOk(state):
+		setCondition(vz, v1alpha1.VizierConditionMetadataStorageHealthy, metav1.ConditionTrue, "Healthy", "")
+	default:
+		setCondition(vz, v1alpha1.VizierConditionMetadataStorageHealthy, metav1.ConditionFalse, string(state.Reason),
+			status.GetMessageFromReason(state.Reason))
+	}
+}
+
+// setHealthConditions sets the conditions which are maintained by the VizierMonitor, based on the state of
+// the Vizier's pods and cloud connector.
+func setHealthConditions(vz *v1alpha1.Vizier, podsState *vizierState, cloudConnState *vizierState) {
+	for condType, state := range map[string]*vizierState{
+		v1alpha1.VizierConditionPodsHealthy:    podsState,
+		v1alpha1.VizierConditionCloudConnected: cloudConnState,
+	} {
+		if isOk(state) {
+			setCondition(vz, condType, metav1.ConditionTrue, "Healthy", "")
+			continue
+		}
+		// Reasons reported by statusz endpoints are not guaranteed to be valid condition reasons, in which case the
+		// message defaults to the reason.
+		reason := string(state.Reason)
+		if !conditionReasonRe.MatchString(reason) {
+			reason = "Unhealthy"
+		}
+		setCondition(vz, condType, metav1.ConditionFalse, reason, getStateMessage(state))
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/shared/status"
+)
+
+func TestSetReconciliationConditions(t *testing.T) {
+	vz := &v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{Version: "0.10.0"}}
+
+	setReconciliationConditions(vz, v1alpha1.ReconciliationPhaseUpdating)
+	assert.True(t, meta.IsStatusConditionTrue(vz.Status.Conditions, v1alpha1.VizierConditionUpdateInProgress))
+	assert.Nil(t, meta.FindStatusCondition(vz.Status.Conditions, v1alpha1.VizierConditionResourcesApplied))
+
+	setReconciliationConditions(vz, v1alpha1.ReconciliationPhaseReady)
+	assert.True(t, meta.IsStatusConditionFalse(vz.Status.Conditions, v1alpha1.VizierConditionUpdateInProgress))
+	assert.True(t, meta.IsStatusConditionTrue(vz.Status.Conditions, v1alpha1.VizierConditionResourcesApplied))
+
+	setReconciliationConditions(vz, v1alpha1.ReconciliationPhaseFailed)
+	assert.True(t, meta.IsStatusConditionFalse(vz.Status.Conditions, v1alpha1.VizierConditionUpdateInProgress))
+	cond := meta.FindStatusCondition(vz.Status.Conditions, v1alpha1.VizierConditionResourcesApplied)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, "UpdateFailed", cond.Reason)
+}
+
+func TestSetHealthConditions(t *testing.T) {
+	vz := &v1alpha1.Vizier{}
+
+	setHealthConditions(vz, okState(), okState())
+	assert.True(t, meta.IsStatusConditionTrue(vz.Status.Conditions, v1alpha1.VizierConditionPodsHealthy))
+	assert.True(t, meta.IsStatusConditionTrue(vz.Status.Conditions, v1alpha1.VizierConditionCloudConnected))
+
+	setHealthConditions(vz, &vizierState{Reason: status.NATSPodFailed}, okState())
+	cond := meta.FindStatusCondition(vz.Status.Conditions, v1alpha1.VizierConditionPodsHealthy)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, string(status.NATSPodFailed), cond.Reason)
+	assert.Equal(t, status.GetMessageFromReason(status.NATSPodFailed), cond.Message)
+	assert.True(t, meta.IsStatusConditionTrue(vz.Status.Conditions, v1alpha1.VizierConditionCloudConnected))
+}
+
+func TestSetHealthConditions_InvalidReason(t *testing.T) {
+	vz := &v1alpha1.Vizier{}
+
+	setHealthConditions(vz, okState(), &vizierState{Reason: "failed to reach cloud"})
+	cond := meta.FindStatusCondition(vz.Status.Conditions, v1alpha1.VizierConditionCloudConnected)
+	require.NotNil(t, cond)
+	assert.Equal(t, "Unhealthy", cond.Reason)
+	assert.Equal(t, "failed to reach cloud", cond.Message)
+}
+
+func TestSetMetadataStorageCondition(t *testing.T) {
+	vz := &v1alpha1.Vizier{}
+
+	setMetadataStorageCondition(vz, okState())
+	assert.True(t, meta.IsStatusConditionTrue(vz.Status.Conditions, v1alpha1.VizierConditionMetadataStorageHealthy))
+
+	setMetadataStorageCondition(vz, &vizierState{Reason: status.MetadataPVCNearlyFull})
+	cond := meta.FindStatusCondition(vz.Status.Conditions, v1alpha1.VizierConditionMetadataStorageHealthy)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, string(status.MetadataPVCNearlyFull), cond.Reason)
+
+	// The condition is removed when the state of the metadata storage is unknown.
+	setMetadataStorageCondition(vz, nil)
+	assert.Nil(t, meta.FindStatusCondition(vz.Status.Conditions, v1alpha1.VizierConditionMetadataStorageHealthy))
+}
+
+func TestSetSpecValidCondition(t *testing.T) {
+	vz := &v1alpha1.Vizier{}
+
+	assert.True(t, setSpecValidCondition(vz, nil))
+	assert.True(t, meta.IsStatusConditionTrue(vz.Status.Conditions, v1alpha1.VizierConditionSpecValid))
+	assert.False(t, setSpecValidCondition(vz, nil))
+
+	errs := validateVizierSpec(&v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{Version: "latest"}})
+	assert.True(t, setSpecValidCondition(vz, errs))
+	cond := meta.FindStatusCondition(vz.Status.Conditions, v1alpha1.VizierConditionSpecValid)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, "InvalidSpec", cond.Reason)
+	assert.Contains(t, cond.Message, "spec.version")
+	assert.False(t, setSpecValidCondition(vz, errs))
+
+	vz.Generation++
+	assert.True(t, setSpecValidCondition(vz, errs))
+}
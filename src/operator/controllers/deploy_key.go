@@ -0,0 +1,190 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2/google"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const (
+	// The key in a Vault secret which contains the deploy key, if no key is specified.
+	defaultVaultDeployKeyKey = "deploy-key"
+	// The path where Vault's Kubernetes auth method is mounted, if no path is specified.
+	defaultVaultAuthMountPath = "kubernetes"
+	// The operator's service account token, which it uses to log in to Vault.
+	serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	// The address of the GCP Secret Manager API.
+	gcpSecretManagerAddr = "https://secretmanager.googleapis.com"
+	// The OAuth scope required to access GCP Secret Manager.
+	gcpCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+	// How long reading the deploy key from a secret manager may take, including any logins.
+	deployKeyTimeout = 30 * time.Second
+)
+
+// resolveDeployKey returns the Vizier's deploy key. Deploy keys stored in an external secret manager are read on
+// each deploy, and are never written back to the Vizier's spec.
+func resolveDeployKey(ctx context.Context, vz *v1alpha1.Vizier) (string, error) {
+	src := vz.Spec.DeployKeySource
+	if src == nil {
+		return vz.Spec.DeployKey, nil
+	}
+
+	// The reconcile's context has no deadline, so a hung secret manager would otherwise block the reconcile forever.
+	ctx, cancel := context.WithTimeout(ctx, deployKeyTimeout)
+	defer cancel()
+
+	switch {
+	case src.Vault != nil:
+		token, err := os.ReadFile(serviceAccountTokenPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read service account token: %w", err)
+		}
+		return readVaultDeployKey(ctx, http.DefaultClient, src.Vault, strings.TrimSpace(string(token)))
+	case src.AWSSecretsManager != nil:
+		creds, err := getAWSCredentials(ctx, http.DefaultClient, src.AWSSecretsManager.Region)
+		if err != nil {
+			return "", fmt.Errorf("failed to get AWS credentials: %w", err)
+		}
+		addr := fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", src.AWSSecretsManager.Region)
+		return readAWSSecretsManagerDeployKey(ctx, http.DefaultClient, addr, creds, src.AWSSecretsManager)
+	case src.GCPSecretManager != nil:
+		client, err := google.DefaultClient(ctx, gcpCloudPlatformScope)
+		if err != nil {
+			return "", fmt.Errorf("failed to get GCP credentials: %w", err)
+		}
+		return readGCPSecretManagerDeployKey(ctx, client, gcpSecretManagerAddr, src.GCPSecretManager)
+	}
+	return "", errors.New("deployKeySource does not specify a secret manager")
+}
+
+// doJSONRequest sends the request, and decodes the JSON response into out.
+func doJSONRequest(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %s failed with status %d: %s", req.URL.Host, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
+
+// readVaultDeployKey logs in to Vault with the given service account token, and reads the deploy key from the
+// secret. Both KV version 1 and version 2 secrets are supported.
+func readVaultDeployKey(ctx context.Context, client *http.Client, src *v1alpha1.VaultDeployKeySource, saToken string) (string, error) {
+	addr := strings.TrimSuffix(src.Address, "/")
+	authMountPath := src.AuthMountPath
+	if authMountPath == "" {
+		authMountPath = defaultVaultAuthMountPath
+	}
+	key := src.Key
+	if key == "" {
+		key = defaultVaultDeployKeyKey
+	}
+
+	loginBody, err := json.Marshal(map[string]string{"role": src.Role, "jwt": saToken})
+	if err != nil {
+		return "", err
+	}
+	loginURL := fmt.Sprintf("%s/v1/auth/%s/login", addr, strings.Trim(authMountPath, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, loginURL, bytes.NewReader(loginBody))
+	if err != nil {
+		return "", err
+	}
+	var login struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	err = doJSONRequest(client, req, &login)
+	if err != nil {
+		return "", fmt.Errorf("failed to log in to Vault: %w", err)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/v1/%s", addr, strings.Trim(src.Path, "/")), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", login.Auth.ClientToken)
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	err = doJSONRequest(client, req, &secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Vault secret: %w", err)
+	}
+
+	data := secret.Data
+	// KV version 2 secrets nest the secret's data within the response data.
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+	deployKey, ok := data[key].(string)
+	if !ok || deployKey == "" {
+		return "", fmt.Errorf("vault secret %s does not contain key %q", src.Path, key)
+	}
+	return deployKey, nil
+}
+
+// readGCPSecretManagerDeployKey reads the deploy key from the GCP Secret Manager secret version. The client must
+// already be authenticated.
+func readGCPSecretManagerDeployKey(ctx context.Context, client *http.Client, addr string, src *v1alpha1.GCPSecretManagerDeployKeySource) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/v1/%s:access", addr, src.Name), nil)
+	if err != nil {
+		return "", err
+	}
+	var version struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	err = doJSONRequest(client, req, &version)
+	if err != nil {
+		return "", fmt.Errorf("failed to access GCP secret version: %w", err)
+	}
+
+	deployKey, err := base64.StdEncoding.DecodeString(version.Payload.Data)
+	if err != nil {
+		return "", err
+	}
+	if len(deployKey) == 0 {
+		return "", fmt.Errorf("GCP secret version %s is empty", src.Name)
+	}
+	return strings.TrimSpace(string(deployKey)), nil
+}
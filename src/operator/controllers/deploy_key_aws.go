@@ -0,0 +1,222 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const (
+	// The format of the timestamps used to sign AWS requests.
+	awsTimeFormat = "20060102T150405Z"
+	// The session name of the operator, when assuming a role with a web identity token.
+	awsRoleSessionName = "pixie-operator"
+)
+
+// awsCredentials are the credentials used to sign requests to AWS.
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// getAWSCredentials returns the AWS credentials in the operator's environment. Static credentials are preferred. If
+// there are none, the role specified by the web identity token, such as the one provided by IAM roles for service
+// accounts, is assumed.
+func getAWSCredentials(ctx context.Context, client *http.Client, region string) (*awsCredentials, error) {
+	if os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+		return &awsCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	roleARN := os.Getenv("AWS_ROLE_ARN")
+	tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	if roleARN == "" || tokenFile == "" {
+		return nil, errors.New("no AWS credentials or web identity token were found in the environment")
+	}
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, err
+	}
+	return assumeAWSRoleWithWebIdentity(ctx, client, fmt.Sprintf("https://sts.%s.amazonaws.com", region), roleARN, strings.TrimSpace(string(token)))
+}
+
+// assumeAWSRoleWithWebIdentity exchanges the web identity token for temporary credentials for the role. This request
+// is authenticated by the token, and is not signed.
+func assumeAWSRoleWithWebIdentity(ctx context.Context, client *http.Client, stsAddr string, roleARN string, token string) (*awsCredentials, error) {
+	params := url.Values{}
+	params.Set("Action", "AssumeRoleWithWebIdentity")
+	params.Set("Version", "2011-06-15")
+	params.Set("RoleArn", roleARN)
+	params.Set("RoleSessionName", awsRoleSessionName)
+	params.Set("WebIdentityToken", token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, stsAddr, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to assume role %s with status %d: %s", roleARN, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Credentials struct {
+			AccessKeyID     string `xml:"AccessKeyId"`
+			SecretAccessKey string `xml:"SecretAccessKey"`
+			SessionToken    string `xml:"SessionToken"`
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	err = xml.Unmarshal(body, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &awsCredentials{
+		AccessKeyID:     result.Credentials.AccessKeyID,
+		SecretAccessKey: result.Credentials.SecretAccessKey,
+		SessionToken:    result.Credentials.SessionToken,
+	}, nil
+}
+
+// readAWSSecretsManagerDeployKey reads the deploy key from the AWS Secrets Manager secret.
+func readAWSSecretsManagerDeployKey(ctx context.Context, client *http.Client, addr string, creds *awsCredentials, src *v1alpha1.AWSSecretsManagerDeployKeySource) (string, error) {
+	body, err := json.Marshal(map[string]string{"SecretId": src.SecretID})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, addr+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	signAWSRequest(req, body, creds, src.Region, "secretsmanager", time.Now())
+
+	var secret struct {
+		SecretString string `json:"SecretString"`
+	}
+	err = doJSONRequest(client, req, &secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to read AWS secret: %w", err)
+	}
+	if src.Key == "" {
+		if secret.SecretString == "" {
+			return "", fmt.Errorf("AWS secret %s is empty", src.SecretID)
+		}
+		return strings.TrimSpace(secret.SecretString), nil
+	}
+
+	var values map[string]interface{}
+	err = json.Unmarshal([]byte(secret.SecretString), &values)
+	if err != nil {
+		return "", fmt.Errorf("AWS secret %s is not a JSON object: %w", src.SecretID, err)
+	}
+	deployKey, ok := values[src.Key].(string)
+	if !ok || deployKey == "" {
+		return "", fmt.Errorf("AWS secret %s does not contain key %q", src.SecretID, src.Key)
+	}
+	return deployKey, nil
+}
+
+// signAWSRequest signs the request with AWS Signature Version 4. All of the request's headers are signed.
+func signAWSRequest(req *http.Request, body []byte, creds *awsCredentials, region string, service string, now time.Time) {
+	amzDate := now.UTC().Format(awsTimeFormat)
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	bodyHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestSignAWSRequest(t *testing.T) {
+	// The "get-vanilla" case from the AWS Signature Version 4 test suite.
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	require.NoError(t, err)
+	creds := &awsCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	signAWSRequest(req, nil, creds, "us-east-1", "service", now)
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+func TestReadAWSSecretsManagerDeployKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		assert.Equal(t, "session-token", r.Header.Get("X-Amz-Security-Token"))
+		assert.Contains(t, r.Header.Get("Authorization"), "Credential=access-key/")
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		switch body["SecretId"] {
+		case "pixie-deploy-key":
+			_, _ = w.Write([]byte(`{"SecretString": "aws-key"}`))
+		case "pixie":
+			_, _ = w.Write([]byte(`{"SecretString": "{\"deploy-key\": \"aws-json-key\"}"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	creds := &awsCredentials{AccessKeyID: "access-key", SecretAccessKey: "secret-key", SessionToken: "session-token"}
+	src := &v1alpha1.AWSSecretsManagerDeployKeySource{Region: "us-west-2", SecretID: "pixie-deploy-key"}
+	deployKey, err := readAWSSecretsManagerDeployKey(context.Background(), server.Client(), server.URL, creds, src)
+	require.NoError(t, err)
+	assert.Equal(t, "aws-key", deployKey)
+
+	src = &v1alpha1.AWSSecretsManagerDeployKeySource{Region: "us-west-2", SecretID: "pixie", Key: "deploy-key"}
+	deployKey, err = readAWSSecretsManagerDeployKey(context.Background(), server.Client(), server.URL, creds, src)
+	require.NoError(t, err)
+	assert.Equal(t, "aws-json-key", deployKey)
+
+	src = &v1alpha1.AWSSecretsManagerDeployKeySource{Region: "us-west-2", SecretID: "missing"}
+	_, err = readAWSSecretsManagerDeployKey(context.Background(), server.Client(), server.URL, creds, src)
+	assert.Error(t, err)
+}
+
+func TestAssumeAWSRoleWithWebIdentity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "AssumeRoleWithWebIdentity", r.Form.Get("Action"))
+		assert.Equal(t, "arn:aws:iam::123456789012:role/pixie", r.Form.Get("RoleArn"))
+		assert.Equal(t, "web-identity-token", r.Form.Get("WebIdentityToken"))
+		_, _ = w.Write([]byte(`<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>access-key</AccessKeyId>
+      <SecretAccessKey>secret-key</SecretAccessKey>
+      <SessionToken>session-token</SessionToken>
+      <Expiration>2026-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`))
+	}))
+	defer server.Close()
+
+	creds, err := assumeAWSRoleWithWebIdentity(context.Background(), server.Client(), server.URL, "arn:aws:iam::123456789012:role/pixie", "web-identity-token")
+	require.NoError(t, err)
+	assert.Equal(t, &awsCredentials{AccessKeyID: "access-key", SecretAccessKey: "secret-key", SessionToken: "session-token"}, creds)
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestResolveDeployKey_Inline(t *testing.T) {
+	vz := &v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{DeployKey: "inline-key"}}
+	deployKey, err := resolveDeployKey(context.Background(), vz)
+	require.NoError(t, err)
+	assert.Equal(t, "inline-key", deployKey)
+
+	vz.Spec.DeployKeySource = &v1alpha1.DeployKeySource{}
+	_, err = resolveDeployKey(context.Background(), vz)
+	assert.Error(t, err)
+}
+
+func TestReadVaultDeployKey(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/auth/k8s-auth/login", func(w http.ResponseWriter, r *http.Request) {
+		var login map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&login))
+		assert.Equal(t, "pixie-operator", login["role"])
+		assert.Equal(t, "sa-token", login["jwt"])
+		_, _ = w.Write([]byte(`{"auth": {"client_token": "vault-token"}}`))
+	})
+	mux.HandleFunc("/v1/secret/data/pixie", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"data": {"deploy-key": "vault-key"}, "metadata": {"version": 2}}}`))
+	})
+	mux.HandleFunc("/v1/kv/pixie", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"key": "vault-kv1-key"}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	src := &v1alpha1.VaultDeployKeySource{
+		Address:       server.URL,
+		Path:          "secret/data/pixie",
+		Role:          "pixie-operator",
+		AuthMountPath: "k8s-auth",
+	}
+	deployKey, err := readVaultDeployKey(context.Background(), server.Client(), src, "sa-token")
+	require.NoError(t, err)
+	assert.Equal(t, "vault-key", deployKey)
+
+	src.Path = "kv/pixie"
+	src.Key = "key"
+	deployKey, err = readVaultDeployKey(context.Background(), server.Client(), src, "sa-token")
+	require.NoError(t, err)
+	assert.Equal(t, "vault-kv1-key", deployKey)
+
+	src.Key = "missing"
+	_, err = readVaultDeployKey(context.Background(), server.Client(), src, "sa-token")
+	assert.Error(t, err)
+}
+
+func TestReadGCPSecretManagerDeployKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/projects/my-project/secrets/pixie-deploy-key/versions/latest:access", r.URL.Path)
+		// "gcp-key\n" base64-encoded.
+		_, _ = w.Write([]byte(`{"name": "projects/123/secrets/pixie-deploy-key/versions/1", "payload": {"data": "Z2NwLWtleQo="}}`))
+	}))
+	defer server.Close()
+
+	src := &v1alpha1.GCPSecretManagerDeployKeySource{Name: "projects/my-project/secrets/pixie-deploy-key/versions/latest"}
+	deployKey, err := readGCPSecretManagerDeployKey(context.Background(), server.Client(), server.URL, src)
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-key", deployKey)
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"time"
+
+	"github.com/cenkalti/backoff/v3"
+)
+
+// The defaults for the backoff with which Vizier resources are applied.
+const (
+	defaultDeployRetryInitialInterval     = 15 * time.Second
+	defaultDeployRetryMaxInterval         = 1 * time.Minute
+	defaultDeployRetryMaxElapsedTime      = 5 * time.Minute
+	defaultDeployRetryRandomizationFactor = 0.5
+)
+
+// DeployRetryConfig is the backoff with which the operator retries applying Vizier resources, such as when
+// admission webhooks are slow to respond. Zero values are replaced with the defaults.
+type DeployRetryConfig struct {
+	// InitialInterval is how long to wait before the first retry.
+	InitialInterval time.Duration
+	// MaxInterval is the longest to wait between retries.
+	MaxInterval time.Duration
+	// MaxElapsedTime is how long to retry for before giving up.
+	MaxElapsedTime time.Duration
+	// RandomizationFactor is the jitter applied to each interval, as a fraction of the interval. For example, with
+	// a factor of 0.5, an interval of 10s is randomized to between 5s and 15s.
+	RandomizationFactor float64
+}
+
+// newBackOff returns the exponential backoff described by the config.
+func (c DeployRetryConfig) newBackOff() *backoff.ExponentialBackOff {
+	bOpts := backoff.NewExponentialBackOff()
+	bOpts.InitialInterval = defaultDeployRetryInitialInterval
+	if c.InitialInterval > 0 {
+		bOpts.InitialInterval = c.InitialInterval
+	}
+	bOpts.MaxInterval = defaultDeployRetryMaxInterval
+	if c.MaxInterval > 0 {
+		bOpts.MaxInterval = c.MaxInterval
+	}
+	bOpts.MaxElapsedTime = defaultDeployRetryMaxElapsedTime
+	if c.MaxElapsedTime > 0 {
+		bOpts.MaxElapsedTime = c.MaxElapsedTime
+	}
+	bOpts.RandomizationFactor = defaultDeployRetryRandomizationFactor
+	if c.RandomizationFactor > 0 {
+		bOpts.RandomizationFactor = c.RandomizationFactor
+	}
+	// The max interval must be at least the initial interval, or the first retry would be capped.
+	if bOpts.MaxInterval < bOpts.InitialInterval {
+		bOpts.MaxInterval = bOpts.InitialInterval
+	}
+	bOpts.Reset()
+	return bOpts
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeployRetryConfig_NewBackOff(t *testing.T) {
+	bOpts := DeployRetryConfig{}.newBackOff()
+	assert.Equal(t, defaultDeployRetryInitialInterval, bOpts.InitialInterval)
+	assert.Equal(t, defaultDeployRetryMaxInterval, bOpts.MaxInterval)
+	assert.Equal(t, defaultDeployRetryMaxElapsedTime, bOpts.MaxElapsedTime)
+	assert.Equal(t, defaultDeployRetryRandomizationFactor, bOpts.RandomizationFactor)
+
+	bOpts = DeployRetryConfig{
+		InitialInterval:     2 * time.Minute,
+		MaxElapsedTime:      30 * time.Minute,
+		RandomizationFactor: 0.2,
+	}.newBackOff()
+	assert.Equal(t, 2*time.Minute, bOpts.InitialInterval)
+	// The max interval is raised to the initial interval.
+	assert.Equal(t, 2*time.Minute, bOpts.MaxInterval)
+	assert.Equal(t, 30*time.Minute, bOpts.MaxElapsedTime)
+	assert.Equal(t, 0.2, bOpts.RandomizationFactor)
+
+	next := bOpts.NextBackOff()
+	assert.GreaterOrEqual(t, next, 96*time.Second)
+	assert.LessOrEqual(t, next, 144*time.Second)
+}
@@ -0,0 +1,325 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// How often the deployed Vizier resources are checked for drift.
+const driftCheckPeriod = 5 * time.Minute
+
+// driftCheckedFields are the top-level fields of a resource which are compared against the live resource.
+var driftCheckedFields = []string{"spec", "data"}
+
+// driftIgnoredFields are fields which the operator changes after applying a resource, and should not be
+// considered drift. The PEM update strategy is switched once a staged PEM rollout completes.
+var driftIgnoredFields = [][]string{{"spec", "updateStrategy"}}
+
+// appliedResources is the set of resources which were last applied for a Vizier.
+type appliedResources struct {
+	checksum  []byte
+	resources []*k8s.Resource
+}
+
+// appliedResourceTracker tracks the resources which were last applied for each Vizier, so that the live
+// resources can be compared against them.
+type appliedResourceTracker struct {
+	mu      sync.Mutex
+	applied map[types.NamespacedName]*appliedResources
+}
+
+func (t *appliedResourceTracker) set(vz types.NamespacedName, checksum []byte, resources []*k8s.Resource) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.applied == nil {
+		t.applied = make(map[types.NamespacedName]*appliedResources)
+	}
+	t.applied[vz] = &appliedResources{checksum: checksum, resources: resources}
+}
+
+func (t *appliedResourceTracker) get(vz types.NamespacedName) *appliedResources {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.applied[vz]
+}
+
+func (t *appliedResourceTracker) delete(vz types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.applied, vz)
+}
+
+// watchForDrift regularly compares the live Vizier resources against the resources which were last applied by the
+// operator, and re-applies any resources which were deleted or modified.
+func (r *VizierReconciler) watchForDrift(ctx context.Context) error {
+	t := time.NewTicker(driftCheckPeriod)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+
+		var viziersList v1alpha1.VizierList
+		err := r.List(ctx, &viziersList)
+		if err != nil {
+			log.WithError(err).Error("Unable to list the vizier objects")
+			continue
+		}
+		for i := range viziersList.Items {
+			err = r.repairVizierDrift(ctx, &viziersList.Items[i])
+			if err != nil {
+				log.WithError(err).WithField("vizier", viziersList.Items[i].Name).Error("Failed to repair drifted Vizier resources")
+			}
+		}
+	}
+}
+
+// repairVizierDrift re-applies the Vizier's resources which have drifted from the resources which were last applied.
+func (r *VizierReconciler) repairVizierDrift(ctx context.Context, vz *v1alpha1.Vizier) error {
+	// Viziers which are being updated or deleted are reconciled elsewhere.
+	if vz.Status.ReconciliationPhase != v1alpha1.ReconciliationPhaseReady || !vz.ObjectMeta.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	if isReconcilePaused(vz) {
+		return nil
+	}
+	applied := r.appliedResources.get(types.NamespacedName{Namespace: vz.Namespace, Name: vz.Name})
+	// Only the resources applied for the current spec are compared. Resources applied before the operator
+	// restarted are not tracked until the Vizier is next deployed.
+	if applied == nil || !bytes.Equal(applied.checksum, vz.Status.Checksum) {
+		return nil
+	}
+	return r.repairDrift(ctx, vz.Namespace, applied.resources)
+}
+
+// repairDrift re-applies the given resources which have drifted from their live state.
+func (r *VizierReconciler) repairDrift(ctx context.Context, namespace string, resources []*k8s.Resource) error {
+	apiGroupResources, err := restmapper.GetAPIGroupResources(r.Clientset.Discovery())
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := dynamic.NewForConfig(r.RestConfig)
+	if err != nil {
+		return err
+	}
+
+	drifted, err := findDriftedResources(ctx, dynamicClient, restmapper.NewDiscoveryRESTMapper(apiGroupResources), namespace, resources)
+	if err != nil {
+		return err
+	}
+	if len(drifted) == 0 {
+		return nil
+	}
+
+	for _, res := range drifted {
+		log.WithField("kind", res.GVK.Kind).WithField("name", res.Object.GetName()).Warn("Re-applying drifted Vizier resource")
+		driftRepairCount.WithLabelValues(res.GVK.Kind).Inc()
+	}
+	return k8s.ApplyResources(r.Clientset, r.RestConfig, drifted, namespace, nil, true)
+}
+
+// findDriftedResources returns the resources which are missing from the cluster, or whose live state no longer
+// matches the applied state.
+func findDriftedResources(ctx context.Context, dynamicClient dynamic.Interface, rm meta.RESTMapper, namespace string, resources []*k8s.Resource) ([]*k8s.Resource, error) {
+	var drifted []*k8s.Resource
+	for _, res := range resources {
+		mapping, err := rm.RESTMapping(res.GVK.GroupKind(), res.GVK.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		var resClient dynamic.ResourceInterface = dynamicClient.Resource(mapping.Resource)
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			resClient = dynamicClient.Resource(mapping.Resource).Namespace(namespace)
+		}
+
+		live, err := resClient.Get(ctx, res.Object.GetName(), metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			drifted = append(drifted, res)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if hasDrifted(res.Object.Object, live.Object) {
+			drifted = append(drifted, res)
+		}
+	}
+	return drifted, nil
+}
+
+// hasDrifted returns whether any of the checked fields which were applied differ from the live resource.
+func hasDrifted(applied map[string]interface{}, live map[string]interface{}) bool {
+	applied = withoutIgnoredFields(applied)
+	live = withoutIgnoredFields(live)
+	for _, field := range driftCheckedFields {
+		appliedVal, ok := applied[field]
+		if !ok {
+			continue
+		}
+		if !isAppliedSubset(appliedVal, live[field]) {
+			return true
+		}
+	}
+	return false
+}
+
+func withoutIgnoredFields(res map[string]interface{}) map[string]interface{} {
+	res = deepCopyMap(res)
+	for _, path := range driftIgnoredFields {
+		m := res
+		for i, key := range path {
+			if i == len(path)-1 {
+				delete(m, key)
+				break
+			}
+			next, ok := m[key].(map[string]interface{})
+			if !ok {
+				break
+			}
+			m = next
+		}
+	}
+	return res
+}
+
+func deepCopyMap(m map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			copied[k] = deepCopyMap(nested)
+		} else {
+			copied[k] = v
+		}
+	}
+	return copied
+}
+
+// isAppliedSubset returns whether every value set in the applied object is unchanged in the live object. Fields
+// which are only present in the live object, such as those defaulted by the API server or added by admission
+// webhooks, are ignored.
+func isAppliedSubset(applied interface{}, live interface{}) bool {
+	switch a := applied.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return live == nil && len(a) == 0
+		}
+		for k, v := range a {
+			lv, ok := l[k]
+			if !ok {
+				// The API server omits empty values.
+				if !isZeroValue(v) {
+					return false
+				}
+				continue
+			}
+			if !isAppliedSubset(v, lv) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok {
+			return live == nil && len(a) == 0
+		}
+		// Admission webhooks may append elements, such as injected sidecar containers.
+		if len(l) < len(a) {
+			return false
+		}
+		for i := range a {
+			if !isAppliedSubset(a[i], l[i]) {
+				return false
+			}
+		}
+		return true
+	case string:
+		l, ok := live.(string)
+		if !ok {
+			return false
+		}
+		if a == l {
+			return true
+		}
+		// The API server normalizes quantities, such as "1000m" to "1".
+		aq, err := resource.ParseQuantity(a)
+		if err != nil {
+			return false
+		}
+		lq, err := resource.ParseQuantity(l)
+		if err != nil {
+			return false
+		}
+		return aq.Cmp(lq) == 0
+	}
+
+	if af, ok := toFloat(applied); ok {
+		lf, ok := toFloat(live)
+		return ok && af == lf
+	}
+	return reflect.DeepEqual(applied, live)
+}
+
+func isZeroValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return len(val) == 0
+	case []interface{}:
+		return len(val) == 0
+	}
+	return reflect.ValueOf(v).IsZero()
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func newTestDeployment(name string, replicas int64, image string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "pl"},
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "app", "image": image},
+					},
+				},
+			},
+		},
+	}}
+}
+
+func TestIsAppliedSubset(t *testing.T) {
+	tests := []struct {
+		name     string
+		applied  interface{}
+		live     interface{}
+		expected bool
+	}{
+		{
+			name:     "defaulted fields",
+			applied:  map[string]interface{}{"image": "a"},
+			live:     map[string]interface{}{"image": "a", "imagePullPolicy": "IfNotPresent"},
+			expected: true,
+		},
+		{
+			name:     "changed field",
+			applied:  map[string]interface{}{"image": "a"},
+			live:     map[string]interface{}{"image": "b"},
+			expected: false,
+		},
+		{
+			name:     "removed field",
+			applied:  map[string]interface{}{"image": "a"},
+			live:     map[string]interface{}{},
+			expected: false,
+		},
+		{
+			name:     "omitted empty field",
+			applied:  map[string]interface{}{"args": []interface{}{}, "hostNetwork": false},
+			live:     map[string]interface{}{},
+			expected: true,
+		},
+		{
+			name:     "appended sidecar",
+			applied:  []interface{}{map[string]interface{}{"name": "app"}},
+			live:     []interface{}{map[string]interface{}{"name": "app"}, map[string]interface{}{"name": "proxy"}},
+			expected: true,
+		},
+		{
+			name:     "removed element",
+			applied:  []interface{}{"a", "b"},
+			live:     []interface{}{"a"},
+			expected: false,
+		},
+		{
+			name:     "normalized quantity",
+			applied:  "1000m",
+			live:     "1",
+			expected: true,
+		},
+		{
+			name:     "numeric types",
+			applied:  float64(2),
+			live:     int64(2),
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, isAppliedSubset(test.applied, test.live))
+		})
+	}
+}
+
+func TestHasDrifted_IgnoresUpdateStrategy(t *testing.T) {
+	applied := map[string]interface{}{
+		"spec": map[string]interface{}{"updateStrategy": map[string]interface{}{"type": "OnDelete"}},
+	}
+	live := map[string]interface{}{
+		"spec": map[string]interface{}{"updateStrategy": map[string]interface{}{"type": "RollingUpdate"}},
+	}
+	assert.False(t, hasDrifted(applied, live))
+	// The ignored fields must not be removed from the applied resource.
+	assert.Contains(t, applied["spec"], "updateStrategy")
+}
+
+func TestFindDriftedResources(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{gvk.GroupVersion()})
+	rm.Add(gvk, meta.RESTScopeNamespace)
+
+	scheme := runtime.NewScheme()
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme,
+		newTestDeployment("vizier-query-broker", 1, "query-broker:1.0"),
+		newTestDeployment("vizier-cloud-connector", 0, "cloud-connector:1.0"),
+	)
+
+	resources := []*k8s.Resource{
+		{GVK: &gvk, Object: newTestDeployment("vizier-query-broker", 1, "query-broker:1.0")},
+		{GVK: &gvk, Object: newTestDeployment("vizier-cloud-connector", 1, "cloud-connector:1.0")},
+		{GVK: &gvk, Object: newTestDeployment("vizier-metadata", 1, "metadata:1.0")},
+	}
+
+	drifted, err := findDriftedResources(context.Background(), dynamicClient, rm, "pl", resources)
+	require.NoError(t, err)
+	var names []string
+	for _, res := range drifted {
+		names = append(names, res.Object.GetName())
+	}
+	assert.Equal(t, []string{"vizier-cloud-connector", "vizier-metadata"}, names)
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/yaml"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+const (
+	// The name of the ConfigMap which the rendered resources are written to during a dry run.
+	dryRunConfigMapName = "vizier-dry-run"
+	// The key in the dry run ConfigMap which contains the rendered resources.
+	dryRunConfigMapKey = "vizier.yaml"
+	// The value which secret data is replaced with in the rendered resources.
+	redactedSecretValue = "REDACTED"
+)
+
+// dryRunVizier renders the resources which would be deployed for the Vizier, and writes them to the dry run
+// ConfigMap instead of applying them. The Vizier's spec is not modified, and its status only records the checksum
+// of the rendered spec, so that the Vizier is deployed as usual once the dry run is disabled.
+func (r *VizierReconciler) dryRunVizier(ctx context.Context, req ctrl.Request, vz *v1alpha1.Vizier) error {
+	checksum, err := getSpecChecksum(vz)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(checksum, vz.Status.Checksum) {
+		log.Info("Checksums matched, no need to render")
+		return nil
+	}
+	log.Info("Rendering Vizier resources for a dry run")
+
+	rendered := vz.DeepCopy()
+	r.setDeployDefaults(ctx, req, rendered)
+	err = r.applySpecDefaults(ctx, rendered)
+	if err != nil {
+		return err
+	}
+	if rendered.Spec.Version == "" && rendered.Spec.YAMLConfigMapName == "" {
+		cloudClient, err := r.cloudConns.get(r.CloudConn, vz.Spec.CloudAddr, vz.Spec.DevCloudNamespace)
+		if err != nil {
+			return err
+		}
+		rendered.Spec.Version, err = getLatestVizierVersion(ctx, cloudpb.NewArtifactTrackerClient(cloudClient))
+		if err != nil {
+			return err
+		}
+	}
+
+	yamlMap, _, err := r.getVizierYAMLs(ctx, req.Namespace, rendered)
+	if err != nil {
+		return err
+	}
+	resources, err := renderVizierResources(rendered, yamlMap)
+	if err != nil {
+		return err
+	}
+	out, err := resourcesToYAML(resources)
+	if err != nil {
+		return err
+	}
+	err = writeDryRunConfigMap(ctx, r.Clientset, req.Namespace, req.Name, out)
+	if err != nil {
+		return err
+	}
+
+	vz.Status.Checksum = checksum
+	vz.Status.Message = fmt.Sprintf("Dry run: the resources for version %s were written to ConfigMap %s", rendered.Spec.Version, dryRunConfigMapName)
+	return r.Status().Update(ctx, vz)
+}
+
+// renderVizierResources returns all of the resources which are deployed for a new Vizier, in the order in which
+// they are deployed. The Vizier certs are excluded, since they are generated at deploy time.
+func renderVizierResources(vz *v1alpha1.Vizier, yamlMap map[string]string) ([]*k8s.Resource, error) {
+	yamlNames := []string{"secrets"}
+	if vz.Spec.ExternalNATS == nil {
+		yamlNames = append(yamlNames, "nats")
+	}
+	if vz.Spec.UseEtcdOperator {
+		yamlNames = append(yamlNames, "etcd")
+	}
+	yamlNames = append(yamlNames, getVizierCoreYAMLName(vz))
+
+	var resources []*k8s.Resource
+	for _, name := range yamlNames {
+		res, err := getConfiguredResources(vz, yamlMap[name])
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, res...)
+	}
+
+	kelvinAutoscaler, err := getKelvinAutoscaler(vz)
+	if err != nil {
+		return nil, err
+	}
+	if kelvinAutoscaler != nil {
+		resources = append(resources, kelvinAutoscaler)
+	}
+	return resources, nil
+}
+
+// resourcesToYAML encodes the resources as a multi-document YAML. The values of secrets are redacted, so that they
+// are not exposed to anyone who can read the output.
+func resourcesToYAML(resources []*k8s.Resource) (string, error) {
+	var buf bytes.Buffer
+	for _, res := range resources {
+		obj := res.Object.DeepCopy()
+		if res.GVK.Kind == "Secret" {
+			for _, field := range []string{"data", "stringData"} {
+				data, ok := obj.Object[field].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				for k := range data {
+					data[k] = redactedSecretValue
+				}
+			}
+		}
+
+		b, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString("---\n")
+		buf.Write(b)
+	}
+	return buf.String(), nil
+}
+
+// writeDryRunConfigMap creates or replaces the dry run ConfigMap with the rendered resources.
+func writeDryRunConfigMap(ctx context.Context, clientset kubernetes.Interface, namespace string, vizierName string, rendered string) error {
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dryRunConfigMapName,
+			Namespace: namespace,
+			// The ConfigMap is labeled like the rest of the Vizier's resources, so that it is cleaned up with them.
+			Labels: map[string]string{operatorAnnotation: vizierName},
+		},
+		Data: map[string]string{dryRunConfigMapKey: rendered},
+	}
+
+	cmClient := clientset.CoreV1().ConfigMaps(namespace)
+	_, err := cmClient.Create(ctx, cm, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = cmClient.Update(ctx, cm, metav1.UpdateOptions{})
+	}
+	return err
+}
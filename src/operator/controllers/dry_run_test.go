@@ -0,0 +1,96 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const testSecretsYAML = `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: pl-deploy-secrets
+stringData:
+  deploy-key: abcd
+`
+
+const testCoreYAML = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kelvin
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: gcr.io/pixie-oss/pixie-prod/vizier-kelvin_image:0.10.0
+`
+
+func TestRenderVizierResources(t *testing.T) {
+	vz := &v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{
+		Registry: "registry.internal/mirror",
+		Pod: &v1alpha1.PodPolicy{
+			Labels:      map[string]string{operatorAnnotation: "vizier"},
+			Annotations: map[string]string{},
+		},
+		ExternalNATS: &v1alpha1.ExternalNATSParams{URL: "tls://nats:4222"},
+	}}
+	yamlMap := map[string]string{
+		"secrets":           testSecretsYAML,
+		"nats":              "",
+		"vizier_persistent": testCoreYAML,
+		"vizier_etcd":       "",
+	}
+
+	resources, err := renderVizierResources(vz, yamlMap)
+	require.NoError(t, err)
+	require.Len(t, resources, 2)
+
+	out, err := resourcesToYAML(resources)
+	require.NoError(t, err)
+	assert.Contains(t, out, "deploy-key: REDACTED")
+	assert.NotContains(t, out, "abcd")
+	assert.Contains(t, out, "image: registry.internal/mirror/pixie-oss/pixie-prod/vizier-kelvin_image:0.10.0")
+	assert.Contains(t, out, "vizier-name: vizier")
+	// The rendered resources must not be modified by the redaction.
+	assert.Equal(t, "abcd", resources[0].Object.Object["stringData"].(map[string]interface{})["deploy-key"])
+}
+
+func TestWriteDryRunConfigMap(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	ctx := context.Background()
+
+	require.NoError(t, writeDryRunConfigMap(ctx, clientset, "pl", "vizier", "first"))
+	require.NoError(t, writeDryRunConfigMap(ctx, clientset, "pl", "vizier", "second"))
+
+	cm, err := clientset.CoreV1().ConfigMaps("pl").Get(ctx, dryRunConfigMapName, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "second", cm.Data[dryRunConfigMapKey])
+	assert.Equal(t, "vizier", cm.Labels[operatorAnnotation])
+}
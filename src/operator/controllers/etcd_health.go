@@ -0,0 +1,142 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/shared/status"
+)
+
+const (
+	// The name of the etcd StatefulSet, which its pods also have as their "etcd_cluster" label.
+	etcdClusterName = "pl-etcd"
+	// The port on which etcd serves clients, including its health endpoint.
+	etcdClientPort = "2379"
+	// The secret holding the certs with which the metadata service connects to etcd.
+	etcdClientCertsName = "etcd-client-tls-certs"
+)
+
+// getEtcdPods returns the running etcd members.
+func getEtcdPods(pods *concurrentPodMap) []*v1.Pod {
+	pods.mapMu.Lock()
+	defer pods.mapMu.Unlock()
+
+	var etcdPods []*v1.Pod
+	for _, labelMap := range pods.unsafeMap {
+		for _, p := range labelMap {
+			if p.pod.Labels["etcd_cluster"] != etcdClusterName {
+				continue
+			}
+			if p.pod.Status.Phase != v1.PodRunning || p.pod.Status.PodIP == "" {
+				continue
+			}
+			etcdPods = append(etcdPods, p.pod)
+		}
+	}
+	return etcdPods
+}
+
+// getEtcdState queries the health endpoint of each etcd member. The etcd cluster is degraded if any of its expected
+// members are unhealthy, and loses quorum once half of them are.
+func getEtcdState(client HTTPClient, pods []*v1.Pod, members int) *vizierState {
+	if members == 0 {
+		members = len(pods)
+	}
+
+	healthy := 0
+	for _, pod := range pods {
+		resp, err := client.Get(fmt.Sprintf("https://%s/health", net.JoinHostPort(pod.Status.PodIP, etcdClientPort)))
+		if err != nil {
+			log.WithError(err).WithField("pod", pod.Name).Info("Failed to check etcd member health")
+			continue
+		}
+		resp.Body.Close()
+		// etcd returns a 503 if the member is unhealthy, for example when it has no leader.
+		if resp.StatusCode == http.StatusOK {
+			healthy++
+		}
+	}
+
+	if healthy <= members/2 {
+		return &vizierState{Reason: status.EtcdQuorumLost}
+	}
+	if healthy < members {
+		return &vizierState{Reason: status.EtcdMembersUnhealthy}
+	}
+	return okState()
+}
+
+// newEtcdHTTPClient returns a client which authenticates to etcd with the same certs as the metadata service.
+func newEtcdHTTPClient(ctx context.Context, clientset kubernetes.Interface, namespace string) (*http.Client, error) {
+	s, err := clientset.CoreV1().Secrets(namespace).Get(ctx, etcdClientCertsName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get etcd client certs secret: %w", err)
+	}
+	cert, err := tls.X509KeyPair(s.Data["etcd-client.crt"], s.Data["etcd-client.key"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid etcd client cert: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(s.Data["etcd-client-ca.crt"]) {
+		return nil, fmt.Errorf("no CA certs found in etcd client certs secret")
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		// The members are queried by pod IP, which is not in their certs.
+		ServerName: etcdClusterName,
+	}
+	return &http.Client{Transport: tr, Timeout: statuszCheckInterval / 2}, nil
+}
+
+// getEtcdClusterState checks the health of the etcd cluster which stores the metadata, against the number of
+// members in its StatefulSet.
+func (m *VizierMonitor) getEtcdClusterState() *vizierState {
+	members := 0
+	ss, err := m.clientset.AppsV1().StatefulSets(m.namespace).Get(m.ctx, etcdClusterName, metav1.GetOptions{})
+	if err != nil {
+		log.WithError(err).Info("Failed to get etcd StatefulSet")
+	} else if ss.Spec.Replicas != nil {
+		members = int(*ss.Spec.Replicas)
+	}
+
+	client := m.etcdHTTPClient
+	if client == nil {
+		c, err := newEtcdHTTPClient(m.ctx, m.clientset, m.namespace)
+		if err != nil {
+			// The certs are deployed along with etcd, so etcd is not checked until they exist.
+			log.WithError(err).Info("Failed to create etcd client")
+			return okState()
+		}
+		client = c
+	}
+	return getEtcdState(client, getEtcdPods(m.podStates), members)
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/shared/status"
+)
+
+func newTestEtcdPodMap(ips ...string) *concurrentPodMap {
+	pods := &concurrentPodMap{unsafeMap: make(map[string]map[string]*podWrapper)}
+	for i, ip := range ips {
+		name := fmt.Sprintf("pl-etcd-%d", i)
+		pods.write("", name, &podWrapper{pod: &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"etcd_cluster": etcdClusterName}},
+			Status:     v1.PodStatus{Phase: v1.PodRunning, PodIP: ip},
+		}})
+	}
+	// Pods of other components are not etcd members.
+	pods.write(vizierMetadataLabel, "vizier-metadata-0", &podWrapper{pod: &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vizier-metadata-0", Labels: map[string]string{"name": vizierMetadataLabel}},
+		Status:     v1.PodStatus{Phase: v1.PodRunning, PodIP: "127.0.0.10"},
+	}})
+	return pods
+}
+
+func TestGetEtcdPods(t *testing.T) {
+	// Members without a pod IP are not running yet.
+	assert.Len(t, getEtcdPods(newTestEtcdPodMap("127.0.0.1", "127.0.0.2", "")), 2)
+}
+
+func TestGetEtcdState(t *testing.T) {
+	httpClient := &FakeHTTPClient{
+		responses: map[string]string{
+			"https://127.0.0.1:2379/health": "",
+			"https://127.0.0.2:2379/health": "",
+			"https://127.0.0.3:2379/health": "",
+			"https://127.0.0.4:2379/health": `{"health":"false","reason":"RAFT NO LEADER"}`,
+			"https://[fd00::1]:2379/health": "",
+		},
+	}
+
+	tests := []struct {
+		name           string
+		ips            []string
+		members        int
+		expectedReason status.VizierReason
+	}{
+		{
+			name:           "healthy",
+			ips:            []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"},
+			members:        3,
+			expectedReason: "",
+		},
+		{
+			name:           "ipv6",
+			ips:            []string{"fd00::1"},
+			members:        1,
+			expectedReason: "",
+		},
+		{
+			name:           "unhealthy member",
+			ips:            []string{"127.0.0.1", "127.0.0.2", "127.0.0.4"},
+			members:        3,
+			expectedReason: status.EtcdMembersUnhealthy,
+		},
+		{
+			name:           "missing member",
+			ips:            []string{"127.0.0.1", "127.0.0.2"},
+			members:        3,
+			expectedReason: status.EtcdMembersUnhealthy,
+		},
+		{
+			name:           "quorum lost",
+			ips:            []string{"127.0.0.1", "127.0.0.4", "127.0.0.5"},
+			members:        3,
+			expectedReason: status.EtcdQuorumLost,
+		},
+		{
+			name:           "no members",
+			members:        3,
+			expectedReason: status.EtcdQuorumLost,
+		},
+		{
+			name:           "unknown member count",
+			ips:            []string{"127.0.0.1", "127.0.0.2", "127.0.0.4"},
+			expectedReason: status.EtcdMembersUnhealthy,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pods := getEtcdPods(newTestEtcdPodMap(test.ips...))
+			assert.Equal(t, test.expectedReason, getEtcdState(httpClient, pods, test.members).Reason)
+		})
+	}
+}
+
+func TestVizierMonitor_getEtcdClusterState(t *testing.T) {
+	replicas := int32(3)
+	clientset := fake.NewSimpleClientset(&appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: etcdClusterName, Namespace: "pl"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+	})
+	m := &VizierMonitor{
+		ctx:       context.Background(),
+		clientset: clientset,
+		namespace: "pl",
+		podStates: newTestEtcdPodMap("127.0.0.1", "127.0.0.2"),
+		etcdHTTPClient: &FakeHTTPClient{
+			responses: map[string]string{
+				"https://127.0.0.1:2379/health": "",
+				"https://127.0.0.2:2379/health": "",
+			},
+		},
+	}
+	assert.Equal(t, status.EtcdMembersUnhealthy, m.getEtcdClusterState().Reason)
+
+	// The etcd cluster is not checked until its client certs exist.
+	m.etcdHTTPClient = nil
+	assert.True(t, isOk(m.getEtcdClusterState()))
+}
@@ -0,0 +1,356 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const (
+	// The registry which serves Docker Hub images, which are referenced without a registry host.
+	dockerHubRegistry = "registry-1.docker.io"
+	// How long each request to an image registry may take.
+	registryRequestTimeout = 30 * time.Second
+)
+
+// The manifest types which are accepted from registries. Multi-arch indexes are preferred, so that the digest of a
+// multi-arch image can be pulled on nodes of any architecture.
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// The parameters of a WWW-Authenticate challenge, such as realm="https://gcr.io/v2/token".
+var authChallengeParamRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// imageReference is an image reference which is split into the parts used by the registry API.
+type imageReference struct {
+	registry   string
+	repository string
+	tag        string
+}
+
+// parseImageReference splits the image into its registry, repository and tag, following the Docker conventions for
+// images without a registry host or tag.
+func parseImageReference(image string) *imageReference {
+	ref := &imageReference{registry: dockerHubRegistry, tag: "latest"}
+	name := image
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.tag = name[i+1:]
+		name = name[:i]
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.registry = normalizeRegistryHost(parts[0])
+		name = parts[1]
+	}
+	if ref.registry == dockerHubRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	ref.repository = name
+	return ref
+}
+
+// normalizeRegistryHost returns the host which serves the registry API for the given registry, which may be written
+// as a URL in Docker config files.
+func normalizeRegistryHost(registry string) string {
+	registry = strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://")
+	registry = strings.SplitN(registry, "/", 2)[0]
+	if registry == "docker.io" || registry == "index.docker.io" {
+		return dockerHubRegistry
+	}
+	return registry
+}
+
+// isDigestReference returns whether the image is already pinned to a digest.
+func isDigestReference(image string) bool {
+	return strings.Contains(image, "@")
+}
+
+// registryCredentials are the credentials used to authenticate with a registry.
+type registryCredentials struct {
+	username string
+	password string
+}
+
+// getRegistryCredentials reads the registry credentials from a Secret of type kubernetes.io/dockerconfigjson,
+// keyed by registry host.
+func getRegistryCredentials(ctx context.Context, clientset kubernetes.Interface, namespace string, name string) (map[string]registryCredentials, error) {
+	s, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}
+	err = json.Unmarshal(s.Data[v1.DockerConfigJsonKey], &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s in secret %s: %w", v1.DockerConfigJsonKey, name, err)
+	}
+
+	creds := make(map[string]registryCredentials)
+	for registry, auth := range config.Auths {
+		c := registryCredentials{username: auth.Username, password: auth.Password}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode the credentials for %s: %w", registry, err)
+			}
+			userPass := strings.SplitN(string(decoded), ":", 2)
+			if len(userPass) == 2 {
+				c = registryCredentials{username: userPass[0], password: userPass[1]}
+			}
+		}
+		creds[normalizeRegistryHost(registry)] = c
+	}
+	return creds, nil
+}
+
+// digestResolver resolves image tags to digests using the registry API.
+type digestResolver struct {
+	client *http.Client
+	// The credentials for registries which do not allow anonymous access, keyed by registry host.
+	credentials map[string]registryCredentials
+}
+
+// resolve returns the digest of the manifest which the image's tag currently points to.
+func (d *digestResolver) resolve(ctx context.Context, image string) (string, error) {
+	ref := parseImageReference(image)
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.registry, ref.repository, ref.tag)
+
+	resp, err := d.getManifest(ctx, http.MethodHead, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	auth := ""
+	if resp.StatusCode == http.StatusUnauthorized {
+		auth, err = d.authorize(ctx, ref, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", err
+		}
+		resp, err = d.getManifest(ctx, http.MethodHead, manifestURL, auth)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" && resp.StatusCode == http.StatusOK {
+		return digest, nil
+	}
+	return d.getManifestDigest(ctx, manifestURL, auth)
+}
+
+// getManifestDigest fetches the manifest, and computes its digest from its contents. This is only needed for
+// registries which do not return the digest in the response headers.
+func (d *digestResolver) getManifestDigest(ctx context.Context, manifestURL string, auth string) (string, error) {
+	resp, err := d.getManifest(ctx, http.MethodGet, manifestURL, auth)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("request to %s failed with status %d: %s", manifestURL, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(body)), nil
+}
+
+func (d *digestResolver) getManifest(ctx context.Context, method string, manifestURL string, auth string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	return d.client.Do(req)
+}
+
+// authorize returns the Authorization header which answers the registry's challenge. Registries which use bearer
+// tokens are asked for a token which allows pulling the image's repository, using the registry's credentials if
+// there are any.
+func (d *digestResolver) authorize(ctx context.Context, ref *imageReference, challenge string) (string, error) {
+	creds, hasCreds := d.credentials[ref.registry]
+	scheme := strings.SplitN(challenge, " ", 2)[0]
+
+	if strings.EqualFold(scheme, "basic") {
+		if !hasCreds {
+			return "", fmt.Errorf("registry %s requires credentials", ref.registry)
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(creds.username+":"+creds.password)), nil
+	}
+	if !strings.EqualFold(scheme, "bearer") {
+		return "", fmt.Errorf("registry %s requested unsupported authentication: %q", ref.registry, challenge)
+	}
+
+	params := make(map[string]string)
+	for _, m := range authChallengeParamRegex.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	if params["realm"] == "" {
+		return "", fmt.Errorf("registry %s did not specify a token realm", ref.registry)
+	}
+	query := url.Values{}
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", ref.repository)
+	}
+	query.Set("scope", scope)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, params["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if hasCreds {
+		req.SetBasicAuth(creds.username, creds.password)
+	}
+	var tokenResp struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	err = doJSONRequest(d.client, req, &tokenResp)
+	if err != nil {
+		return "", err
+	}
+	token := tokenResp.Token
+	if token == "" {
+		token = tokenResp.AccessToken
+	}
+	if token == "" {
+		return "", errors.New("registry token response did not contain a token")
+	}
+	return "Bearer " + token, nil
+}
+
+// resolveImageDigests resolves the images of all Vizier containers to their digests. The returned map contains the
+// image pinned to its digest, keyed by the image as it is referenced in the configured resources.
+func (r *VizierReconciler) resolveImageDigests(ctx context.Context, namespace string, vz *v1alpha1.Vizier, yamlMap map[string]string) (map[string]string, error) {
+	// The digests from the previous deploy must not be applied, so that every image is resolved again.
+	unpinned := vz.DeepCopy()
+	unpinned.Status.ImageDigests = nil
+	resources, err := renderVizierResources(unpinned, yamlMap)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := &digestResolver{client: &http.Client{Timeout: registryRequestTimeout}}
+	if vz.Spec.ImageDigests.PullSecretName != "" {
+		resolver.credentials, err = getRegistryCredentials(ctx, r.Clientset, namespace, vz.Spec.ImageDigests.PullSecretName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read registry credentials: %w", err)
+		}
+	}
+
+	digests := make(map[string]string)
+	for _, res := range resources {
+		for _, image := range getContainerImages(res.Object.Object) {
+			if _, ok := digests[image]; ok || isDigestReference(image) {
+				continue
+			}
+			digest, err := resolver.resolve(ctx, image)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve the digest of %s: %w", image, err)
+			}
+			digests[image] = image + "@" + digest
+		}
+	}
+	return digests, nil
+}
+
+// getContainerImages returns the images of all containers in the resource's pod template.
+func getContainerImages(res map[string]interface{}) []string {
+	var images []string
+	for _, field := range []string{"containers", "initContainers"} {
+		containers, ok, err := unstructured.NestedSlice(res, "spec", "template", "spec", field)
+		if !ok || err != nil {
+			continue
+		}
+		for _, c := range containers {
+			castedContainer, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if image, ok := castedContainer["image"].(string); ok && image != "" {
+				images = append(images, image)
+			}
+		}
+	}
+	return images
+}
+
+// updateImageDigests replaces the images of all containers in the resource with the images pinned to their digests.
+func updateImageDigests(digests map[string]string, res map[string]interface{}) {
+	for _, field := range []string{"containers", "initContainers"} {
+		containers, ok, err := unstructured.NestedFieldNoCopy(res, "spec", "template", "spec", field)
+		if !ok || err != nil {
+			continue
+		}
+		cList, ok := containers.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, c := range cList {
+			castedContainer, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			image, _ := castedContainer["image"].(string)
+			if pinned, ok := digests[image]; ok {
+				castedContainer["image"] = pinned
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected *imageReference
+	}{
+		{
+			image:    "gcr.io/pixie-oss/pixie-prod/vizier-pem_image:0.10.0",
+			expected: &imageReference{registry: "gcr.io", repository: "pixie-oss/pixie-prod/vizier-pem_image", tag: "0.10.0"},
+		},
+		{
+			image:    "localhost:5000/vizier-pem_image",
+			expected: &imageReference{registry: "localhost:5000", repository: "vizier-pem_image", tag: "latest"},
+		},
+		{
+			image:    "nats:2.9",
+			expected: &imageReference{registry: "registry-1.docker.io", repository: "library/nats", tag: "2.9"},
+		},
+		{
+			image:    "docker.io/bitnami/etcd:3.5",
+			expected: &imageReference{registry: "registry-1.docker.io", repository: "bitnami/etcd", tag: "3.5"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.image, func(t *testing.T) {
+			assert.Equal(t, tc.expected, parseImageReference(tc.image))
+		})
+	}
+}
+
+func TestDigestResolver_BearerToken(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			assert.Equal(t, "repository:pixie/pem:pull", r.URL.Query().Get("scope"))
+			fmt.Fprint(w, `{"token": "abc"}`)
+		case "/v2/pixie/pem/manifests/0.1":
+			if r.Header.Get("Authorization") != "Bearer abc" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, srv.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Equal(t, http.MethodHead, r.Method)
+			assert.Contains(t, r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json")
+			w.Header().Set("Docker-Content-Digest", "sha256:1234")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	resolver := &digestResolver{client: srv.Client()}
+	digest, err := resolver.resolve(context.Background(), strings.TrimPrefix(srv.URL, "https://")+"/pixie/pem:0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:1234", digest)
+}
+
+func TestDigestResolver_ComputedDigest(t *testing.T) {
+	manifest := `{"schemaVersion": 2}`
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "user" || pass != "pass" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, manifest)
+		}
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "https://")
+	resolver := &digestResolver{
+		client:      srv.Client(),
+		credentials: map[string]registryCredentials{host: {username: "user", password: "pass"}},
+	}
+	digest, err := resolver.resolve(context.Background(), host+"/pixie/pem:0.1")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(manifest))), digest)
+}
+
+func TestGetRegistryCredentials(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-credentials", Namespace: "pl"},
+		Type:       v1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			v1.DockerConfigJsonKey: []byte(`{"auths": {
+				"https://index.docker.io/v1/": {"auth": "dXNlcjpwYXNz"},
+				"registry.internal": {"username": "admin", "password": "secret"}
+			}}`),
+		},
+	})
+
+	creds, err := getRegistryCredentials(context.Background(), clientset, "pl", "registry-credentials")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]registryCredentials{
+		"registry-1.docker.io": {username: "user", password: "pass"},
+		"registry.internal":    {username: "admin", password: "secret"},
+	}, creds)
+}
+
+func TestUpdateImageDigests(t *testing.T) {
+	res := newTestPodResource(map[string]interface{}{
+		"initContainers": []interface{}{
+			map[string]interface{}{"name": "wait", "image": "busybox:1.36"},
+		},
+		"containers": []interface{}{
+			map[string]interface{}{"name": "pem", "image": "gcr.io/pixie-oss/vizier-pem_image:0.10.0"},
+		},
+	})
+
+	updateImageDigests(map[string]string{
+		"gcr.io/pixie-oss/vizier-pem_image:0.10.0": "gcr.io/pixie-oss/vizier-pem_image:0.10.0@sha256:1234",
+	}, res)
+
+	assert.Equal(t, []string{"gcr.io/pixie-oss/vizier-pem_image:0.10.0@sha256:1234", "busybox:1.36"}, getContainerImages(res))
+}
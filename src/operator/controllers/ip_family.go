@@ -0,0 +1,206 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+const (
+	// The wildcard IPv4 bind address, which is rewritten to the IPv6 wildcard on clusters which prefer IPv6.
+	ipv4BindAddress = "0.0.0.0:"
+	ipv6BindAddress = "[::]:"
+)
+
+// getIPFamily returns the IP family of the given IP address, or an empty family if it is not a valid IP address.
+func getIPFamily(ip net.IP) v1.IPFamily {
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return v1.IPv4Protocol
+	default:
+		return v1.IPv6Protocol
+	}
+}
+
+// getClusterIPFamilies detects the IP families of the cluster, in order of preference. The preferred family is the
+// family of the cluster IP of the API server's service, and a second family is added if the nodes are assigned pod
+// CIDRs of both families.
+func getClusterIPFamilies(ctx context.Context, clientset kubernetes.Interface) ([]v1.IPFamily, error) {
+	svc, err := clientset.CoreV1().Services(metav1.NamespaceDefault).Get(ctx, "kubernetes", metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	primary := getIPFamily(net.ParseIP(svc.Spec.ClusterIP))
+	if primary == "" {
+		return nil, fmt.Errorf("could not determine the IP family of cluster IP %q", svc.Spec.ClusterIP)
+	}
+	families := []v1.IPFamily{primary}
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes.Items) == 0 {
+		return families, nil
+	}
+	for _, cidr := range nodes.Items[0].Spec.PodCIDRs {
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		if family := getIPFamily(ip); family != primary {
+			return append(families, family), nil
+		}
+	}
+	return families, nil
+}
+
+// getClusterCIDRs detects the pod CIDRs assigned to the cluster's nodes, and the cluster IPs of the API server's
+// service. The service CIDR itself is not exposed by the cluster, so the API server's cluster IPs are returned as
+// single-address CIDRs in its place.
+func getClusterCIDRs(ctx context.Context, clientset kubernetes.Interface) ([]string, []string, error) {
+	svc, err := clientset.CoreV1().Services(metav1.NamespaceDefault).Get(ctx, "kubernetes", metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	clusterIPs := svc.Spec.ClusterIPs
+	if len(clusterIPs) == 0 && svc.Spec.ClusterIP != "" {
+		clusterIPs = []string{svc.Spec.ClusterIP}
+	}
+	var serviceCIDRs []string
+	for _, clusterIP := range clusterIPs {
+		ip := net.ParseIP(clusterIP)
+		if ip == nil {
+			continue
+		}
+		bits := 8 * net.IPv6len
+		if getIPFamily(ip) == v1.IPv4Protocol {
+			bits = 8 * net.IPv4len
+		}
+		serviceCIDRs = append(serviceCIDRs, (&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}).String())
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	var podCIDRs []string
+	seen := make(map[string]bool)
+	for _, node := range nodes.Items {
+		cidrs := node.Spec.PodCIDRs
+		if len(cidrs) == 0 && node.Spec.PodCIDR != "" {
+			cidrs = []string{node.Spec.PodCIDR}
+		}
+		for _, cidr := range cidrs {
+			if seen[cidr] {
+				continue
+			}
+			seen[cidr] = true
+			podCIDRs = append(podCIDRs, cidr)
+		}
+	}
+	return podCIDRs, serviceCIDRs, nil
+}
+
+// validateIPFamilies checks that the IP families are distinct, supported families.
+func validateIPFamilies(families []v1.IPFamily) error {
+	seen := make(map[v1.IPFamily]bool)
+	for _, f := range families {
+		if f != v1.IPv4Protocol && f != v1.IPv6Protocol {
+			return fmt.Errorf("spec.ipFamilies %q is not supported, must be %q or %q", f, v1.IPv4Protocol, v1.IPv6Protocol)
+		}
+		if seen[f] {
+			return fmt.Errorf("spec.ipFamilies must not contain %q more than once", f)
+		}
+		seen[f] = true
+	}
+	return nil
+}
+
+// updateIPFamilies sets the IP families of the Vizier's services, and, if IPv6 is preferred, rewrites the IPv4
+// wildcard bind addresses in the container arguments to the IPv6 wildcard.
+func updateIPFamilies(families []v1.IPFamily, resource *k8s.Resource) error {
+	if len(families) == 0 {
+		return nil
+	}
+	res := resource.Object.Object
+	if resource.GVK.Kind == "Service" {
+		svcType, _, _ := unstructured.NestedString(res, "spec", "type")
+		if svcType == string(v1.ServiceTypeExternalName) {
+			return nil
+		}
+		policy := v1.IPFamilyPolicySingleStack
+		if len(families) > 1 {
+			policy = v1.IPFamilyPolicyPreferDualStack
+		}
+		var ipFamilies []interface{}
+		for _, f := range families {
+			ipFamilies = append(ipFamilies, string(f))
+		}
+		err := unstructured.SetNestedSlice(res, ipFamilies, "spec", "ipFamilies")
+		if err != nil {
+			return err
+		}
+		return unstructured.SetNestedField(res, string(policy), "spec", "ipFamilyPolicy")
+	}
+
+	if families[0] != v1.IPv6Protocol {
+		return nil
+	}
+	for _, field := range []string{"containers", "initContainers"} {
+		containers, ok, err := unstructured.NestedFieldNoCopy(res, "spec", "template", "spec", field)
+		if !ok || err != nil {
+			continue
+		}
+		cList, ok := containers.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, c := range cList {
+			castedContainer, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, key := range []string{"command", "args"} {
+				vals, ok := castedContainer[key].([]interface{})
+				if !ok {
+					continue
+				}
+				for i, v := range vals {
+					if s, ok := v.(string); ok {
+						vals[i] = strings.ReplaceAll(s, ipv4BindAddress, ipv6BindAddress)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
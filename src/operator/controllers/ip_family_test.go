@@ -0,0 +1,160 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func newTestAPIServerService(clusterIP string) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: metav1.NamespaceDefault},
+		Spec:       v1.ServiceSpec{ClusterIP: clusterIP},
+	}
+}
+
+func newTestNodeWithPodCIDRs(podCIDRs ...string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec:       v1.NodeSpec{PodCIDRs: podCIDRs},
+	}
+}
+
+func TestGetClusterIPFamilies(t *testing.T) {
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		expected []v1.IPFamily
+	}{
+		{
+			name:     "IPv4 only",
+			objects:  []runtime.Object{newTestAPIServerService("10.96.0.1"), newTestNodeWithPodCIDRs("10.244.0.0/24")},
+			expected: []v1.IPFamily{v1.IPv4Protocol},
+		},
+		{
+			name:     "IPv6 only",
+			objects:  []runtime.Object{newTestAPIServerService("fd00:10:96::1"), newTestNodeWithPodCIDRs("fd00:10:244::/64")},
+			expected: []v1.IPFamily{v1.IPv6Protocol},
+		},
+		{
+			name:     "dual-stack",
+			objects:  []runtime.Object{newTestAPIServerService("10.96.0.1"), newTestNodeWithPodCIDRs("10.244.0.0/24", "fd00:10:244::/64")},
+			expected: []v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol},
+		},
+		{
+			name:     "no nodes",
+			objects:  []runtime.Object{newTestAPIServerService("fd00:10:96::1")},
+			expected: []v1.IPFamily{v1.IPv6Protocol},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			families, err := getClusterIPFamilies(context.Background(), fake.NewSimpleClientset(test.objects...))
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, families)
+		})
+	}
+
+	_, err := getClusterIPFamilies(context.Background(), fake.NewSimpleClientset())
+	assert.Error(t, err)
+}
+
+func TestGetClusterCIDRs(t *testing.T) {
+	node2 := newTestNodeWithPodCIDRs("10.244.1.0/24", "fd00:10:244:1::/64")
+	node2.Name = "node-2"
+	svc := newTestAPIServerService("10.96.0.1")
+	svc.Spec.ClusterIPs = []string{"10.96.0.1", "fd00:10:96::1"}
+
+	podCIDRs, serviceCIDRs, err := getClusterCIDRs(context.Background(), fake.NewSimpleClientset(
+		svc, newTestNodeWithPodCIDRs("10.244.0.0/24", "fd00:10:244::/64"), node2))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"10.244.0.0/24", "fd00:10:244::/64", "10.244.1.0/24", "fd00:10:244:1::/64"}, podCIDRs)
+	assert.Equal(t, []string{"10.96.0.1/32", "fd00:10:96::1/128"}, serviceCIDRs)
+
+	_, _, err = getClusterCIDRs(context.Background(), fake.NewSimpleClientset())
+	assert.Error(t, err)
+}
+
+func TestValidateIPFamilies(t *testing.T) {
+	assert.NoError(t, validateIPFamilies(nil))
+	assert.NoError(t, validateIPFamilies([]v1.IPFamily{v1.IPv6Protocol, v1.IPv4Protocol}))
+	assert.Error(t, validateIPFamilies([]v1.IPFamily{"IPv5"}))
+	assert.Error(t, validateIPFamilies([]v1.IPFamily{v1.IPv6Protocol, v1.IPv6Protocol}))
+}
+
+func TestUpdateIPFamilies_Service(t *testing.T) {
+	svc := &k8s.Resource{
+		Object: &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"type": "ClusterIP"},
+		}},
+		GVK: &schema.GroupVersionKind{Version: "v1", Kind: "Service"},
+	}
+	require.NoError(t, updateIPFamilies([]v1.IPFamily{v1.IPv6Protocol, v1.IPv4Protocol}, svc))
+	assert.Equal(t, map[string]interface{}{
+		"type":           "ClusterIP",
+		"ipFamilies":     []interface{}{"IPv6", "IPv4"},
+		"ipFamilyPolicy": "PreferDualStack",
+	}, svc.Object.Object["spec"])
+
+	require.NoError(t, updateIPFamilies([]v1.IPFamily{v1.IPv6Protocol}, svc))
+	assert.Equal(t, "SingleStack", svc.Object.Object["spec"].(map[string]interface{})["ipFamilyPolicy"])
+
+	externalName := &k8s.Resource{
+		Object: &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"type": "ExternalName"},
+		}},
+		GVK: &schema.GroupVersionKind{Version: "v1", Kind: "Service"},
+	}
+	require.NoError(t, updateIPFamilies([]v1.IPFamily{v1.IPv6Protocol}, externalName))
+	assert.Equal(t, map[string]interface{}{"type": "ExternalName"}, externalName.Object.Object["spec"])
+}
+
+func TestUpdateIPFamilies_BindAddresses(t *testing.T) {
+	newEtcd := func() *k8s.Resource {
+		res := newTestPatchResource("StatefulSet", "pl-etcd", nil)
+		containers := testPodSpec(res.Object.Object)["containers"].([]interface{})
+		containers[0].(map[string]interface{})["command"] = []interface{}{"/bin/sh", "-ec", "etcd --listen-client-urls https://0.0.0.0:2379"}
+		return res
+	}
+
+	res := newEtcd()
+	require.NoError(t, updateIPFamilies([]v1.IPFamily{v1.IPv6Protocol, v1.IPv4Protocol}, res))
+	app := testPodSpec(res.Object.Object)["containers"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{"/bin/sh", "-ec", "etcd --listen-client-urls https://[::]:2379"}, app["command"])
+	assert.Equal(t, []interface{}{"--verbose"}, app["args"])
+
+	// The bind addresses are left unchanged when IPv4 is preferred.
+	res = newEtcd()
+	require.NoError(t, updateIPFamilies([]v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol}, res))
+	app = testPodSpec(res.Object.Object)["containers"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{"/bin/sh", "-ec", "etcd --listen-client-urls https://0.0.0.0:2379"}, app["command"])
+}
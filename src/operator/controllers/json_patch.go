@@ -0,0 +1,98 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/apimachinery/pkg/labels"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/yaml"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// applyJSONPatches applies each of the JSON patches whose target matches the given resource, in order.
+func applyJSONPatches(patches []v1alpha1.JSONPatch, resource *k8s.Resource) error {
+	for i, p := range patches {
+		matches, err := matchesPatchTarget(&p.Target, resource)
+		if err != nil {
+			return fmt.Errorf("invalid target for JSON patch %d: %w", i, err)
+		}
+		if !matches {
+			continue
+		}
+
+		// YAML is a superset of JSON, so this accepts patches in either format.
+		patchJSON, err := yaml.YAMLToJSON([]byte(p.Patch))
+		if err != nil {
+			return fmt.Errorf("invalid JSON patch %d: %w", i, err)
+		}
+		patch, err := jsonpatch.DecodePatch(patchJSON)
+		if err != nil {
+			return fmt.Errorf("invalid JSON patch %d: %w", i, err)
+		}
+
+		objJSON, err := resource.Object.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		patchedJSON, err := patch.Apply(objJSON)
+		if err != nil {
+			return fmt.Errorf("failed to apply JSON patch %d to %s %s: %w", i, resource.GVK.Kind, resource.Object.GetName(), err)
+		}
+
+		// Decode into a new object, so that fields removed by the patch are not left behind.
+		var patched map[string]interface{}
+		err = utiljson.Unmarshal(patchedJSON, &patched)
+		if err != nil {
+			return err
+		}
+		resource.Object.Object = patched
+	}
+	return nil
+}
+
+// matchesPatchTarget returns whether the resource matches all of the fields specified in the patch target.
+func matchesPatchTarget(target *v1alpha1.PatchTarget, resource *k8s.Resource) (bool, error) {
+	if target.Group != "" && target.Group != resource.GVK.Group {
+		return false, nil
+	}
+	if target.Version != "" && target.Version != resource.GVK.Version {
+		return false, nil
+	}
+	if target.Kind != "" && target.Kind != resource.GVK.Kind {
+		return false, nil
+	}
+	if target.Name != "" && target.Name != resource.Object.GetName() {
+		return false, nil
+	}
+	if target.LabelSelector != "" {
+		selector, err := labels.Parse(target.LabelSelector)
+		if err != nil {
+			return false, err
+		}
+		if !selector.Matches(labels.Set(resource.Object.GetLabels())) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func newTestPatchResource(kind string, name string, labels map[string]string) *k8s.Resource {
+	obj := &unstructured.Unstructured{Object: newTestPodResource(map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "app", "args": []interface{}{"--verbose"}},
+		},
+	})}
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	return &k8s.Resource{
+		Object: obj,
+		GVK:    &schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: kind},
+	}
+}
+
+func TestApplyJSONPatches(t *testing.T) {
+	patches := []v1alpha1.JSONPatch{
+		{
+			Target: v1alpha1.PatchTarget{Kind: "Deployment", Name: "kelvin"},
+			Patch:  `[{"op": "add", "path": "/spec/template/spec/volumes", "value": [{"name": "tmp", "emptyDir": {}}]}]`,
+		},
+		{
+			Target: v1alpha1.PatchTarget{Group: "apps", LabelSelector: "component=vizier"},
+			Patch: `
+- op: remove
+  path: /spec/template/spec/containers/0/args
+- op: add
+  path: /spec/replicas
+  value: 2
+`,
+		},
+	}
+
+	kelvin := newTestPatchResource("Deployment", "kelvin", map[string]string{"component": "vizier"})
+	require.NoError(t, applyJSONPatches(patches, kelvin))
+	podSpec := testPodSpec(kelvin.Object.Object)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "tmp", "emptyDir": map[string]interface{}{}},
+	}, podSpec["volumes"])
+	assert.NotContains(t, podSpec["containers"].([]interface{})[0], "args")
+	assert.Equal(t, int64(2), kelvin.Object.Object["spec"].(map[string]interface{})["replicas"])
+
+	pem := newTestPatchResource("DaemonSet", "vizier-pem", map[string]string{"component": "other"})
+	require.NoError(t, applyJSONPatches(patches, pem))
+	podSpec = testPodSpec(pem.Object.Object)
+	assert.NotContains(t, podSpec, "volumes")
+	assert.Contains(t, podSpec["containers"].([]interface{})[0], "args")
+}
+
+func TestApplyJSONPatches_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch v1alpha1.JSONPatch
+	}{
+		{
+			name:  "malformed patch",
+			patch: v1alpha1.JSONPatch{Patch: `{"op": "add"`},
+		},
+		{
+			name:  "missing path",
+			patch: v1alpha1.JSONPatch{Patch: `[{"op": "replace", "path": "/spec/missing/field", "value": 1}]`},
+		},
+		{
+			name: "invalid label selector",
+			patch: v1alpha1.JSONPatch{
+				Target: v1alpha1.PatchTarget{LabelSelector: "component in"},
+				Patch:  `[]`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := newTestPatchResource("Deployment", "kelvin", nil)
+			assert.Error(t, applyJSONPatches([]v1alpha1.JSONPatch{test.patch}, res))
+		})
+	}
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const (
+	// The secret which holds the JWT signing keys.
+	clusterSecretName = "pl-cluster-secrets"
+	// The key in the cluster secret which holds the JWT signing key from before the most recent rotation.
+	clusterSecretPreviousJWTKey = "previous-jwt-signing-key"
+	// The annotation on the cluster secret which records when the JWT signing key was last rotated.
+	jwtKeyRotatedAtAnnotation = "px.dev/jwt-signing-key-rotated-at"
+	// The annotation on pod templates which triggers a rolling restart, as set by `kubectl rollout restart`.
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+)
+
+// generateJWTSigningKey generates a random JWT signing key.
+func generateJWTSigningKey() ([]byte, error) {
+	jwtSigningKey := make([]byte, 64)
+	_, err := rand.Read(jwtSigningKey)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%x", jwtSigningKey)), nil
+}
+
+// getJWTKeyRotationDelay returns how long until the JWT signing key in the cluster secret is due to be rotated. Keys
+// which have never been rotated are as old as the secret.
+func getJWTKeyRotationDelay(s *v1.Secret, period time.Duration, now time.Time) time.Duration {
+	rotatedAt, err := time.Parse(time.RFC3339, s.Annotations[jwtKeyRotatedAtAnnotation])
+	if err != nil {
+		rotatedAt = s.CreationTimestamp.Time
+	}
+	return rotatedAt.Add(period).Sub(now)
+}
+
+// rotateJWTSigningKey replaces the JWT signing key in the cluster secret with a new key. The replaced key is kept as
+// the previous key, so that tokens which were signed with it remain valid until the next rotation.
+func rotateJWTSigningKey(ctx context.Context, clientset kubernetes.Interface, s *v1.Secret, now time.Time) error {
+	key, err := generateJWTSigningKey()
+	if err != nil {
+		return err
+	}
+	if s.Data == nil {
+		s.Data = make(map[string][]byte)
+	}
+	if current, ok := s.Data[clusterSecretJWTKey]; ok {
+		s.Data[clusterSecretPreviousJWTKey] = current
+	}
+	s.Data[clusterSecretJWTKey] = key
+	if s.Annotations == nil {
+		s.Annotations = make(map[string]string)
+	}
+	s.Annotations[jwtKeyRotatedAtAnnotation] = now.UTC().Format(time.RFC3339)
+
+	_, err = clientset.CoreV1().Secrets(s.Namespace).Update(ctx, s, metav1.UpdateOptions{})
+	return err
+}
+
+// reconcileJWTKeyRotation rotates the Vizier's JWT signing key if its rotation period has elapsed, and restarts the
+// Vizier pods so that they pick up the new key. It returns how long until the key is next due to be rotated, or 0 if
+// rotation is disabled.
+func (r *VizierReconciler) reconcileJWTKeyRotation(ctx context.Context, namespace string, vz *v1alpha1.Vizier) (time.Duration, error) {
+	if vz.Spec.JWTSigningKeyRotationPeriod == nil || vz.Spec.JWTSigningKeyRotationPeriod.Duration <= 0 {
+		return 0, nil
+	}
+	period := vz.Spec.JWTSigningKeyRotationPeriod.Duration
+
+	s, err := r.Clientset.CoreV1().Secrets(namespace).Get(ctx, clusterSecretName, metav1.GetOptions{})
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	if delay := getJWTKeyRotationDelay(s, period, now); delay > 0 {
+		return delay, nil
+	}
+
+	log.WithField("namespace", namespace).Info("Rotating JWT signing key")
+	err = rotateJWTSigningKey(ctx, r.Clientset, s, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rotate JWT signing key: %w", err)
+	}
+	err = restartVizierPods(ctx, r.Clientset, namespace, vz.Name, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to restart Vizier pods after rotating JWT signing key: %w", err)
+	}
+	return period, nil
+}
+
+// restartVizierPods triggers a rolling restart of the Vizier's deployments, statefulsets and daemonsets.
+func restartVizierPods(ctx context.Context, clientset kubernetes.Interface, namespace string, vizierName string, now time.Time) error {
+	opts := metav1.ListOptions{LabelSelector: operatorAnnotation + "=" + vizierName}
+	patch := []byte(fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`,
+		restartedAtAnnotation, now.UTC().Format(time.RFC3339)))
+
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, opts)
+	if err != nil {
+		return err
+	}
+	for _, d := range deployments.Items {
+		_, err = clientset.AppsV1().Deployments(namespace).Patch(ctx, d.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	statefulSets, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, opts)
+	if err != nil {
+		return err
+	}
+	for _, s := range statefulSets.Items {
+		_, err = clientset.AppsV1().StatefulSets(namespace).Patch(ctx, s.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	daemonSets, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, opts)
+	if err != nil {
+		return err
+	}
+	for _, d := range daemonSets.Items {
+		_, err = clientset.AppsV1().DaemonSets(namespace).Patch(ctx, d.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updatePreviousJWTKeyEnv adds the previous JWT signing key to all containers in the resource which are given the
+// JWT signing key, so that they continue to accept tokens signed before the key was rotated.
+func updatePreviousJWTKeyEnv(res map[string]interface{}) {
+	for _, field := range []string{"containers", "initContainers"} {
+		containers, ok, err := unstructured.NestedFieldNoCopy(res, "spec", "template", "spec", field)
+		if !ok || err != nil {
+			continue
+		}
+		cList, ok := containers.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, c := range cList {
+			castedContainer, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			env, _ := castedContainer["env"].([]interface{})
+			existing := make(map[string]bool)
+			for _, e := range env {
+				if castedEnv, ok := e.(map[string]interface{}); ok {
+					if name, ok := castedEnv["name"].(string); ok {
+						existing[name] = true
+					}
+				}
+			}
+			if !existing["PL_JWT_SIGNING_KEY"] || existing["PL_PREVIOUS_JWT_SIGNING_KEY"] {
+				continue
+			}
+			castedContainer["env"] = append(env, map[string]interface{}{
+				"name": "PL_PREVIOUS_JWT_SIGNING_KEY",
+				"valueFrom": map[string]interface{}{
+					"secretKeyRef": map[string]interface{}{
+						"name":     clusterSecretName,
+						"key":      clusterSecretPreviousJWTKey,
+						"optional": true,
+					},
+				},
+			})
+		}
+	}
+}
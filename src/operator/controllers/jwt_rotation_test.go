@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetJWTKeyRotationDelay(t *testing.T) {
+	now := time.Date(2022, 1, 10, 0, 0, 0, 0, time.UTC)
+	s := &v1.Secret{ObjectMeta: metav1.ObjectMeta{
+		CreationTimestamp: metav1.NewTime(now.Add(-48 * time.Hour)),
+	}}
+	assert.Equal(t, -24*time.Hour, getJWTKeyRotationDelay(s, 24*time.Hour, now))
+
+	s.Annotations = map[string]string{jwtKeyRotatedAtAnnotation: now.Add(-time.Hour).Format(time.RFC3339)}
+	assert.Equal(t, 23*time.Hour, getJWTKeyRotationDelay(s, 24*time.Hour, now))
+}
+
+func TestRotateJWTSigningKey(t *testing.T) {
+	s := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterSecretName, Namespace: "pl"},
+		Data:       map[string][]byte{clusterSecretJWTKey: []byte("old-key")},
+	}
+	clientset := fake.NewSimpleClientset(s.DeepCopy())
+	now := time.Date(2022, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, rotateJWTSigningKey(context.Background(), clientset, s, now))
+
+	updated, err := clientset.CoreV1().Secrets("pl").Get(context.Background(), clusterSecretName, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "old-key", string(updated.Data[clusterSecretPreviousJWTKey]))
+	assert.Len(t, updated.Data[clusterSecretJWTKey], 128)
+	assert.Equal(t, "2022-01-10T00:00:00Z", updated.Annotations[jwtKeyRotatedAtAnnotation])
+}
+
+func TestRestartVizierPods(t *testing.T) {
+	labels := map[string]string{operatorAnnotation: "vizier"}
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "kelvin", Namespace: "pl", Labels: labels}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "pl"}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "vizier-pem", Namespace: "pl", Labels: labels}},
+	)
+	now := time.Date(2022, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, restartVizierPods(context.Background(), clientset, "pl", "vizier", now))
+
+	kelvin, err := clientset.AppsV1().Deployments("pl").Get(context.Background(), "kelvin", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "2022-01-10T00:00:00Z", kelvin.Spec.Template.Annotations[restartedAtAnnotation])
+	pem, err := clientset.AppsV1().DaemonSets("pl").Get(context.Background(), "vizier-pem", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "2022-01-10T00:00:00Z", pem.Spec.Template.Annotations[restartedAtAnnotation])
+	other, err := clientset.AppsV1().Deployments("pl").Get(context.Background(), "other", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, other.Spec.Template.Annotations)
+}
+
+func TestUpdatePreviousJWTKeyEnv(t *testing.T) {
+	res := newTestPodResource(map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{
+				"name": "app",
+				"env": []interface{}{
+					map[string]interface{}{"name": "PL_JWT_SIGNING_KEY", "value": "key"},
+				},
+			},
+			map[string]interface{}{
+				"name": "sidecar",
+			},
+		},
+	})
+
+	updatePreviousJWTKeyEnv(res)
+	// Updating again does not add the variable twice.
+	updatePreviousJWTKeyEnv(res)
+
+	containers := testPodSpec(res)["containers"].([]interface{})
+	assert.Len(t, containers[0].(map[string]interface{})["env"], 2)
+	assert.Nil(t, containers[1].(map[string]interface{})["env"])
+}
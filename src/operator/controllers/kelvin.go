@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+const (
+	// The name of the Kelvin deployment.
+	kelvinName = "kelvin"
+	// The default average CPU utilization which the Kelvin autoscaler maintains.
+	defaultKelvinTargetCPUUtilization = int32(80)
+)
+
+// updateKelvinConfiguration sets the number of replicas of the Kelvin deployment. When autoscaling, the replicas are
+// left unset, so that deploys do not override the replicas chosen by the autoscaler.
+func updateKelvinConfiguration(kelvin *v1alpha1.KelvinParams, resource *k8s.Resource) error {
+	if resource.GVK.Kind != "Deployment" || resource.Object.GetName() != kelvinName {
+		return nil
+	}
+	if kelvin.Autoscaling != nil {
+		unstructured.RemoveNestedField(resource.Object.Object, "spec", "replicas")
+		return nil
+	}
+	if kelvin.Replicas == nil {
+		return nil
+	}
+	return unstructured.SetNestedField(resource.Object.Object, int64(*kelvin.Replicas), "spec", "replicas")
+}
+
+// getKelvinAutoscaler returns the HorizontalPodAutoscaler which scales Kelvin, or nil if autoscaling is disabled.
+func getKelvinAutoscaler(vz *v1alpha1.Vizier) (*k8s.Resource, error) {
+	if vz.Spec.Kelvin == nil || vz.Spec.Kelvin.Autoscaling == nil {
+		return nil, nil
+	}
+	autoscaling := vz.Spec.Kelvin.Autoscaling
+
+	targetCPU := defaultKelvinTargetCPUUtilization
+	if autoscaling.TargetCPUUtilizationPercentage != nil {
+		targetCPU = *autoscaling.TargetCPUUtilizationPercentage
+	}
+	hpa := &autoscalingv1.HorizontalPodAutoscaler{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "autoscaling/v1", Kind: "HorizontalPodAutoscaler"},
+		ObjectMeta: metav1.ObjectMeta{Name: kelvinName},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       kelvinName,
+			},
+			MinReplicas:                    autoscaling.MinReplicas,
+			MaxReplicas:                    autoscaling.MaxReplicas,
+			TargetCPUUtilizationPercentage: &targetCPU,
+		},
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(hpa)
+	if err != nil {
+		return nil, err
+	}
+	delete(obj, "status")
+	// The autoscaler is labeled like the rest of the Vizier's resources, so that it is cleaned up with them.
+	addKeyValueMapToResource("labels", vz.Spec.Pod.Labels, obj)
+	addKeyValueMapToResource("annotations", vz.Spec.Pod.Annotations, obj)
+	gvk := autoscalingv1.SchemeGroupVersion.WithKind("HorizontalPodAutoscaler")
+	return &k8s.Resource{
+		Object: &unstructured.Unstructured{Object: obj},
+		GVK:    &gvk,
+	}, nil
+}
+
+// deleteKelvinAutoscaler deletes the Kelvin HorizontalPodAutoscaler, if it exists, so that Kelvin is no longer scaled
+// once autoscaling is disabled.
+func deleteKelvinAutoscaler(ctx context.Context, clientset kubernetes.Interface, namespace string) error {
+	err := clientset.AutoscalingV1().HorizontalPodAutoscalers(namespace).Delete(ctx, kelvinName, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
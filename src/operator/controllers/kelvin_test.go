@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestUpdateKelvinConfiguration(t *testing.T) {
+	replicas := int32(3)
+	kelvin := newTestPatchResource("Deployment", kelvinName, nil)
+	require.NoError(t, unstructured.SetNestedField(kelvin.Object.Object, int64(1), "spec", "replicas"))
+
+	require.NoError(t, updateKelvinConfiguration(&v1alpha1.KelvinParams{Replicas: &replicas}, kelvin))
+	r, _, _ := unstructured.NestedInt64(kelvin.Object.Object, "spec", "replicas")
+	assert.Equal(t, int64(3), r)
+
+	// The autoscaler owns the replicas when autoscaling.
+	require.NoError(t, updateKelvinConfiguration(&v1alpha1.KelvinParams{
+		Replicas:    &replicas,
+		Autoscaling: &v1alpha1.KelvinAutoscalingParams{MaxReplicas: 5},
+	}, kelvin))
+	_, ok, _ := unstructured.NestedInt64(kelvin.Object.Object, "spec", "replicas")
+	assert.False(t, ok)
+}
+
+func TestGetKelvinAutoscaler(t *testing.T) {
+	vz := &v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{
+		Pod: &v1alpha1.PodPolicy{Labels: map[string]string{operatorAnnotation: "vizier"}},
+	}}
+	res, err := getKelvinAutoscaler(vz)
+	require.NoError(t, err)
+	assert.Nil(t, res)
+
+	minReplicas := int32(2)
+	vz.Spec.Kelvin = &v1alpha1.KelvinParams{
+		Autoscaling: &v1alpha1.KelvinAutoscalingParams{MinReplicas: &minReplicas, MaxReplicas: 5},
+	}
+	res, err = getKelvinAutoscaler(vz)
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	assert.Equal(t, "HorizontalPodAutoscaler", res.GVK.Kind)
+	assert.Equal(t, "vizier", res.Object.GetLabels()[operatorAnnotation])
+	assert.Equal(t, map[string]interface{}{
+		"scaleTargetRef": map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"name":       kelvinName,
+		},
+		"minReplicas":                    int64(2),
+		"maxReplicas":                    int64(5),
+		"targetCPUUtilizationPercentage": int64(80),
+	}, res.Object.Object["spec"])
+}
+
+func TestDeleteKelvinAutoscaler(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewSimpleClientset(&autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: kelvinName, Namespace: "pl"},
+	})
+
+	require.NoError(t, deleteKelvinAutoscaler(ctx, clientset, "pl"))
+	_, err := clientset.AutoscalingV1().HorizontalPodAutoscalers("pl").Get(ctx, kelvinName, metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+
+	// Deleting an autoscaler which does not exist is a no-op.
+	require.NoError(t, deleteKelvinAutoscaler(ctx, clientset, "pl"))
+}
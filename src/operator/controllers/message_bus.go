@@ -0,0 +1,135 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/shared/status"
+)
+
+const (
+	// The port that NATS clients connect to, when the external NATS URL does not specify one.
+	defaultNATSClientPort = "4222"
+	// How long the monitor waits when dialing the external NATS cluster.
+	externalNATSDialTimeout = 5 * time.Second
+)
+
+// validateExternalNATS checks that the external NATS params are well-formed.
+func validateExternalNATS(nats *v1alpha1.ExternalNATSParams) []error {
+	if nats == nil {
+		return nil
+	}
+	var errs []error
+	if _, err := getExternalNATSAddrs(nats.URL); err != nil {
+		errs = append(errs, fmt.Errorf("spec.externalNATS.url: %v", err))
+	}
+	switch nats.Streaming {
+	case "", v1alpha1.MessageBusStreamingSTAN:
+	case v1alpha1.MessageBusStreamingJetStream:
+		if nats.STANClusterID != "" {
+			errs = append(errs, fmt.Errorf("spec.externalNATS.stanClusterID must not be set when streaming is %q", nats.Streaming))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("spec.externalNATS.streaming %q is not supported, must be one of %q or %q", nats.Streaming,
+			v1alpha1.MessageBusStreamingSTAN, v1alpha1.MessageBusStreamingJetStream))
+	}
+	return errs
+}
+
+// getExternalNATSAddrs returns the host:port addresses of the servers in a NATS URL, which may list several
+// comma-separated servers, for example: "tls://nats-0:4222,tls://nats-1:4222".
+func getExternalNATSAddrs(natsURL string) ([]string, error) {
+	if strings.TrimSpace(natsURL) == "" {
+		return nil, fmt.Errorf("no NATS servers specified")
+	}
+	var addrs []string
+	for _, server := range strings.Split(natsURL, ",") {
+		server = strings.TrimSpace(server)
+		if !strings.Contains(server, "://") {
+			server = "nats://" + server
+		}
+		u, err := url.Parse(server)
+		if err != nil {
+			return nil, err
+		}
+		if u.Hostname() == "" {
+			return nil, fmt.Errorf("NATS server %q has no host", server)
+		}
+		port := u.Port()
+		if port == "" {
+			port = defaultNATSClientPort
+		}
+		addrs = append(addrs, net.JoinHostPort(u.Hostname(), port))
+	}
+	return addrs, nil
+}
+
+// getExternalNATSState checks that the secrets referenced by the external NATS params contain the files Vizier
+// expects, and that at least one of the NATS servers accepts connections.
+func getExternalNATSState(ctx context.Context, clientset kubernetes.Interface, namespace string, nats *v1alpha1.ExternalNATSParams) *vizierState {
+	if nats.TLSSecretName != "" && !hasSecretKeys(ctx, clientset, namespace, nats.TLSSecretName, "ca.crt", "client.crt", "client.key") {
+		return &vizierState{Reason: status.ExternalNATSSecretInvalid}
+	}
+	if nats.CredentialsSecretName != "" && !hasSecretKeys(ctx, clientset, namespace, nats.CredentialsSecretName, externalNATSCredsKey) {
+		return &vizierState{Reason: status.ExternalNATSSecretInvalid}
+	}
+
+	addrs, err := getExternalNATSAddrs(nats.URL)
+	if err != nil {
+		return &vizierState{Reason: status.ExternalNATSUnreachable}
+	}
+	dialer := net.Dialer{Timeout: externalNATSDialTimeout}
+	for _, addr := range addrs {
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			log.WithError(err).WithField("addr", addr).Info("Failed to connect to external NATS server")
+			continue
+		}
+		conn.Close()
+		return okState()
+	}
+	return &vizierState{Reason: status.ExternalNATSUnreachable}
+}
+
+// hasSecretKeys returns whether the secret exists and contains all of the given keys.
+func hasSecretKeys(ctx context.Context, clientset kubernetes.Interface, namespace, name string, keys ...string) bool {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		log.WithError(err).WithField("secret", name).Info("Failed to get external NATS secret")
+		return false
+	}
+	for _, k := range keys {
+		if _, ok := secret.Data[k]; !ok {
+			log.WithField("secret", name).WithField("key", k).Info("External NATS secret is missing a key")
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/shared/status"
+)
+
+func TestGetExternalNATSAddrs(t *testing.T) {
+	addrs, err := getExternalNATSAddrs("tls://nats-0.nats:4222, nats-1.nats,nats://[fd00::1]:4333")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nats-0.nats:4222", "nats-1.nats:4222", "[fd00::1]:4333"}, addrs)
+
+	_, err = getExternalNATSAddrs("")
+	assert.Error(t, err)
+	_, err = getExternalNATSAddrs("tls://:4222")
+	assert.Error(t, err)
+}
+
+func TestValidateExternalNATS(t *testing.T) {
+	assert.Empty(t, validateExternalNATS(nil))
+	assert.Empty(t, validateExternalNATS(&v1alpha1.ExternalNATSParams{
+		URL:           "tls://nats:4222",
+		Streaming:     v1alpha1.MessageBusStreamingSTAN,
+		STANClusterID: "nats-streaming",
+	}))
+	assert.Empty(t, validateExternalNATS(&v1alpha1.ExternalNATSParams{
+		URL:       "tls://nats:4222",
+		Streaming: v1alpha1.MessageBusStreamingJetStream,
+	}))
+
+	errs := validateExternalNATS(&v1alpha1.ExternalNATSParams{
+		Streaming:     v1alpha1.MessageBusStreamingJetStream,
+		STANClusterID: "nats-streaming",
+	})
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Error(), "spec.externalNATS.url")
+	assert.Contains(t, errs[1].Error(), "spec.externalNATS.stanClusterID")
+
+	errs = validateExternalNATS(&v1alpha1.ExternalNATSParams{URL: "tls://nats:4222", Streaming: "Kafka"})
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "spec.externalNATS.streaming")
+}
+
+func TestGetExternalNATSState(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	// A closed listener gives an address which refuses connections.
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	clientset := fake.NewSimpleClientset(
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "nats-creds", Namespace: "pl"},
+			Data:       map[string][]byte{"nats.creds": []byte("creds")},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "nats-client-certs", Namespace: "pl"},
+			Data:       map[string][]byte{"ca.crt": []byte("ca")},
+		},
+	)
+
+	tests := []struct {
+		name           string
+		nats           *v1alpha1.ExternalNATSParams
+		expectedReason status.VizierReason
+	}{
+		{
+			name: "reachable",
+			nats: &v1alpha1.ExternalNATSParams{
+				URL:                   "nats://" + closedAddr + ",nats://" + lis.Addr().String(),
+				CredentialsSecretName: "nats-creds",
+			},
+			expectedReason: "",
+		},
+		{
+			name:           "unreachable",
+			nats:           &v1alpha1.ExternalNATSParams{URL: "nats://" + closedAddr},
+			expectedReason: status.ExternalNATSUnreachable,
+		},
+		{
+			name: "missing credentials secret",
+			nats: &v1alpha1.ExternalNATSParams{
+				URL:                   "nats://" + lis.Addr().String(),
+				CredentialsSecretName: "missing",
+			},
+			expectedReason: status.ExternalNATSSecretInvalid,
+		},
+		{
+			name: "incomplete TLS secret",
+			nats: &v1alpha1.ExternalNATSParams{
+				URL:           "nats://" + lis.Addr().String(),
+				TLSSecretName: "nats-client-certs",
+			},
+			expectedReason: status.ExternalNATSSecretInvalid,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := getExternalNATSState(context.Background(), clientset, "pl", test.nats)
+			assert.Equal(t, test.expectedReason, state.Reason)
+		})
+	}
+}
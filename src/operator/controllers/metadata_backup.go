@@ -0,0 +1,241 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const (
+	// The image used to archive and extract the metadata PVC's contents.
+	metadataBackupImage = "gcr.io/pixie-oss/pixie-dev-public/curl:1.0"
+	// The image used to snapshot etcd. This should match the etcd version deployed with Vizier.
+	etcdBackupImage = "quay.io/coreos/etcd:v3.4.3"
+	// The name label of the metadata service.
+	vizierMetadataLabel = "vizier-metadata"
+	// How long to wait for a backup or restore job to complete.
+	metadataBackupTimeout = 10 * time.Minute
+	// How often a backup or restore job is checked for completion.
+	metadataBackupPollInterval = 5 * time.Second
+	// How long finished backup and restore jobs are kept around, so that their logs can be inspected.
+	metadataBackupJobTTL = int32(24 * 60 * 60)
+)
+
+// backupMetadata backs up the metadata store to the backup claim, and returns the name of the backup.
+func backupMetadata(ctx context.Context, clientset kubernetes.Interface, namespace string, vz *v1alpha1.Vizier) (string, error) {
+	backupName := fmt.Sprintf("metadata-%s", time.Now().UTC().Format("20060102-150405"))
+	log.WithField("backup", backupName).Info("Backing up metadata store")
+
+	job := newMetadataBackupJob(namespace, vz, backupName)
+	err := runJob(ctx, clientset, job)
+	if err != nil {
+		return "", err
+	}
+	return backupName, nil
+}
+
+// restoreMetadata restores the metadata PVC from the given backup. The metadata service is scaled down while
+// restoring, and is scaled back up when the Vizier's resources are next applied.
+func restoreMetadata(ctx context.Context, clientset kubernetes.Interface, namespace string, vz *v1alpha1.Vizier, backupName string) error {
+	if vz.Spec.UseEtcdOperator {
+		return fmt.Errorf("restoring the metadata store is not supported when using the etcd operator")
+	}
+	log.WithField("backup", backupName).Info("Restoring metadata store")
+
+	_, err := clientset.AppsV1().StatefulSets(namespace).Patch(ctx, vizierMetadataLabel, types.MergePatchType,
+		[]byte(`{"spec":{"replicas":0}}`), metav1.PatchOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	// The metadata PVC can only be mounted once the metadata service has shut down.
+	err = wait.PollImmediateWithContext(ctx, metadataBackupPollInterval, metadataBackupTimeout, func(ctx context.Context) (bool, error) {
+		pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "name=" + vizierMetadataLabel})
+		if err != nil {
+			return false, err
+		}
+		return len(pods.Items) == 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for the metadata service to shut down: %w", err)
+	}
+
+	return runJob(ctx, clientset, newMetadataRestoreJob(namespace, vz, backupName))
+}
+
+// newMetadataBackupJob creates the job which backs up the metadata store. When the metadata service uses the etcd
+// operator, the job saves an etcd snapshot. Otherwise, the job archives the contents of the metadata PVC. Since the
+// PVC may only be mountable from a single node, the job is scheduled onto the same node as the metadata service.
+func newMetadataBackupJob(namespace string, vz *v1alpha1.Vizier, backupName string) *batchv1.Job {
+	job := newMetadataJob(namespace, vz, fmt.Sprintf("vizier-%s-backup", backupName))
+	podSpec := &job.Spec.Template.Spec
+
+	if vz.Spec.UseEtcdOperator {
+		podSpec.Containers = []v1.Container{{
+			Name:  "backup",
+			Image: getMetadataJobImage(vz, etcdBackupImage),
+			Command: []string{"etcdctl",
+				"--cert=/certs/etcd-client.crt",
+				"--key=/certs/etcd-client.key",
+				"--cacert=/certs/etcd-client-ca.crt",
+				fmt.Sprintf("--endpoints=https://pl-etcd-client.%s.svc:2379", namespace),
+				"snapshot", "save", fmt.Sprintf("/backup/%s.db", backupName),
+			},
+			Env:          []v1.EnvVar{{Name: "ETCDCTL_API", Value: "3"}},
+			VolumeMounts: []v1.VolumeMount{{Name: "backup", MountPath: "/backup"}, {Name: "certs", MountPath: "/certs"}},
+		}}
+		podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+			Name:         "certs",
+			VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "etcd-client-tls-certs"}},
+		})
+		return job
+	}
+
+	podSpec.Containers = []v1.Container{{
+		Name:         "backup",
+		Image:        getMetadataJobImage(vz, metadataBackupImage),
+		Command:      []string{"sh", "-c", fmt.Sprintf("set -e; tar czf /backup/%s.tar.gz -C /metadata .", backupName)},
+		VolumeMounts: []v1.VolumeMount{{Name: "backup", MountPath: "/backup"}, {Name: "metadata", MountPath: "/metadata", ReadOnly: true}},
+	}}
+	podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+		Name: "metadata",
+		VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+			ClaimName: metadataPVC,
+			ReadOnly:  true,
+		}},
+	})
+	podSpec.Affinity = &v1.Affinity{PodAffinity: &v1.PodAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{{
+			LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": vizierMetadataLabel}},
+			TopologyKey:   "kubernetes.io/hostname",
+		}},
+	}}
+	return job
+}
+
+// newMetadataRestoreJob creates the job which replaces the contents of the metadata PVC with the given backup.
+func newMetadataRestoreJob(namespace string, vz *v1alpha1.Vizier, backupName string) *batchv1.Job {
+	job := newMetadataJob(namespace, vz, fmt.Sprintf("vizier-%s-restore", backupName))
+	podSpec := &job.Spec.Template.Spec
+
+	podSpec.Containers = []v1.Container{{
+		Name:  "restore",
+		Image: getMetadataJobImage(vz, metadataBackupImage),
+		Command: []string{"sh", "-c", fmt.Sprintf("set -e; test -f /backup/%[1]s.tar.gz; "+
+			"find /metadata -mindepth 1 -delete; tar xzf /backup/%[1]s.tar.gz -C /metadata", backupName)},
+		VolumeMounts: []v1.VolumeMount{{Name: "backup", MountPath: "/backup", ReadOnly: true}, {Name: "metadata", MountPath: "/metadata"}},
+	}}
+	podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+		Name: "metadata",
+		VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+			ClaimName: metadataPVC,
+		}},
+	})
+	return job
+}
+
+// newMetadataJob creates a job with the backup claim mounted, which runs once to completion.
+func newMetadataJob(namespace string, vz *v1alpha1.Vizier, name string) *batchv1.Job {
+	var podLabels map[string]string
+	if vz.Spec.Pod != nil {
+		podLabels = vz.Spec.Pod.Labels
+	}
+	backoffLimit := int32(0)
+	ttl := metadataBackupJobTTL
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    podLabels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttl,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+				Spec: v1.PodSpec{
+					RestartPolicy: v1.RestartPolicyNever,
+					Volumes: []v1.Volume{{
+						Name: "backup",
+						VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+							ClaimName: vz.Spec.MetadataBackup.ClaimName,
+						}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// getMetadataJobImage returns the image to use in a backup or restore job, which is pulled from the Vizier's custom
+// registry if one is specified.
+func getMetadataJobImage(vz *v1alpha1.Vizier, image string) string {
+	if vz.Spec.Registry == "" {
+		return image
+	}
+	return rewriteImageRegistry(image, vz.Spec.Registry)
+}
+
+// runJob creates the given job, and waits for it to complete.
+func runJob(ctx context.Context, clientset kubernetes.Interface, job *batchv1.Job) error {
+	_, err := clientset.BatchV1().Jobs(job.Namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	return waitForJob(ctx, clientset, job.Namespace, job.Name)
+}
+
+// waitForJob waits for the named job to complete, and returns an error if the job fails.
+func waitForJob(ctx context.Context, clientset kubernetes.Interface, namespace string, name string) error {
+	err := wait.PollImmediateWithContext(ctx, metadataBackupPollInterval, metadataBackupTimeout, func(ctx context.Context) (bool, error) {
+		job, err := clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, c := range job.Status.Conditions {
+			if c.Status != v1.ConditionTrue {
+				continue
+			}
+			if c.Type == batchv1.JobComplete {
+				return true, nil
+			}
+			if c.Type == batchv1.JobFailed {
+				return false, fmt.Errorf("job %s failed: %s", name, c.Message)
+			}
+		}
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out waiting for job %s to complete", name)
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestNewMetadataBackupJob(t *testing.T) {
+	tests := []struct {
+		name             string
+		useEtcdOperator  bool
+		registry         string
+		expectedImage    string
+		expectedVolumes  []string
+		expectedAffinity bool
+	}{
+		{
+			name:             "persistent metadata",
+			expectedImage:    "gcr.io/pixie-oss/pixie-dev-public/curl:1.0",
+			expectedVolumes:  []string{"backup", "metadata"},
+			expectedAffinity: true,
+		},
+		{
+			name:            "etcd metadata",
+			useEtcdOperator: true,
+			expectedImage:   "quay.io/coreos/etcd:v3.4.3",
+			expectedVolumes: []string{"backup", "certs"},
+		},
+		{
+			name:             "custom registry",
+			registry:         "registry.internal/mirror",
+			expectedImage:    "registry.internal/mirror/pixie-oss/pixie-dev-public/curl:1.0",
+			expectedVolumes:  []string{"backup", "metadata"},
+			expectedAffinity: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vz := &v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{
+				UseEtcdOperator: test.useEtcdOperator,
+				Registry:        test.registry,
+				MetadataBackup:  &v1alpha1.MetadataBackupParams{ClaimName: "metadata-backups"},
+				Pod:             &v1alpha1.PodPolicy{Labels: map[string]string{operatorAnnotation: "vizier"}},
+			}}
+			job := newMetadataBackupJob("pl", vz, "metadata-20220101-000000")
+
+			assert.Equal(t, "vizier-metadata-20220101-000000-backup", job.Name)
+			assert.Equal(t, "vizier", job.Labels[operatorAnnotation])
+			podSpec := job.Spec.Template.Spec
+			require.Len(t, podSpec.Containers, 1)
+			assert.Equal(t, test.expectedImage, podSpec.Containers[0].Image)
+			assert.Equal(t, "metadata-backups", podSpec.Volumes[0].PersistentVolumeClaim.ClaimName)
+
+			var volumes []string
+			for _, v := range podSpec.Volumes {
+				volumes = append(volumes, v.Name)
+			}
+			assert.Equal(t, test.expectedVolumes, volumes)
+			assert.Equal(t, test.expectedAffinity, podSpec.Affinity != nil)
+		})
+	}
+}
+
+func TestWaitForJob(t *testing.T) {
+	tests := []struct {
+		name        string
+		condition   batchv1.JobConditionType
+		expectError bool
+	}{
+		{
+			name:      "complete",
+			condition: batchv1.JobComplete,
+		},
+		{
+			name:        "failed",
+			condition:   batchv1.JobFailed,
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(&batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "vizier-metadata-backup", Namespace: "pl"},
+				Status: batchv1.JobStatus{
+					Conditions: []batchv1.JobCondition{{Type: test.condition, Status: v1.ConditionTrue}},
+				},
+			})
+			err := waitForJob(context.Background(), clientset, "pl", "vizier-metadata-backup")
+			if test.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
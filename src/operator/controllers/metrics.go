@@ -0,0 +1,183 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+var reconciliationPhases = []v1alpha1.ReconciliationPhase{
+	v1alpha1.ReconciliationPhaseReady,
+	v1alpha1.ReconciliationPhaseUpdating,
+	v1alpha1.ReconciliationPhaseFailed,
+}
+
+var vizierPhases = []v1alpha1.VizierPhase{
+	v1alpha1.VizierPhaseHealthy,
+	v1alpha1.VizierPhaseDegraded,
+	v1alpha1.VizierPhaseUnhealthy,
+	v1alpha1.VizierPhaseDisconnected,
+	v1alpha1.VizierPhaseUpdating,
+}
+
+// The components which are reported in the component metrics, by the name of their field in the ComponentsStatus.
+var vizierComponents = []string{"pem", "kelvin", "metadata", "nats", "cloudConnector"}
+
+var (
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vizier_reconcile_duration_seconds",
+		Help:    "Time taken to reconcile a Vizier.",
+		Buckets: []float64{0.1, 0.5, 1, 5, 15, 30, 60, 120, 300, 600},
+	}, []string{"operation"})
+	deployFailureCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vizier_deploy_failure_count",
+		Help: "Number of failed Vizier deploys, by the step of the deploy which failed.",
+	}, []string{"step"})
+	cloudRPCErrorCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vizier_cloud_rpc_error_count",
+		Help: "Number of failed RPCs to Pixie Cloud.",
+	}, []string{"rpc"})
+	driftRepairCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vizier_drift_repair_count",
+		Help: "Number of Vizier resources which were re-applied after drifting from their applied state, by kind.",
+	}, []string{"kind"})
+	reconciliationPhaseGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vizier_reconciliation_phase",
+		Help: "The current reconciliation phase of each Vizier. The gauge is 1 for the current phase and 0 otherwise.",
+	}, []string{"namespace", "name", "phase"})
+	vizierOperationCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vizier_operation_count",
+		Help: "Number of attempts to create, update or delete a Vizier.",
+	}, []string{"namespace", "operation"})
+	vizierOperationFailureCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vizier_operation_failure_count",
+		Help: "Number of failed attempts to create, update or delete a Vizier.",
+	}, []string{"namespace", "operation"})
+	cloudConfigFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vizier_cloud_config_fetch_duration_seconds",
+		Help:    "Time taken to fetch the Vizier YAMLs from Pixie Cloud.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"namespace"})
+	applyRetryCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vizier_apply_retry_count",
+		Help: "Number of times applying Vizier resources failed and was retried.",
+	}, []string{"namespace"})
+	vizierPhaseGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vizier_phase",
+		Help: "The current health phase of each Vizier, as observed by the monitor. The gauge is 1 for the current phase and 0 otherwise.",
+	}, []string{"namespace", "name", "phase"})
+	vizierPhaseTransitionCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vizier_phase_transition_count",
+		Help: "Number of times each Vizier transitioned into a health phase, as observed by the monitor.",
+	}, []string{"namespace", "name", "phase"})
+	componentReadyPodsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vizier_component_ready_pods",
+		Help: "Number of ready pods of each Vizier component.",
+	}, []string{"namespace", "name", "component"})
+	componentPodsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vizier_component_pods",
+		Help: "Number of running or pending pods of each Vizier component.",
+	}, []string{"namespace", "name", "component"})
+)
+
+func init() {
+	// Metrics must be registered with controller-runtime's registry to be exposed on the manager's metrics endpoint.
+	metrics.Registry.MustRegister(reconcileDuration)
+	metrics.Registry.MustRegister(deployFailureCount)
+	metrics.Registry.MustRegister(cloudRPCErrorCount)
+	metrics.Registry.MustRegister(driftRepairCount)
+	metrics.Registry.MustRegister(reconciliationPhaseGauge)
+	metrics.Registry.MustRegister(vizierOperationCount)
+	metrics.Registry.MustRegister(vizierOperationFailureCount)
+	metrics.Registry.MustRegister(cloudConfigFetchDuration)
+	metrics.Registry.MustRegister(applyRetryCount)
+	metrics.Registry.MustRegister(vizierPhaseGauge)
+	metrics.Registry.MustRegister(vizierPhaseTransitionCount)
+	metrics.Registry.MustRegister(componentReadyPodsGauge)
+	metrics.Registry.MustRegister(componentPodsGauge)
+}
+
+func recordReconciliationPhase(vz *v1alpha1.Vizier) {
+	for _, phase := range reconciliationPhases {
+		val := 0.0
+		if vz.Status.ReconciliationPhase == phase {
+			val = 1
+		}
+		reconciliationPhaseGauge.WithLabelValues(vz.Namespace, vz.Name, string(phase)).Set(val)
+	}
+}
+
+func deleteReconciliationPhase(namespace string, name string) {
+	for _, phase := range reconciliationPhases {
+		reconciliationPhaseGauge.DeleteLabelValues(namespace, name, string(phase))
+	}
+}
+
+// recordVizierHealth records the Vizier's health phase and the readiness of its components, and counts a transition
+// if the phase changed from the given previous phase.
+func recordVizierHealth(vz *v1alpha1.Vizier, prevPhase v1alpha1.VizierPhase) {
+	for _, phase := range vizierPhases {
+		val := 0.0
+		if vz.Status.VizierPhase == phase {
+			val = 1
+		}
+		vizierPhaseGauge.WithLabelValues(vz.Namespace, vz.Name, string(phase)).Set(val)
+	}
+	if vz.Status.VizierPhase != prevPhase {
+		vizierPhaseTransitionCount.WithLabelValues(vz.Namespace, vz.Name, string(vz.Status.VizierPhase)).Inc()
+	}
+
+	if vz.Status.Components == nil {
+		return
+	}
+	components := map[string]v1alpha1.ComponentStatus{
+		"pem":            vz.Status.Components.PEM,
+		"kelvin":         vz.Status.Components.Kelvin,
+		"metadata":       vz.Status.Components.Metadata,
+		"nats":           vz.Status.Components.NATS,
+		"cloudConnector": vz.Status.Components.CloudConnector,
+	}
+	for _, component := range vizierComponents {
+		componentReadyPodsGauge.WithLabelValues(vz.Namespace, vz.Name, component).Set(float64(components[component].Ready))
+		componentPodsGauge.WithLabelValues(vz.Namespace, vz.Name, component).Set(float64(components[component].Total))
+	}
+}
+
+func deleteVizierHealth(namespace string, name string) {
+	for _, phase := range vizierPhases {
+		vizierPhaseGauge.DeleteLabelValues(namespace, name, string(phase))
+		vizierPhaseTransitionCount.DeleteLabelValues(namespace, name, string(phase))
+	}
+	for _, component := range vizierComponents {
+		componentReadyPodsGauge.DeleteLabelValues(namespace, name, component)
+		componentPodsGauge.DeleteLabelValues(namespace, name, component)
+	}
+}
+
+// recordVizierOperation counts an attempt to create, update or delete the Vizier in the given namespace, and whether
+// it failed.
+func recordVizierOperation(namespace string, operation string, err error) {
+	vizierOperationCount.WithLabelValues(namespace, operation).Inc()
+	if err != nil {
+		vizierOperationFailureCount.WithLabelValues(namespace, operation).Inc()
+	}
+}
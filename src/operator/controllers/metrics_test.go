@@ -0,0 +1,69 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestRecordVizierOperation(t *testing.T) {
+	recordVizierOperation("pl-metrics-test", "update", nil)
+	recordVizierOperation("pl-metrics-test", "update", errors.New("failed to deploy"))
+	recordVizierOperation("pl-metrics-test", "delete", nil)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(vizierOperationCount.WithLabelValues("pl-metrics-test", "update")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(vizierOperationFailureCount.WithLabelValues("pl-metrics-test", "update")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(vizierOperationCount.WithLabelValues("pl-metrics-test", "delete")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(vizierOperationFailureCount.WithLabelValues("pl-metrics-test", "delete")))
+}
+
+func TestRecordVizierHealth(t *testing.T) {
+	vz := &v1alpha1.Vizier{
+		ObjectMeta: metav1.ObjectMeta{Name: "pixie", Namespace: "pl-health-test"},
+		Status: v1alpha1.VizierStatus{
+			VizierPhase: v1alpha1.VizierPhaseHealthy,
+			Components: &v1alpha1.ComponentsStatus{
+				PEM:    v1alpha1.ComponentStatus{Ready: 2, Total: 3},
+				Kelvin: v1alpha1.ComponentStatus{Ready: 1, Total: 1},
+			},
+		},
+	}
+	recordVizierHealth(vz, v1alpha1.VizierPhaseNone)
+	// The phase is unchanged, so no transition is counted.
+	recordVizierHealth(vz, v1alpha1.VizierPhaseHealthy)
+	vz.Status.VizierPhase = v1alpha1.VizierPhaseDegraded
+	recordVizierHealth(vz, v1alpha1.VizierPhaseHealthy)
+
+	assert.Equal(t, 0.0, testutil.ToFloat64(vizierPhaseGauge.WithLabelValues("pl-health-test", "pixie", "Healthy")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(vizierPhaseGauge.WithLabelValues("pl-health-test", "pixie", "Degraded")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(vizierPhaseTransitionCount.WithLabelValues("pl-health-test", "pixie", "Healthy")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(vizierPhaseTransitionCount.WithLabelValues("pl-health-test", "pixie", "Degraded")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(componentReadyPodsGauge.WithLabelValues("pl-health-test", "pixie", "pem")))
+	assert.Equal(t, 3.0, testutil.ToFloat64(componentPodsGauge.WithLabelValues("pl-health-test", "pixie", "pem")))
+
+	deleteVizierHealth("pl-health-test", "pixie")
+	assert.Equal(t, 0, testutil.CollectAndCount(componentPodsGauge))
+}
@@ -20,12 +20,13 @@ package controllers
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -39,6 +40,7 @@ import (
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
@@ -57,7 +59,7 @@ const (
 	natsPodName = "pl-nats-0"
 	// How often we should ping the vizier pods for status updates.
 	statuszCheckInterval = 20 * time.Second
-	// The threshold of number of crashing PEM pods before we declare a cluster degraded.
+	// The default threshold of number of crashing PEM pods before we declare a cluster degraded.
 	pemCrashingThreshold = 0.25
 )
 
@@ -113,9 +115,21 @@ type VizierMonitor struct {
 	namespace      string
 	namespacedName types.NamespacedName
 
-	podStates *concurrentPodMap
-	nodeState *vizierState
-	pvcState  *vizierState
+	podStates     *concurrentPodMap
+	nodeState     *vizierState
+	pvcState      *vizierState
+	pvcUsageState *vizierState
+	etcdState     *vizierState
+	// The reason of the last metadata storage failure for which an event was recorded.
+	lastStorageReason status.VizierReason
+
+	recorder       record.EventRecorder
+	statsSummary   func(ctx context.Context, node string) ([]byte, error)
+	natsProbe      func(ctx context.Context, vz *pixiev1alpha1.Vizier) error
+	etcdHTTPClient HTTPClient
+	remediator     *podRemediator
+	stateDebouncer stateDebouncer
+	webhookClient  *http.Client
 
 	vzUpdate     func(context.Context, client.Object, ...client.UpdateOption) error
 	vzGet        func(context.Context, types.NamespacedName, client.Object) error
@@ -125,15 +139,26 @@ type VizierMonitor struct {
 // InitAndStartMonitor initializes and starts the status monitor for the Vizier.
 func (m *VizierMonitor) InitAndStartMonitor(cloudClient *grpc.ClientConn) error {
 	// Initialize current state.
-	tr := http.DefaultTransport.(*http.Transport).Clone()
-	tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	m.httpClient = &http.Client{Transport: tr}
+	m.httpClient = newStatuszHTTPClient()
 	m.cloudClient = cloudClient
 	m.ctx, m.cancel = context.WithCancel(context.Background())
 	m.podStates = &concurrentPodMap{unsafeMap: make(map[string]map[string]*podWrapper)}
 
 	m.nodeState = okState()
 	m.pvcState = okState()
+	m.pvcUsageState = okState()
+	m.etcdState = okState()
+	m.remediator = newPodRemediator(m.clientset, m.namespace, m.recorder)
+	if m.statsSummary == nil {
+		m.statsSummary = func(ctx context.Context, node string) ([]byte, error) {
+			return getNodeStatsSummary(ctx, m.clientset, node)
+		}
+	}
+	if m.natsProbe == nil {
+		m.natsProbe = func(ctx context.Context, vz *pixiev1alpha1.Vizier) error {
+			return probeNATS(ctx, m.clientset, m.namespace, vz)
+		}
+	}
 
 	m.factory = informers.NewSharedInformerFactoryWithOptions(m.clientset, 0, informers.WithNamespace(m.namespace))
 
@@ -201,20 +226,23 @@ func (m *VizierMonitor) onDeletePod(obj interface{}) {
 
 func (m *VizierMonitor) watchK8sPods() {
 	informer := m.factory.Core().V1().Pods().Informer()
-	stopper := make(chan struct{})
-	defer close(stopper)
 	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    m.onAddPod,
 		UpdateFunc: m.onUpdatePod,
 		DeleteFunc: m.onDeletePod,
 	})
-	informer.Run(stopper)
+	// The informer stops when the monitor quits.
+	informer.Run(m.ctx.Done())
 }
 
 // vizierState details the state of Vizier at a snapshot.
 type vizierState struct {
 	// Reason is the description of the state. Should only be set with values enumerated in `src/shared/status/vzstatus.go`
 	Reason status.VizierReason
+	// Pod is the name of the pod which failed the check, if the failure is caused by a single pod.
+	Pod string
+	// Detail describes why the pod failed the check, such as the reason its container is waiting.
+	Detail string
 }
 
 func okState() *vizierState {
@@ -242,22 +270,22 @@ func getNATSState(client HTTPClient, pods *concurrentPodMap) *vizierState {
 	}
 
 	if natsPod.pod.Status.Phase == v1.PodPending {
-		return &vizierState{Reason: status.NATSPodPending}
+		return &vizierState{Reason: status.NATSPodPending, Pod: natsPodName, Detail: getPodFailureDetail(natsPod.pod)}
 	}
 
 	if natsPod.pod.Status.Phase != v1.PodRunning {
-		return &vizierState{Reason: status.NATSPodFailed}
+		return &vizierState{Reason: status.NATSPodFailed, Pod: natsPodName, Detail: getPodFailureDetail(natsPod.pod)}
 	}
 
-	resp, err := client.Get(fmt.Sprintf("http://%s:%d/", natsPod.pod.Status.PodIP, 8222))
+	resp, err := client.Get(fmt.Sprintf("http://%s/", net.JoinHostPort(natsPod.pod.Status.PodIP, "8222")))
 	if err != nil {
 		log.WithError(err).Error("Error making nats monitoring call")
-		return &vizierState{Reason: status.NATSPodFailed}
+		return &vizierState{Reason: status.NATSPodFailed, Pod: natsPodName, Detail: err.Error()}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return &vizierState{Reason: status.NATSPodFailed}
+		return &vizierState{Reason: status.NATSPodFailed, Pod: natsPodName, Detail: fmt.Sprintf("monitoring endpoint returned %s", resp.Status)}
 	}
 
 	// Return the value of the cloud connector.
@@ -277,16 +305,16 @@ func getCloudConnState(client HTTPClient, pods *concurrentPodMap) *vizierState {
 	// This should account for failed updates, or catching the cluster during an upgrade.
 	for _, ccPod := range labelMap {
 		if ccPod.pod.Status.Phase == v1.PodPending {
-			return &vizierState{Reason: status.CloudConnectorPodPending}
+			return &vizierState{Reason: status.CloudConnectorPodPending, Pod: ccPod.pod.Name, Detail: getPodFailureDetail(ccPod.pod)}
 		}
 
 		if ccPod.pod.Status.Phase != v1.PodRunning {
-			return &vizierState{Reason: status.CloudConnectorPodFailed}
+			return &vizierState{Reason: status.CloudConnectorPodFailed, Pod: ccPod.pod.Name, Detail: getPodFailureDetail(ccPod.pod)}
 		}
 		// Ping cloudConn's statusz.
 		ok, podStatus := queryPodStatusz(client, ccPod.pod)
 		if !ok {
-			return &vizierState{Reason: status.VizierReason(podStatus)}
+			return &vizierState{Reason: status.VizierReason(podStatus), Pod: ccPod.pod.Name}
 		}
 	}
 
@@ -312,16 +340,17 @@ func getControlPlanePodState(pods *concurrentPodMap) *vizierState {
 			if p.pod.Status.Phase == v1.PodPending {
 				for _, cond := range p.pod.Status.Conditions {
 					if cond.Type == v1.PodScheduled && cond.Status == v1.ConditionFalse && cond.Reason == v1.PodReasonUnschedulable {
+						reason := status.ControlPlaneFailedToSchedule
 						if taintRe.MatchString(cond.Message) {
-							return &vizierState{Reason: status.ControlPlaneFailedToScheduleBecauseOfTaints}
+							reason = status.ControlPlaneFailedToScheduleBecauseOfTaints
 						}
-						return &vizierState{Reason: status.ControlPlaneFailedToSchedule}
+						return &vizierState{Reason: reason, Pod: p.pod.Name, Detail: cond.Message}
 					}
 				}
-				return &vizierState{Reason: status.ControlPlanePodsPending}
+				return &vizierState{Reason: status.ControlPlanePodsPending, Pod: p.pod.Name, Detail: getPodFailureDetail(p.pod)}
 			}
 			if p.pod.Status.Phase != v1.PodRunning && p.pod.Status.Phase != v1.PodSucceeded {
-				return &vizierState{Reason: status.ControlPlanePodsFailed}
+				return &vizierState{Reason: status.ControlPlanePodsFailed, Pod: p.pod.Name, Detail: getPodFailureDetail(p.pod)}
 			}
 		}
 	}
@@ -398,8 +427,8 @@ func getVizierVersionState(atClient cloudpb.ArtifactTrackerClient, vz *pixiev1al
 	return okState()
 }
 
-// getPEMCrashingState reads the state of running PEMs to see if a large portion are failing.
-func getPEMCrashingState(pods *concurrentPodMap) *vizierState {
+// getPEMCrashingState reads the state of running PEMs to see if more than the threshold fraction of them are failing.
+func getPEMCrashingState(pods *concurrentPodMap, threshold float64) *vizierState {
 	pods.mapMu.Lock()
 	defer pods.mapMu.Unlock()
 	pems, ok := pods.unsafeMap[vizierPemLabel]
@@ -409,75 +438,205 @@ func getPEMCrashingState(pods *concurrentPodMap) *vizierState {
 
 	pemCrashing := 0.0
 	for _, pem := range pems {
-		if pem.pod.Status.Phase != v1.PodRunning {
-			continue
-		}
-		for _, c := range pem.pod.Status.ContainerStatuses {
-			if c.State.Terminated != nil && c.State.Terminated.Reason == "Error" {
-				pemCrashing++
-				break
-			}
-			if c.State.Waiting != nil && c.State.Waiting.Reason == "CrashLoopBackOff" {
-				pemCrashing++
-				break
-			}
+		if pem.pod.Status.Phase == v1.PodRunning && isPodCrashing(pem.pod) {
+			pemCrashing++
 		}
 	}
 	numPems := float64(len(pems))
 	if pemCrashing == numPems {
 		return &vizierState{Reason: status.PEMsAllFailing}
 	}
-	if pemCrashing > numPems*pemCrashingThreshold {
+	if pemCrashing > numPems*threshold {
 		return &vizierState{Reason: status.PEMsHighFailureRate}
 	}
 	return okState()
 }
 
+// isPodCrashing returns whether any of the pod's containers have errored or are in a crash loop.
+func isPodCrashing(pod *v1.Pod) bool {
+	for _, c := range pod.Status.ContainerStatuses {
+		if c.State.Terminated != nil && c.State.Terminated.Reason == "Error" {
+			return true
+		}
+		if c.State.Waiting != nil && c.State.Waiting.Reason == "CrashLoopBackOff" {
+			return true
+		}
+	}
+	return false
+}
+
+// getPodFailureDetail describes why a pod is not running, from the state of its first waiting or failed container.
+func getPodFailureDetail(pod *v1.Pod) string {
+	statuses := append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, c := range statuses {
+		if w := c.State.Waiting; w != nil && w.Reason != "" {
+			if w.Message != "" {
+				return fmt.Sprintf("container %s is waiting: %s: %s", c.Name, w.Reason, w.Message)
+			}
+			return fmt.Sprintf("container %s is waiting: %s", c.Name, w.Reason)
+		}
+		if t := c.State.Terminated; t != nil && t.ExitCode != 0 {
+			return fmt.Sprintf("container %s terminated: %s (exit code %d)", c.Name, t.Reason, t.ExitCode)
+		}
+	}
+	if pod.Status.Message != "" {
+		return pod.Status.Message
+	}
+	return fmt.Sprintf("pod is %s", pod.Status.Phase)
+}
+
+// getStateMessage returns the human-readable message for the state, followed by the pod which failed the check
+// and why.
+func getStateMessage(state *vizierState) string {
+	msg := status.GetMessageFromReason(state.Reason)
+	// Default to the reason if the message is empty.
+	if msg == "" {
+		msg = string(state.Reason)
+	}
+
+	var details []string
+	if state.Pod != "" {
+		details = append(details, fmt.Sprintf("pod %s", state.Pod))
+	}
+	if state.Detail != "" {
+		details = append(details, state.Detail)
+	}
+	if len(details) > 0 {
+		msg = fmt.Sprintf("%s (%s)", msg, strings.Join(details, ": "))
+	}
+	return msg
+}
+
+// setVizierState records the state in the Vizier's status. The time of the state is only updated when its reason
+// changes, so that the status reports how long the Vizier has been in its current state.
+func setVizierState(vz *pixiev1alpha1.Vizier, state *vizierState, now time.Time) {
+	reason := string(state.Reason)
+	if vz.Status.LastVizierReasonTime == nil || vz.Status.VizierReason != reason {
+		t := metav1.NewTime(now)
+		vz.Status.LastVizierReasonTime = &t
+	}
+	vz.Status.VizierPhase = translateReasonToPhase(state.Reason)
+	vz.Status.VizierReason = reason
+	vz.Status.UnhealthyPod = state.Pod
+	vz.Status.Message = getStateMessage(state)
+}
+
 // getVizierState determines the state of the Vizier instance based on the snapshot
 // of data available at call time. Reports the first state that fails (does not aggregate),
 // otherwise reports a healthy state.
 func (m *VizierMonitor) getVizierState(vz *pixiev1alpha1.Vizier) *vizierState {
 	// Check the latest vizier version, and current vizier version first. Regardless of
 	// whether the vizier pods are running, we consider the cluster in a degraded state.
-	atClient := cloudpb.NewArtifactTrackerClient(m.cloudClient)
-	vzVersionState := getVizierVersionState(atClient, vz)
-	if vzVersionState != nil && !isOk(vzVersionState) {
-		return vzVersionState
+	if isCheckEnabled(vz, pixiev1alpha1.MonitorCheckVizierVersion) {
+		atClient := cloudpb.NewArtifactTrackerClient(m.cloudClient)
+		vzVersionState := getVizierVersionState(atClient, vz)
+		if vzVersionState != nil && !isOk(vzVersionState) {
+			return vzVersionState
+		}
 	}
 
 	if !vz.Spec.UseEtcdOperator && !isOk(m.pvcState) {
 		return m.pvcState
 	}
 
+	if vz.Spec.UseEtcdOperator && m.etcdState.Reason == status.EtcdQuorumLost {
+		return m.etcdState
+	}
+
 	if !isOk(m.nodeState) {
 		return m.nodeState
 	}
 
+	podsState := m.getPodsState(vz)
+	if !isOk(podsState) {
+		return podsState
+	}
+
+	ccState := getCloudConnState(m.httpClient, m.podStates)
+	if !isOk(ccState) {
+		return ccState
+	}
+
+	// A nearly full metadata PVC only degrades the Vizier, so it is reported after all other failures.
+	if !vz.Spec.UseEtcdOperator && !isOk(m.pvcUsageState) {
+		return m.pvcUsageState
+	}
+
+	// Unhealthy etcd members only degrade the Vizier while the etcd cluster still has quorum.
+	if vz.Spec.UseEtcdOperator && !isOk(m.etcdState) {
+		return m.etcdState
+	}
+
+	return okState()
+}
+
+// getMetadataStorageState returns the state of the metadata PVC, or of the etcd cluster if the metadata is stored
+// in etcd instead.
+func (m *VizierMonitor) getMetadataStorageState(vz *pixiev1alpha1.Vizier) *vizierState {
+	if vz.Spec.UseEtcdOperator {
+		return m.etcdState
+	}
+	if !isOk(m.pvcState) {
+		return m.pvcState
+	}
+	return m.pvcUsageState
+}
+
+// recordMetadataStorageEvent records a warning event on the Vizier when its metadata storage becomes unhealthy,
+// once per failure reason.
+func (m *VizierMonitor) recordMetadataStorageEvent(vz *pixiev1alpha1.Vizier, state *vizierState) {
+	var reason status.VizierReason
+	if state != nil {
+		reason = state.Reason
+	}
+	if reason == m.lastStorageReason {
+		return
+	}
+	m.lastStorageReason = reason
+	if reason == "" || m.recorder == nil {
+		return
+	}
+	m.recorder.Event(vz, v1.EventTypeWarning, string(reason), status.GetMessageFromReason(reason))
+}
+
+// getPodsState determines the state of the Vizier's pods, excluding the cloud connector. Reports the first
+// state that fails, otherwise reports a healthy state.
+func (m *VizierMonitor) getPodsState(vz *pixiev1alpha1.Vizier) *vizierState {
 	podState := getControlPlanePodState(m.podStates)
 	if !isOk(podState) {
 		return podState
 	}
 
-	natsState := getNATSState(m.httpClient, m.podStates)
-	if !isOk(natsState) {
-		return natsState
+	// An external NATS cluster has no pods in the Vizier namespace, so the monitor only checks that it is reachable.
+	if vz.Spec.ExternalNATS == nil {
+		natsState := getNATSState(m.httpClient, m.podStates)
+		if !isOk(natsState) {
+			return natsState
+		}
+	} else {
+		natsState := getExternalNATSState(m.ctx, m.clientset, m.namespace, vz.Spec.ExternalNATS)
+		if !isOk(natsState) {
+			return natsState
+		}
 	}
-
-	pemResourceState := getPEMResourceLimitsState(m.podStates)
-	if !isOk(pemResourceState) {
-		return pemResourceState
+	// The NATS pods may be running while the message bus is partitioned, so it is also probed directly.
+	if isCheckEnabled(vz, pixiev1alpha1.MonitorCheckNATSConnectivity) {
+		natsConnState := m.getNATSConnectivityState(vz)
+		if !isOk(natsConnState) {
+			return natsConnState
+		}
 	}
 
-	pemCrashingState := getPEMCrashingState(m.podStates)
-	if !isOk(pemCrashingState) {
-		return pemCrashingState
+	if isCheckEnabled(vz, pixiev1alpha1.MonitorCheckPEMResources) {
+		pemResourceState := getPEMResourceLimitsState(m.podStates)
+		if !isOk(pemResourceState) {
+			return pemResourceState
+		}
 	}
 
-	ccState := getCloudConnState(m.httpClient, m.podStates)
-	if !isOk(ccState) {
-		return ccState
+	if isCheckEnabled(vz, pixiev1alpha1.MonitorCheckPEMCrashing) {
+		return getPEMCrashingState(m.podStates, getPEMCrashingThreshold(vz))
 	}
-
 	return okState()
 }
 
@@ -487,7 +646,7 @@ func translateReasonToPhase(reason status.VizierReason) pixiev1alpha1.VizierPhas
 	if reason == "" {
 		return pixiev1alpha1.VizierPhaseHealthy
 	}
-	if reason == status.CloudConnectorMissing {
+	if reason == status.CloudConnectorMissing || reason == status.CloudConnectorHeartbeatFailed {
 		return pixiev1alpha1.VizierPhaseDisconnected
 	}
 	if reason == status.PEMsSomeInsufficientMemory {
@@ -499,6 +658,12 @@ func translateReasonToPhase(reason status.VizierReason) pixiev1alpha1.VizierPhas
 	if reason == status.PEMsHighFailureRate {
 		return pixiev1alpha1.VizierPhaseDegraded
 	}
+	if reason == status.MetadataPVCNearlyFull {
+		return pixiev1alpha1.VizierPhaseDegraded
+	}
+	if reason == status.EtcdMembersUnhealthy {
+		return pixiev1alpha1.VizierPhaseDegraded
+	}
 	return pixiev1alpha1.VizierPhaseUnhealthy
 }
 
@@ -577,20 +742,42 @@ func (m *VizierMonitor) runReconciler() {
 				log.WithError(err).Error("Failed to get vizier")
 				continue
 			}
-
-			vizierState := m.getVizierState(vz)
-			vz.Status.VizierPhase = translateReasonToPhase(vizierState.Reason)
-			vz.Status.VizierReason = string(vizierState.Reason)
-
-			vz.Status.Message = status.GetMessageFromReason(vizierState.Reason)
-			// Default to the VizierReason if the message is empty.
-			if vz.Status.Message == "" {
-				vz.Status.Message = vz.Status.VizierReason
+			t.Reset(getMonitorInterval(vz))
+
+			switch {
+			case !isCheckEnabled(vz, pixiev1alpha1.MonitorCheckMetadataStorage):
+				m.etcdState, m.pvcUsageState = okState(), okState()
+			case vz.Spec.UseEtcdOperator:
+				m.etcdState = m.getEtcdClusterState()
+			default:
+				m.pvcUsageState = m.getMetadataPVCUsageState()
 			}
+			// The Vizier's status only changes once the new state has been observed by enough consecutive checks.
+			vizierState := m.stateDebouncer.observe(m.getVizierState(vz), getMonitorFailureThreshold(vz))
+			prevPhase := vz.Status.VizierPhase
+			setVizierState(vz, vizierState, time.Now())
+
+			// A healthy Vizier implies that the pods and cloud connector are healthy, otherwise these are
+			// checked individually as the Vizier state only reports the first failure.
+			podsState, ccState := vizierState, vizierState
+			if !isOk(vizierState) {
+				podsState = m.getPodsState(vz)
+				ccState = getCloudConnState(m.httpClient, m.podStates)
+			}
+			setHealthConditions(vz, podsState, ccState)
+			storageState := m.getMetadataStorageState(vz)
+			setMetadataStorageCondition(vz, storageState)
+			m.recordMetadataStorageEvent(vz, storageState)
+			vz.Status.Components = getComponentsStatus(m.podStates, vz.Status.Components, ccState, time.Now())
+			recordVizierHealth(vz, prevPhase)
 			err = m.vzUpdate(context.Background(), vz)
 			if err != nil {
 				log.WithError(err).Error("Failed to update vizier status")
 			}
+			m.notifyWebhooks(vz, prevPhase, time.Now())
+
+			// Stuck pods are only restarted if the Vizier opted into automatic remediation.
+			m.remediator.remediate(m.ctx, vz, getAllPods(m.podStates), time.Now())
 
 			if vizierState != okState() {
 				err := m.repairVizier(vizierState)
@@ -611,7 +798,7 @@ func queryPodStatusz(client HTTPClient, pod *v1.Pod) (bool, string) {
 		port = pod.Spec.Containers[0].Ports[0].ContainerPort
 	}
 
-	resp, err := client.Get(fmt.Sprintf("https://%s:%d/statusz", podIP, port))
+	resp, err := client.Get(fmt.Sprintf("https://%s/statusz", net.JoinHostPort(podIP, strconv.Itoa(int(port)))))
 	if err != nil {
 		log.WithError(err).Error("Error making statusz call")
 		return false, ""
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const (
+	// The shortest interval at which the monitor may check the Vizier's health.
+	minMonitorInterval = 5 * time.Second
+)
+
+// getMonitorInterval returns how often the monitor checks the Vizier's health.
+func getMonitorInterval(vz *v1alpha1.Vizier) time.Duration {
+	if p := vz.Spec.Monitor; p != nil && p.Interval != nil && p.Interval.Duration >= minMonitorInterval {
+		return p.Interval.Duration
+	}
+	return statuszCheckInterval
+}
+
+// getMonitorFailureThreshold returns the number of consecutive checks which must observe a new state before the
+// Vizier's status changes to it.
+func getMonitorFailureThreshold(vz *v1alpha1.Vizier) int {
+	if p := vz.Spec.Monitor; p != nil && p.FailureThreshold > 0 {
+		return int(p.FailureThreshold)
+	}
+	return 1
+}
+
+// getPEMCrashingThreshold returns the fraction of PEMs which must be crashing for the Vizier to be degraded.
+func getPEMCrashingThreshold(vz *v1alpha1.Vizier) float64 {
+	if p := vz.Spec.Monitor; p != nil && p.PEMCrashingPercent > 0 {
+		return float64(p.PEMCrashingPercent) / 100
+	}
+	return pemCrashingThreshold
+}
+
+// isCheckEnabled returns whether the monitor should run the given health check.
+func isCheckEnabled(vz *v1alpha1.Vizier, check v1alpha1.MonitorCheck) bool {
+	if vz.Spec.Monitor == nil {
+		return true
+	}
+	for _, c := range vz.Spec.Monitor.DisabledChecks {
+		if c == check {
+			return false
+		}
+	}
+	return true
+}
+
+// validateMonitor checks the monitor's interval, which the CRD schema cannot validate.
+func validateMonitor(p *v1alpha1.MonitorParams) error {
+	if p == nil || p.Interval == nil {
+		return nil
+	}
+	if p.Interval.Duration < minMonitorInterval {
+		return fmt.Errorf("spec.monitor.interval %s must be at least %s", p.Interval.Duration, minMonitorInterval)
+	}
+	return nil
+}
+
+// stateDebouncer only reports a new state once it has been observed by several consecutive checks, so that a check
+// which fails intermittently does not make the Vizier's status flap.
+type stateDebouncer struct {
+	reported  *vizierState
+	candidate *vizierState
+	count     int
+}
+
+// observe records the latest state, and returns the state which should be reported.
+func (d *stateDebouncer) observe(state *vizierState, threshold int) *vizierState {
+	if d.reported == nil || threshold <= 1 || state.Reason == d.reported.Reason {
+		d.reported = state
+		d.candidate = nil
+		d.count = 0
+		return state
+	}
+
+	if d.candidate != nil && d.candidate.Reason == state.Reason {
+		d.count++
+	} else {
+		d.count = 1
+	}
+	d.candidate = state
+	if d.count >= threshold {
+		d.reported = state
+		d.candidate = nil
+		d.count = 0
+	}
+	return d.reported
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/shared/status"
+)
+
+func TestMonitorParams(t *testing.T) {
+	vz := &v1alpha1.Vizier{}
+	assert.Equal(t, statuszCheckInterval, getMonitorInterval(vz))
+	assert.Equal(t, 1, getMonitorFailureThreshold(vz))
+	assert.Equal(t, pemCrashingThreshold, getPEMCrashingThreshold(vz))
+	assert.True(t, isCheckEnabled(vz, v1alpha1.MonitorCheckPEMCrashing))
+
+	vz.Spec.Monitor = &v1alpha1.MonitorParams{
+		Interval:           &metav1.Duration{Duration: time.Minute},
+		FailureThreshold:   3,
+		PEMCrashingPercent: 50,
+		DisabledChecks:     []v1alpha1.MonitorCheck{v1alpha1.MonitorCheckPEMCrashing},
+	}
+	assert.Equal(t, time.Minute, getMonitorInterval(vz))
+	assert.Equal(t, 3, getMonitorFailureThreshold(vz))
+	assert.Equal(t, 0.5, getPEMCrashingThreshold(vz))
+	assert.False(t, isCheckEnabled(vz, v1alpha1.MonitorCheckPEMCrashing))
+	assert.True(t, isCheckEnabled(vz, v1alpha1.MonitorCheckVizierVersion))
+	assert.NoError(t, validateMonitor(vz.Spec.Monitor))
+
+	vz.Spec.Monitor.Interval = &metav1.Duration{Duration: time.Second}
+	assert.Equal(t, statuszCheckInterval, getMonitorInterval(vz))
+	assert.Error(t, validateMonitor(vz.Spec.Monitor))
+}
+
+func TestStateDebouncer(t *testing.T) {
+	var d stateDebouncer
+	failing := &vizierState{Reason: status.PEMsHighFailureRate}
+
+	assert.True(t, isOk(d.observe(okState(), 3)))
+	// A failure is only reported once it is observed by 3 consecutive checks.
+	assert.True(t, isOk(d.observe(failing, 3)))
+	assert.True(t, isOk(d.observe(failing, 3)))
+	assert.Equal(t, status.PEMsHighFailureRate, d.observe(failing, 3).Reason)
+
+	// Recovering is debounced too, and an interrupted streak starts over.
+	assert.Equal(t, status.PEMsHighFailureRate, d.observe(okState(), 3).Reason)
+	assert.Equal(t, status.PEMsHighFailureRate, d.observe(okState(), 3).Reason)
+	assert.Equal(t, status.PEMsHighFailureRate, d.observe(&vizierState{Reason: status.PEMsAllFailing}, 3).Reason)
+	assert.Equal(t, status.PEMsHighFailureRate, d.observe(okState(), 3).Reason)
+
+	// Without a threshold, every state is reported immediately.
+	assert.True(t, isOk(d.observe(okState(), 1)))
+}
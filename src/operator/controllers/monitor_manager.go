@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"k8s.io/apimachinery/pkg/types"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+// How often the monitored Viziers are synced with the Viziers in the cluster.
+const monitorSyncPeriod = time.Minute
+
+// monitorManager runs a VizierMonitor for each Vizier, so that Viziers in different namespaces are monitored at the
+// same time rather than the monitor following whichever Vizier was last reconciled.
+type monitorManager struct {
+	mu       sync.Mutex
+	monitors map[types.NamespacedName]*VizierMonitor
+}
+
+// ensure starts a monitor for the Vizier if there is none, or if the running monitor uses a different connection to
+// Pixie Cloud. newMonitor is only called if a monitor is started.
+func (mm *monitorManager) ensure(key types.NamespacedName, cloudClient *grpc.ClientConn, newMonitor func() *VizierMonitor) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if m, ok := mm.monitors[key]; ok {
+		if m.cloudClient == cloudClient {
+			return nil
+		}
+		m.Quit()
+		delete(mm.monitors, key)
+	}
+
+	m := newMonitor()
+	err := m.InitAndStartMonitor(cloudClient)
+	if err != nil {
+		return err
+	}
+	if mm.monitors == nil {
+		mm.monitors = make(map[types.NamespacedName]*VizierMonitor)
+	}
+	mm.monitors[key] = m
+	log.WithField("vizier", key).Info("Started Vizier monitor")
+	return nil
+}
+
+// stop stops the Vizier's monitor, if it is running.
+func (mm *monitorManager) stop(key types.NamespacedName) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if m, ok := mm.monitors[key]; ok {
+		m.Quit()
+		delete(mm.monitors, key)
+		log.WithField("vizier", key).Info("Stopped Vizier monitor")
+	}
+}
+
+// retain stops the monitors of all Viziers other than the given ones.
+func (mm *monitorManager) retain(keys map[types.NamespacedName]bool) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	for key, m := range mm.monitors {
+		if !keys[key] {
+			m.Quit()
+			delete(mm.monitors, key)
+			log.WithField("vizier", key).Info("Stopped Vizier monitor")
+		}
+	}
+}
+
+// startMonitor starts monitoring the Vizier, unless it is already being monitored.
+func (r *VizierReconciler) startMonitor(vz *v1alpha1.Vizier) error {
+	cloudClient, err := r.cloudConns.get(r.CloudConn, vz.Spec.CloudAddr, vz.Spec.DevCloudNamespace)
+	if err != nil {
+		return err
+	}
+	key := types.NamespacedName{Namespace: vz.Namespace, Name: vz.Name}
+	return r.monitors.ensure(key, cloudClient, func() *VizierMonitor {
+		return &VizierMonitor{
+			namespace:      vz.Namespace,
+			namespacedName: key,
+			vzUpdate:       r.Status().Update,
+			vzGet:          r.Get,
+			clientset:      r.Clientset,
+			vzSpecUpdate:   r.Update,
+			recorder:       r.Recorder,
+		}
+	})
+}
+
+// manageMonitors periodically syncs the monitors with the Viziers in the cluster, starting monitors for Viziers which
+// are not being monitored and stopping those of Viziers which were deleted. This ensures each Vizier is monitored
+// regardless of the order in which Viziers are reconciled. All monitors are stopped when the context is done.
+func (r *VizierReconciler) manageMonitors(ctx context.Context) error {
+	defer r.monitors.retain(nil)
+
+	t := time.NewTicker(monitorSyncPeriod)
+	defer t.Stop()
+	for {
+		r.syncMonitors(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+func (r *VizierReconciler) syncMonitors(ctx context.Context) {
+	var viziersList v1alpha1.VizierList
+	err := r.List(ctx, &viziersList)
+	if err != nil {
+		log.WithError(err).Error("Unable to list the vizier objects")
+		return
+	}
+
+	keys := make(map[types.NamespacedName]bool)
+	for i := range viziersList.Items {
+		vz := &viziersList.Items[i]
+		// Viziers which are being deleted are no longer monitored.
+		if !vz.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+		keys[types.NamespacedName{Namespace: vz.Namespace, Name: vz.Name}] = true
+		err = r.startMonitor(vz)
+		if err != nil {
+			log.WithError(err).WithField("vizier", vz.Name).Error("Failed to start vizier monitor")
+		}
+	}
+	r.monitors.retain(keys)
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestMonitorManager(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	started := 0
+	newMonitor := func(key types.NamespacedName) func() *VizierMonitor {
+		return func() *VizierMonitor {
+			started++
+			return &VizierMonitor{
+				namespace:      key.Namespace,
+				namespacedName: key,
+				clientset:      clientset,
+				vzGet: func(context.Context, types.NamespacedName, client.Object) error {
+					return nil
+				},
+			}
+		}
+	}
+
+	var mm monitorManager
+	defer mm.retain(nil)
+	a := types.NamespacedName{Namespace: "pl-a", Name: "vizier"}
+	b := types.NamespacedName{Namespace: "pl-b", Name: "vizier"}
+	conn := &grpc.ClientConn{}
+
+	// Viziers in different namespaces are monitored at the same time.
+	require.NoError(t, mm.ensure(a, conn, newMonitor(a)))
+	require.NoError(t, mm.ensure(b, conn, newMonitor(b)))
+	require.NoError(t, mm.ensure(a, conn, newMonitor(a)))
+	assert.Equal(t, 2, started)
+	assert.Len(t, mm.monitors, 2)
+	monitorA := mm.monitors[a]
+
+	// The monitor is restarted if the Vizier's connection to Pixie Cloud changes.
+	require.NoError(t, mm.ensure(a, &grpc.ClientConn{}, newMonitor(a)))
+	assert.Equal(t, 3, started)
+	assert.Error(t, monitorA.ctx.Err())
+	assert.NotEqual(t, monitorA, mm.monitors[a])
+
+	monitorB := mm.monitors[b]
+	mm.stop(b)
+	assert.Error(t, monitorB.ctx.Err())
+	assert.Len(t, mm.monitors, 1)
+	// Stopping a Vizier which is not monitored is a no-op.
+	mm.stop(b)
+
+	monitorA = mm.monitors[a]
+	mm.retain(map[types.NamespacedName]bool{b: true})
+	assert.Error(t, monitorA.ctx.Err())
+	assert.Empty(t, mm.monitors)
+}
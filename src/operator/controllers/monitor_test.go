@@ -25,6 +25,7 @@ import (
 	"io"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/gogo/protobuf/types"
 	"github.com/golang/mock/gomock"
@@ -75,6 +76,7 @@ func TestMonitor_queryPodStatusz(t *testing.T) {
 		responses: map[string]string{
 			"https://127.0.0.1:8080/statusz":  "",
 			"https://127.0.0.3:50100/statusz": "CloudConnectFailed",
+			"https://[fd00::3]:50100/statusz": "CloudConnectFailed",
 		},
 	}
 
@@ -106,6 +108,13 @@ func TestMonitor_queryPodStatusz(t *testing.T) {
 			expectedStatus: "CloudConnectFailed",
 			expectedOK:     false,
 		},
+		{
+			name:           "unhealthy IPv6",
+			podPort:        50100,
+			podIP:          "fd00::3",
+			expectedStatus: "CloudConnectFailed",
+			expectedOK:     false,
+		},
 	}
 
 	for _, test := range tests {
@@ -162,6 +171,13 @@ func TestMonitor_getCloudConnState(t *testing.T) {
 			expectedVizierPhase: v1alpha1.VizierPhaseUnhealthy,
 			expectedReason:      status.CloudConnectorFailedToConnect,
 		},
+		{
+			name:                "heartbeats stopped",
+			cloudConnStatusz:    "CloudConnectorHeartbeatFailed",
+			cloudConnPhase:      v1.PodRunning,
+			expectedVizierPhase: v1alpha1.VizierPhaseDisconnected,
+			expectedReason:      status.CloudConnectorHeartbeatFailed,
+		},
 	}
 
 	for _, test := range tests {
@@ -1066,9 +1082,115 @@ func TestMonitor_getPEMCrashingState(t *testing.T) {
 				})
 			}
 
-			state := getPEMCrashingState(pems)
+			state := getPEMCrashingState(pems, pemCrashingThreshold)
 			assert.Equal(t, test.expectedReason, state.Reason)
 			assert.Equal(t, test.expectedVizierPhase, translateReasonToPhase(state.Reason))
 		})
 	}
 }
+
+func TestGetPodFailureDetail(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   v1.PodStatus
+		expected string
+	}{
+		{
+			name: "waiting container",
+			status: v1.PodStatus{
+				Phase: v1.PodPending,
+				ContainerStatuses: []v1.ContainerStatus{
+					{Name: "app", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+					{Name: "sidecar", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{
+						Reason:  "ImagePullBackOff",
+						Message: `Back-off pulling image "sidecar:1.0"`,
+					}}},
+				},
+			},
+			expected: `container sidecar is waiting: ImagePullBackOff: Back-off pulling image "sidecar:1.0"`,
+		},
+		{
+			name: "failed init container",
+			status: v1.PodStatus{
+				Phase: v1.PodPending,
+				InitContainerStatuses: []v1.ContainerStatus{
+					{Name: "wait-for-nats", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}}},
+				},
+			},
+			expected: "container wait-for-nats terminated: Error (exit code 1)",
+		},
+		{
+			name:     "evicted",
+			status:   v1.PodStatus{Phase: v1.PodFailed, Message: "The node was low on resource: memory."},
+			expected: "The node was low on resource: memory.",
+		},
+		{
+			name:     "no details",
+			status:   v1.PodStatus{Phase: v1.PodUnknown},
+			expected: "pod is Unknown",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, getPodFailureDetail(&v1.Pod{Status: test.status}))
+		})
+	}
+}
+
+func TestMonitor_getControlPlanePodState_FailingPod(t *testing.T) {
+	pods := &concurrentPodMap{unsafeMap: make(map[string]map[string]*podWrapper)}
+	pods.write(vizierMetadataLabel, "vizier-metadata-0", &podWrapper{pod: &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vizier-metadata-0", Labels: map[string]string{"plane": "control"}},
+		Status: v1.PodStatus{
+			Phase: v1.PodPending,
+			Conditions: []v1.PodCondition{{
+				Type:    v1.PodScheduled,
+				Status:  v1.ConditionFalse,
+				Reason:  v1.PodReasonUnschedulable,
+				Message: "0/3 nodes are available: 3 node(s) had volume node affinity conflict.",
+			}},
+		},
+	}})
+
+	state := getControlPlanePodState(pods)
+	assert.Equal(t, status.ControlPlaneFailedToSchedule, state.Reason)
+	assert.Equal(t, "vizier-metadata-0", state.Pod)
+	assert.Equal(t, "0/3 nodes are available: 3 node(s) had volume node affinity conflict.", state.Detail)
+}
+
+func TestGetStateMessage(t *testing.T) {
+	assert.Equal(t, "", getStateMessage(okState()))
+	assert.Equal(t, "failed to reach cloud", getStateMessage(&vizierState{Reason: "failed to reach cloud"}))
+	assert.Equal(t,
+		status.GetMessageFromReason(status.NATSPodFailed)+" (pod pl-nats-0: monitoring endpoint returned 503)",
+		getStateMessage(&vizierState{Reason: status.NATSPodFailed, Pod: "pl-nats-0", Detail: "monitoring endpoint returned 503"}))
+	assert.Equal(t, "statusz unavailable (pod vizier-cloud-connector-1)",
+		getStateMessage(&vizierState{Reason: "statusz unavailable", Pod: "vizier-cloud-connector-1"}))
+}
+
+func TestSetVizierState(t *testing.T) {
+	vz := &v1alpha1.Vizier{}
+	start := time.Unix(1000, 0)
+
+	setVizierState(vz, okState(), start)
+	assert.Equal(t, v1alpha1.VizierPhaseHealthy, vz.Status.VizierPhase)
+	assert.Equal(t, start.Unix(), vz.Status.LastVizierReasonTime.Unix())
+
+	failing := &vizierState{Reason: status.ControlPlanePodsFailed, Pod: "vizier-metadata-0", Detail: "pod is Failed"}
+	setVizierState(vz, failing, start.Add(time.Minute))
+	assert.Equal(t, v1alpha1.VizierPhaseUnhealthy, vz.Status.VizierPhase)
+	assert.Equal(t, string(status.ControlPlanePodsFailed), vz.Status.VizierReason)
+	assert.Equal(t, "vizier-metadata-0", vz.Status.UnhealthyPod)
+	assert.Contains(t, vz.Status.Message, "(pod vizier-metadata-0: pod is Failed)")
+	assert.Equal(t, start.Add(time.Minute).Unix(), vz.Status.LastVizierReasonTime.Unix())
+
+	// The time is kept while the reason is unchanged.
+	setVizierState(vz, failing, start.Add(2*time.Minute))
+	assert.Equal(t, start.Add(time.Minute).Unix(), vz.Status.LastVizierReasonTime.Unix())
+
+	setVizierState(vz, okState(), start.Add(3*time.Minute))
+	assert.Equal(t, "", vz.Status.UnhealthyPod)
+	assert.Equal(t, "", vz.Status.Message)
+	assert.Equal(t, start.Add(3*time.Minute).Unix(), vz.Status.LastVizierReasonTime.Unix())
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ensureVizierNamespace creates the Vizier's namespace with the given labels if it does not exist. If the namespace
+// exists, any of the given labels which it is missing or which have a different value are set on it. Other labels on
+// the namespace are left unchanged.
+func ensureVizierNamespace(ctx context.Context, clientset kubernetes.Interface, namespace string, nsLabels map[string]string) error {
+	ns, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = clientset.CoreV1().Namespaces().Create(ctx, &v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: namespace, Labels: nsLabels},
+		}, metav1.CreateOptions{})
+		if k8serrors.IsAlreadyExists(err) {
+			// The namespace was created in the meantime, so its labels are added on the next deploy.
+			return nil
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	missing := make(map[string]string)
+	for k, v := range nsLabels {
+		if existing, ok := ns.Labels[k]; !ok || existing != v {
+			missing[k] = v
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": missing},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = clientset.CoreV1().Namespaces().Patch(ctx, namespace, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEnsureVizierNamespace_Create(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	nsLabels := map[string]string{"pod-security.kubernetes.io/enforce": "privileged"}
+
+	require.NoError(t, ensureVizierNamespace(context.Background(), clientset, "pl", nsLabels))
+
+	ns, err := clientset.CoreV1().Namespaces().Get(context.Background(), "pl", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"pod-security.kubernetes.io/enforce": "privileged"}, ns.Labels)
+}
+
+func TestEnsureVizierNamespace_AddLabels(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "pl",
+			Labels: map[string]string{
+				"team":                               "observability",
+				"pod-security.kubernetes.io/enforce": "baseline",
+			},
+		},
+	})
+	nsLabels := map[string]string{"pod-security.kubernetes.io/enforce": "privileged"}
+
+	require.NoError(t, ensureVizierNamespace(context.Background(), clientset, "pl", nsLabels))
+
+	ns, err := clientset.CoreV1().Namespaces().Get(context.Background(), "pl", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"team":                               "observability",
+		"pod-security.kubernetes.io/enforce": "privileged",
+	}, ns.Labels)
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/shared/status"
+)
+
+const (
+	// How long the NATS probe waits to connect, and for its message to be delivered.
+	natsProbeTimeout = 5 * time.Second
+	// The secret holding the Vizier service certs, which Vizier uses to connect to the NATS deployed with it.
+	serviceTLSCertsName = "service-tls-certs"
+)
+
+// natsProbeConfig is how the operator connects to the NATS cluster which the Vizier uses.
+type natsProbeConfig struct {
+	url         string
+	certsSecret string
+	credsSecret string
+}
+
+// getNATSProbeConfig returns how to connect to the Vizier's NATS cluster with the same certs and credentials as the
+// Vizier itself.
+func getNATSProbeConfig(namespace string, vz *v1alpha1.Vizier) natsProbeConfig {
+	conf := natsProbeConfig{
+		url:         fmt.Sprintf("tls://%s.%s.svc:4222", natsLabel, namespace),
+		certsSecret: serviceTLSCertsName,
+	}
+	if ext := vz.Spec.ExternalNATS; ext != nil {
+		conf.url = ext.URL
+		if ext.TLSSecretName != "" {
+			conf.certsSecret = ext.TLSSecretName
+		}
+		conf.credsSecret = ext.CredentialsSecretName
+	}
+	return conf
+}
+
+// getNATSClientTLSConfig returns the client TLS config from a secret containing the "ca.crt", "client.crt" and
+// "client.key".
+func getNATSClientTLSConfig(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*tls.Config, error) {
+	s, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get NATS certs secret %s: %w", name, err)
+	}
+	cert, err := tls.X509KeyPair(s.Data["client.crt"], s.Data["client.key"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid client cert in NATS certs secret %s: %w", name, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(s.Data["ca.crt"]) {
+		return nil, fmt.Errorf("no CA certs found in NATS certs secret %s", name)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, RootCAs: pool}, nil
+}
+
+// probeNATS connects to the Vizier's NATS cluster and round-trips a message, so that a partitioned message bus is
+// detected even when all of the pods are running.
+func probeNATS(ctx context.Context, clientset kubernetes.Interface, namespace string, vz *v1alpha1.Vizier) error {
+	conf := getNATSProbeConfig(namespace, vz)
+	tlsConfig, err := getNATSClientTLSConfig(ctx, clientset, namespace, conf.certsSecret)
+	if err != nil {
+		return err
+	}
+	opts := []nats.Option{nats.Secure(tlsConfig)}
+
+	if conf.credsSecret != "" {
+		s, err := clientset.CoreV1().Secrets(namespace).Get(ctx, conf.credsSecret, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get NATS credentials secret %s: %w", conf.credsSecret, err)
+		}
+		// The NATS client only reads user credentials from a file.
+		f, err := os.CreateTemp("", "nats-creds")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		_, err = f.Write(s.Data[externalNATSCredsKey])
+		f.Close()
+		if err != nil {
+			return err
+		}
+		opts = append(opts, nats.UserCredentials(f.Name()))
+	}
+	return roundTripNATS(conf.url, opts...)
+}
+
+// roundTripNATS publishes a message on a new inbox, and waits for it to be delivered back to the subscriber.
+func roundTripNATS(url string, opts ...nats.Option) error {
+	opts = append(opts, nats.Timeout(natsProbeTimeout), nats.NoReconnect())
+	nc, err := nats.Connect(url, opts...)
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	inbox := nats.NewInbox()
+	sub, err := nc.SubscribeSync(inbox)
+	if err != nil {
+		return err
+	}
+	err = nc.Publish(inbox, []byte("ping"))
+	if err != nil {
+		return err
+	}
+	_, err = sub.NextMsg(natsProbeTimeout)
+	return err
+}
+
+// getNATSConnectivityState probes the Vizier's NATS cluster, reporting a failure separately from the health of the
+// NATS pods.
+func (m *VizierMonitor) getNATSConnectivityState(vz *v1alpha1.Vizier) *vizierState {
+	if m.natsProbe == nil {
+		return okState()
+	}
+	err := m.natsProbe(m.ctx, vz)
+	if err != nil {
+		log.WithError(err).Info("NATS probe failed")
+		return &vizierState{Reason: status.NATSConnectivityFailed}
+	}
+	return okState()
+}
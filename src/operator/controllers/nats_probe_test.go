@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/shared/status"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+func TestGetNATSProbeConfig(t *testing.T) {
+	assert.Equal(t, natsProbeConfig{
+		url:         "tls://pl-nats.pl.svc:4222",
+		certsSecret: "service-tls-certs",
+	}, getNATSProbeConfig("pl", &v1alpha1.Vizier{}))
+
+	assert.Equal(t, natsProbeConfig{
+		url:         "tls://nats.nats-system.svc:4222",
+		certsSecret: "nats-client-certs",
+		credsSecret: "nats-creds",
+	}, getNATSProbeConfig("pl", &v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{ExternalNATS: &v1alpha1.ExternalNATSParams{
+		URL:                   "tls://nats.nats-system.svc:4222",
+		TLSSecretName:         "nats-client-certs",
+		CredentialsSecretName: "nats-creds",
+	}}}))
+
+	// Without a TLS secret, Vizier connects to an external NATS cluster with its service certs.
+	conf := getNATSProbeConfig("pl", &v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{ExternalNATS: &v1alpha1.ExternalNATSParams{
+		URL: "tls://nats.nats-system.svc:4222",
+	}}})
+	assert.Equal(t, "service-tls-certs", conf.certsSecret)
+}
+
+func TestGetNATSClientTLSConfig(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "service-tls-certs", Namespace: "pl"},
+		Data:       map[string][]byte{"ca.crt": []byte("not a cert")},
+	})
+
+	_, err := getNATSClientTLSConfig(context.Background(), clientset, "pl", "service-tls-certs")
+	assert.Error(t, err)
+	_, err = getNATSClientTLSConfig(context.Background(), clientset, "pl", "missing")
+	assert.Error(t, err)
+}
+
+func TestRoundTripNATS(t *testing.T) {
+	nc, cleanup := testingutils.MustStartTestNATS(t)
+	url := nc.ConnectedUrl()
+	err := roundTripNATS(url)
+	cleanup()
+	require.NoError(t, err)
+
+	// The probe fails once the server is gone.
+	assert.Error(t, roundTripNATS(url))
+}
+
+func TestVizierMonitor_getNATSConnectivityState(t *testing.T) {
+	m := &VizierMonitor{ctx: context.Background()}
+	assert.True(t, isOk(m.getNATSConnectivityState(&v1alpha1.Vizier{})))
+
+	m.natsProbe = func(context.Context, *v1alpha1.Vizier) error { return nil }
+	assert.True(t, isOk(m.getNATSConnectivityState(&v1alpha1.Vizier{})))
+
+	m.natsProbe = func(context.Context, *v1alpha1.Vizier) error { return errors.New("nats: timeout") }
+	assert.Equal(t, status.NATSConnectivityFailed, m.getNATSConnectivityState(&v1alpha1.Vizier{}).Reason)
+}
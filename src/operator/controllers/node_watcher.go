@@ -115,14 +115,12 @@ func (nw *nodeWatcher) start(ctx context.Context) {
 	}
 
 	informer := nw.factory.Core().V1().Nodes().Informer()
-	stopper := make(chan struct{})
-	defer close(stopper)
 	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    nw.onAdd,
 		UpdateFunc: nw.onUpdate,
 		DeleteFunc: nw.onDelete,
 	})
-	informer.Run(stopper)
+	informer.Run(ctx.Done())
 }
 
 func (nw *nodeWatcher) onAdd(obj interface{}) {
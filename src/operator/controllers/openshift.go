@@ -0,0 +1,160 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// The API group which is only served by OpenShift clusters.
+	openShiftSecurityGroup = "security.openshift.io"
+	// The SCC which allows the PEM's host access.
+	openShiftPrivilegedSCC = "privileged"
+	// The name of the ClusterRole and RoleBinding which allow privileged Vizier pods to use the privileged SCC.
+	openShiftSCCRoleName = "pl-vizier-privileged-scc"
+	// The service account used by the Vizier pods which require host access, such as the PEM and Kelvin.
+	privilegedServiceAccount = "default"
+)
+
+// isOpenShift returns whether the cluster is an OpenShift cluster, which requires Vizier pods to be admitted by a
+// SecurityContextConstraint.
+func isOpenShift(clientset kubernetes.Interface) (bool, error) {
+	groups, err := clientset.Discovery().ServerGroups()
+	if err != nil {
+		return false, err
+	}
+	for _, g := range groups.Groups {
+		if g.Name == openShiftSecurityGroup {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// deployOpenShiftSCCBindings allows the Vizier pods which require host access to use the privileged SCC. All other
+// Vizier pods run under the restricted SCC, which is available to all service accounts.
+func deployOpenShiftSCCBindings(ctx context.Context, clientset kubernetes.Interface, namespace string, vizierName string) error {
+	labels := map[string]string{operatorAnnotation: vizierName, vizierNamespaceLabel: namespace}
+
+	role := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: openShiftSCCRoleName, Labels: labels},
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups:     []string{openShiftSecurityGroup},
+			Resources:     []string{"securitycontextconstraints"},
+			ResourceNames: []string{openShiftPrivilegedSCC},
+			Verbs:         []string{"use"},
+		}},
+	}
+	_, err := clientset.RbacV1().ClusterRoles().Create(ctx, role, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = clientset.RbacV1().ClusterRoles().Update(ctx, role, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: openShiftSCCRoleName, Namespace: namespace, Labels: labels},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     openShiftSCCRoleName,
+		},
+		Subjects: []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      privilegedServiceAccount,
+			Namespace: namespace,
+		}},
+	}
+	_, err = clientset.RbacV1().RoleBindings(namespace).Create(ctx, binding, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = clientset.RbacV1().RoleBindings(namespace).Update(ctx, binding, metav1.UpdateOptions{})
+	}
+	return err
+}
+
+// updateOpenShiftSecurityContext removes the fixed user and group IDs from pods which do not require host access, so
+// that they can be admitted by the restricted SCC, which assigns IDs from the namespace's range instead. IDs which are
+// set in the Vizier's pod policy are still applied afterwards.
+func updateOpenShiftSecurityContext(res map[string]interface{}) {
+	md, ok, err := unstructured.NestedFieldNoCopy(res, "spec", "template", "spec")
+	if !ok || err != nil {
+		return
+	}
+	podSpec, ok := md.(map[string]interface{})
+	if !ok || requiresHostAccess(podSpec) {
+		return
+	}
+
+	if sc, ok := podSpec["securityContext"].(map[string]interface{}); ok {
+		for _, field := range []string{"runAsUser", "runAsGroup", "fsGroup"} {
+			delete(sc, field)
+		}
+	}
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _ := podSpec[field].([]interface{})
+		for _, c := range containers {
+			castedContainer, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if sc, ok := castedContainer["securityContext"].(map[string]interface{}); ok {
+				delete(sc, "runAsUser")
+				delete(sc, "runAsGroup")
+			}
+		}
+	}
+}
+
+// requiresHostAccess returns whether the pod uses the host's namespaces, host paths or privileged containers, which
+// are only allowed by the privileged SCC.
+func requiresHostAccess(podSpec map[string]interface{}) bool {
+	for _, field := range []string{"hostNetwork", "hostPID", "hostIPC"} {
+		if enabled, _ := podSpec[field].(bool); enabled {
+			return true
+		}
+	}
+	volumes, _ := podSpec["volumes"].([]interface{})
+	for _, v := range volumes {
+		if castedVolume, ok := v.(map[string]interface{}); ok && castedVolume["hostPath"] != nil {
+			return true
+		}
+	}
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _ := podSpec[field].([]interface{})
+		for _, c := range containers {
+			castedContainer, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			sc, _ := castedContainer["securityContext"].(map[string]interface{})
+			if privileged, _ := sc["privileged"].(bool); privileged {
+				return true
+			}
+		}
+	}
+	return false
+}
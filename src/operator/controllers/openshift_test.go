@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestIsOpenShift(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	openShift, err := isOpenShift(clientset)
+	require.NoError(t, err)
+	assert.False(t, openShift)
+
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{GroupVersion: "security.openshift.io/v1"},
+	}
+	openShift, err = isOpenShift(clientset)
+	require.NoError(t, err)
+	assert.True(t, openShift)
+}
+
+func TestDeployOpenShiftSCCBindings(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	ctx := context.Background()
+	// Deploying the bindings must be idempotent, since it happens on every update.
+	require.NoError(t, deployOpenShiftSCCBindings(ctx, clientset, "pl", "vizier"))
+	require.NoError(t, deployOpenShiftSCCBindings(ctx, clientset, "pl", "vizier"))
+
+	role, err := clientset.RbacV1().ClusterRoles().Get(ctx, openShiftSCCRoleName, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"privileged"}, role.Rules[0].ResourceNames)
+	assert.Equal(t, "vizier", role.Labels[operatorAnnotation])
+	assert.Equal(t, "pl", role.Labels[vizierNamespaceLabel])
+
+	binding, err := clientset.RbacV1().RoleBindings("pl").Get(ctx, openShiftSCCRoleName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Len(t, binding.Subjects, 1)
+	assert.Equal(t, "default", binding.Subjects[0].Name)
+	assert.Equal(t, "pl", binding.Subjects[0].Namespace)
+}
+
+func TestUpdateOpenShiftSecurityContext(t *testing.T) {
+	newPodSpec := func() map[string]interface{} {
+		return map[string]interface{}{
+			"securityContext": map[string]interface{}{"runAsUser": int64(10100), "fsGroup": int64(10100), "runAsNonRoot": true},
+			"containers": []interface{}{
+				map[string]interface{}{
+					"name":            "app",
+					"securityContext": map[string]interface{}{"runAsUser": int64(10100), "readOnlyRootFilesystem": true},
+				},
+			},
+		}
+	}
+
+	res := newTestPodResource(newPodSpec())
+	updateOpenShiftSecurityContext(res)
+	podSpec := testPodSpec(res)
+	assert.Equal(t, map[string]interface{}{"runAsNonRoot": true}, podSpec["securityContext"])
+	container := podSpec["containers"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"readOnlyRootFilesystem": true}, container["securityContext"])
+
+	// Pods which require host access run under the privileged SCC, so are left unchanged.
+	hostPodSpec := newPodSpec()
+	hostPodSpec["hostPID"] = true
+	res = newTestPodResource(hostPodSpec)
+	updateOpenShiftSecurityContext(res)
+	assert.Equal(t, newPodSpec()["securityContext"], testPodSpec(res)["securityContext"])
+}
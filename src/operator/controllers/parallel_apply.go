@@ -0,0 +1,128 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"sort"
+
+	"golang.org/x/sync/errgroup"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// The maximum number of resources which are applied at once.
+const applyConcurrency = 8
+
+// applyKindOrder is the order in which the kinds of resources are applied, so that resources are only applied once
+// the resources they depend on exist. Kinds which are not listed are applied last.
+var applyKindOrder = []string{
+	"Namespace",
+	"CustomResourceDefinition",
+	"PodSecurityPolicy",
+	"ServiceAccount",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolumeClaim",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Service",
+	"StatefulSet",
+	"Deployment",
+	"DaemonSet",
+	"Job",
+	"CronJob",
+	"HorizontalPodAutoscaler",
+	"PodDisruptionBudget",
+}
+
+// groupResourcesByKind groups the resources by their kind, in the order in which the kinds should be applied.
+// Unlisted kinds are ordered by their first appearance, and resources keep their order within each group.
+func groupResourcesByKind(resources []*k8s.Resource) [][]*k8s.Resource {
+	rank := make(map[string]int, len(applyKindOrder))
+	for i, kind := range applyKindOrder {
+		rank[kind] = i
+	}
+
+	var kinds []string
+	groups := make(map[string][]*k8s.Resource)
+	for _, res := range resources {
+		kind := res.GVK.Kind
+		if _, ok := groups[kind]; !ok {
+			kinds = append(kinds, kind)
+		}
+		groups[kind] = append(groups[kind], res)
+	}
+
+	getRank := func(kind string) int {
+		if r, ok := rank[kind]; ok {
+			return r
+		}
+		return len(applyKindOrder)
+	}
+	sort.SliceStable(kinds, func(i, j int) bool {
+		return getRank(kinds[i]) < getRank(kinds[j])
+	})
+
+	ordered := make([][]*k8s.Resource, len(kinds))
+	for i, kind := range kinds {
+		ordered[i] = groups[kind]
+	}
+	return ordered
+}
+
+// applyInKindOrder applies the resources one kind at a time. The resources of each kind are applied concurrently, by
+// at most the given number of workers, and the next kind is only applied once all of them have been applied.
+func applyInKindOrder(resources []*k8s.Resource, concurrency int, apply func(*k8s.Resource) error) error {
+	for _, group := range groupResourcesByKind(resources) {
+		var g errgroup.Group
+		sem := make(chan struct{}, concurrency)
+		for _, res := range group {
+			res := res
+			sem <- struct{}{}
+			g.Go(func() error {
+				defer func() { <-sem }()
+				return apply(res)
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyResourcesConcurrently applies the resources like k8s.ApplyResources, but applies resources of the same kind
+// concurrently. The API resources are only discovered once, rather than for each resource.
+func applyResourcesConcurrently(clientset kubernetes.Interface, config *rest.Config, resources []*k8s.Resource, namespace string, allowUpdate bool) error {
+	apiGroupResources, err := restmapper.GetAPIGroupResources(clientset.Discovery())
+	if err != nil {
+		return err
+	}
+	rm := restmapper.NewDiscoveryRESTMapper(apiGroupResources)
+
+	return applyInKindOrder(resources, applyConcurrency, func(res *k8s.Resource) error {
+		return k8s.ApplyResourcesWithMapper(config, rm, []*k8s.Resource{res}, namespace, nil, allowUpdate)
+	})
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func TestGroupResourcesByKind(t *testing.T) {
+	kelvin := newTestPatchResource("Deployment", "kelvin", nil)
+	pem := newTestPatchResource("DaemonSet", "vizier-pem", nil)
+	svc := newTestPatchResource("Service", "kelvin-service", nil)
+	query := newTestPatchResource("Deployment", "vizier-query-broker", nil)
+	custom := newTestPatchResource("VerticalPodAutoscaler", "kelvin", nil)
+	sa := newTestPatchResource("ServiceAccount", "pl-vizier-crd", nil)
+
+	groups := groupResourcesByKind([]*k8s.Resource{kelvin, pem, custom, svc, query, sa})
+	assert.Equal(t, [][]*k8s.Resource{
+		{sa},
+		{svc},
+		{kelvin, query},
+		{pem},
+		{custom},
+	}, groups)
+}
+
+func TestApplyInKindOrder(t *testing.T) {
+	var resources []*k8s.Resource
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		resources = append(resources, newTestPatchResource("Deployment", name, nil))
+	}
+	svc := newTestPatchResource("Service", "svc", nil)
+	resources = append(resources, svc)
+
+	var mu sync.Mutex
+	var applied []string
+	var running, maxRunning int32
+	err := applyInKindOrder(resources, 2, func(res *k8s.Resource) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		mu.Lock()
+		defer mu.Unlock()
+		if n > maxRunning {
+			maxRunning = n
+		}
+		applied = append(applied, res.Object.GetName())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, applied, 7)
+	// Services are applied before deployments.
+	assert.Equal(t, "svc", applied[0])
+	assert.LessOrEqual(t, maxRunning, int32(2))
+}
+
+func TestApplyInKindOrder_Error(t *testing.T) {
+	svc := newTestPatchResource("Service", "svc", nil)
+	kelvin := newTestPatchResource("Deployment", "kelvin", nil)
+
+	var applied []string
+	err := applyInKindOrder([]*k8s.Resource{kelvin, svc}, 2, func(res *k8s.Resource) error {
+		applied = append(applied, res.Object.GetName())
+		return errors.New("apply failed")
+	})
+	assert.Error(t, err)
+	// Later kinds are not applied once an earlier kind fails.
+	assert.Equal(t, []string{"svc"}, applied)
+}
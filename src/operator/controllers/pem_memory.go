@@ -0,0 +1,126 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const (
+	// The name of the PEM container in the PEM daemonset.
+	pemContainerName = "pem"
+	// The percentage of a node's allocatable memory which auto-sized PEMs may use, if none is specified.
+	defaultPEMNodeMemoryPercent = 25
+)
+
+// getAutoSizedPEMMemory returns the memory for PEMs, as a percentage of the allocatable memory of the smallest node
+// which PEMs are scheduled on. Since all PEMs share the same daemonset, PEMs on larger nodes are given the same
+// memory as the smallest node.
+func getAutoSizedPEMMemory(ctx context.Context, clientset kubernetes.Interface, vz *v1alpha1.Vizier) (*resource.Quantity, error) {
+	params := vz.Spec.PEMMemoryAutoSize
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	percent := int64(defaultPEMNodeMemoryPercent)
+	if params.NodeMemoryPercent > 0 {
+		percent = int64(params.NodeMemoryPercent)
+	}
+
+	var memory *resource.Quantity
+	for i := range nodes.Items {
+		n := &nodes.Items[i]
+		if !isPEMNode(vz.Spec.Pod, n) {
+			continue
+		}
+		allocatable, ok := n.Status.Allocatable[v1.ResourceMemory]
+		if !ok {
+			continue
+		}
+		nodeMemory := resource.NewQuantity(allocatable.Value()*percent/100, resource.BinarySI)
+		if memory == nil || nodeMemory.Cmp(*memory) < 0 {
+			memory = nodeMemory
+		}
+	}
+	if memory == nil {
+		return nil, errors.New("no nodes which PEMs are scheduled on report their allocatable memory")
+	}
+
+	minMemory := resource.MustParse(minDefaultPEMMemoryLimit)
+	if params.Min != nil {
+		minMemory = *params.Min
+	}
+	if memory.Cmp(minMemory) < 0 {
+		memory = &minMemory
+	}
+	if params.Max != nil && memory.Cmp(*params.Max) > 0 {
+		memory = params.Max
+	}
+	return memory, nil
+}
+
+// isPEMNode returns whether PEMs may be scheduled on the node, according to the node selectors in the pod policy.
+func isPEMNode(pod *v1alpha1.PodPolicy, n *v1.Node) bool {
+	if pod == nil {
+		return true
+	}
+	nodeLabels := labels.Set(n.Labels)
+	if !labels.SelectorFromSet(getComponentNodeSelector(pod, vizierPemLabel)).Matches(nodeLabels) {
+		return false
+	}
+	for k, v := range pod.PEMExcludeNodeSelector {
+		if nodeLabels.Has(k) && (v == "" || nodeLabels.Get(k) == v) {
+			return false
+		}
+	}
+	return true
+}
+
+// updatePEMMemory sets both the memory request and limit of the PEM container, so that PEMs are not evicted before
+// reaching their limit.
+func updatePEMMemory(memory *resource.Quantity, res map[string]interface{}) error {
+	containers, ok, err := unstructured.NestedSlice(res, "spec", "template", "spec", "containers")
+	if !ok || err != nil {
+		return err
+	}
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok || container["name"] != pemContainerName {
+			continue
+		}
+		for _, field := range []string{"requests", "limits"} {
+			err = unstructured.SetNestedField(container, memory.String(), "resources", field, string(v1.ResourceMemory))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return unstructured.SetNestedSlice(res, containers, "spec", "template", "spec", "containers")
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func newTestNode(name string, memory string, nodeLabels map[string]string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nodeLabels},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{v1.ResourceMemory: resource.MustParse(memory)},
+		},
+	}
+}
+
+func TestGetAutoSizedPEMMemory(t *testing.T) {
+	maxMemory := resource.MustParse("6Gi")
+	minMemory := resource.MustParse("512Mi")
+
+	tests := []struct {
+		name     string
+		pod      *v1alpha1.PodPolicy
+		params   *v1alpha1.PEMMemoryAutoSizeParams
+		expected string
+	}{
+		{
+			name:     "default percent of smallest node",
+			params:   &v1alpha1.PEMMemoryAutoSizeParams{},
+			expected: "4Gi",
+		},
+		{
+			name:     "custom percent",
+			params:   &v1alpha1.PEMMemoryAutoSizeParams{NodeMemoryPercent: 50},
+			expected: "8Gi",
+		},
+		{
+			name: "node selector",
+			pod: &v1alpha1.PodPolicy{
+				NodeSelector: map[string]string{"pool": "large"},
+			},
+			params:   &v1alpha1.PEMMemoryAutoSizeParams{},
+			expected: "16Gi",
+		},
+		{
+			name: "excluded nodes",
+			pod: &v1alpha1.PodPolicy{
+				PEMExcludeNodeSelector: map[string]string{"pool": "small"},
+			},
+			params:   &v1alpha1.PEMMemoryAutoSizeParams{},
+			expected: "16Gi",
+		},
+		{
+			name:     "clamped to max",
+			params:   &v1alpha1.PEMMemoryAutoSizeParams{NodeMemoryPercent: 100, Max: &maxMemory},
+			expected: "6Gi",
+		},
+		{
+			name:     "clamped to default min",
+			params:   &v1alpha1.PEMMemoryAutoSizeParams{NodeMemoryPercent: 1},
+			expected: "1Gi",
+		},
+		{
+			name:     "clamped to custom min",
+			params:   &v1alpha1.PEMMemoryAutoSizeParams{NodeMemoryPercent: 1, Min: &minMemory},
+			expected: "512Mi",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(
+				newTestNode("small", "16Gi", map[string]string{"pool": "small"}),
+				newTestNode("large", "64Gi", map[string]string{"pool": "large"}),
+			)
+			vz := &v1alpha1.Vizier{
+				Spec: v1alpha1.VizierSpec{Pod: tc.pod, PEMMemoryAutoSize: tc.params},
+			}
+
+			memory, err := getAutoSizedPEMMemory(context.Background(), clientset, vz)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, memory.String())
+		})
+	}
+}
+
+func TestGetAutoSizedPEMMemory_NoNodes(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newTestNode("small", "16Gi", map[string]string{"pool": "small"}),
+	)
+	vz := &v1alpha1.Vizier{
+		Spec: v1alpha1.VizierSpec{
+			Pod:               &v1alpha1.PodPolicy{NodeSelector: map[string]string{"pool": "large"}},
+			PEMMemoryAutoSize: &v1alpha1.PEMMemoryAutoSizeParams{},
+		},
+	}
+
+	_, err := getAutoSizedPEMMemory(context.Background(), clientset, vz)
+	assert.Error(t, err)
+}
+
+func TestGetDeployChecksum_AutoSizedPEMMemory(t *testing.T) {
+	vz := &v1alpha1.Vizier{
+		Spec: v1alpha1.VizierSpec{PEMMemoryAutoSize: &v1alpha1.PEMMemoryAutoSizeParams{}},
+	}
+	checksum, err := getDeployChecksum(context.Background(), fake.NewSimpleClientset(newTestNode("small", "16Gi", nil)), vz)
+	require.NoError(t, err)
+
+	// The PEMs are redeployed when the nodes are resized, even though the spec is unchanged.
+	clientset := fake.NewSimpleClientset(newTestNode("small", "32Gi", nil))
+	resized, err := getDeployChecksum(context.Background(), clientset, vz)
+	require.NoError(t, err)
+	assert.NotEqual(t, checksum, resized)
+
+	same, err := getDeployChecksum(context.Background(), clientset, vz)
+	require.NoError(t, err)
+	assert.Equal(t, resized, same)
+}
+
+func TestUpdatePEMMemory(t *testing.T) {
+	res := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name": "pem",
+							"resources": map[string]interface{}{
+								"limits": map[string]interface{}{
+									"cpu":    "1",
+									"memory": "2Gi",
+								},
+							},
+						},
+						map[string]interface{}{
+							"name": "sidecar",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	memory := resource.MustParse("4Gi")
+	require.NoError(t, updatePEMMemory(&memory, res))
+
+	containers := res["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"limits": map[string]interface{}{
+			"cpu":    "1",
+			"memory": "4Gi",
+		},
+		"requests": map[string]interface{}{
+			"memory": "4Gi",
+		},
+	}, containers[0].(map[string]interface{})["resources"])
+	assert.Equal(t, map[string]interface{}{"name": "sidecar"}, containers[1])
+}
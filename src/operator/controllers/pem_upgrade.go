@@ -0,0 +1,276 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const (
+	// How long canary PEMs must remain healthy before the upgrade proceeds, if no verification period is specified.
+	defaultCanaryVerificationPeriod = 300 * time.Second
+	// How long to wait for the PEM daemonset to be updated and for the canary PEMs to become ready.
+	pemRolloutTimeout = 5 * time.Minute
+	// How often the PEMs are checked during a staged rollout.
+	pemRolloutPollInterval = 10 * time.Second
+)
+
+// setPEMOnDeleteUpdateStrategy sets the update strategy of the PEM daemonset to OnDelete, so that updated PEMs are
+// only rolled out when the operator deletes the outdated PEM pods.
+func setPEMOnDeleteUpdateStrategy(res map[string]interface{}) error {
+	return unstructured.SetNestedMap(res, map[string]interface{}{
+		"type": string(appsv1.OnDeleteDaemonSetStrategyType),
+	}, "spec", "updateStrategy")
+}
+
+// rolloutPEMs performs a staged rollout of the PEM daemonset, which must have an OnDelete update strategy. The
+// outdated PEMs on the canary nodes are deleted first, so that they are replaced with updated PEMs. Once the canary
+// PEMs have been healthy for the verification period, the daemonset is switched to a rolling update, which upgrades
+// the remaining PEMs. If the canary PEMs are unhealthy, the remaining PEMs are left on their current version.
+func rolloutPEMs(ctx context.Context, clientset kubernetes.Interface, namespace string, strategy *v1alpha1.PEMUpgradeStrategy) error {
+	dsClient := clientset.AppsV1().DaemonSets(namespace)
+
+	// Wait for the daemonset controller to create a revision for the updated PEMs.
+	var ds *appsv1.DaemonSet
+	err := wait.PollImmediateWithContext(ctx, pemRolloutPollInterval, pemRolloutTimeout, func(ctx context.Context) (bool, error) {
+		var err error
+		ds, err = dsClient.Get(ctx, vizierPemLabel, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return ds.Status.ObservedGeneration >= ds.Generation, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for the PEM daemonset to be updated: %w", err)
+	}
+
+	if ds.Status.UpdatedNumberScheduled < ds.Status.DesiredNumberScheduled {
+		err = rolloutCanaryPEMs(ctx, clientset, ds, strategy)
+		if err != nil {
+			return err
+		}
+	}
+
+	updateStrategy := appsv1.DaemonSetUpdateStrategy{Type: appsv1.RollingUpdateDaemonSetStrategyType}
+	if strategy.MaxUnavailable != nil {
+		updateStrategy.RollingUpdate = &appsv1.RollingUpdateDaemonSet{MaxUnavailable: strategy.MaxUnavailable}
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"updateStrategy": updateStrategy},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = dsClient.Patch(ctx, vizierPemLabel, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// rolloutCanaryPEMs replaces the outdated PEMs on the canary nodes, and verifies that the updated PEMs are healthy.
+func rolloutCanaryPEMs(ctx context.Context, clientset kubernetes.Interface, ds *appsv1.DaemonSet, strategy *v1alpha1.PEMUpgradeStrategy) error {
+	hash, err := getDaemonSetRevisionHash(ctx, clientset, ds)
+	if err != nil {
+		return err
+	}
+
+	pods, err := clientset.CoreV1().Pods(ds.Namespace).List(ctx, metav1.ListOptions{LabelSelector: "name=" + vizierPemLabel})
+	if err != nil {
+		return err
+	}
+
+	var canaryNodes map[string]bool
+	if len(strategy.CanaryNodeSelector) > 0 {
+		nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{
+			LabelSelector: labels.SelectorFromSet(strategy.CanaryNodeSelector).String(),
+		})
+		if err != nil {
+			return err
+		}
+		canaryNodes = make(map[string]bool)
+		for _, n := range nodes.Items {
+			canaryNodes[n.Name] = true
+		}
+	}
+
+	canaries, err := selectCanaryPEMs(pods.Items, hash, canaryNodes, strategy.CanaryNodes, int(ds.Status.DesiredNumberScheduled))
+	if err != nil {
+		return err
+	}
+	if len(canaries) == 0 {
+		return fmt.Errorf("no outdated PEMs are running on the canary nodes")
+	}
+
+	nodeNames := make([]string, len(canaries))
+	for i, p := range canaries {
+		log.WithField("node", p.Spec.NodeName).Info("Upgrading canary PEM")
+		err = clientset.CoreV1().Pods(ds.Namespace).Delete(ctx, p.Name, metav1.DeleteOptions{})
+		if err != nil {
+			return err
+		}
+		nodeNames[i] = p.Spec.NodeName
+	}
+
+	period := defaultCanaryVerificationPeriod
+	if strategy.VerificationPeriodSeconds > 0 {
+		period = time.Duration(strategy.VerificationPeriodSeconds) * time.Second
+	}
+	return verifyCanaryPEMs(ctx, clientset, ds.Namespace, hash, nodeNames, period)
+}
+
+// getDaemonSetRevisionHash returns the revision hash of the daemonset's current pod template, which the daemonset
+// controller sets as a label on all pods which are up to date.
+func getDaemonSetRevisionHash(ctx context.Context, clientset kubernetes.Interface, ds *appsv1.DaemonSet) (string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(ds.Spec.Selector)
+	if err != nil {
+		return "", err
+	}
+	revisions, err := clientset.AppsV1().ControllerRevisions(ds.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return "", err
+	}
+
+	var current *appsv1.ControllerRevision
+	for i, rev := range revisions.Items {
+		if !metav1.IsControlledBy(&revisions.Items[i], ds) {
+			continue
+		}
+		if current == nil || rev.Revision > current.Revision {
+			current = &revisions.Items[i]
+		}
+	}
+	if current == nil {
+		return "", fmt.Errorf("no revisions found for daemonset %s", ds.Name)
+	}
+	return current.Labels[appsv1.DefaultDaemonSetUniqueLabelKey], nil
+}
+
+// selectCanaryPEMs returns the outdated PEMs which should be upgraded first. If canaryNodes is non-nil, only PEMs
+// on those nodes are selected. The number of canaries is scaled against the total number of PEMs.
+func selectCanaryPEMs(pods []v1.Pod, hash string, canaryNodes map[string]bool, count *intstr.IntOrString, total int) ([]v1.Pod, error) {
+	numCanaries := 1
+	if count != nil {
+		var err error
+		numCanaries, err = intstr.GetScaledValueFromIntOrPercent(count, total, true)
+		if err != nil {
+			return nil, err
+		}
+		if numCanaries < 1 {
+			numCanaries = 1
+		}
+	}
+
+	var outdated []v1.Pod
+	for _, p := range pods {
+		if p.Labels[appsv1.DefaultDaemonSetUniqueLabelKey] == hash || p.Spec.NodeName == "" {
+			continue
+		}
+		if canaryNodes != nil && !canaryNodes[p.Spec.NodeName] {
+			continue
+		}
+		outdated = append(outdated, p)
+	}
+
+	// Choose the canaries deterministically, so that retries upgrade the same nodes.
+	sort.Slice(outdated, func(i, j int) bool {
+		return outdated[i].Spec.NodeName < outdated[j].Spec.NodeName
+	})
+	if len(outdated) > numCanaries {
+		outdated = outdated[:numCanaries]
+	}
+	return outdated, nil
+}
+
+// verifyCanaryPEMs waits for the updated PEMs on the given nodes to become ready, and then checks that they remain
+// healthy for the verification period.
+func verifyCanaryPEMs(ctx context.Context, clientset kubernetes.Interface, namespace string, hash string, nodes []string, period time.Duration) error {
+	nodeSet := make(map[string]bool)
+	for _, n := range nodes {
+		nodeSet[n] = true
+	}
+
+	var readySince time.Time
+	err := wait.PollImmediateWithContext(ctx, pemRolloutPollInterval, pemRolloutTimeout+period, func(ctx context.Context) (bool, error) {
+		pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "name=" + vizierPemLabel})
+		if err != nil {
+			return false, err
+		}
+
+		ready := 0
+		for i, p := range pods.Items {
+			if p.Labels[appsv1.DefaultDaemonSetUniqueLabelKey] != hash || !nodeSet[p.Spec.NodeName] {
+				continue
+			}
+			if isPodCrashing(&pods.Items[i]) || hasContainerRestarts(&pods.Items[i]) {
+				return false, fmt.Errorf("canary PEM %s on node %s is failing", p.Name, p.Spec.NodeName)
+			}
+			if isPodReady(&pods.Items[i]) {
+				ready++
+			}
+		}
+
+		if ready < len(nodes) {
+			if !readySince.IsZero() {
+				return false, fmt.Errorf("canary PEMs became unready during verification")
+			}
+			return false, nil
+		}
+		if readySince.IsZero() {
+			readySince = time.Now()
+		}
+		return time.Since(readySince) >= period, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out waiting for canary PEMs to become ready")
+	}
+	return err
+}
+
+func isPodReady(pod *v1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == v1.PodReady {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func hasContainerRestarts(pod *v1.Pod) bool {
+	for _, c := range pod.Status.ContainerStatuses {
+		if c.RestartCount > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestPEM(name string, node string, hash string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "pl",
+			Labels: map[string]string{
+				"name":                                vizierPemLabel,
+				appsv1.DefaultDaemonSetUniqueLabelKey: hash,
+			},
+		},
+		Spec: v1.PodSpec{NodeName: node},
+		Status: v1.PodStatus{
+			Phase:      v1.PodRunning,
+			Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}},
+		},
+	}
+}
+
+func TestSelectCanaryPEMs(t *testing.T) {
+	pods := []v1.Pod{
+		*newTestPEM("pem-d", "node-d", "old"),
+		*newTestPEM("pem-a", "node-a", "new"),
+		*newTestPEM("pem-c", "node-c", "old"),
+		*newTestPEM("pem-b", "node-b", "old"),
+	}
+	tenPercent := intstr.FromString("10%")
+	fiftyPercent := intstr.FromString("50%")
+	five := intstr.FromInt(5)
+
+	tests := []struct {
+		name          string
+		canaryNodes   map[string]bool
+		count         *intstr.IntOrString
+		expectedNodes []string
+	}{
+		{
+			name:          "default",
+			expectedNodes: []string{"node-b"},
+		},
+		{
+			name:          "percentage rounded up",
+			count:         &tenPercent,
+			expectedNodes: []string{"node-b"},
+		},
+		{
+			name:          "percentage",
+			count:         &fiftyPercent,
+			expectedNodes: []string{"node-b", "node-c"},
+		},
+		{
+			name:          "more than outdated",
+			count:         &five,
+			expectedNodes: []string{"node-b", "node-c", "node-d"},
+		},
+		{
+			name:          "node selector",
+			canaryNodes:   map[string]bool{"node-a": true, "node-d": true},
+			count:         &five,
+			expectedNodes: []string{"node-d"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			canaries, err := selectCanaryPEMs(pods, "new", test.canaryNodes, test.count, len(pods))
+			require.NoError(t, err)
+			nodes := make([]string, len(canaries))
+			for i, p := range canaries {
+				nodes[i] = p.Spec.NodeName
+			}
+			assert.Equal(t, test.expectedNodes, nodes)
+		})
+	}
+}
+
+func TestVerifyCanaryPEMs(t *testing.T) {
+	crashing := newTestPEM("pem-b", "node-b", "new")
+	crashing.Status.ContainerStatuses = []v1.ContainerStatus{{
+		State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+	}}
+	restarted := newTestPEM("pem-b", "node-b", "new")
+	restarted.Status.ContainerStatuses = []v1.ContainerStatus{{RestartCount: 1}}
+
+	tests := []struct {
+		name        string
+		pods        []runtime.Object
+		expectError bool
+	}{
+		{
+			name: "healthy",
+			pods: []runtime.Object{
+				newTestPEM("pem-a", "node-a", "new"),
+				newTestPEM("pem-b", "node-b", "new"),
+				newTestPEM("pem-c", "node-c", "old"),
+			},
+		},
+		{
+			name: "crashing",
+			pods: []runtime.Object{
+				newTestPEM("pem-a", "node-a", "new"),
+				crashing,
+			},
+			expectError: true,
+		},
+		{
+			name: "restarted",
+			pods: []runtime.Object{
+				newTestPEM("pem-a", "node-a", "new"),
+				restarted,
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(test.pods...)
+			err := verifyCanaryPEMs(context.Background(), clientset, "pl", "new", []string{"node-a", "node-b"}, 0)
+			if test.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSetPEMOnDeleteUpdateStrategy(t *testing.T) {
+	res := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"updateStrategy": map[string]interface{}{
+				"type":          "RollingUpdate",
+				"rollingUpdate": map[string]interface{}{"maxUnavailable": int64(1)},
+			},
+		},
+	}
+	require.NoError(t, setPEMOnDeleteUpdateStrategy(res))
+	assert.Equal(t, map[string]interface{}{"type": "OnDelete"}, res["spec"].(map[string]interface{})["updateStrategy"])
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const (
+	// The default time before a component is restarted again after its first restart.
+	defaultRemediationMinBackoff = 5 * time.Minute
+	// The backoff of a component's restarts stops doubling once it reaches this.
+	remediationMaxBackoff = time.Hour
+	// The default number of pods which may be restarted within an hour.
+	defaultRemediationRestartsPerHour = 5
+)
+
+// remediationBackoff tracks the restarts of a single component.
+type remediationBackoff struct {
+	lastRestart time.Time
+	delay       time.Duration
+}
+
+// podRemediator restarts Vizier pods which are stuck in CrashLoopBackOff or ImagePullBackOff by deleting them, so
+// that their controllers recreate them. The restarts of each component are backed off, and the total number of
+// restarts is limited, so that a Vizier which fails for reasons a restart cannot fix is not churned indefinitely.
+type podRemediator struct {
+	clientset kubernetes.Interface
+	namespace string
+	recorder  record.EventRecorder
+
+	// The times of the restarts within the last hour.
+	restarts []time.Time
+	// The backoff of each component which has been restarted, keyed by getRemediationKey.
+	backoffs map[string]*remediationBackoff
+}
+
+func newPodRemediator(clientset kubernetes.Interface, namespace string, recorder record.EventRecorder) *podRemediator {
+	return &podRemediator{
+		clientset: clientset,
+		namespace: namespace,
+		recorder:  recorder,
+		backoffs:  make(map[string]*remediationBackoff),
+	}
+}
+
+// getStuckReason returns the reason that one of the pod's containers is waiting, if the pod is stuck in a state
+// which restarting it may fix.
+func getStuckReason(pod *v1.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return ""
+	}
+	for _, statuses := range [][]v1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, c := range statuses {
+			if c.State.Waiting == nil {
+				continue
+			}
+			switch c.State.Waiting.Reason {
+			case "CrashLoopBackOff", "ImagePullBackOff":
+				return c.State.Waiting.Reason
+			}
+		}
+	}
+	return ""
+}
+
+// getRemediationKey identifies the component of the pod, so that its restarts stay backed off once it is recreated
+// with a new name. Pods of a DaemonSet are backed off per node.
+func getRemediationKey(pod *v1.Pod) string {
+	name := pod.Labels["name"]
+	if name == "" {
+		// Pods without a name label, such as those of the etcd StatefulSet, keep their name when recreated.
+		return pod.Name
+	}
+	if pod.Spec.NodeName == "" {
+		return name
+	}
+	return name + "/" + pod.Spec.NodeName
+}
+
+// remediate restarts the stuck pods whose components are not backed off, until the Vizier's hourly limit of restarts
+// is reached.
+func (r *podRemediator) remediate(ctx context.Context, vz *v1alpha1.Vizier, pods []*v1.Pod, now time.Time) {
+	params := vz.Spec.AutoRemediation
+	if params == nil {
+		return
+	}
+	minBackoff := defaultRemediationMinBackoff
+	if params.MinBackoff != nil && params.MinBackoff.Duration > 0 {
+		minBackoff = params.MinBackoff.Duration
+	}
+	maxRestarts := defaultRemediationRestartsPerHour
+	if params.MaxRestartsPerHour > 0 {
+		maxRestarts = int(params.MaxRestartsPerHour)
+	}
+
+	recent := r.restarts[:0]
+	for _, t := range r.restarts {
+		if now.Sub(t) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	r.restarts = recent
+
+	for _, pod := range pods {
+		reason := getStuckReason(pod)
+		if reason == "" {
+			continue
+		}
+		key := getRemediationKey(pod)
+		backoff, ok := r.backoffs[key]
+		if ok && now.Before(backoff.lastRestart.Add(backoff.delay)) {
+			continue
+		}
+		if len(r.restarts) >= maxRestarts {
+			log.WithField("pod", pod.Name).Info("Not restarting stuck pod, the hourly limit of restarts has been reached")
+			return
+		}
+
+		err := r.clientset.CoreV1().Pods(r.namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			log.WithError(err).WithField("pod", pod.Name).Error("Failed to restart stuck pod")
+			continue
+		}
+
+		switch {
+		case !ok:
+			backoff = &remediationBackoff{delay: minBackoff}
+			r.backoffs[key] = backoff
+		case now.Sub(backoff.lastRestart) > 2*backoff.delay:
+			// The component recovered for a while since its last restart.
+			backoff.delay = minBackoff
+		case backoff.delay*2 <= remediationMaxBackoff:
+			backoff.delay *= 2
+		}
+		backoff.lastRestart = now
+		r.restarts = append(r.restarts, now)
+
+		log.WithField("pod", pod.Name).WithField("reason", reason).Info("Restarted stuck pod")
+		if r.recorder != nil {
+			r.recorder.Eventf(vz, v1.EventTypeNormal, "PodRestarted", "Restarted pod %s which was stuck in %s, it will not be restarted again for %s",
+				pod.Name, reason, backoff.delay)
+		}
+	}
+}
+
+// getAllPods returns the pods which the monitor is watching.
+func getAllPods(pods *concurrentPodMap) []*v1.Pod {
+	pods.mapMu.Lock()
+	defer pods.mapMu.Unlock()
+
+	var all []*v1.Pod
+	for _, labelMap := range pods.unsafeMap {
+		for _, p := range labelMap {
+			all = append(all, p.pod)
+		}
+	}
+	return all
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func newTestStuckPod(name, nameLabel, node, waitingReason string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "pl", Labels: map[string]string{"name": nameLabel}},
+		Spec:       v1.PodSpec{NodeName: node},
+		Status: v1.PodStatus{
+			Phase: v1.PodRunning,
+			ContainerStatuses: []v1.ContainerStatus{
+				{Name: "app", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: waitingReason}}},
+			},
+		},
+	}
+}
+
+func TestGetStuckReason(t *testing.T) {
+	assert.Equal(t, "CrashLoopBackOff", getStuckReason(newTestStuckPod("kelvin-1", "kelvin", "", "CrashLoopBackOff")))
+	assert.Equal(t, "ImagePullBackOff", getStuckReason(newTestStuckPod("kelvin-1", "kelvin", "", "ImagePullBackOff")))
+	assert.Equal(t, "", getStuckReason(newTestStuckPod("kelvin-1", "kelvin", "", "ContainerCreating")))
+
+	deleting := newTestStuckPod("kelvin-1", "kelvin", "", "CrashLoopBackOff")
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	assert.Equal(t, "", getStuckReason(deleting))
+}
+
+func TestGetRemediationKey(t *testing.T) {
+	assert.Equal(t, "kelvin", getRemediationKey(newTestStuckPod("kelvin-abc", "kelvin", "", "")))
+	assert.Equal(t, "vizier-pem/node-1", getRemediationKey(newTestStuckPod("vizier-pem-abc", "vizier-pem", "node-1", "")))
+	assert.Equal(t, "pl-etcd-0", getRemediationKey(newTestStuckPod("pl-etcd-0", "", "node-1", "")))
+}
+
+func TestPodRemediator_remediate(t *testing.T) {
+	ctx := context.Background()
+	vz := &v1alpha1.Vizier{
+		ObjectMeta: metav1.ObjectMeta{Name: "pixie", Namespace: "pl"},
+		Spec: v1alpha1.VizierSpec{
+			AutoRemediation: &v1alpha1.AutoRemediationParams{
+				MinBackoff:         &metav1.Duration{Duration: time.Minute},
+				MaxRestartsPerHour: 3,
+			},
+		},
+	}
+	crashing := newTestStuckPod("kelvin-1", "kelvin", "node-1", "CrashLoopBackOff")
+	healthy := newTestStuckPod("vizier-query-broker-1", "vizier-query-broker", "node-1", "")
+	healthy.Status.ContainerStatuses[0].State = v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	clientset := fake.NewSimpleClientset(crashing, healthy)
+	recorder := record.NewFakeRecorder(10)
+	r := newPodRemediator(clientset, "pl", recorder)
+
+	deleted := func() []string {
+		var names []string
+		for _, action := range clientset.Actions() {
+			if action, ok := action.(k8stesting.DeleteAction); ok {
+				names = append(names, action.GetName())
+			}
+		}
+		return names
+	}
+
+	start := time.Unix(10000, 0)
+	r.remediate(ctx, vz, []*v1.Pod{crashing, healthy}, start)
+	assert.Equal(t, []string{"kelvin-1"}, deleted())
+	require.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Normal PodRestarted Restarted pod kelvin-1 which was stuck in CrashLoopBackOff")
+
+	// The recreated pod is backed off, even though it has a different name.
+	recreated := newTestStuckPod("kelvin-2", "kelvin", "node-1", "CrashLoopBackOff")
+	r.remediate(ctx, vz, []*v1.Pod{recreated}, start.Add(30*time.Second))
+	assert.Len(t, deleted(), 1)
+
+	r.remediate(ctx, vz, []*v1.Pod{recreated}, start.Add(time.Minute))
+	assert.Len(t, deleted(), 2)
+	// The backoff doubles with each restart.
+	r.remediate(ctx, vz, []*v1.Pod{recreated}, start.Add(2*time.Minute))
+	assert.Len(t, deleted(), 2)
+	r.remediate(ctx, vz, []*v1.Pod{recreated}, start.Add(3*time.Minute))
+	assert.Len(t, deleted(), 3)
+
+	// The hourly limit of restarts has been reached.
+	r.remediate(ctx, vz, []*v1.Pod{recreated}, start.Add(30*time.Minute))
+	assert.Len(t, deleted(), 3)
+	r.remediate(ctx, vz, []*v1.Pod{recreated}, start.Add(61*time.Minute))
+	assert.Len(t, deleted(), 4)
+
+	// Pods are not restarted unless the Vizier opted in.
+	r.remediate(ctx, &v1alpha1.Vizier{}, []*v1.Pod{newTestStuckPod("kelvin-3", "kelvin", "node-2", "ImagePullBackOff")}, start.Add(62*time.Minute))
+	assert.Len(t, deleted(), 4)
+}
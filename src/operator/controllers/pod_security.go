@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// The non-root user which restricted pods run as, if the pod does not already specify a user.
+	restrictedRunAsUser = int64(10100)
+	// The name of the volume which provides restricted containers with a writable /tmp, since their root filesystem
+	// is read-only.
+	restrictedTmpVolume = "restricted-tmp"
+)
+
+// updateRestrictedPodSecurity sets the security contexts required by the "restricted" Pod Security Standard on pods
+// which do not require host access. Settings which are already specified on the pod are left unchanged, except
+// that privilege escalation is always disallowed and all capabilities are always dropped. When running on OpenShift,
+// the user is assigned by the restricted SCC instead.
+func updateRestrictedPodSecurity(res map[string]interface{}, openShift bool) {
+	md, ok, err := unstructured.NestedFieldNoCopy(res, "spec", "template", "spec")
+	if !ok || err != nil {
+		return
+	}
+	podSpec, ok := md.(map[string]interface{})
+	if !ok || requiresHostAccess(podSpec) {
+		return
+	}
+
+	sc, _ := podSpec["securityContext"].(map[string]interface{})
+	if sc == nil {
+		sc = make(map[string]interface{})
+	}
+	sc["runAsNonRoot"] = true
+	if _, ok := sc["runAsUser"]; !ok && !openShift {
+		sc["runAsUser"] = restrictedRunAsUser
+	}
+	if _, ok := sc["seccompProfile"]; !ok {
+		sc["seccompProfile"] = map[string]interface{}{"type": string(v1.SeccompProfileTypeRuntimeDefault)}
+	}
+	podSpec["securityContext"] = sc
+
+	needsTmp := false
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _ := podSpec[field].([]interface{})
+		for _, c := range containers {
+			castedContainer, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			containerSC, _ := castedContainer["securityContext"].(map[string]interface{})
+			if containerSC == nil {
+				containerSC = make(map[string]interface{})
+			}
+			containerSC["allowPrivilegeEscalation"] = false
+			containerSC["capabilities"] = map[string]interface{}{"drop": []interface{}{"ALL"}}
+			if _, ok := containerSC["readOnlyRootFilesystem"]; !ok {
+				containerSC["readOnlyRootFilesystem"] = true
+				needsTmp = addTmpVolumeMount(castedContainer) || needsTmp
+			}
+			castedContainer["securityContext"] = containerSC
+		}
+	}
+
+	if !needsTmp {
+		return
+	}
+	volumes, _ := podSpec["volumes"].([]interface{})
+	podSpec["volumes"] = append(volumes, map[string]interface{}{
+		"name":     restrictedTmpVolume,
+		"emptyDir": map[string]interface{}{},
+	})
+}
+
+// addTmpVolumeMount mounts the writable tmp volume at /tmp in the container, unless /tmp is already mounted, and
+// returns whether the volume was mounted.
+func addTmpVolumeMount(container map[string]interface{}) bool {
+	mounts, _ := container["volumeMounts"].([]interface{})
+	for _, m := range mounts {
+		if castedMount, ok := m.(map[string]interface{}); ok && castedMount["mountPath"] == "/tmp" {
+			return false
+		}
+	}
+	container["volumeMounts"] = append(mounts, map[string]interface{}{
+		"name":      restrictedTmpVolume,
+		"mountPath": "/tmp",
+	})
+	return true
+}
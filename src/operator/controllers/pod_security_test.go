@@ -0,0 +1,87 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateRestrictedPodSecurity(t *testing.T) {
+	res := newTestPodResource(map[string]interface{}{
+		"securityContext": map[string]interface{}{"runAsUser": int64(1000)},
+		"containers": []interface{}{
+			map[string]interface{}{
+				"name":            "app",
+				"securityContext": map[string]interface{}{"capabilities": map[string]interface{}{"add": []interface{}{"NET_ADMIN"}}},
+			},
+			map[string]interface{}{
+				"name":            "writer",
+				"securityContext": map[string]interface{}{"readOnlyRootFilesystem": false},
+			},
+		},
+	})
+	updateRestrictedPodSecurity(res, false)
+
+	podSpec := testPodSpec(res)
+	assert.Equal(t, map[string]interface{}{
+		"runAsUser":      int64(1000),
+		"runAsNonRoot":   true,
+		"seccompProfile": map[string]interface{}{"type": "RuntimeDefault"},
+	}, podSpec["securityContext"])
+
+	containers := podSpec["containers"].([]interface{})
+	app := containers[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"allowPrivilegeEscalation": false,
+		"capabilities":             map[string]interface{}{"drop": []interface{}{"ALL"}},
+		"readOnlyRootFilesystem":   true,
+	}, app["securityContext"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": restrictedTmpVolume, "mountPath": "/tmp"},
+	}, app["volumeMounts"])
+
+	// Containers which need a writable root filesystem don't need the tmp volume.
+	writer := containers[1].(map[string]interface{})
+	assert.Equal(t, false, writer["securityContext"].(map[string]interface{})["readOnlyRootFilesystem"])
+	assert.NotContains(t, writer, "volumeMounts")
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": restrictedTmpVolume, "emptyDir": map[string]interface{}{}},
+	}, podSpec["volumes"])
+}
+
+func TestUpdateRestrictedPodSecurity_OpenShift(t *testing.T) {
+	res := newTestPodResource(map[string]interface{}{
+		"containers": []interface{}{map[string]interface{}{"name": "app"}},
+	})
+	updateRestrictedPodSecurity(res, true)
+	assert.NotContains(t, testPodSpec(res)["securityContext"], "runAsUser")
+}
+
+func TestUpdateRestrictedPodSecurity_HostAccess(t *testing.T) {
+	podSpec := map[string]interface{}{
+		"hostNetwork": true,
+		"containers":  []interface{}{map[string]interface{}{"name": "pem"}},
+	}
+	res := newTestPodResource(podSpec)
+	updateRestrictedPodSecurity(res, false)
+	assert.NotContains(t, testPodSpec(res), "securityContext")
+	assert.NotContains(t, testPodSpec(res)["containers"].([]interface{})[0], "securityContext")
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+// prunableResources are the namespaced resource types which are deleted when a resource of that type, labeled as
+// belonging to the Vizier, is no longer part of the Vizier's rendered YAMLs. Secrets and PVCs are never pruned, as
+// they hold certs and data which are not rendered from the YAMLs.
+var prunableResources = []schema.GroupVersionResource{
+	{Group: "apps", Version: "v1", Resource: "deployments"},
+	{Group: "apps", Version: "v1", Resource: "statefulsets"},
+	{Group: "apps", Version: "v1", Resource: "daemonsets"},
+	{Group: "autoscaling", Version: "v1", Resource: "horizontalpodautoscalers"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"},
+	{Version: "v1", Resource: "configmaps"},
+	{Version: "v1", Resource: "serviceaccounts"},
+	{Version: "v1", Resource: "services"},
+}
+
+// resourceKey identifies a resource by its kind and name, within the Vizier's namespace.
+type resourceKey struct {
+	kind string
+	name string
+}
+
+// getExpectedResources returns the keys of all resources which the operator deploys for the Vizier's current spec.
+func getExpectedResources(vz *v1alpha1.Vizier, yamlMap map[string]string) (map[resourceKey]bool, error) {
+	resources, err := renderVizierResources(vz, yamlMap)
+	if err != nil {
+		return nil, err
+	}
+	// Never prune against an empty set, which would delete the entire Vizier.
+	if len(resources) == 0 {
+		return nil, errors.New("no Vizier resources were rendered")
+	}
+
+	expected := make(map[resourceKey]bool)
+	for _, res := range resources {
+		expected[resourceKey{kind: res.GVK.Kind, name: res.Object.GetName()}] = true
+	}
+	// The output of the last dry run is kept until the Vizier is deleted.
+	expected[resourceKey{kind: "ConfigMap", name: dryRunConfigMapName}] = true
+	// The OpenShift SCC binding is deployed separately from the YAMLs.
+	if vz.Spec.OpenShift {
+		expected[resourceKey{kind: "RoleBinding", name: openShiftSCCRoleName}] = true
+	}
+	return expected, nil
+}
+
+// pruneOrphanedResources deletes the resources labeled as belonging to the Vizier which are no longer deployed for
+// its current spec, such as deployments which were renamed or removed in a newer Vizier version.
+func (r *VizierReconciler) pruneOrphanedResources(ctx context.Context, namespace string, vz *v1alpha1.Vizier, yamlMap map[string]string) error {
+	expected, err := getExpectedResources(vz, yamlMap)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := dynamic.NewForConfig(r.RestConfig)
+	if err != nil {
+		return err
+	}
+	return pruneResources(ctx, dynamicClient, namespace, vz.Name, expected)
+}
+
+// pruneResources deletes the Vizier's resources of the prunable types which are not in the expected set.
+func pruneResources(ctx context.Context, dynamicClient dynamic.Interface, namespace string, vizierName string, expected map[resourceKey]bool) error {
+	opts := metav1.ListOptions{LabelSelector: operatorAnnotation + "=" + vizierName}
+	for _, gvr := range prunableResources {
+		resClient := dynamicClient.Resource(gvr).Namespace(namespace)
+		list, err := resClient.List(ctx, opts)
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, item := range list.Items {
+			if expected[resourceKey{kind: item.GetKind(), name: item.GetName()}] {
+				continue
+			}
+			log.WithField("kind", item.GetKind()).WithField("name", item.GetName()).Info("Pruning orphaned Vizier resource")
+			policy := metav1.DeletePropagationBackground
+			err = resClient.Delete(ctx, item.GetName(), metav1.DeleteOptions{PropagationPolicy: &policy})
+			if err != nil && !k8serrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
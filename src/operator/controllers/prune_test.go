@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func newTestLabeledResource(apiVersion string, kind string, name string, vizierName string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "namespace": "pl"},
+	}}
+	if vizierName != "" {
+		obj.SetLabels(map[string]string{operatorAnnotation: vizierName})
+	}
+	return obj
+}
+
+func TestGetExpectedResources(t *testing.T) {
+	vz := &v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{
+		Pod:          &v1alpha1.PodPolicy{Labels: map[string]string{operatorAnnotation: "vizier"}},
+		ExternalNATS: &v1alpha1.ExternalNATSParams{URL: "tls://nats:4222"},
+		OpenShift:    true,
+	}}
+	yamlMap := map[string]string{
+		"secrets":           testSecretsYAML,
+		"vizier_persistent": testCoreYAML,
+	}
+
+	expected, err := getExpectedResources(vz, yamlMap)
+	require.NoError(t, err)
+	assert.Equal(t, map[resourceKey]bool{
+		{kind: "Secret", name: "pl-deploy-secrets"}:       true,
+		{kind: "Deployment", name: "kelvin"}:              true,
+		{kind: "ConfigMap", name: dryRunConfigMapName}:    true,
+		{kind: "RoleBinding", name: openShiftSCCRoleName}: true,
+	}, expected)
+
+	_, err = getExpectedResources(vz, map[string]string{})
+	assert.Error(t, err)
+}
+
+func TestPruneResources(t *testing.T) {
+	listKinds := map[schema.GroupVersionResource]string{
+		{Group: "apps", Version: "v1", Resource: "deployments"}:                       "DeploymentList",
+		{Group: "apps", Version: "v1", Resource: "statefulsets"}:                      "StatefulSetList",
+		{Group: "apps", Version: "v1", Resource: "daemonsets"}:                        "DaemonSetList",
+		{Group: "autoscaling", Version: "v1", Resource: "horizontalpodautoscalers"}:   "HorizontalPodAutoscalerList",
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"}:        "RoleList",
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"}: "RoleBindingList",
+		{Version: "v1", Resource: "configmaps"}:                                       "ConfigMapList",
+		{Version: "v1", Resource: "serviceaccounts"}:                                  "ServiceAccountList",
+		{Version: "v1", Resource: "services"}:                                         "ServiceList",
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		newTestLabeledResource("apps/v1", "Deployment", "kelvin", "vizier"),
+		newTestLabeledResource("apps/v1", "Deployment", "vizier-deprecated", "vizier"),
+		newTestLabeledResource("apps/v1", "Deployment", "user-app", ""),
+		newTestLabeledResource("v1", "Service", "vizier-deprecated", "vizier"),
+		newTestLabeledResource("v1", "Service", "other-vizier", "other"),
+	)
+	expected := map[resourceKey]bool{{kind: "Deployment", name: "kelvin"}: true}
+
+	ctx := context.Background()
+	require.NoError(t, pruneResources(ctx, dynamicClient, "pl", "vizier", expected))
+
+	remaining := func(gvr schema.GroupVersionResource) []string {
+		list, err := dynamicClient.Resource(gvr).Namespace("pl").List(ctx, metav1.ListOptions{})
+		require.NoError(t, err)
+		var names []string
+		for _, item := range list.Items {
+			names = append(names, item.GetName())
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"kelvin", "user-app"}, remaining(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}))
+	assert.ElementsMatch(t, []string{"other-vizier"}, remaining(schema.GroupVersionResource{Version: "v1", Resource: "services"}))
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/shared/status"
+)
+
+const (
+	// The fraction of the metadata PVC's capacity above which the Vizier is considered degraded.
+	metadataPVCUsageThreshold = 0.9
+)
+
+// statsSummary is the subset of the kubelet's stats summary which reports the usage of the volumes of each pod.
+type statsSummary struct {
+	Pods []struct {
+		Volumes []struct {
+			UsedBytes     *uint64 `json:"usedBytes"`
+			CapacityBytes *uint64 `json:"capacityBytes"`
+			PVCRef        *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"pvcRef"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+// getNodeStatsSummary fetches the kubelet's stats summary of the given node through the API server.
+func getNodeStatsSummary(ctx context.Context, clientset kubernetes.Interface, node string) ([]byte, error) {
+	return clientset.CoreV1().RESTClient().Get().
+		Resource("nodes").Name(node).SubResource("proxy").Suffix("stats/summary").
+		DoRaw(ctx)
+}
+
+// getPVCUsage returns the used and total bytes of the PVC from a kubelet's stats summary. It returns false if the
+// PVC is not mounted on the node, or the kubelet does not report its usage.
+func getPVCUsage(summary []byte, namespace, name string) (uint64, uint64, bool, error) {
+	var s statsSummary
+	if err := json.Unmarshal(summary, &s); err != nil {
+		return 0, 0, false, err
+	}
+	for _, p := range s.Pods {
+		for _, v := range p.Volumes {
+			if v.PVCRef == nil || v.PVCRef.Namespace != namespace || v.PVCRef.Name != name {
+				continue
+			}
+			if v.UsedBytes == nil || v.CapacityBytes == nil || *v.CapacityBytes == 0 {
+				return 0, 0, false, nil
+			}
+			return *v.UsedBytes, *v.CapacityBytes, true, nil
+		}
+	}
+	return 0, 0, false, nil
+}
+
+// getMetadataPVCUsageState checks how full the metadata PVC is, using the stats summary of the node on which the
+// metadata pod runs. Failures to get the usage are not reported as unhealthy, since they say nothing about the PVC.
+func (m *VizierMonitor) getMetadataPVCUsageState() *vizierState {
+	var node string
+	m.podStates.mapMu.Lock()
+	for _, p := range m.podStates.unsafeMap[vizierMetadataLabel] {
+		if p.pod.Spec.NodeName != "" {
+			node = p.pod.Spec.NodeName
+			break
+		}
+	}
+	m.podStates.mapMu.Unlock()
+	if node == "" {
+		return okState()
+	}
+
+	summary, err := m.statsSummary(m.ctx, node)
+	if err != nil {
+		log.WithError(err).WithField("node", node).Info("Failed to get node stats summary")
+		return okState()
+	}
+	used, capacity, ok, err := getPVCUsage(summary, m.namespace, metadataPVC)
+	if err != nil {
+		log.WithError(err).WithField("node", node).Info("Failed to parse node stats summary")
+		return okState()
+	}
+	if !ok {
+		return okState()
+	}
+	if float64(used) > metadataPVCUsageThreshold*float64(capacity) {
+		return &vizierState{Reason: status.MetadataPVCNearlyFull}
+	}
+	return okState()
+}
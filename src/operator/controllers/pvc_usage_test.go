@@ -0,0 +1,140 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/shared/status"
+)
+
+func newTestStatsSummary(namespace, pvcName string, used, capacity uint64) []byte {
+	return []byte(fmt.Sprintf(`{
+  "node": {"nodeName": "node-1"},
+  "pods": [
+    {"podRef": {"name": "kelvin-1", "namespace": "pl"}, "volume": [{"name": "tmp", "usedBytes": 10}]},
+    {
+      "podRef": {"name": "vizier-metadata-0", "namespace": "pl"},
+      "volume": [
+        {"name": "certs", "usedBytes": 10, "capacityBytes": 100},
+        {"name": "metadata-volume", "usedBytes": %d, "capacityBytes": %d, "pvcRef": {"name": %q, "namespace": %q}}
+      ]
+    }
+  ]
+}`, used, capacity, pvcName, namespace))
+}
+
+func TestGetPVCUsage(t *testing.T) {
+	used, capacity, ok, err := getPVCUsage(newTestStatsSummary("pl", metadataPVC, 30, 100), "pl", metadataPVC)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(30), used)
+	assert.Equal(t, uint64(100), capacity)
+
+	_, _, ok, err = getPVCUsage(newTestStatsSummary("other", metadataPVC, 30, 100), "pl", metadataPVC)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, ok, err = getPVCUsage(newTestStatsSummary("pl", metadataPVC, 0, 0), "pl", metadataPVC)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, _, err = getPVCUsage([]byte("not json"), "pl", metadataPVC)
+	assert.Error(t, err)
+}
+
+func TestVizierMonitor_getMetadataPVCUsageState(t *testing.T) {
+	tests := []struct {
+		name           string
+		nodeName       string
+		summary        []byte
+		summaryErr     error
+		expectedReason status.VizierReason
+	}{
+		{
+			name:           "space left",
+			nodeName:       "node-1",
+			summary:        newTestStatsSummary("pl", metadataPVC, 50, 100),
+			expectedReason: "",
+		},
+		{
+			name:           "nearly full",
+			nodeName:       "node-1",
+			summary:        newTestStatsSummary("pl", metadataPVC, 95, 100),
+			expectedReason: status.MetadataPVCNearlyFull,
+		},
+		{
+			name:           "unscheduled",
+			expectedReason: "",
+		},
+		{
+			name:           "summary unavailable",
+			nodeName:       "node-1",
+			summaryErr:     errors.New("forbidden"),
+			expectedReason: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pods := &concurrentPodMap{unsafeMap: make(map[string]map[string]*podWrapper)}
+			pods.write(vizierMetadataLabel, "vizier-metadata-0", &podWrapper{pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "vizier-metadata-0"},
+				Spec:       v1.PodSpec{NodeName: test.nodeName},
+			}})
+			m := &VizierMonitor{
+				ctx:       context.Background(),
+				namespace: "pl",
+				podStates: pods,
+				statsSummary: func(ctx context.Context, node string) ([]byte, error) {
+					assert.Equal(t, test.nodeName, node)
+					return test.summary, test.summaryErr
+				},
+			}
+			assert.Equal(t, test.expectedReason, m.getMetadataPVCUsageState().Reason)
+		})
+	}
+}
+
+func TestVizierMonitor_recordMetadataStorageEvent(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	m := &VizierMonitor{recorder: recorder}
+	vz := &v1alpha1.Vizier{ObjectMeta: metav1.ObjectMeta{Name: "pixie", Namespace: "pl"}}
+
+	m.recordMetadataStorageEvent(vz, okState())
+	m.recordMetadataStorageEvent(vz, &vizierState{Reason: status.MetadataPVCNearlyFull})
+	// The event is only recorded once while the failure persists.
+	m.recordMetadataStorageEvent(vz, &vizierState{Reason: status.MetadataPVCNearlyFull})
+	m.recordMetadataStorageEvent(vz, okState())
+	m.recordMetadataStorageEvent(vz, &vizierState{Reason: status.MetadataPVCPendingBinding})
+
+	require.Len(t, recorder.Events, 2)
+	assert.Contains(t, <-recorder.Events, "Warning MetadataPVCNearlyFull")
+	assert.Contains(t, <-recorder.Events, "Warning MetadataPVCPendingBinding")
+}
@@ -65,14 +65,12 @@ type pvcWatcher struct {
 
 func (pw *pvcWatcher) start(ctx context.Context) {
 	informer := pw.factory.Core().V1().PersistentVolumeClaims().Informer()
-	stopper := make(chan struct{})
-	defer close(stopper)
 	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    pw.onAdd,
 		UpdateFunc: pw.onUpdate,
 		DeleteFunc: pw.onDelete,
 	})
-	informer.Run(stopper)
+	informer.Run(ctx.Done())
 }
 
 func (pw *pvcWatcher) isMetadataPVC(obj interface{}) bool {
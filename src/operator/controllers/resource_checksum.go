@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// getResourceChecksumKey returns the key of the resource's checksum in the Vizier status.
+func getResourceChecksumKey(res *k8s.Resource) string {
+	return res.GVK.Kind + "/" + res.Object.GetName()
+}
+
+// getResourceChecksums returns the checksum of each of the given resources, as they will be applied. Like the spec
+// checksum, each checksum includes the operator version. It also includes the ForceRedeployGeneration, so that
+// incrementing it redeploys every resource.
+func getResourceChecksums(vz *v1alpha1.Vizier, resources []*k8s.Resource) (map[string]string, error) {
+	checksums := make(map[string]string, len(resources))
+	for _, res := range resources {
+		// Maps are marshalled with sorted keys, so the checksum does not depend on the order of the fields.
+		b, err := json.Marshal(res.Object.Object)
+		if err != nil {
+			return nil, err
+		}
+		h := sha256.New()
+		h.Write(b)
+		fmt.Fprintf(h, "%s/%d", getOperatorVersion(), vz.Spec.ForceRedeployGeneration)
+		checksums[getResourceChecksumKey(res)] = hex.EncodeToString(h.Sum(nil))
+	}
+	return checksums, nil
+}
+
+// filterChangedResources returns the resources whose checksum differs from the checksum recorded when they were
+// last applied, or which were not applied before.
+func filterChangedResources(resources []*k8s.Resource, checksums map[string]string, applied map[string]string) []*k8s.Resource {
+	changed := make([]*k8s.Resource, 0)
+	for _, res := range resources {
+		key := getResourceChecksumKey(res)
+		if prev, ok := applied[key]; ok && prev == checksums[key] {
+			continue
+		}
+		changed = append(changed, res)
+	}
+	return changed
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func TestGetResourceChecksums(t *testing.T) {
+	vz := &v1alpha1.Vizier{}
+	kelvin := newTestPatchResource("Deployment", "kelvin", map[string]string{"app": "pl"})
+	pem := newTestPatchResource("DaemonSet", "vizier-pem", map[string]string{"app": "pl"})
+
+	checksums, err := getResourceChecksums(vz, []*k8s.Resource{kelvin, pem})
+	require.NoError(t, err)
+	require.Len(t, checksums, 2)
+	assert.NotEqual(t, checksums["Deployment/kelvin"], checksums["DaemonSet/vizier-pem"])
+
+	// The checksum only changes if the resource changes.
+	same, err := getResourceChecksums(vz, []*k8s.Resource{kelvin, pem})
+	require.NoError(t, err)
+	assert.Equal(t, checksums, same)
+
+	kelvin.Object.SetLabels(map[string]string{"app": "pl", "team": "observability"})
+	changed, err := getResourceChecksums(vz, []*k8s.Resource{kelvin, pem})
+	require.NoError(t, err)
+	assert.NotEqual(t, checksums["Deployment/kelvin"], changed["Deployment/kelvin"])
+	assert.Equal(t, checksums["DaemonSet/vizier-pem"], changed["DaemonSet/vizier-pem"])
+
+	// Forcing a redeploy changes the checksum of every resource.
+	vz.Spec.ForceRedeployGeneration = 1
+	forced, err := getResourceChecksums(vz, []*k8s.Resource{kelvin, pem})
+	require.NoError(t, err)
+	assert.NotEqual(t, changed["Deployment/kelvin"], forced["Deployment/kelvin"])
+	assert.NotEqual(t, changed["DaemonSet/vizier-pem"], forced["DaemonSet/vizier-pem"])
+}
+
+func TestFilterChangedResources(t *testing.T) {
+	kelvin := newTestPatchResource("Deployment", "kelvin", nil)
+	pem := newTestPatchResource("DaemonSet", "vizier-pem", nil)
+	metadata := newTestPatchResource("StatefulSet", "vizier-metadata", nil)
+	resources := []*k8s.Resource{kelvin, pem, metadata}
+
+	checksums := map[string]string{
+		"Deployment/kelvin":           "a",
+		"DaemonSet/vizier-pem":        "b",
+		"StatefulSet/vizier-metadata": "c",
+	}
+	applied := map[string]string{
+		"Deployment/kelvin":    "a",
+		"DaemonSet/vizier-pem": "old",
+	}
+
+	changed := filterChangedResources(resources, checksums, applied)
+	assert.Equal(t, []*k8s.Resource{pem, metadata}, changed)
+
+	assert.Equal(t, resources, filterChangedResources(resources, checksums, nil))
+	assert.Empty(t, filterChangedResources(resources, checksums, checksums))
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+// resyncTracker tracks when each Vizier was last deployed, so that Viziers can be periodically redeployed even when
+// their spec has not changed.
+type resyncTracker struct {
+	mu         sync.Mutex
+	lastDeploy map[types.NamespacedName]time.Time
+}
+
+func (t *resyncTracker) set(vz types.NamespacedName, deployedAt time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.lastDeploy == nil {
+		t.lastDeploy = make(map[types.NamespacedName]time.Time)
+	}
+	t.lastDeploy[vz] = deployedAt
+}
+
+func (t *resyncTracker) delete(vz types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.lastDeploy, vz)
+}
+
+// isDue returns whether the Vizier was last deployed at least the given interval ago. Viziers which have not been
+// deployed since the operator started are always due, so that the first resync happens right away.
+func (t *resyncTracker) isDue(vz types.NamespacedName, interval time.Duration, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lastDeploy, ok := t.lastDeploy[vz]
+	return !ok || now.Sub(lastDeploy) >= interval
+}
+
+// getResyncInterval returns how often the Vizier should be fully reconciled, or 0 if it should only be reconciled
+// when its spec changes. The interval in the Vizier spec takes precedence over the operator's default.
+func (r *VizierReconciler) getResyncInterval(vz *v1alpha1.Vizier) time.Duration {
+	if vz.Spec.ResyncInterval != nil {
+		return vz.Spec.ResyncInterval.Duration
+	}
+	return r.ResyncInterval
+}
+
+// minRequeueAfter returns the sooner of the two requeue delays, where 0 means the reconcile is not requeued.
+func minRequeueAfter(a time.Duration, b time.Duration) time.Duration {
+	if a <= 0 {
+		return b
+	}
+	if b <= 0 || a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestResyncTracker(t *testing.T) {
+	vz := types.NamespacedName{Namespace: "pl", Name: "vizier"}
+	now := time.Date(2022, 1, 10, 0, 0, 0, 0, time.UTC)
+	var tracker resyncTracker
+
+	assert.True(t, tracker.isDue(vz, time.Hour, now))
+	tracker.set(vz, now)
+	assert.False(t, tracker.isDue(vz, time.Hour, now.Add(30*time.Minute)))
+	assert.True(t, tracker.isDue(vz, time.Hour, now.Add(time.Hour)))
+	tracker.delete(vz)
+	assert.True(t, tracker.isDue(vz, time.Hour, now))
+}
+
+func TestGetResyncInterval(t *testing.T) {
+	r := &VizierReconciler{ResyncInterval: time.Hour}
+	vz := &v1alpha1.Vizier{}
+	assert.Equal(t, time.Hour, r.getResyncInterval(vz))
+
+	vz.Spec.ResyncInterval = &metav1.Duration{Duration: 5 * time.Minute}
+	assert.Equal(t, 5*time.Minute, r.getResyncInterval(vz))
+
+	vz.Spec.ResyncInterval = &metav1.Duration{}
+	assert.Equal(t, time.Duration(0), r.getResyncInterval(vz))
+}
+
+func TestMinRequeueAfter(t *testing.T) {
+	assert.Equal(t, time.Minute, minRequeueAfter(0, time.Minute))
+	assert.Equal(t, time.Minute, minRequeueAfter(time.Minute, 0))
+	assert.Equal(t, time.Minute, minRequeueAfter(time.Hour, time.Minute))
+	assert.Equal(t, time.Duration(0), minRequeueAfter(0, 0))
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+// getScheduledRestartDelay returns how long until the Vizier pods are due to be restarted. Viziers which have never
+// been restarted on a schedule are counted from when the Vizier was created.
+func getScheduledRestartDelay(vz *v1alpha1.Vizier, period time.Duration, now time.Time) time.Duration {
+	restartedAt := vz.CreationTimestamp.Time
+	if vz.Status.LastScheduledRestartTime != nil {
+		restartedAt = vz.Status.LastScheduledRestartTime.Time
+	}
+	return restartedAt.Add(period).Sub(now)
+}
+
+// reconcileScheduledRestart performs a rolling restart of the Vizier pods if the Vizier's restart period has elapsed.
+// It returns how long until the pods are next due to be restarted, or 0 if scheduled restarts are disabled.
+func (r *VizierReconciler) reconcileScheduledRestart(ctx context.Context, namespace string, vz *v1alpha1.Vizier) (time.Duration, error) {
+	if vz.Spec.RestartPeriod == nil || vz.Spec.RestartPeriod.Duration <= 0 {
+		return 0, nil
+	}
+	period := vz.Spec.RestartPeriod.Duration
+
+	now := time.Now()
+	if delay := getScheduledRestartDelay(vz, period, now); delay > 0 {
+		return delay, nil
+	}
+	// Restarting during a deploy would interfere with the rollout, so the restart waits for the next reconcile.
+	if vz.Status.ReconciliationPhase != v1alpha1.ReconciliationPhaseReady {
+		return 0, nil
+	}
+
+	log.WithField("namespace", namespace).Info("Restarting Vizier pods on schedule")
+	err := restartVizierPods(ctx, r.Clientset, namespace, vz.Name, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to restart Vizier pods: %w", err)
+	}
+	vz.Status.LastScheduledRestartTime = &metav1.Time{Time: now}
+	err = r.Status().Update(ctx, vz)
+	if err != nil {
+		return 0, err
+	}
+	return period, nil
+}
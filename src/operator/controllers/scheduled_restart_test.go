@@ -0,0 +1,59 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestGetScheduledRestartDelay(t *testing.T) {
+	now := time.Date(2022, 1, 10, 0, 0, 0, 0, time.UTC)
+	vz := &v1alpha1.Vizier{ObjectMeta: metav1.ObjectMeta{
+		CreationTimestamp: metav1.NewTime(now.Add(-48 * time.Hour)),
+	}}
+	assert.Equal(t, -24*time.Hour, getScheduledRestartDelay(vz, 24*time.Hour, now))
+
+	restartedAt := metav1.NewTime(now.Add(-time.Hour))
+	vz.Status.LastScheduledRestartTime = &restartedAt
+	assert.Equal(t, 23*time.Hour, getScheduledRestartDelay(vz, 24*time.Hour, now))
+}
+
+func TestReconcileScheduledRestart_NotDue(t *testing.T) {
+	r := &VizierReconciler{}
+
+	vz := &v1alpha1.Vizier{}
+	delay, err := r.reconcileScheduledRestart(context.Background(), "pl", vz)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), delay)
+
+	restartedAt := metav1.NewTime(time.Now())
+	vz.Spec.RestartPeriod = &metav1.Duration{Duration: 168 * time.Hour}
+	vz.Status.LastScheduledRestartTime = &restartedAt
+	delay, err = r.reconcileScheduledRestart(context.Background(), "pl", vz)
+	require.NoError(t, err)
+	assert.InDelta(t, float64(168*time.Hour), float64(delay), float64(time.Minute))
+}
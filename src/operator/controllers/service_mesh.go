@@ -0,0 +1,83 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+// The annotations which Istio and Linkerd read from pods to decide whether to inject a sidecar, and which ports
+// the injected sidecar should not intercept.
+const (
+	istioInjectAnnotation               = "sidecar.istio.io/inject"
+	istioExcludeInboundPortsAnnotation  = "traffic.sidecar.istio.io/excludeInboundPorts"
+	istioExcludeOutboundPortsAnnotation = "traffic.sidecar.istio.io/excludeOutboundPorts"
+	linkerdInjectAnnotation             = "linkerd.io/inject"
+	linkerdSkipInboundPortsAnnotation   = "config.linkerd.io/skip-inbound-ports"
+	linkerdSkipOutboundPortsAnnotation  = "config.linkerd.io/skip-outbound-ports"
+)
+
+// getServiceMeshAnnotations returns the Istio and Linkerd pod annotations for the service mesh settings.
+func getServiceMeshAnnotations(mesh *v1alpha1.ServiceMeshParams) map[string]string {
+	annotations := make(map[string]string)
+	if mesh.DisableSidecarInjection {
+		annotations[istioInjectAnnotation] = "false"
+		annotations[linkerdInjectAnnotation] = "disabled"
+		// Ports are only excluded from sidecars, so there is nothing else to configure.
+		return annotations
+	}
+	if len(mesh.ExcludeInboundPorts) > 0 {
+		ports := joinPorts(mesh.ExcludeInboundPorts)
+		annotations[istioExcludeInboundPortsAnnotation] = ports
+		annotations[linkerdSkipInboundPortsAnnotation] = ports
+	}
+	if len(mesh.ExcludeOutboundPorts) > 0 {
+		ports := joinPorts(mesh.ExcludeOutboundPorts)
+		annotations[istioExcludeOutboundPortsAnnotation] = ports
+		annotations[linkerdSkipOutboundPortsAnnotation] = ports
+	}
+	return annotations
+}
+
+// updateServiceMeshAnnotations adds the service mesh annotations to the pod template of the resource. Resources
+// without a pod template are left unchanged, since the annotations only take effect on pods.
+func updateServiceMeshAnnotations(mesh *v1alpha1.ServiceMeshParams, res map[string]interface{}) {
+	template, ok, err := unstructured.NestedFieldNoCopy(res, "spec", "template")
+	if !ok || err != nil {
+		return
+	}
+	templateCast, ok := template.(map[string]interface{})
+	if !ok {
+		return
+	}
+	addKeyValueMapToResource("annotations", getServiceMeshAnnotations(mesh), templateCast)
+}
+
+func joinPorts(ports []int32) string {
+	strs := make([]string, len(ports))
+	for i, p := range ports {
+		strs[i] = strconv.Itoa(int(p))
+	}
+	return strings.Join(strs, ",")
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestGetServiceMeshAnnotations(t *testing.T) {
+	tests := []struct {
+		name     string
+		mesh     *v1alpha1.ServiceMeshParams
+		expected map[string]string
+	}{
+		{
+			name: "disabled injection",
+			mesh: &v1alpha1.ServiceMeshParams{
+				DisableSidecarInjection: true,
+				ExcludeInboundPorts:     []int32{50100},
+			},
+			expected: map[string]string{
+				"sidecar.istio.io/inject": "false",
+				"linkerd.io/inject":       "disabled",
+			},
+		},
+		{
+			name: "excluded ports",
+			mesh: &v1alpha1.ServiceMeshParams{
+				ExcludeInboundPorts:  []int32{50100, 50300},
+				ExcludeOutboundPorts: []int32{4222},
+			},
+			expected: map[string]string{
+				"traffic.sidecar.istio.io/excludeInboundPorts":  "50100,50300",
+				"config.linkerd.io/skip-inbound-ports":          "50100,50300",
+				"traffic.sidecar.istio.io/excludeOutboundPorts": "4222",
+				"config.linkerd.io/skip-outbound-ports":         "4222",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, getServiceMeshAnnotations(tc.mesh))
+		})
+	}
+}
+
+func TestUpdateServiceMeshAnnotations(t *testing.T) {
+	mesh := &v1alpha1.ServiceMeshParams{DisableSidecarInjection: true}
+
+	res := newTestPodResource(map[string]interface{}{})
+	res["metadata"] = map[string]interface{}{"name": "vizier-pem"}
+	updateServiceMeshAnnotations(mesh, res)
+	assert.Equal(t, map[string]interface{}{
+		"sidecar.istio.io/inject": "false",
+		"linkerd.io/inject":       "disabled",
+	}, res["spec"].(map[string]interface{})["template"].(map[string]interface{})["metadata"].(map[string]interface{})["annotations"])
+	// Only the pods are annotated.
+	assert.Equal(t, map[string]interface{}{"name": "vizier-pem"}, res["metadata"])
+
+	service := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "vizier-metadata"},
+		"spec":     map[string]interface{}{"type": "ClusterIP"},
+	}
+	updateServiceMeshAnnotations(mesh, service)
+	assert.Equal(t, map[string]interface{}{"name": "vizier-metadata"}, service["metadata"])
+}
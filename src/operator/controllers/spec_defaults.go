@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+// The key in the spec defaults ConfigMap which holds the default Vizier spec, as YAML.
+const specDefaultsConfigMapKey = "spec.yaml"
+
+// getSpecDefaults reads the default Vizier spec from the given ConfigMap, which is referenced as "<namespace>/<name>".
+// The defaults are returned in their unstructured form, without the fields which are unset. If the ConfigMap does not
+// exist, there are no defaults.
+func getSpecDefaults(ctx context.Context, clientset kubernetes.Interface, configMapRef string) (map[string]interface{}, error) {
+	parts := strings.Split(configMapRef, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("spec defaults ConfigMap %q must be of the form <namespace>/<name>", configMapRef)
+	}
+	cm, err := clientset.CoreV1().ConfigMaps(parts[0]).Get(ctx, parts[1], metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The defaults are parsed as a spec, so that misspelled or mistyped fields are rejected rather than ignored.
+	var spec v1alpha1.VizierSpec
+	err = yaml.UnmarshalStrict([]byte(cm.Data[specDefaultsConfigMapKey]), &spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid default Vizier spec in ConfigMap %s: %w", configMapRef, err)
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(&spec)
+}
+
+// mergeSpecDefaults merges the defaults into the given values. Values which are set take precedence over the
+// defaults, objects are merged field by field, and lists replace the defaults rather than being merged with them.
+func mergeSpecDefaults(values map[string]interface{}, defaults map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(defaults)+len(values))
+	for k, v := range defaults {
+		merged[k] = runtime.DeepCopyJSONValue(v)
+	}
+	for k, v := range values {
+		valueMap, ok := v.(map[string]interface{})
+		defaultMap, hasDefault := merged[k].(map[string]interface{})
+		if ok && hasDefault {
+			merged[k] = mergeSpecDefaults(valueMap, defaultMap)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// applySpecDefaults merges the operator's default spec into the Vizier's spec, so that cluster-wide policies do not
+// need to be repeated in every Vizier. The defaults are only applied in memory when rendering the Vizier, and are
+// never written to the Vizier itself, so that changes to the defaults are picked up by the next deploy.
+func (r *VizierReconciler) applySpecDefaults(ctx context.Context, vz *v1alpha1.Vizier) error {
+	if r.SpecDefaultsConfigMap == "" {
+		return nil
+	}
+	defaults, err := getSpecDefaults(ctx, r.Clientset, r.SpecDefaultsConfigMap)
+	if err != nil || defaults == nil {
+		return err
+	}
+
+	spec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&vz.Spec)
+	if err != nil {
+		return err
+	}
+	var merged v1alpha1.VizierSpec
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(mergeSpecDefaults(spec, defaults), &merged)
+	if err != nil {
+		return err
+	}
+	vz.Spec = merged
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func newTestSpecDefaultsConfigMap(spec string) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "vizier-defaults", Namespace: "px-operator"},
+		Data:       map[string]string{specDefaultsConfigMapKey: spec},
+	}
+}
+
+func TestGetSpecDefaults(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newTestSpecDefaultsConfigMap(`
+registry: registry.corp/pixie
+pod:
+  tolerations:
+  - key: dedicated
+    operator: Exists
+`))
+
+	defaults, err := getSpecDefaults(context.Background(), clientset, "px-operator/vizier-defaults")
+	require.NoError(t, err)
+	assert.Equal(t, "registry.corp/pixie", defaults["registry"])
+	assert.Contains(t, defaults, "pod")
+
+	// A missing ConfigMap has no defaults.
+	defaults, err = getSpecDefaults(context.Background(), clientset, "px-operator/missing")
+	require.NoError(t, err)
+	assert.Nil(t, defaults)
+
+	_, err = getSpecDefaults(context.Background(), clientset, "vizier-defaults")
+	assert.Error(t, err)
+}
+
+func TestGetSpecDefaults_UnknownField(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newTestSpecDefaultsConfigMap("registy: registry.corp/pixie\n"))
+
+	_, err := getSpecDefaults(context.Background(), clientset, "px-operator/vizier-defaults")
+	assert.Error(t, err)
+}
+
+func TestMergeSpecDefaults(t *testing.T) {
+	defaults := v1alpha1.VizierSpec{
+		Registry: "registry.corp/pixie",
+		Pod: &v1alpha1.PodPolicy{
+			Labels:      map[string]string{"team": "observability", "env": "prod"},
+			Tolerations: []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpExists}},
+		},
+		PemMemoryLimit: "4Gi",
+	}
+	spec := v1alpha1.VizierSpec{
+		Version:        "0.12.3",
+		PemMemoryLimit: "2Gi",
+		Pod: &v1alpha1.PodPolicy{
+			Labels: map[string]string{"env": "staging"},
+		},
+	}
+
+	defaultsMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&defaults)
+	require.NoError(t, err)
+	specMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&spec)
+	require.NoError(t, err)
+
+	var merged v1alpha1.VizierSpec
+	require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(mergeSpecDefaults(specMap, defaultsMap), &merged))
+	assert.Equal(t, "0.12.3", merged.Version)
+	assert.Equal(t, "registry.corp/pixie", merged.Registry)
+	assert.Equal(t, "2Gi", merged.PemMemoryLimit)
+	assert.Equal(t, map[string]string{"team": "observability", "env": "staging"}, merged.Pod.Labels)
+	assert.Equal(t, defaults.Pod.Tolerations, merged.Pod.Tolerations)
+	// The defaults are not modified by merging.
+	assert.Equal(t, "prod", defaultsMap["pod"].(map[string]interface{})["labels"].(map[string]interface{})["env"])
+}
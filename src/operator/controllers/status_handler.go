@@ -0,0 +1,141 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+// The conditions which report the health of the Vizier's components, as observed by the VizierMonitor.
+var componentConditions = []string{
+	v1alpha1.VizierConditionPodsHealthy,
+	v1alpha1.VizierConditionCloudConnected,
+}
+
+// VizierOperatorStatus is the operator's view of a Vizier, as reported by the StatusHandler.
+type VizierOperatorStatus struct {
+	Name                        string                         `json:"name"`
+	Namespace                   string                         `json:"namespace"`
+	Version                     string                         `json:"version,omitempty"`
+	ReconciliationPhase         v1alpha1.ReconciliationPhase   `json:"reconciliationPhase,omitempty"`
+	LastReconciliationPhaseTime *metav1.Time                   `json:"lastReconciliationPhaseTime,omitempty"`
+	ReconcilePaused             bool                           `json:"reconcilePaused,omitempty"`
+	Checksum                    string                         `json:"checksum,omitempty"`
+	LastError                   string                         `json:"lastError,omitempty"`
+	VizierPhase                 v1alpha1.VizierPhase           `json:"vizierPhase,omitempty"`
+	VizierReason                string                         `json:"vizierReason,omitempty"`
+	LastVizierReasonTime        *metav1.Time                   `json:"lastVizierReasonTime,omitempty"`
+	UnhealthyPod                string                         `json:"unhealthyPod,omitempty"`
+	Message                     string                         `json:"message,omitempty"`
+	Components                  map[string]ComponentHealthInfo `json:"components,omitempty"`
+}
+
+// ComponentHealthInfo describes the health of one of the Vizier's components.
+type ComponentHealthInfo struct {
+	Healthy bool   `json:"healthy"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// StatusHandler serves the operator's view of each Vizier as JSON, so that external automation can follow what the
+// operator is doing without scraping the Vizier status and the operator logs. The results may be filtered with the
+// "namespace" and "name" query parameters.
+type StatusHandler struct {
+	Client client.Reader
+}
+
+// ServeHTTP lists the Viziers and writes their status.
+func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var opts []client.ListOption
+	if ns := req.URL.Query().Get("namespace"); ns != "" {
+		opts = append(opts, client.InNamespace(ns))
+	}
+	var viziers v1alpha1.VizierList
+	if err := h.Client.List(req.Context(), &viziers, opts...); err != nil {
+		log.WithError(err).Error("Failed to list Viziers")
+		http.Error(w, "failed to list Viziers", http.StatusInternalServerError)
+		return
+	}
+
+	statuses := make([]*VizierOperatorStatus, 0, len(viziers.Items))
+	name := req.URL.Query().Get("name")
+	for i := range viziers.Items {
+		if name != "" && viziers.Items[i].Name != name {
+			continue
+		}
+		statuses = append(statuses, getVizierOperatorStatus(&viziers.Items[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(statuses); err != nil {
+		log.WithError(err).Error("Failed to write Vizier statuses")
+	}
+}
+
+// getVizierOperatorStatus summarizes the reconciliation state and component health recorded in the Vizier's status.
+func getVizierOperatorStatus(vz *v1alpha1.Vizier) *VizierOperatorStatus {
+	s := &VizierOperatorStatus{
+		Name:                        vz.Name,
+		Namespace:                   vz.Namespace,
+		Version:                     vz.Status.Version,
+		ReconciliationPhase:         vz.Status.ReconciliationPhase,
+		LastReconciliationPhaseTime: vz.Status.LastReconciliationPhaseTime,
+		ReconcilePaused:             isReconcilePaused(vz),
+		Checksum:                    hex.EncodeToString(vz.Status.Checksum),
+		VizierPhase:                 vz.Status.VizierPhase,
+		VizierReason:                vz.Status.VizierReason,
+		LastVizierReasonTime:        vz.Status.LastVizierReasonTime,
+		UnhealthyPod:                vz.Status.UnhealthyPod,
+		Message:                     vz.Status.Message,
+	}
+
+	if cond := meta.FindStatusCondition(vz.Status.Conditions, v1alpha1.VizierConditionResourcesApplied); cond != nil && cond.Status == metav1.ConditionFalse {
+		s.LastError = cond.Message
+	}
+
+	for _, condType := range componentConditions {
+		cond := meta.FindStatusCondition(vz.Status.Conditions, condType)
+		if cond == nil {
+			continue
+		}
+		if s.Components == nil {
+			s.Components = make(map[string]ComponentHealthInfo)
+		}
+		s.Components[condType] = ComponentHealthInfo{
+			Healthy: cond.Status == metav1.ConditionTrue,
+			Reason:  cond.Reason,
+			Message: cond.Message,
+		}
+	}
+	return s
+}
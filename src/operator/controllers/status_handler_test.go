@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestGetVizierOperatorStatus(t *testing.T) {
+	vz := &v1alpha1.Vizier{
+		ObjectMeta: metav1.ObjectMeta{Name: "vizier", Namespace: "pl"},
+		Status: v1alpha1.VizierStatus{
+			Version:             "0.10.0",
+			ReconciliationPhase: v1alpha1.ReconciliationPhaseUpdating,
+			Checksum:            []byte{0xab, 0xcd},
+		},
+	}
+	setCondition(vz, v1alpha1.VizierConditionResourcesApplied, metav1.ConditionFalse, "DeployFailed", "Failed to deploy Vizier core: timeout")
+	setCondition(vz, v1alpha1.VizierConditionPodsHealthy, metav1.ConditionFalse, "PEMsAllFailing", "All PEMs are failing")
+
+	s := getVizierOperatorStatus(vz)
+	assert.Equal(t, "abcd", s.Checksum)
+	assert.Equal(t, "Failed to deploy Vizier core: timeout", s.LastError)
+	assert.Equal(t, map[string]ComponentHealthInfo{
+		v1alpha1.VizierConditionPodsHealthy: {Healthy: false, Reason: "PEMsAllFailing", Message: "All PEMs are failing"},
+	}, s.Components)
+}
+
+func TestStatusHandler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&v1alpha1.Vizier{ObjectMeta: metav1.ObjectMeta{Name: "vizier", Namespace: "pl"}},
+		&v1alpha1.Vizier{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "pl"}},
+		&v1alpha1.Vizier{ObjectMeta: metav1.ObjectMeta{Name: "vizier", Namespace: "staging"}},
+	).Build()
+	h := &StatusHandler{Client: c}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected int
+	}{
+		{name: "all", query: "", expected: 3},
+		{name: "namespace", query: "?namespace=pl", expected: 2},
+		{name: "name", query: "?namespace=pl&name=vizier", expected: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/vizier-status"+test.query, nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var statuses []*VizierOperatorStatus
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &statuses))
+			assert.Len(t, statuses, test.expected)
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/vizier-status", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -21,11 +21,11 @@ package controllers
 import (
 	"bytes"
 	"context"
-	"crypto/rand"
 	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -34,33 +34,85 @@ import (
 	"google.golang.org/grpc"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/api/proto/vizierconfigpb"
 	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
-	"px.dev/pixie/src/shared/services"
+	version "px.dev/pixie/src/shared/goversion"
 	"px.dev/pixie/src/utils/shared/certs"
 	"px.dev/pixie/src/utils/shared/k8s"
 )
 
 const (
 	// This is the key for the annotation that the operator applies on all of its deployed resources for a CRD.
-	operatorAnnotation  = "vizier-name"
-	clusterSecretJWTKey = "jwt-signing-key"
+	operatorAnnotation = "vizier-name"
+	// The label which the operator applies on all of its deployed resources, with the namespace of the Vizier.
+	vizierNamespaceLabel = "vizier-namespace"
+	clusterSecretJWTKey  = "jwt-signing-key"
 	// updatingFailedTimeout is the amount of time we wait since an Updated started
 	// before we consider the Update Failed.
 	updatingFailedTimeout = 10 * time.Minute
 	// How often we should check whether a Vizier update failed.
 	updatingVizierCheckPeriod = 1 * time.Minute
+	// The finalizer which ensures that the Vizier's resources are torn down before the Vizier is deleted.
+	vizierFinalizer = "px.dev/vizier-cleanup"
+	// How long to wait for PEMs to terminate when deleting a Vizier.
+	pemDrainTimeout = 2 * time.Minute
+	// The annotation which pauses reconciliation of a Vizier when set to reconcilePaused, so that its resources
+	// can be modified manually without being reverted by the operator.
+	reconcileAnnotation = "pixie.px.dev/reconcile"
+	reconcilePaused     = "paused"
 )
 
+// defaultNoProxyHosts are the hosts which are never proxied, so that Vizier components can continue to reach each other.
+var defaultNoProxyHosts = []string{"localhost", "127.0.0.1", ".svc", ".cluster.local"}
+
+// customTLSCertsKeys are the keys which must be present in a custom TLS certs secret.
+var customTLSCertsKeys = []string{"ca.crt", "server.crt", "server.key", "client.crt", "client.key"}
+
+const (
+	// The name of the ConfigMap containing the NATS config.
+	natsConfigMapName = "nats-config"
+	// The name of the init container which waits for the NATS deployed with Vizier to become available.
+	natsWaitContainerName = "nats-wait"
+	// The path at which the TLS secret for an external NATS cluster is mounted.
+	externalNATSCertsPath = "/nats-certs"
+	// The path at which the credentials secret for an external NATS cluster is mounted.
+	externalNATSCredsPath = "/nats-creds"
+	// The key of the user credentials file in the credentials secret for an external NATS cluster.
+	externalNATSCredsKey = "nats.creds"
+)
+
+// natsClusterConfig is the config which clusters the NATS servers. Each server connects to the headless NATS service,
+// which resolves to one of the other servers, and discovers the remaining servers through that server.
+const natsClusterConfig = `
+cluster {
+  name: pl-nats
+  port: 6222
+  routes: [
+    nats://pl-nats-mgmt:6222
+  ]
+  connect_retries: 120
+}
+`
+
 // defaultClassAnnotationKey is the key in the annotation map which indicates
 // a storage class is default.
 var defaultClassAnnotationKeys = []string{"storageclass.kubernetes.io/is-default-class", "storageclass.beta.kubernetes.io/is-default-class"}
@@ -72,35 +124,36 @@ type VizierReconciler struct {
 
 	Clientset  *kubernetes.Clientset
 	RestConfig *rest.Config
-
-	monitor      *VizierMonitor
+	// ResyncInterval is how often each Vizier is fully reconciled, even if its spec has not changed. A Vizier's
+	// spec may override this. If 0, Viziers are only reconciled when their spec changes.
+	ResyncInterval time.Duration
+	// DeployRetry is the backoff with which Vizier resources are applied.
+	DeployRetry DeployRetryConfig
+	// CloudConn configures the connection to Pixie Cloud.
+	CloudConn CloudConnConfig
+	// SpecDefaultsConfigMap is the ConfigMap, referenced as "<namespace>/<name>", which holds the default spec that
+	// is merged into the spec of every Vizier when it is deployed. If empty, there are no defaults.
+	SpecDefaultsConfigMap string
+	// YAMLVerificationKeyFile is the path to the PEM encoded public key which the Vizier YAMLs from Pixie Cloud must
+	// be signed with. If empty, the YAMLs are applied without verification.
+	YAMLVerificationKeyFile string
+	// Recorder records events on the Vizier, such as those raised by the VizierMonitor.
+	Recorder record.EventRecorder
+
+	// The monitor of each Vizier, which reports the Vizier's health in its status.
+	monitors     monitorManager
 	lastChecksum []byte
+	// The resources last applied for each Vizier, which are checked for drift.
+	appliedResources appliedResourceTracker
+	// When each Vizier was last deployed, which determines when it is next resynced.
+	resyncs resyncTracker
+	// The connections to Pixie Cloud, which are shared across reconciles.
+	cloudConns cloudConnPool
 }
 
 // +kubebuilder:rbac:groups=pixie.px.dev,resources=viziers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=pixie.px.dev,resources=viziers/status,verbs=get;update;patch
 
-func getCloudClientConnection(cloudAddr string, devCloudNS string) (*grpc.ClientConn, error) {
-	isInternal := false
-
-	if devCloudNS != "" {
-		cloudAddr = fmt.Sprintf("api-service.%s.svc.cluster.local:51200", devCloudNS)
-		isInternal = true
-	}
-
-	dialOpts, err := services.GetGRPCClientDialOptsServerSideTLS(isInternal)
-	if err != nil {
-		return nil, err
-	}
-
-	c, err := grpc.Dial(cloudAddr, dialOpts...)
-	if err != nil {
-		return nil, err
-	}
-
-	return c, nil
-}
-
 func getLatestVizierVersion(ctx context.Context, client cloudpb.ArtifactTrackerClient) (string, error) {
 	req := &cloudpb.GetArtifactListRequest{
 		ArtifactName: "vizier",
@@ -109,6 +162,7 @@ func getLatestVizierVersion(ctx context.Context, client cloudpb.ArtifactTrackerC
 	}
 	resp, err := client.GetArtifactList(ctx, req)
 	if err != nil {
+		cloudRPCErrorCount.WithLabelValues("GetArtifactList").Inc()
 		return "", err
 	}
 
@@ -121,7 +175,7 @@ func getLatestVizierVersion(ctx context.Context, client cloudpb.ArtifactTrackerC
 
 // validateNumDefaultStorageClasses returns a boolean whether there is exactly
 // 1 default storage class or not.
-func validateNumDefaultStorageClasses(clientset *kubernetes.Clientset) (bool, error) {
+func validateNumDefaultStorageClasses(clientset kubernetes.Interface) (bool, error) {
 	storageClasses, err := clientset.StorageV1().StorageClasses().List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return false, err
@@ -144,29 +198,117 @@ func validateNumDefaultStorageClasses(clientset *kubernetes.Clientset) (bool, er
 	return defaultClassCount == 1, nil
 }
 
+// hasMetadataStorageClass returns whether the storage class for the metadata PVC exists. This is the storage class
+// specified for the Vizier if there is one, and the cluster's default storage class otherwise.
+func hasMetadataStorageClass(ctx context.Context, clientset kubernetes.Interface, vz *v1alpha1.Vizier) (bool, error) {
+	if vz.Spec.StorageClassName == "" {
+		return validateNumDefaultStorageClasses(clientset)
+	}
+	_, err := clientset.StorageV1().StorageClasses().Get(ctx, vz.Spec.StorageClassName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // Reconcile updates the Vizier running in the cluster to match the expected state.
 func (r *VizierReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log.WithField("req", req).Info("Reconciling...")
+	start := time.Now()
+	operation := "update"
+	defer func() {
+		reconcileDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	}()
 
 	// Fetch vizier CRD to determine what operation should be performed.
 	var vizier v1alpha1.Vizier
 	if err := r.Get(ctx, req.NamespacedName, &vizier); err != nil {
+		// Only a Vizier which no longer exists is deleted, rather than one which could not be fetched.
+		if !k8serrors.IsNotFound(err) {
+			log.WithError(err).Error("Failed to get Vizier")
+			return ctrl.Result{}, err
+		}
+		operation = "delete"
+		deleteReconciliationPhase(req.Namespace, req.Name)
+		deleteVizierHealth(req.Namespace, req.Name)
 		err = r.deleteVizier(ctx, req)
+		recordVizierOperation(req.Namespace, operation, err)
 		if err != nil {
 			log.WithError(err).Info("Failed to delete Vizier instance")
 		}
 
-		if r.monitor != nil && r.monitor.namespace == req.Namespace {
-			r.monitor.Quit()
-			r.monitor = nil
-		}
+		r.monitors.stop(req.NamespacedName)
 		// Vizier CRD deleted. The vizier instance should also be deleted.
 		return ctrl.Result{}, err
 	}
 
+	recordReconciliationPhase(&vizier)
+
+	if !vizier.ObjectMeta.DeletionTimestamp.IsZero() {
+		operation = "delete"
+		// Protected Viziers keep their resources until the protection is disabled, which triggers another reconcile.
+		if vizier.Spec.PreventDeletion {
+			log.WithField("req", req).Warn("Vizier is protected from deletion, not deleting its resources")
+			return ctrl.Result{}, nil
+		}
+		err := r.finalizeVizier(ctx, req, &vizier)
+		recordVizierOperation(req.Namespace, operation, err)
+		if err != nil {
+			log.WithError(err).Info("Failed to finalize Vizier instance")
+		}
+		return ctrl.Result{}, err
+	}
+
+	// The Vizier is monitored as soon as it is reconciled, rather than waiting for the next sync of the monitors.
+	if err := r.startMonitor(&vizier); err != nil {
+		log.WithError(err).Error("Failed to start vizier monitor")
+	}
+
+	// Deleting a Vizier is always allowed, so that a paused Vizier is not stuck on its finalizer.
+	if isReconcilePaused(&vizier) {
+		log.WithField("req", req).Info("Reconciliation is paused, skipping")
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(&vizier, vizierFinalizer) {
+		controllerutil.AddFinalizer(&vizier, vizierFinalizer)
+		if err := r.Update(ctx, &vizier); err != nil {
+			log.WithError(err).Error("Failed to add finalizer to Vizier")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Specs which would fail part way through a deploy are reported in the status, rather than deployed. Fixing the
+	// spec triggers another reconcile.
+	specErrs := validateVizierSpec(&vizier)
+	if setSpecValidCondition(&vizier, specErrs) {
+		if err := r.Status().Update(ctx, &vizier); err != nil {
+			log.WithError(err).Error("Failed to update Vizier spec validation status")
+			return ctrl.Result{}, err
+		}
+	}
+	if len(specErrs) > 0 {
+		log.WithField("req", req).WithError(joinValidationErrors(specErrs)).Info("Vizier spec is invalid, skipping")
+		return ctrl.Result{}, nil
+	}
+
+	if vizier.Spec.DryRun {
+		operation = "dry-run"
+		err := r.dryRunVizier(ctx, req, &vizier)
+		if err != nil {
+			log.WithError(err).Info("Failed to render Vizier resources")
+		}
+		return ctrl.Result{}, err
+	}
+
 	if vizier.Status.VizierPhase == v1alpha1.VizierPhaseNone && vizier.Status.ReconciliationPhase == v1alpha1.ReconciliationPhaseNone {
+		operation = "create"
 		// We are creating a new vizier instance.
 		err := r.createVizier(ctx, req, &vizier)
+		recordVizierOperation(req.Namespace, operation, err)
 		if err != nil {
 			log.WithError(err).Info("Failed to deploy new Vizier instance")
 		}
@@ -174,53 +316,69 @@ func (r *VizierReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	}
 
 	err := r.updateVizier(ctx, req, &vizier)
+	recordVizierOperation(req.Namespace, operation, err)
 	if err != nil {
 		log.WithError(err).Info("Failed to update Vizier instance")
 	}
 
-	// Check if we are already monitoring this Vizier.
-	if r.monitor == nil || r.monitor.namespace != req.Namespace {
-		if r.monitor != nil {
-			r.monitor.Quit()
-			r.monitor = nil
+	// Workloads which were deleted or modified since they were deployed are repaired right away.
+	if err == nil {
+		err = r.repairVizierDrift(ctx, &vizier)
+		if err != nil {
+			log.WithError(err).Info("Failed to repair drifted Vizier resources")
 		}
+	}
 
-		r.monitor = &VizierMonitor{
-			namespace:      req.Namespace,
-			namespacedName: req.NamespacedName,
-			vzUpdate:       r.Status().Update,
-			vzGet:          r.Get,
-			clientset:      r.Clientset,
-			vzSpecUpdate:   r.Update,
-		}
-		cloudClient, err := getCloudClientConnection(vizier.Spec.CloudAddr, vizier.Spec.DevCloudNamespace)
+	var result ctrl.Result
+	if err == nil {
+		result.RequeueAfter, err = r.reconcileJWTKeyRotation(ctx, req.Namespace, &vizier)
 		if err != nil {
-			log.WithError(err).Fatal("Failed to initialize vizier monitor")
+			log.WithError(err).Info("Failed to rotate JWT signing key")
 		}
-		err = r.monitor.InitAndStartMonitor(cloudClient)
+	}
+	if err == nil {
+		var restartAfter time.Duration
+		restartAfter, err = r.reconcileScheduledRestart(ctx, req.Namespace, &vizier)
 		if err != nil {
-			log.WithError(err).Fatal("Failed to initialize vizier monitor")
+			log.WithError(err).Info("Failed to restart Vizier pods on schedule")
 		}
+		result.RequeueAfter = minRequeueAfter(result.RequeueAfter, restartAfter)
 	}
+	result.RequeueAfter = minRequeueAfter(result.RequeueAfter, r.getResyncInterval(&vizier))
 
 	// Vizier CRD has been updated, and we should update the running vizier accordingly.
-	return ctrl.Result{}, err
+	return result, err
+}
+
+// isReconcilePaused returns whether reconciliation has been paused for the given Vizier. Reconciliation resumes once
+// the annotation is removed, at which point any changes made to the spec in the meantime are deployed.
+func isReconcilePaused(vz *v1alpha1.Vizier) bool {
+	return vz.GetAnnotations()[reconcileAnnotation] == reconcilePaused
 }
 
 // updateVizier updates the vizier instance according to the spec. As of the current moment, we only support updates to the Vizier version.
 // Other updates to the Vizier spec will be ignored.
 func (r *VizierReconciler) updateVizier(ctx context.Context, req ctrl.Request, vz *v1alpha1.Vizier) error {
-	checksum, err := getSpecChecksum(vz)
+	// The deployed checksum includes the operator's spec defaults, so that changes to the defaults are deployed.
+	withDefaults := vz.DeepCopy()
+	err := r.applySpecDefaults(ctx, withDefaults)
+	if err != nil {
+		return err
+	}
+	checksum, err := getDeployChecksum(ctx, r.Clientset, withDefaults)
 	if err != nil {
 		return err
 	}
 
-	if bytes.Equal(checksum, vz.Status.Checksum) {
+	interval := r.getResyncInterval(vz)
+	resyncDue := interval > 0 && r.resyncs.isDue(req.NamespacedName, interval, time.Now())
+
+	if bytes.Equal(checksum, vz.Status.Checksum) && !resyncDue {
 		log.Info("Checksums matched, no need to reconcile")
 		return nil
 	}
 
-	if len(vz.Status.Checksum) == 0 && bytes.Equal(checksum, r.lastChecksum) {
+	if len(vz.Status.Checksum) == 0 && bytes.Equal(checksum, r.lastChecksum) && !resyncDue {
 		log.Warn("No checksum written to status")
 		log.Info("Checksums matched, no need to reconcile")
 		return nil
@@ -230,7 +388,13 @@ func (r *VizierReconciler) updateVizier(ctx context.Context, req ctrl.Request, v
 		log.Info("Already in the process of updating, nothing to do")
 		return nil
 	}
-	log.Infof("Status checksum '%x' does not match spec checksum '%x' - running an update", vz.Status.Checksum, checksum)
+	if bytes.Equal(checksum, vz.Status.Checksum) {
+		log.Info("Resync interval elapsed - running an update")
+		// A resync reapplies every resource, including those which have not changed since the last deploy.
+		vz.Status.ResourceChecksums = nil
+	} else {
+		log.Infof("Status checksum '%x' does not match spec checksum '%x' - running an update", vz.Status.Checksum, checksum)
+	}
 
 	return r.deployVizier(ctx, req, vz, true)
 }
@@ -238,6 +402,17 @@ func (r *VizierReconciler) updateVizier(ctx context.Context, req ctrl.Request, v
 // deleteVizier deletes the vizier instance in the given namespace.
 func (r *VizierReconciler) deleteVizier(ctx context.Context, req ctrl.Request) error {
 	log.WithField("req", req).Info("Deleting Vizier...")
+	r.deleteNamespacedResources(req)
+
+	err := r.deleteClusterScopedResources(req.Namespace, req.Name)
+	if err != nil {
+		log.WithError(err).Warn("Failed to delete cluster-scoped Vizier resources")
+	}
+	return nil
+}
+
+// deleteNamespacedResources deletes the Vizier's resources in its namespace, and forgets any state kept for it.
+func (r *VizierReconciler) deleteNamespacedResources(req ctrl.Request) {
 	od := k8s.ObjectDeleter{
 		Namespace:  req.Namespace,
 		Clientset:  r.Clientset,
@@ -245,23 +420,82 @@ func (r *VizierReconciler) deleteVizier(ctx context.Context, req ctrl.Request) e
 		Timeout:    2 * time.Minute,
 	}
 
+	r.appliedResources.delete(req.NamespacedName)
+	r.resyncs.delete(req.NamespacedName)
+
 	keyValueLabel := operatorAnnotation + "=" + req.Name
 	_, _ = od.DeleteByLabel(keyValueLabel)
-	return nil
+}
+
+// finalizeVizier tears down the Vizier's resources in order, before allowing the Vizier to be deleted. PEMs are
+// drained first so that they can clean up after themselves on each node, followed by the cluster-scoped resources
+// and secrets, which would otherwise be leaked. Finally, the remaining resources are deleted.
+func (r *VizierReconciler) finalizeVizier(ctx context.Context, req ctrl.Request, vz *v1alpha1.Vizier) error {
+	if !controllerutil.ContainsFinalizer(vz, vizierFinalizer) {
+		return nil
+	}
+	log.WithField("req", req).Info("Finalizing Vizier...")
+
+	r.monitors.stop(req.NamespacedName)
+
+	err := r.drainPEMs(ctx, req.Namespace)
+	if err != nil {
+		// The PEMs will be force deleted along with the rest of the resources.
+		log.WithError(err).Warn("Failed to drain PEMs")
+	}
+
+	od := k8s.ObjectDeleter{
+		Namespace:  req.Namespace,
+		Clientset:  r.Clientset,
+		RestConfig: r.RestConfig,
+		Timeout:    2 * time.Minute,
+	}
+	err = r.deleteClusterScopedResources(req.Namespace, req.Name)
+	if err != nil {
+		return fmt.Errorf("failed to delete cluster-scoped resources: %w", err)
+	}
+	keyValueLabel := operatorAnnotation + "=" + req.Name
+	_, err = od.DeleteByLabel(keyValueLabel, "secrets")
+	if err != nil {
+		return fmt.Errorf("failed to delete secrets: %w", err)
+	}
+
+	r.deleteNamespacedResources(req)
+
+	controllerutil.RemoveFinalizer(vz, vizierFinalizer)
+	return r.Update(ctx, vz)
+}
+
+// drainPEMs deletes the PEM daemonset and waits for all PEMs to terminate gracefully.
+func (r *VizierReconciler) drainPEMs(ctx context.Context, namespace string) error {
+	policy := metav1.DeletePropagationForeground
+	err := r.Clientset.AppsV1().DaemonSets(namespace).Delete(ctx, vizierPemLabel, metav1.DeleteOptions{PropagationPolicy: &policy})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	return wait.PollImmediateWithContext(ctx, 5*time.Second, pemDrainTimeout, func(ctx context.Context) (bool, error) {
+		pods, err := r.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "name=" + vizierPemLabel})
+		if err != nil {
+			return false, err
+		}
+		return len(pods.Items) == 0, nil
+	})
 }
 
 // createVizier deploys a new vizier instance in the given namespace.
 func (r *VizierReconciler) createVizier(ctx context.Context, req ctrl.Request, vz *v1alpha1.Vizier) error {
 	log.Info("Creating a new vizier instance")
-	cloudClient, err := getCloudClientConnection(vz.Spec.CloudAddr, vz.Spec.DevCloudNamespace)
+	cloudClient, err := r.cloudConns.get(r.CloudConn, vz.Spec.CloudAddr, vz.Spec.DevCloudNamespace)
 	if err != nil {
 		log.WithError(err).Error("Failed to connect to cloud client")
 		return err
 	}
 
 	// If no version is set, we should fetch the latest version. This will trigger another reconcile that will do
-	// the actual vizier deployment.
-	if vz.Spec.Version == "" {
+	// the actual vizier deployment. Versions can't be fetched for Viziers deployed from local YAMLs, since these
+	// may not have access to Pixie Cloud.
+	if vz.Spec.Version == "" && vz.Spec.YAMLConfigMapName == "" {
 		atClient := cloudpb.NewArtifactTrackerClient(cloudClient)
 		latest, err := getLatestVizierVersion(ctx, atClient)
 		if err != nil {
@@ -285,58 +519,25 @@ func setReconciliationPhase(vz *v1alpha1.Vizier, rp v1alpha1.ReconciliationPhase
 	vz.Status.ReconciliationPhase = rp
 	timeNow := metav1.Now()
 	vz.Status.LastReconciliationPhaseTime = &timeNow
+	setReconciliationConditions(vz, rp)
+	recordReconciliationPhase(vz)
 	return vz
 }
 
 func (r *VizierReconciler) deployVizier(ctx context.Context, req ctrl.Request, vz *v1alpha1.Vizier, update bool) error {
 	log.Info("Starting a vizier deploy")
-	cloudClient, err := getCloudClientConnection(vz.Spec.CloudAddr, vz.Spec.DevCloudNamespace)
-	if err != nil {
-		log.WithError(err).Error("Failed to connect to cloud client")
-		return err
-	}
+
+	r.resyncs.set(req.NamespacedName, time.Now())
 
 	// Set the status of the Vizier.
 	vz = setReconciliationPhase(vz, v1alpha1.ReconciliationPhaseUpdating)
-	err = r.Status().Update(ctx, vz)
+	err := r.Status().Update(ctx, vz)
 	if err != nil {
 		log.WithError(err).Error("Failed to update status in Vizier spec")
 		return err
 	}
 
-	// Add an additional annotation to our deployed vizier-resources, to allow easier tracking of the vizier resources.
-	if vz.Spec.Pod == nil {
-		vz.Spec.Pod = &v1alpha1.PodPolicy{}
-	}
-
-	if vz.Spec.Pod.Annotations == nil {
-		vz.Spec.Pod.Annotations = make(map[string]string)
-	}
-
-	if vz.Spec.Pod.Labels == nil {
-		vz.Spec.Pod.Labels = make(map[string]string)
-	}
-
-	if vz.Spec.Pod.NodeSelector == nil {
-		vz.Spec.Pod.NodeSelector = make(map[string]string)
-	}
-
-	if !vz.Spec.UseEtcdOperator {
-		// Check if the cluster offers PVC support.
-		// If it does not, we should default to using the etcd operator, which does not
-		// require PVC support.
-		defaultStorageExists, err := validateNumDefaultStorageClasses(r.Clientset)
-		if err != nil {
-			log.WithError(err).Error("Error checking default storage classes")
-		}
-		if !defaultStorageExists {
-			log.Warn("No default storage class detected for cluster. Deploying etcd operator instead of statefulset for metadata backend.")
-			vz.Spec.UseEtcdOperator = true
-		}
-	}
-
-	vz.Spec.Pod.Annotations[operatorAnnotation] = req.Name
-	vz.Spec.Pod.Labels[operatorAnnotation] = req.Name
+	r.setDeployDefaults(ctx, req, vz)
 
 	// Update the spec in the k8s api as other parts of the code expect this to be true.
 	err = r.Update(ctx, vz)
@@ -345,57 +546,132 @@ func (r *VizierReconciler) deployVizier(ctx context.Context, req ctrl.Request, v
 		return err
 	}
 
+	// The operator's spec defaults are only merged in once the spec has been updated, so that they are not written
+	// to the Vizier.
+	err = r.applySpecDefaults(ctx, vz)
+	if err != nil {
+		log.WithError(err).Error("Failed to apply the operator's Vizier spec defaults")
+		r.recordDeployFailure(ctx, vz, "spec-defaults", err)
+		return err
+	}
+
 	// Get the checksum up here in case the spec changes midway through.
-	checksum, err := getSpecChecksum(vz)
+	checksum, err := getDeployChecksum(ctx, r.Clientset, vz)
 	if err != nil {
 		return err
 	}
 
-	configForVizierResp, err := generateVizierYAMLsConfig(ctx, req.Namespace, vz, cloudClient)
+	yamlMap, sentryDSN, err := r.getVizierYAMLs(ctx, req.Namespace, vz)
 	if err != nil {
-		log.WithError(err).Error("Failed to generate configs for Vizier YAMLs")
+		log.WithError(err).Error("Failed to get Vizier YAMLs")
+		r.recordDeployFailure(ctx, vz, "yamls", err)
 		return err
 	}
-	yamlMap := configForVizierResp.NameToYamlContent
+	if vz.Spec.YAMLConfigMapName == "" {
+		// Update Vizier CRD status sentryDSN so that it can be accessed by other
+		// vizier pods.
+		vz.Status.SentryDSN = sentryDSN
+	}
 
-	// Update Vizier CRD status sentryDSN so that it can be accessed by other
-	// vizier pods.
-	vz.Status.SentryDSN = configForVizierResp.SentryDSN
+	// The resolved images are recorded in the status, from which they are applied to the configured resources.
+	var imageDigests map[string]string
+	if vz.Spec.ImageDigests != nil {
+		imageDigests, err = r.resolveImageDigests(ctx, req.Namespace, vz, yamlMap)
+		if err != nil {
+			log.WithError(err).Error("Failed to resolve Vizier image digests")
+			r.recordDeployFailure(ctx, vz, "image-digests", err)
+			return err
+		}
+		vz.Status.ImageDigests = imageDigests
+	}
+
+	if len(vz.Spec.NamespaceLabels) > 0 {
+		err = ensureVizierNamespace(ctx, r.Clientset, req.Namespace, vz.Spec.NamespaceLabels)
+		if err != nil {
+			log.WithError(err).Error("Failed to ensure Vizier namespace")
+			r.recordDeployFailure(ctx, vz, "namespace", err)
+			return err
+		}
+	}
+
+	if update && vz.Spec.MetadataBackup != nil {
+		err = r.backupAndRestoreMetadata(ctx, req.Namespace, vz)
+		if err != nil {
+			log.WithError(err).Error("Failed to back up or restore metadata")
+			r.recordDeployFailure(ctx, vz, "metadata-backup", err)
+			return err
+		}
+	}
 
 	if !update {
 		err = r.deployVizierConfigs(ctx, req.Namespace, vz, yamlMap)
 		if err != nil {
 			log.WithError(err).Error("Failed to deploy Vizier configs")
+			r.recordDeployFailure(ctx, vz, "configs", err)
 			return err
 		}
 
 		err = r.deployVizierCerts(ctx, req.Namespace, vz)
 		if err != nil {
 			log.WithError(err).Error("Failed to deploy Vizier certs")
+			r.recordDeployFailure(ctx, vz, "certs", err)
 			return err
 		}
 
 		err = r.deployVizierDeps(ctx, req.Namespace, vz, yamlMap)
 		if err != nil {
 			log.WithError(err).Error("Failed to deploy Vizier deps")
+			r.recordDeployFailure(ctx, vz, "deps", err)
 			return err
 		}
 	} else {
+		// Externally issued certs are copied on every update, so that reissued certs are picked up.
+		if hasExternallyIssuedCerts(vz) {
+			err = r.applyVizierCerts(ctx, req.Namespace, vz)
+			if err != nil {
+				log.WithError(err).Error("Failed to deploy Vizier certs")
+				r.recordDeployFailure(ctx, vz, "certs", err)
+				return err
+			}
+		}
+
 		err = r.upgradeNats(ctx, req.Namespace, vz, yamlMap)
 		if err != nil {
 			log.WithError(err).Warning("Failed to upgrade nats")
 		}
 	}
 
-	err = r.deployVizierCore(ctx, req.Namespace, vz, yamlMap, update)
+	if vz.Spec.OpenShift {
+		err = deployOpenShiftSCCBindings(ctx, r.Clientset, req.Namespace, req.Name)
+		if err != nil {
+			log.WithError(err).Error("Failed to deploy OpenShift SCC bindings")
+			r.recordDeployFailure(ctx, vz, "scc", err)
+			return err
+		}
+	}
+
+	resourceChecksums, err := r.deployVizierCore(ctx, req.Namespace, vz, yamlMap, update)
 	if err != nil {
 		log.WithError(err).Error("Failed to deploy Vizier core")
+		r.recordDeployFailure(ctx, vz, "core", err)
 		return err
 	}
 
-	// TODO(michellenguyen): Remove when the operator has the ability to ping CloudConn for Vizier Version.
-	// We are currently blindly assuming that the new version is correct.
-	_ = waitForCluster(r.Clientset, req.Namespace)
+	if update {
+		// Resources which are no longer deployed are only cleaned up once the new resources have been applied.
+		err = r.pruneOrphanedResources(ctx, req.Namespace, vz, yamlMap)
+		if err != nil {
+			log.WithError(err).Warn("Failed to prune orphaned Vizier resources")
+		}
+	}
+
+	// The Vizier is only ready once the cloud connector of the deployed version is connected to Pixie Cloud.
+	err = waitForCloudConnHealthy(ctx, r.Clientset, newStatuszHTTPClient(), req.Namespace)
+	if err != nil {
+		log.WithError(err).Error("Cloud connector is unhealthy after deploy")
+		r.recordDeployFailure(ctx, vz, "health", err)
+		return err
+	}
 
 	// Refetch the Vizier resource, as it may have changed in the time in which we were waiting for the cluster.
 	err = r.Get(ctx, req.NamespacedName, vz)
@@ -406,6 +682,8 @@ func (r *VizierReconciler) deployVizier(ctx context.Context, req ctrl.Request, v
 	}
 
 	vz.Status.Version = vz.Spec.Version
+	vz.Status.ImageDigests = imageDigests
+	vz.Status.ResourceChecksums = resourceChecksums
 	vz = setReconciliationPhase(vz, v1alpha1.ReconciliationPhaseReady)
 
 	vz.Status.Checksum = checksum
@@ -419,6 +697,148 @@ func (r *VizierReconciler) deployVizier(ctx context.Context, req ctrl.Request, v
 	return nil
 }
 
+// setDeployDefaults fills in the parts of the spec which are required to deploy the Vizier, if they are unset.
+func (r *VizierReconciler) setDeployDefaults(ctx context.Context, req ctrl.Request, vz *v1alpha1.Vizier) {
+	// Add an additional annotation to our deployed vizier-resources, to allow easier tracking of the vizier resources.
+	if vz.Spec.Pod == nil {
+		vz.Spec.Pod = &v1alpha1.PodPolicy{}
+	}
+
+	if vz.Spec.Pod.Annotations == nil {
+		vz.Spec.Pod.Annotations = make(map[string]string)
+	}
+
+	if vz.Spec.Pod.Labels == nil {
+		vz.Spec.Pod.Labels = make(map[string]string)
+	}
+
+	if vz.Spec.Pod.NodeSelector == nil {
+		vz.Spec.Pod.NodeSelector = make(map[string]string)
+	}
+
+	if !vz.Spec.UseEtcdOperator {
+		// Check if the cluster offers PVC support.
+		// If it does not, we should default to using the etcd operator, which does not
+		// require PVC support.
+		storageClassExists, err := hasMetadataStorageClass(ctx, r.Clientset, vz)
+		if err != nil {
+			log.WithError(err).Error("Error checking storage classes")
+		}
+		if !storageClassExists {
+			log.Warn("No usable storage class detected for cluster. Deploying etcd operator instead of statefulset for metadata backend.")
+			vz.Spec.UseEtcdOperator = true
+		}
+	}
+
+	if !vz.Spec.OpenShift {
+		openShift, err := isOpenShift(r.Clientset)
+		if err != nil {
+			log.WithError(err).Error("Error checking for OpenShift")
+		} else if openShift {
+			log.Info("OpenShift detected. Deploying Vizier with SecurityContextConstraints.")
+			vz.Spec.OpenShift = true
+		}
+	}
+
+	if len(vz.Spec.IPFamilies) == 0 {
+		families, err := getClusterIPFamilies(ctx, r.Clientset)
+		if err != nil {
+			log.WithError(err).Error("Error detecting the cluster's IP families")
+		} else if len(families) > 1 || families[0] != v1.IPv4Protocol {
+			// IPv4-only clusters are left to the cluster's defaults, which the Vizier YAMLs already assume.
+			log.WithField("ipFamilies", families).Info("IPv6 detected. Deploying Vizier services with the cluster's IP families.")
+			vz.Spec.IPFamilies = families
+		}
+	}
+
+	if vz.Spec.Proxy != nil && (len(vz.Spec.Proxy.PodCIDRs) == 0 || len(vz.Spec.Proxy.ServiceCIDRs) == 0) {
+		// Vizier components reach the API server and each other by IP, which must not go through the proxy.
+		podCIDRs, serviceCIDRs, err := getClusterCIDRs(ctx, r.Clientset)
+		if err != nil {
+			log.WithError(err).Error("Error detecting the cluster's CIDRs")
+		} else {
+			if len(vz.Spec.Proxy.PodCIDRs) == 0 {
+				vz.Spec.Proxy.PodCIDRs = podCIDRs
+			}
+			if len(vz.Spec.Proxy.ServiceCIDRs) == 0 {
+				vz.Spec.Proxy.ServiceCIDRs = serviceCIDRs
+			}
+		}
+	}
+
+	vz.Spec.Pod.Annotations[operatorAnnotation] = req.Name
+	vz.Spec.Pod.Labels[operatorAnnotation] = req.Name
+	vz.Spec.Pod.Labels[vizierNamespaceLabel] = req.Namespace
+}
+
+// getVizierYAMLs returns the YAMLs to deploy for the Vizier, keyed by YAML name, along with the Sentry DSN
+// which Vizier should report errors to.
+func (r *VizierReconciler) getVizierYAMLs(ctx context.Context, namespace string, vz *v1alpha1.Vizier) (map[string]string, string, error) {
+	if vz.Spec.YAMLConfigMapName != "" {
+		yamlMap, err := getVizierYAMLsFromCluster(ctx, r.Clientset, namespace, vz)
+		if err != nil {
+			return nil, "", err
+		}
+		return yamlMap, "", nil
+	}
+
+	cloudClient, err := r.cloudConns.get(r.CloudConn, vz.Spec.CloudAddr, vz.Spec.DevCloudNamespace)
+	if err != nil {
+		return nil, "", err
+	}
+
+	configForVizierResp, err := generateVizierYAMLsConfig(ctx, namespace, vz, cloudClient)
+	if err != nil {
+		return nil, "", err
+	}
+	yamlMap := configForVizierResp.NameToYamlContent
+	if r.YAMLVerificationKeyFile != "" {
+		// The key is read on each fetch, so that a rotated key is picked up without restarting the operator.
+		key, err := loadYAMLVerificationKey(r.YAMLVerificationKeyFile)
+		if err != nil {
+			return nil, "", err
+		}
+		yamlMap, err = verifyVizierYAMLs(key, yamlMap)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	return yamlMap, configForVizierResp.SentryDSN, nil
+}
+
+// backupAndRestoreMetadata backs up the metadata store before an update. If a restore was requested from a backup
+// which has not been restored yet, the metadata store is then restored from that backup.
+func (r *VizierReconciler) backupAndRestoreMetadata(ctx context.Context, namespace string, vz *v1alpha1.Vizier) error {
+	backupName, err := backupMetadata(ctx, r.Clientset, namespace, vz)
+	if err != nil {
+		return err
+	}
+	vz.Status.LastMetadataBackup = backupName
+
+	restoreFrom := vz.Spec.MetadataBackup.RestoreFrom
+	if restoreFrom != "" && restoreFrom != vz.Status.LastMetadataRestore {
+		err = restoreMetadata(ctx, r.Clientset, namespace, vz, restoreFrom)
+		if err != nil {
+			return err
+		}
+		vz.Status.LastMetadataRestore = restoreFrom
+	}
+	return r.Status().Update(ctx, vz)
+}
+
+// recordDeployFailure records that the given step of a Vizier deploy failed.
+func (r *VizierReconciler) recordDeployFailure(ctx context.Context, vz *v1alpha1.Vizier, step string, deployErr error) {
+	deployFailureCount.WithLabelValues(step).Inc()
+
+	setCondition(vz, v1alpha1.VizierConditionResourcesApplied, metav1.ConditionFalse, "DeployFailed",
+		fmt.Sprintf("Failed to deploy Vizier %s: %v", step, deployErr))
+	if err := r.Status().Update(ctx, vz); err != nil {
+		log.WithError(err).Error("Failed to update Vizier status")
+	}
+}
+
+// getSpecChecksum returns the checksum of the Vizier's spec and the version of the operator. The operator version is
+// included so that fixes to the rendered resources in a new operator are deployed to existing Viziers.
 func getSpecChecksum(vz *v1alpha1.Vizier) ([]byte, error) {
 	specStr, err := json.Marshal(vz.Spec)
 	if err != nil {
@@ -427,10 +847,41 @@ func getSpecChecksum(vz *v1alpha1.Vizier) ([]byte, error) {
 	}
 	h := sha256.New()
 	h.Write([]byte(specStr))
+	h.Write([]byte(getOperatorVersion()))
 	return h.Sum(nil), nil
 }
 
+// getDeployChecksum returns the checksum of everything which determines the deployed resources. This is the spec
+// checksum, along with the auto-sized PEM memory, which depends on the sizes of the nodes rather than the spec, so that
+// PEMs are resized when the nodes are.
+func getDeployChecksum(ctx context.Context, clientset kubernetes.Interface, vz *v1alpha1.Vizier) ([]byte, error) {
+	checksum, err := getSpecChecksum(vz)
+	if err != nil || vz.Spec.PEMMemoryAutoSize == nil {
+		return checksum, err
+	}
+	pemMemory, err := getAutoSizedPEMMemory(ctx, clientset, vz)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write(checksum)
+	h.Write([]byte(pemMemory.String()))
+	return h.Sum(nil), nil
+}
+
+// getOperatorVersion returns the semantic version of the operator, without its build metadata, which differs between
+// builds of the same version.
+func getOperatorVersion() string {
+	v := version.GetVersion().Semver()
+	v.Build = nil
+	return v.String()
+}
+
 func (r *VizierReconciler) upgradeNats(ctx context.Context, namespace string, vz *v1alpha1.Vizier, yamlMap map[string]string) error {
+	if vz.Spec.ExternalNATS != nil {
+		log.Info("Using external NATS. Nothing to upgrade")
+		return nil
+	}
 	log.Info("Upgrading NATS if necessary")
 
 	ss, err := r.Clientset.AppsV1().StatefulSets(namespace).Get(ctx, "pl-nats", metav1.GetOptions{})
@@ -469,7 +920,8 @@ func (r *VizierReconciler) upgradeNats(ctx context.Context, namespace string, vz
 		return r.deployNATSStatefulset(ctx, namespace, vz, yamlMap)
 	}
 
-	if natsImage == newSS.Spec.Template.Spec.Containers[0].Image {
+	// The NATS params may have changed, even if the image has not.
+	if natsImage == newSS.Spec.Template.Spec.Containers[0].Image && vz.Spec.NATS == nil {
 		log.Info("NATS up to date. Nothing to do.")
 		return nil
 	}
@@ -485,149 +937,613 @@ func (r *VizierReconciler) deployVizierCerts(ctx context.Context, namespace stri
 	log.Info("Generating certs")
 
 	// Assign JWT signing key.
-	jwtSigningKey := make([]byte, 64)
-	_, err := rand.Read(jwtSigningKey)
+	jwtSigningKey, err := generateJWTSigningKey()
 	if err != nil {
 		return err
 	}
-	s := k8s.GetSecret(r.Clientset, namespace, "pl-cluster-secrets")
+	s := k8s.GetSecret(r.Clientset, namespace, clusterSecretName)
 	if s == nil {
 		return errors.New("pl-cluster-secrets does not exist")
 	}
-	s.Data[clusterSecretJWTKey] = []byte(fmt.Sprintf("%x", jwtSigningKey))
+	s.Data[clusterSecretJWTKey] = jwtSigningKey
 
 	_, err = r.Clientset.CoreV1().Secrets(namespace).Update(ctx, s, metav1.UpdateOptions{})
 	if err != nil {
-		return err
+		return err
+	}
+
+	return r.applyVizierCerts(ctx, namespace, vz)
+}
+
+// hasExternallyIssuedCerts returns whether the Vizier's certs are issued outside of the operator, in which case they
+// are copied into the Vizier cert secrets on every update.
+func hasExternallyIssuedCerts(vz *v1alpha1.Vizier) bool {
+	return vz.Spec.CustomTLSCertsSecret != "" || vz.Spec.CertManager != nil
+}
+
+// applyVizierCerts deploys the secrets containing the certs which Vizier services use to communicate. The certs are
+// copied from the custom TLS certs secret or issued by cert-manager if specified, and are otherwise generated.
+func (r *VizierReconciler) applyVizierCerts(ctx context.Context, namespace string, vz *v1alpha1.Vizier) error {
+	var certYAMLs string
+	var err error
+	switch {
+	case vz.Spec.CustomTLSCertsSecret != "":
+		certYAMLs, err = getCustomVizierCertYAMLs(ctx, r.Clientset, namespace, vz.Spec.CustomTLSCertsSecret)
+	case vz.Spec.CertManager != nil:
+		certYAMLs, err = r.getCertManagerVizierCertYAMLs(ctx, namespace, vz)
+	default:
+		certYAMLs, err = certs.GenerateVizierCertYAMLs(namespace)
+	}
+	if err != nil {
+		return err
+	}
+
+	resources, err := k8s.GetResourcesFromYAML(strings.NewReader(certYAMLs))
+	if err != nil {
+		return err
+	}
+	for _, r := range resources {
+		err = updateResourceConfiguration(r, vz)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Generated certs are never replaced, since the running pods would no longer trust each other.
+	return k8s.ApplyResources(r.Clientset, r.RestConfig, resources, namespace, nil, hasExternallyIssuedCerts(vz))
+}
+
+// getCustomVizierCertYAMLs returns the YAMLs for the Vizier cert secrets, containing the certs from the given custom
+// TLS certs secret.
+func getCustomVizierCertYAMLs(ctx context.Context, clientset kubernetes.Interface, namespace string, name string) (string, error) {
+	s, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get custom TLS certs secret %s: %w", name, err)
+	}
+	for _, key := range customTLSCertsKeys {
+		if len(s.Data[key]) == 0 {
+			return "", fmt.Errorf("custom TLS certs secret %s is missing %s", name, key)
+		}
+	}
+	return certs.VizierCertYAMLs(namespace, s.Data["ca.crt"], s.Data["server.crt"], s.Data["server.key"],
+		s.Data["client.crt"], s.Data["client.key"])
+}
+
+// deployVizierConfigs deploys the secrets, configmaps, and certs that are necessary for running vizier.
+func (r *VizierReconciler) deployVizierConfigs(ctx context.Context, namespace string, vz *v1alpha1.Vizier, yamlMap map[string]string) error {
+	log.Info("Deploying Vizier configs and secrets")
+	resources, err := getConfiguredResources(vz, yamlMap["secrets"])
+	if err != nil {
+		return err
+	}
+	return k8s.ApplyResources(r.Clientset, r.RestConfig, resources, namespace, nil, false)
+}
+
+// deployNATSStatefulset deploys nats to the given namespace.
+func (r *VizierReconciler) deployNATSStatefulset(ctx context.Context, namespace string, vz *v1alpha1.Vizier, yamlMap map[string]string) error {
+	log.Info("Deploying NATS")
+	resources, err := getConfiguredResources(vz, yamlMap["nats"])
+	if err != nil {
+		return err
+	}
+	return r.retryDeploy(namespace, resources, true)
+}
+
+// updateNATSConfiguration applies the NATS params to the NATS statefulset and its config. When running more than
+// one NATS server, the servers are clustered by discovering each other through the headless NATS service.
+func updateNATSConfiguration(nats *v1alpha1.NATSParams, resource *k8s.Resource) error {
+	res := resource.Object.Object
+	switch {
+	case resource.GVK.Kind == "ConfigMap" && resource.Object.GetName() == natsConfigMapName:
+		if nats.Replicas == nil || *nats.Replicas <= 1 {
+			return nil
+		}
+		conf, ok, err := unstructured.NestedString(res, "data", "nats.conf")
+		if !ok || err != nil {
+			return err
+		}
+		conf += natsClusterConfig
+		return unstructured.SetNestedField(res, conf, "data", "nats.conf")
+	case resource.GVK.Kind == "StatefulSet" && resource.Object.GetName() == natsLabel:
+		if nats.Replicas != nil {
+			err := unstructured.SetNestedField(res, int64(*nats.Replicas), "spec", "replicas")
+			if err != nil {
+				return err
+			}
+		}
+		if nats.Resources == nil {
+			return nil
+		}
+		natsResources, err := runtime.DefaultUnstructuredConverter.ToUnstructured(nats.Resources)
+		if err != nil {
+			return err
+		}
+		containers, _, err := unstructured.NestedSlice(res, "spec", "template", "spec", "containers")
+		if err != nil {
+			return err
+		}
+		for _, c := range containers {
+			castedContainer, ok := c.(map[string]interface{})
+			if ok && castedContainer["name"] == natsLabel {
+				castedContainer["resources"] = natsResources
+			}
+		}
+		return unstructured.SetNestedSlice(res, containers, "spec", "template", "spec", "containers")
+	}
+	return nil
+}
+
+// deployEtcdStatefulset deploys etcd to the given namespace.
+func (r *VizierReconciler) deployEtcdStatefulset(ctx context.Context, namespace string, vz *v1alpha1.Vizier, yamlMap map[string]string) error {
+	log.Info("Deploying etcd")
+	resources, err := getConfiguredResources(vz, yamlMap["etcd"])
+	if err != nil {
+		return err
+	}
+	return r.retryDeploy(namespace, resources, false)
+}
+
+// deployVizierDeps deploys the vizier deps to the given namespace. This includes deploying deps like etcd and nats.
+func (r *VizierReconciler) deployVizierDeps(ctx context.Context, namespace string, vz *v1alpha1.Vizier, yamlMap map[string]string) error {
+	if vz.Spec.ExternalNATS == nil {
+		err := r.deployNATSStatefulset(ctx, namespace, vz, yamlMap)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !vz.Spec.UseEtcdOperator {
+		return nil
+	}
+
+	return r.deployEtcdStatefulset(ctx, namespace, vz, yamlMap)
+}
+
+// deployVizierCore deploys the core pods and services for running vizier. When updating, only the resources which
+// changed since the last deploy are applied. It returns the checksums of the deployed resources.
+func (r *VizierReconciler) deployVizierCore(ctx context.Context, namespace string, vz *v1alpha1.Vizier, yamlMap map[string]string, allowUpdate bool) (map[string]string, error) {
+	log.Info("Deploying Vizier")
+
+	resources, err := getConfiguredResources(vz, yamlMap[getVizierCoreYAMLName(vz)])
+	if err != nil {
+		return nil, err
+	}
+	kelvinAutoscaler, err := getKelvinAutoscaler(vz)
+	if err != nil {
+		return nil, err
+	}
+	if kelvinAutoscaler != nil {
+		resources = append(resources, kelvinAutoscaler)
+	} else if allowUpdate {
+		err = deleteKelvinAutoscaler(ctx, r.Clientset, namespace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// If updating, don't reapply service accounts as that will create duplicate service tokens.
+	if allowUpdate {
+		filteredResources := make([]*k8s.Resource, 0)
+		for _, r := range resources {
+			if r.GVK.Kind != "ServiceAccount" {
+				filteredResources = append(filteredResources, r)
+			}
+		}
+		resources = filteredResources
+	}
+
+	if vz.Spec.PEMMemoryAutoSize != nil {
+		pemMemory, err := getAutoSizedPEMMemory(ctx, r.Clientset, vz)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resources {
+			if r.GVK.Kind == "DaemonSet" && r.Object.GetName() == vizierPemLabel {
+				err = updatePEMMemory(pemMemory, r.Object.Object)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for _, r := range resources {
+		// The operator rolls out updated PEMs itself when a PEM upgrade strategy is specified.
+		if allowUpdate && vz.Spec.PEMUpgradeStrategy != nil && r.GVK.Kind == "DaemonSet" && r.Object.GetName() == vizierPemLabel {
+			err = setPEMOnDeleteUpdateStrategy(r.Object.Object)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	resourceChecksums, err := getResourceChecksums(vz, resources)
+	if err != nil {
+		return nil, err
 	}
-
-	certYAMLs, err := certs.GenerateVizierCertYAMLs(namespace)
+	toApply := resources
+	if allowUpdate {
+		toApply = filterChangedResources(resources, resourceChecksums, vz.Status.ResourceChecksums)
+		log.Infof("Applying %d of %d Vizier resources which changed since the last deploy", len(toApply), len(resources))
+	}
+	err = r.retryDeploy(namespace, toApply, allowUpdate)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	resources, err := k8s.GetResourcesFromYAML(strings.NewReader(certYAMLs))
+	checksum, err := getDeployChecksum(ctx, r.Clientset, vz)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	for _, r := range resources {
-		err = updateResourceConfiguration(r, vz)
+	r.appliedResources.set(types.NamespacedName{Namespace: namespace, Name: vz.Name}, checksum, resources)
+
+	if allowUpdate && vz.Spec.PEMUpgradeStrategy != nil {
+		err = rolloutPEMs(ctx, r.Clientset, namespace, vz.Spec.PEMUpgradeStrategy)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
+	return resourceChecksums, nil
+}
 
-	return k8s.ApplyResources(r.Clientset, r.RestConfig, resources, namespace, nil, false)
+// getVizierCoreYAMLName returns the name of the YAML containing the core Vizier resources, which depends on the
+// metadata store backend.
+func getVizierCoreYAMLName(vz *v1alpha1.Vizier) string {
+	if vz.Spec.UseEtcdOperator {
+		return "vizier_etcd"
+	}
+	return "vizier_persistent"
 }
 
-// deployVizierConfigs deploys the secrets, configmaps, and certs that are necessary for running vizier.
-func (r *VizierReconciler) deployVizierConfigs(ctx context.Context, namespace string, vz *v1alpha1.Vizier, yamlMap map[string]string) error {
-	log.Info("Deploying Vizier configs and secrets")
-	resources, err := k8s.GetResourcesFromYAML(strings.NewReader(yamlMap["secrets"]))
+// getConfiguredResources parses the resources in the given YAML, and applies the Vizier's spec to them.
+func getConfiguredResources(vz *v1alpha1.Vizier, yaml string) ([]*k8s.Resource, error) {
+	resources, err := k8s.GetResourcesFromYAML(strings.NewReader(yaml))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	for _, r := range resources {
 		err = updateResourceConfiguration(r, vz)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
-	return k8s.ApplyResources(r.Clientset, r.RestConfig, resources, namespace, nil, false)
+	return resources, nil
 }
 
-// deployNATSStatefulset deploys nats to the given namespace.
-func (r *VizierReconciler) deployNATSStatefulset(ctx context.Context, namespace string, vz *v1alpha1.Vizier, yamlMap map[string]string) error {
-	log.Info("Deploying NATS")
-	resources, err := k8s.GetResourcesFromYAML(strings.NewReader(yamlMap["nats"]))
-	if err != nil {
-		return err
+func updateResourceConfiguration(resource *k8s.Resource, vz *v1alpha1.Vizier) error {
+	// Add custom labels and annotations to the k8s resource.
+	addKeyValueMapToResource("labels", vz.Spec.Pod.Labels, resource.Object.Object)
+	addKeyValueMapToResource("annotations", vz.Spec.Pod.Annotations, resource.Object.Object)
+	updateResourceRequirements(vz.Spec.Pod.Resources, resource.Object.Object)
+	isPEM := resource.GVK.Kind == "DaemonSet" && resource.Object.GetName() == vizierPemLabel
+	if vz.Spec.OpenShift {
+		updateOpenShiftSecurityContext(resource.Object.Object)
 	}
-	for _, r := range resources {
-		err = updateResourceConfiguration(r, vz)
+	updatePodSpec(vz.Spec.Pod, isPEM, resource.Object.Object)
+	if vz.Spec.Pod.RestrictedPodSecurity {
+		updateRestrictedPodSecurity(resource.Object.Object, vz.Spec.OpenShift)
+	}
+	if vz.Spec.Registry != "" {
+		updateImageRegistry(vz.Spec.Registry, resource.Object.Object)
+	}
+	if vz.Spec.Architecture != nil {
+		err := updateArchitecture(vz.Spec.Architecture, resource.Object.Object)
+		if err != nil {
+			return err
+		}
+	}
+	// Images are pinned once all other changes to the images have been made, since the digests are resolved for the
+	// final images.
+	if vz.Spec.ImageDigests != nil {
+		updateImageDigests(vz.Status.ImageDigests, resource.Object.Object)
+	}
+	if vz.Spec.Proxy != nil {
+		updateProxyEnv(vz.Spec.Proxy, resource.Object.Object)
+	}
+	if vz.Spec.Logging != nil {
+		updateLoggingEnv(vz.Spec.Logging, resource.Object.Object)
+	}
+	if vz.Spec.ServiceMesh != nil {
+		updateServiceMeshAnnotations(vz.Spec.ServiceMesh, resource.Object.Object)
+	}
+	if vz.Spec.JWTSigningKeyRotationPeriod != nil {
+		updatePreviousJWTKeyEnv(resource.Object.Object)
+	}
+	if vz.Spec.ExternalNATS != nil {
+		updateExternalNATS(vz.Spec.ExternalNATS, resource.Object.Object)
+	}
+	if resource.GVK.Kind == "PersistentVolumeClaim" && resource.Object.GetName() == metadataPVC {
+		err := updateMetadataPVC(vz, resource.Object.Object)
+		if err != nil {
+			return err
+		}
+	}
+	if vz.Spec.NATS != nil {
+		err := updateNATSConfiguration(vz.Spec.NATS, resource)
+		if err != nil {
+			return err
+		}
+	}
+	if vz.Spec.Kelvin != nil {
+		err := updateKelvinConfiguration(vz.Spec.Kelvin, resource)
+		if err != nil {
+			return err
+		}
+	}
+	if len(vz.Spec.IPFamilies) > 0 {
+		err := updateIPFamilies(vz.Spec.IPFamilies, resource)
 		if err != nil {
 			return err
 		}
 	}
-	return retryDeploy(r.Clientset, r.RestConfig, namespace, resources, true)
+	// JSON patches are applied last, so that they can modify anything set by the operator.
+	return applyJSONPatches(vz.Spec.JSONPatches, resource)
 }
 
-// deployEtcdStatefulset deploys etcd to the given namespace.
-func (r *VizierReconciler) deployEtcdStatefulset(ctx context.Context, namespace string, vz *v1alpha1.Vizier, yamlMap map[string]string) error {
-	log.Info("Deploying etcd")
-	resources, err := k8s.GetResourcesFromYAML(strings.NewReader(yamlMap["etcd"]))
-	if err != nil {
-		return err
+// updateMetadataPVC applies the storage settings for the metadata PVC.
+func updateMetadataPVC(vz *v1alpha1.Vizier, res map[string]interface{}) error {
+	if vz.Spec.StorageClassName != "" {
+		err := unstructured.SetNestedField(res, vz.Spec.StorageClassName, "spec", "storageClassName")
+		if err != nil {
+			return err
+		}
 	}
-	for _, r := range resources {
-		err = updateResourceConfiguration(r, vz)
+	if vz.Spec.MetadataStorageSize != "" {
+		size, err := resource.ParseQuantity(vz.Spec.MetadataStorageSize)
+		if err != nil {
+			return fmt.Errorf("invalid metadata storage size %q: %w", vz.Spec.MetadataStorageSize, err)
+		}
+		err = unstructured.SetNestedField(res, size.String(), "spec", "resources", "requests", "storage")
 		if err != nil {
 			return err
 		}
 	}
-	return retryDeploy(r.Clientset, r.RestConfig, namespace, resources, false)
+	return nil
 }
 
-// deployVizierDeps deploys the vizier deps to the given namespace. This includes deploying deps like etcd and nats.
-func (r *VizierReconciler) deployVizierDeps(ctx context.Context, namespace string, vz *v1alpha1.Vizier, yamlMap map[string]string) error {
-	err := r.deployNATSStatefulset(ctx, namespace, vz, yamlMap)
-	if err != nil {
-		return err
+// updateProxyEnv adds the proxy environment variables to all containers in the resource. Variables which are already
+// set on a container are left unchanged.
+func updateProxyEnv(proxy *v1alpha1.ProxyParams, res map[string]interface{}) {
+	var noProxyHosts []string
+	if proxy.NoProxy != "" {
+		noProxyHosts = append(noProxyHosts, proxy.NoProxy)
+	}
+	noProxyHosts = append(noProxyHosts, defaultNoProxyHosts...)
+	noProxyHosts = append(noProxyHosts, proxy.ServiceCIDRs...)
+	noProxyHosts = append(noProxyHosts, proxy.PodCIDRs...)
+	noProxy := strings.Join(noProxyHosts, ",")
+
+	var envVars []v1.EnvVar
+	// Not all clients agree on the casing of the proxy variables, so both are set.
+	for _, e := range []v1.EnvVar{
+		{Name: "HTTP_PROXY", Value: proxy.HTTPProxy},
+		{Name: "HTTPS_PROXY", Value: proxy.HTTPSProxy},
+		{Name: "NO_PROXY", Value: noProxy},
+	} {
+		if e.Value == "" {
+			continue
+		}
+		envVars = append(envVars, e, v1.EnvVar{Name: strings.ToLower(e.Name), Value: e.Value})
 	}
 
-	if !vz.Spec.UseEtcdOperator {
-		return nil
-	}
+	for _, field := range []string{"containers", "initContainers"} {
+		containers, ok, err := unstructured.NestedFieldNoCopy(res, "spec", "template", "spec", field)
+		if !ok || err != nil {
+			continue
+		}
+		cList, ok := containers.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, c := range cList {
+			castedContainer, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
 
-	return r.deployEtcdStatefulset(ctx, namespace, vz, yamlMap)
+			env, _ := castedContainer["env"].([]interface{})
+			existing := make(map[string]bool)
+			for _, e := range env {
+				if castedEnv, ok := e.(map[string]interface{}); ok {
+					if name, ok := castedEnv["name"].(string); ok {
+						existing[name] = true
+					}
+				}
+			}
+			for _, e := range envVars {
+				if existing[e.Name] {
+					continue
+				}
+				env = append(env, map[string]interface{}{"name": e.Name, "value": e.Value})
+			}
+			castedContainer["env"] = env
+		}
+	}
 }
 
-// deployVizierCore deploys the core pods and services for running vizier.
-func (r *VizierReconciler) deployVizierCore(ctx context.Context, namespace string, vz *v1alpha1.Vizier, yamlMap map[string]string, allowUpdate bool) error {
-	log.Info("Deploying Vizier")
+// getLoggingEnv returns the environment variables which configure the logging of the Vizier components. The Go
+// services read the PL_LOG_* variables, and the PEM and Kelvin read the glog variables.
+func getLoggingEnv(logging *v1alpha1.LoggingParams) []v1.EnvVar {
+	var envVars []v1.EnvVar
+	if logging.Level != "" {
+		envVars = append(envVars, v1.EnvVar{Name: "PL_LOG_LEVEL", Value: string(logging.Level)})
+		minLogLevel := "0"
+		switch logging.Level {
+		case v1alpha1.LogLevelDebug:
+			envVars = append(envVars, v1.EnvVar{Name: "GLOG_v", Value: "1"})
+		case v1alpha1.LogLevelWarn:
+			minLogLevel = "1"
+		case v1alpha1.LogLevelError:
+			minLogLevel = "2"
+		}
+		envVars = append(envVars, v1.EnvVar{Name: "GLOG_minloglevel", Value: minLogLevel})
+	}
+	if logging.Format != "" {
+		envVars = append(envVars, v1.EnvVar{Name: "PL_LOG_FORMAT", Value: string(logging.Format)})
+	}
+	return envVars
+}
 
-	vzYaml := "vizier_persistent"
-	if vz.Spec.UseEtcdOperator {
-		vzYaml = "vizier_etcd"
+// updateLoggingEnv sets the logging environment variables in all containers in the resource, replacing any values
+// which are already set in the Vizier YAMLs.
+func updateLoggingEnv(logging *v1alpha1.LoggingParams, res map[string]interface{}) {
+	envVars := getLoggingEnv(logging)
+	if len(envVars) == 0 {
+		return
+	}
+	for _, field := range []string{"containers", "initContainers"} {
+		containers, ok, err := unstructured.NestedFieldNoCopy(res, "spec", "template", "spec", field)
+		if !ok || err != nil {
+			continue
+		}
+		cList, ok := containers.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, c := range cList {
+			castedContainer, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			env, _ := castedContainer["env"].([]interface{})
+			for _, e := range envVars {
+				env = setEnvVar(env, e)
+			}
+			castedContainer["env"] = env
+		}
 	}
+}
 
-	resources, err := k8s.GetResourcesFromYAML(strings.NewReader(yamlMap[vzYaml]))
-	if err != nil {
-		return err
+// updateExternalNATS configures all containers in the resource to connect to the external NATS cluster, rather than
+// the NATS deployed with Vizier. Init containers which wait on the NATS deployed with Vizier are removed.
+func updateExternalNATS(nats *v1alpha1.ExternalNATSParams, res map[string]interface{}) {
+	podSpec, ok, err := unstructured.NestedMap(res, "spec", "template", "spec")
+	if !ok || err != nil {
+		return
 	}
 
-	// If updating, don't reapply service accounts as that will create duplicate service tokens.
-	if allowUpdate {
-		filteredResources := make([]*k8s.Resource, 0)
-		for _, r := range resources {
-			if r.GVK.Kind != "ServiceAccount" {
-				filteredResources = append(filteredResources, r)
+	envVars := []v1.EnvVar{{Name: "PL_NATS_URL", Value: nats.URL}}
+	var mounts []interface{}
+	volumes, _ := podSpec["volumes"].([]interface{})
+	if nats.TLSSecretName != "" {
+		envVars = append(envVars,
+			v1.EnvVar{Name: "PL_NATS_TLS_CA_CERT", Value: externalNATSCertsPath + "/ca.crt"},
+			v1.EnvVar{Name: "PL_NATS_TLS_CERT", Value: externalNATSCertsPath + "/client.crt"},
+			v1.EnvVar{Name: "PL_NATS_TLS_KEY", Value: externalNATSCertsPath + "/client.key"},
+		)
+		volumes = append(volumes, map[string]interface{}{
+			"name":   "nats-certs",
+			"secret": map[string]interface{}{"secretName": nats.TLSSecretName},
+		})
+		mounts = append(mounts, map[string]interface{}{
+			"name":      "nats-certs",
+			"mountPath": externalNATSCertsPath,
+			"readOnly":  true,
+		})
+	}
+	if nats.CredentialsSecretName != "" {
+		envVars = append(envVars, v1.EnvVar{Name: "PL_NATS_CREDS", Value: externalNATSCredsPath + "/" + externalNATSCredsKey})
+		volumes = append(volumes, map[string]interface{}{
+			"name":   "nats-creds",
+			"secret": map[string]interface{}{"secretName": nats.CredentialsSecretName},
+		})
+		mounts = append(mounts, map[string]interface{}{
+			"name":      "nats-creds",
+			"mountPath": externalNATSCredsPath,
+			"readOnly":  true,
+		})
+	}
+	if len(volumes) > 0 {
+		podSpec["volumes"] = volumes
+	}
+
+	if nats.Streaming == v1alpha1.MessageBusStreamingJetStream {
+		envVars = append(envVars, v1.EnvVar{Name: "PL_JETSTREAM", Value: "true"})
+	} else if nats.STANClusterID != "" {
+		envVars = append(envVars, v1.EnvVar{Name: "PL_STAN_CLUSTER", Value: nats.STANClusterID})
+	}
+
+	for _, field := range []string{"containers", "initContainers"} {
+		cList, ok := podSpec[field].([]interface{})
+		if !ok {
+			continue
+		}
+		var updated []interface{}
+		for _, c := range cList {
+			castedContainer, ok := c.(map[string]interface{})
+			if !ok {
+				updated = append(updated, c)
+				continue
+			}
+			if field == "initContainers" && castedContainer["name"] == natsWaitContainerName {
+				continue
+			}
+
+			env, _ := castedContainer["env"].([]interface{})
+			for _, e := range envVars {
+				env = setEnvVar(env, e)
+			}
+			castedContainer["env"] = env
+
+			if len(mounts) > 0 {
+				containerMounts, _ := castedContainer["volumeMounts"].([]interface{})
+				castedContainer["volumeMounts"] = append(containerMounts, runtime.DeepCopyJSONValue(mounts).([]interface{})...)
 			}
+			updated = append(updated, castedContainer)
 		}
-		resources = filteredResources
+		podSpec[field] = updated
 	}
 
-	for _, r := range resources {
-		err = updateResourceConfiguration(r, vz)
-		if err != nil {
-			return err
+	_ = unstructured.SetNestedMap(res, podSpec, "spec", "template", "spec")
+}
+
+// setEnvVar sets the environment variable in the given list of container environment variables, replacing the
+// value of the variable if it is already set.
+func setEnvVar(env []interface{}, envVar v1.EnvVar) []interface{} {
+	for _, e := range env {
+		castedEnv, ok := e.(map[string]interface{})
+		if ok && castedEnv["name"] == envVar.Name {
+			delete(castedEnv, "valueFrom")
+			castedEnv["value"] = envVar.Value
+			return env
 		}
 	}
-	err = retryDeploy(r.Clientset, r.RestConfig, namespace, resources, allowUpdate)
-	if err != nil {
-		return err
-	}
+	return append(env, map[string]interface{}{"name": envVar.Name, "value": envVar.Value})
+}
 
-	return nil
+// updateImageRegistry updates the images of all containers in the resource to be pulled from the given registry.
+func updateImageRegistry(registry string, res map[string]interface{}) {
+	for _, field := range []string{"containers", "initContainers"} {
+		containers, ok, err := unstructured.NestedFieldNoCopy(res, "spec", "template", "spec", field)
+		if !ok || err != nil {
+			continue
+		}
+		cList, ok := containers.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, c := range cList {
+			castedContainer, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if image, ok := castedContainer["image"].(string); ok && image != "" {
+				castedContainer["image"] = rewriteImageRegistry(image, registry)
+			}
+		}
+	}
 }
 
-func updateResourceConfiguration(resource *k8s.Resource, vz *v1alpha1.Vizier) error {
-	// Add custom labels and annotations to the k8s resource.
-	addKeyValueMapToResource("labels", vz.Spec.Pod.Labels, resource.Object.Object)
-	addKeyValueMapToResource("annotations", vz.Spec.Pod.Annotations, resource.Object.Object)
-	updateResourceRequirements(vz.Spec.Pod.Resources, resource.Object.Object)
-	updatePodSpec(vz.Spec.Pod.NodeSelector, vz.Spec.Pod.SecurityContext, resource.Object.Object)
-	return nil
+// rewriteImageRegistry replaces the registry host of the image with the given registry. Images without an explicit
+// registry host, such as Docker Hub images, are prefixed with the registry.
+func rewriteImageRegistry(image string, registry string) string {
+	registry = strings.TrimSuffix(registry, "/")
+	parts := strings.SplitN(image, "/", 2)
+	// Following the Docker convention, the first component of the image is only a registry host if it contains
+	// a "." or ":", or is "localhost".
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return registry + "/" + parts[1]
+	}
+	return registry + "/" + image
 }
 
 func convertResourceType(originalLst v1.ResourceList) *vizierconfigpb.ResourceList {
@@ -648,11 +1564,16 @@ func generateVizierYAMLsConfig(ctx context.Context, ns string, vz *v1alpha1.Vizi
 	error) {
 	client := cloudpb.NewConfigServiceClient(conn)
 
+	deployKey, err := resolveDeployKey(ctx, vz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve deploy key: %w", err)
+	}
+
 	req := &cloudpb.ConfigForVizierRequest{
 		Namespace: ns,
 		VzSpec: &vizierconfigpb.VizierSpec{
 			Version:               vz.Spec.Version,
-			DeployKey:             vz.Spec.DeployKey,
+			DeployKey:             deployKey,
 			CustomDeployKeySecret: vz.Spec.CustomDeployKeySecret,
 			DisableAutoUpdate:     vz.Spec.DisableAutoUpdate,
 			UseEtcdOperator:       vz.Spec.UseEtcdOperator,
@@ -690,13 +1611,54 @@ func generateVizierYAMLsConfig(ctx context.Context, ns string, vz *v1alpha1.Vizi
 		}
 	}
 
+	start := time.Now()
 	resp, err := client.GetConfigForVizier(ctx, req)
+	cloudConfigFetchDuration.WithLabelValues(ns).Observe(time.Since(start).Seconds())
 	if err != nil {
+		cloudRPCErrorCount.WithLabelValues("GetConfigForVizier").Inc()
 		return nil, err
 	}
 	return resp, nil
 }
 
+// getVizierYAMLsFromCluster reads the Vizier YAMLs from the ConfigMap or Secret specified in the Vizier spec.
+func getVizierYAMLsFromCluster(ctx context.Context, clientset kubernetes.Interface, ns string, vz *v1alpha1.Vizier) (map[string]string, error) {
+	name := vz.Spec.YAMLConfigMapName
+
+	var yamlMap map[string]string
+	cm, err := clientset.CoreV1().ConfigMaps(ns).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		yamlMap = cm.Data
+	case k8serrors.IsNotFound(err):
+		s, err := clientset.CoreV1().Secrets(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ConfigMap or Secret %s: %w", name, err)
+		}
+		yamlMap = make(map[string]string, len(s.Data))
+		for k, v := range s.Data {
+			yamlMap[k] = string(v)
+		}
+	default:
+		return nil, err
+	}
+
+	vzYaml := "vizier_persistent"
+	if vz.Spec.UseEtcdOperator {
+		vzYaml = "vizier_etcd"
+	}
+	required := []string{"secrets", "nats", vzYaml}
+	if vz.Spec.UseEtcdOperator {
+		required = append(required, "etcd")
+	}
+	for _, k := range required {
+		if _, ok := yamlMap[k]; !ok {
+			return nil, fmt.Errorf("%s is missing the %s YAML", name, k)
+		}
+	}
+	return yamlMap, nil
+}
+
 // addKeyValueMapToResource adds the given keyValue map to the K8s resource.
 func addKeyValueMapToResource(mapName string, keyValues map[string]string, res map[string]interface{}) {
 	metadata := make(map[string]interface{})
@@ -796,7 +1758,7 @@ func updateResourceRequirements(requirements v1.ResourceRequirements, res map[st
 		castedContainer["resources"] = resources
 	}
 }
-func updatePodSpec(nodeSelector map[string]string, securityCtx *v1alpha1.PodSecurityContext, res map[string]interface{}) {
+func updatePodSpec(pod *v1alpha1.PodPolicy, isPEM bool, res map[string]interface{}) {
 	podSpec := make(map[string]interface{})
 	md, ok, err := unstructured.NestedFieldNoCopy(res, "spec", "template", "spec")
 	if ok && err == nil {
@@ -805,12 +1767,14 @@ func updatePodSpec(nodeSelector map[string]string, securityCtx *v1alpha1.PodSecu
 		}
 	}
 
+	component := getComponentName(res)
+
 	castedNodeSelector := make(map[string]interface{})
 	ns, ok := podSpec["nodeSelector"].(map[string]interface{})
 	if ok {
 		castedNodeSelector = ns
 	}
-	for k, v := range nodeSelector {
+	for k, v := range getComponentNodeSelector(pod, component) {
 		if _, ok := castedNodeSelector[k]; ok {
 			continue
 		}
@@ -818,7 +1782,66 @@ func updatePodSpec(nodeSelector map[string]string, securityCtx *v1alpha1.PodSecu
 	}
 	podSpec["nodeSelector"] = castedNodeSelector
 
+	if componentTolerations := getComponentTolerations(pod, component); len(componentTolerations) > 0 {
+		tolerations, _ := podSpec["tolerations"].([]interface{})
+		for i := range componentTolerations {
+			t, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&componentTolerations[i])
+			if err != nil {
+				log.WithError(err).Error("Failed to convert toleration")
+				continue
+			}
+			tolerations = append(tolerations, t)
+		}
+		podSpec["tolerations"] = tolerations
+	}
+
+	updateComponentTermination(pod, component, podSpec)
+
+	if pod.Affinity != nil {
+		if err := updateAffinity(pod.Affinity, podSpec); err != nil {
+			log.WithError(err).Error("Failed to update affinity")
+		}
+	}
+
+	if isPEM && len(pod.PEMExcludeNodeSelector) > 0 {
+		if err := updateAffinity(getExcludeNodeAffinity(pod.PEMExcludeNodeSelector), podSpec); err != nil {
+			log.WithError(err).Error("Failed to update affinity")
+		}
+	}
+
+	if _, ok := podSpec["priorityClassName"]; !ok && pod.PriorityClassName != "" {
+		podSpec["priorityClassName"] = pod.PriorityClassName
+	}
+
+	// Pods in the host network must use ClusterFirstWithHostNet to continue resolving cluster services.
+	if isPEM && pod.PEMHostNetwork {
+		podSpec["hostNetwork"] = true
+		podSpec["dnsPolicy"] = string(v1.DNSClusterFirstWithHostNet)
+	}
+
+	if isPEM && pod.PEMHostPID != nil {
+		podSpec["hostPID"] = *pod.PEMHostPID
+	}
+
+	if pod.DNSPolicy != "" {
+		dnsPolicy := pod.DNSPolicy
+		if hostNetwork, _ := podSpec["hostNetwork"].(bool); hostNetwork && dnsPolicy == v1.DNSClusterFirst {
+			dnsPolicy = v1.DNSClusterFirstWithHostNet
+		}
+		podSpec["dnsPolicy"] = string(dnsPolicy)
+	}
+
+	if pod.DNSConfig != nil {
+		dnsConfig, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod.DNSConfig)
+		if err != nil {
+			log.WithError(err).Error("Failed to convert DNS config")
+		} else {
+			podSpec["dnsConfig"] = dnsConfig
+		}
+	}
+
 	// Add securityContext only if enabled.
+	securityCtx := pod.SecurityContext
 	if securityCtx == nil || !securityCtx.Enabled {
 		return
 	}
@@ -843,39 +1866,124 @@ func updatePodSpec(nodeSelector map[string]string, securityCtx *v1alpha1.PodSecu
 	podSpec["securityContext"] = sCtx
 }
 
-func waitForCluster(clientset *kubernetes.Clientset, namespace string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancel()
-	t := time.NewTicker(2 * time.Second)
-	defer t.Stop()
+// updateAffinity merges the given affinity into the affinity already specified in the pod spec.
+func updateAffinity(affinity *v1.Affinity, podSpec map[string]interface{}) error {
+	merged := &v1.Affinity{}
+	if existing, ok := podSpec["affinity"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(existing, merged); err != nil {
+			return err
+		}
+	}
 
-	clusterID := false
-	for !clusterID { // Wait for secret to be updated with clusterID.
-		select {
-		case <-ctx.Done():
-			return errors.New("Timed out waiting for cluster ID")
-		case <-t.C:
-			s := k8s.GetSecret(clientset, namespace, "pl-cluster-secrets")
-			if s == nil {
-				return errors.New("Missing cluster secrets")
-			}
-			if _, ok := s.Data["cluster-id"]; ok {
-				clusterID = true
-			}
+	if affinity.NodeAffinity != nil {
+		if merged.NodeAffinity == nil {
+			merged.NodeAffinity = &v1.NodeAffinity{}
+		}
+		merged.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = mergeNodeSelectors(
+			merged.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+			affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
+		merged.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+			merged.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+			affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution...)
+	}
+
+	if affinity.PodAffinity != nil {
+		if merged.PodAffinity == nil {
+			merged.PodAffinity = &v1.PodAffinity{}
+		}
+		merged.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+			merged.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+			affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution...)
+		merged.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+			merged.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+			affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution...)
+	}
+
+	if affinity.PodAntiAffinity != nil {
+		if merged.PodAntiAffinity == nil {
+			merged.PodAntiAffinity = &v1.PodAntiAffinity{}
 		}
+		merged.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+			merged.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+			affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution...)
+		merged.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+			merged.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+			affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution...)
 	}
 
+	a, err := runtime.DefaultUnstructuredConverter.ToUnstructured(merged)
+	if err != nil {
+		return err
+	}
+	podSpec["affinity"] = a
 	return nil
 }
 
+// getExcludeNodeAffinity returns a node affinity which only allows nodes that have none of the given labels. A label
+// with an empty value excludes all nodes which have the label.
+func getExcludeNodeAffinity(excluded map[string]string) *v1.Affinity {
+	keys := make([]string, 0, len(excluded))
+	for k := range excluded {
+		keys = append(keys, k)
+	}
+	// Sort the keys, so that the resulting pod spec does not change between reconciles.
+	sort.Strings(keys)
+
+	term := v1.NodeSelectorTerm{}
+	for _, k := range keys {
+		req := v1.NodeSelectorRequirement{Key: k, Operator: v1.NodeSelectorOpDoesNotExist}
+		if excluded[k] != "" {
+			req.Operator = v1.NodeSelectorOpNotIn
+			req.Values = []string{excluded[k]}
+		}
+		term.MatchExpressions = append(term.MatchExpressions, req)
+	}
+	return &v1.Affinity{
+		NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{term},
+			},
+		},
+	}
+}
+
+// mergeNodeSelectors returns a node selector which is satisfied only when both of the given selectors are.
+// Node selector terms are ORed together, so each existing term is combined with each additional term.
+func mergeNodeSelectors(existing *v1.NodeSelector, additional *v1.NodeSelector) *v1.NodeSelector {
+	if additional == nil || len(additional.NodeSelectorTerms) == 0 {
+		return existing
+	}
+	if existing == nil || len(existing.NodeSelectorTerms) == 0 {
+		return additional.DeepCopy()
+	}
+
+	merged := &v1.NodeSelector{}
+	for _, e := range existing.NodeSelectorTerms {
+		for _, a := range additional.NodeSelectorTerms {
+			term := v1.NodeSelectorTerm{}
+			term.MatchExpressions = append(term.MatchExpressions, e.MatchExpressions...)
+			term.MatchExpressions = append(term.MatchExpressions, a.MatchExpressions...)
+			term.MatchFields = append(term.MatchFields, e.MatchFields...)
+			term.MatchFields = append(term.MatchFields, a.MatchFields...)
+			merged.NodeSelectorTerms = append(merged.NodeSelectorTerms, term)
+		}
+	}
+	return merged
+}
+
 // watchForFailedVizierUpdates regularly polls for timed-out viziers
 // and marks matching Viziers ReconciliationPhases as failed.
-func (r *VizierReconciler) watchForFailedVizierUpdates() {
+func (r *VizierReconciler) watchForFailedVizierUpdates(ctx context.Context) error {
 	t := time.NewTicker(updatingVizierCheckPeriod)
 	defer t.Stop()
-	for range t.C {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+
 		var viziersList v1alpha1.VizierList
-		ctx := context.Background()
 		err := r.List(ctx, &viziersList)
 		if err != nil {
 			log.WithError(err).Error("Unable to list the vizier objects")
@@ -898,20 +2006,38 @@ func (r *VizierReconciler) watchForFailedVizierUpdates() {
 	}
 }
 
-// SetupWithManager sets up the reconciler.
+// SetupWithManager sets up the reconciler. When leader election is enabled, the reconciler only runs in the
+// operator replica which holds the lease.
 func (r *VizierReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	go r.watchForFailedVizierUpdates()
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.Vizier{}).
-		Complete(r)
+	// Runnables added to the manager are only started once leader election is won.
+	err := mgr.Add(manager.RunnableFunc(r.watchForFailedVizierUpdates))
+	if err != nil {
+		return err
+	}
+	err = mgr.Add(manager.RunnableFunc(r.watchForDrift))
+	if err != nil {
+		return err
+	}
+	err = mgr.Add(manager.RunnableFunc(r.manageMonitors))
+	if err != nil {
+		return err
+	}
+	// The Vizier's workloads are not owned by the Vizier, since they are deployed with labels rather than owner
+	// references, so they are mapped back to the Vizier through their labels.
+	b := ctrl.NewControllerManagedBy(mgr).For(&v1alpha1.Vizier{})
+	for _, workload := range []client.Object{&appsv1.Deployment{}, &appsv1.DaemonSet{}, &appsv1.StatefulSet{}} {
+		b = b.Watches(&source.Kind{Type: workload}, handler.EnqueueRequestsFromMapFunc(mapWorkloadToVizier),
+			builder.WithPredicates(workloadPredicate()))
+	}
+	return b.Complete(r)
 }
 
-func retryDeploy(clientset *kubernetes.Clientset, config *rest.Config, namespace string, resources []*k8s.Resource, allowUpdate bool) error {
-	bOpts := backoff.NewExponentialBackOff()
-	bOpts.InitialInterval = 15 * time.Second
-	bOpts.MaxElapsedTime = 5 * time.Minute
-
-	return backoff.Retry(func() error {
-		return k8s.ApplyResources(clientset, config, resources, namespace, nil, allowUpdate)
-	}, bOpts)
+// retryDeploy applies the resources concurrently, retrying with the configured backoff if they fail to apply.
+func (r *VizierReconciler) retryDeploy(namespace string, resources []*k8s.Resource, allowUpdate bool) error {
+	return backoff.RetryNotify(func() error {
+		return applyResourcesConcurrently(r.Clientset, r.RestConfig, resources, namespace, allowUpdate)
+	}, r.DeployRetry.newBackOff(), func(err error, next time.Duration) {
+		applyRetryCount.WithLabelValues(namespace).Inc()
+		log.WithError(err).Infof("Failed to apply Vizier resources, retrying in %s", next)
+	})
 }
@@ -0,0 +1,599 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func newTestPodResource(podSpec map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": podSpec,
+			},
+		},
+	}
+}
+
+func testPodSpec(res map[string]interface{}) map[string]interface{} {
+	return res["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})
+}
+
+func TestUpdatePodSpec_Tolerations(t *testing.T) {
+	res := newTestPodResource(map[string]interface{}{
+		"tolerations": []interface{}{
+			map[string]interface{}{"operator": "Exists"},
+		},
+	})
+
+	updatePodSpec(&v1alpha1.PodPolicy{
+		Tolerations: []v1.Toleration{
+			{
+				Key:      "dedicated",
+				Operator: v1.TolerationOpEqual,
+				Value:    "infra",
+				Effect:   v1.TaintEffectNoSchedule,
+			},
+		},
+	}, false, res)
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"operator": "Exists"},
+		map[string]interface{}{"key": "dedicated", "operator": "Equal", "value": "infra", "effect": "NoSchedule"},
+	}, testPodSpec(res)["tolerations"])
+}
+
+func TestUpdatePodSpec_ComponentPolicy(t *testing.T) {
+	pod := &v1alpha1.PodPolicy{
+		Components: map[string]v1alpha1.ComponentPodPolicy{
+			"kelvin": {
+				NodeSelector: map[string]string{"pool": "observability"},
+				Tolerations: []v1.Toleration{
+					{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "observability", Effect: v1.TaintEffectNoSchedule},
+				},
+			},
+		},
+	}
+
+	kelvin := newTestPodResource(map[string]interface{}{})
+	kelvin["metadata"] = map[string]interface{}{"name": "kelvin"}
+	updatePodSpec(pod, false, kelvin)
+	assert.Equal(t, map[string]interface{}{"pool": "observability"}, testPodSpec(kelvin)["nodeSelector"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"key": "dedicated", "operator": "Equal", "value": "observability", "effect": "NoSchedule"},
+	}, testPodSpec(kelvin)["tolerations"])
+
+	pem := newTestPodResource(map[string]interface{}{})
+	pem["metadata"] = map[string]interface{}{"name": vizierPemLabel}
+	updatePodSpec(pod, true, pem)
+	assert.Equal(t, map[string]interface{}{}, testPodSpec(pem)["nodeSelector"])
+	assert.Nil(t, testPodSpec(pem)["tolerations"])
+}
+
+func TestUpdatePodSpec_PEMHostNetwork(t *testing.T) {
+	pod := &v1alpha1.PodPolicy{PEMHostNetwork: true}
+
+	res := newTestPodResource(map[string]interface{}{})
+	updatePodSpec(pod, false, res)
+	podSpec := testPodSpec(res)
+	assert.NotContains(t, podSpec, "hostNetwork")
+
+	res = newTestPodResource(map[string]interface{}{})
+	updatePodSpec(pod, true, res)
+	podSpec = testPodSpec(res)
+	assert.Equal(t, true, podSpec["hostNetwork"])
+	assert.Equal(t, "ClusterFirstWithHostNet", podSpec["dnsPolicy"])
+}
+
+func TestUpdatePodSpec_PEMHostPID(t *testing.T) {
+	res := newTestPodResource(map[string]interface{}{"hostPID": true})
+	updatePodSpec(&v1alpha1.PodPolicy{}, true, res)
+	assert.Equal(t, true, testPodSpec(res)["hostPID"])
+
+	hostPID := false
+	pod := &v1alpha1.PodPolicy{PEMHostPID: &hostPID}
+
+	res = newTestPodResource(map[string]interface{}{})
+	updatePodSpec(pod, false, res)
+	assert.NotContains(t, testPodSpec(res), "hostPID")
+
+	res = newTestPodResource(map[string]interface{}{"hostPID": true})
+	updatePodSpec(pod, true, res)
+	assert.Equal(t, false, testPodSpec(res)["hostPID"])
+}
+
+func TestUpdatePodSpec_PEMExcludeNodeSelector(t *testing.T) {
+	pod := &v1alpha1.PodPolicy{PEMExcludeNodeSelector: map[string]string{
+		"kubernetes.io/os":    "windows",
+		"example.com/no-ebpf": "",
+	}}
+
+	res := newTestPodResource(map[string]interface{}{})
+	updatePodSpec(pod, false, res)
+	assert.Nil(t, testPodSpec(res)["affinity"])
+
+	res = newTestPodResource(map[string]interface{}{})
+	updatePodSpec(pod, true, res)
+	assert.Equal(t, map[string]interface{}{
+		"nodeAffinity": map[string]interface{}{
+			"requiredDuringSchedulingIgnoredDuringExecution": map[string]interface{}{
+				"nodeSelectorTerms": []interface{}{
+					map[string]interface{}{
+						"matchExpressions": []interface{}{
+							map[string]interface{}{"key": "example.com/no-ebpf", "operator": "DoesNotExist"},
+							map[string]interface{}{"key": "kubernetes.io/os", "operator": "NotIn", "values": []interface{}{"windows"}},
+						},
+					},
+				},
+			},
+		},
+	}, testPodSpec(res)["affinity"])
+}
+
+func TestUpdatePodSpec_Affinity(t *testing.T) {
+	res := newTestPodResource(map[string]interface{}{
+		"affinity": map[string]interface{}{
+			"nodeAffinity": map[string]interface{}{
+				"requiredDuringSchedulingIgnoredDuringExecution": map[string]interface{}{
+					"nodeSelectorTerms": []interface{}{
+						map[string]interface{}{
+							"matchExpressions": []interface{}{
+								map[string]interface{}{"key": "kubernetes.io/os", "operator": "Exists"},
+							},
+						},
+						map[string]interface{}{
+							"matchExpressions": []interface{}{
+								map[string]interface{}{"key": "beta.kubernetes.io/os", "operator": "Exists"},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	spotExpr := v1.NodeSelectorRequirement{Key: "spot", Operator: v1.NodeSelectorOpDoesNotExist}
+	antiAffinity := v1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": "kelvin"}},
+		TopologyKey:   "kubernetes.io/hostname",
+	}
+	updatePodSpec(&v1alpha1.PodPolicy{
+		Affinity: &v1.Affinity{
+			NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{
+						{MatchExpressions: []v1.NodeSelectorRequirement{spotExpr}},
+					},
+				},
+			},
+			PodAntiAffinity: &v1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{antiAffinity},
+			},
+		},
+	}, false, res)
+
+	podSpec := testPodSpec(res)
+	affinity := &v1.Affinity{}
+	require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(podSpec["affinity"].(map[string]interface{}), affinity))
+	assert.Equal(t, &v1.NodeSelector{
+		NodeSelectorTerms: []v1.NodeSelectorTerm{
+			{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "kubernetes.io/os", Operator: v1.NodeSelectorOpExists}, spotExpr}},
+			{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "beta.kubernetes.io/os", Operator: v1.NodeSelectorOpExists}, spotExpr}},
+		},
+	}, affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
+	assert.Equal(t, []v1.PodAffinityTerm{antiAffinity}, affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
+	assert.Nil(t, affinity.PodAffinity)
+}
+
+func TestUpdatePodSpec_PriorityClassName(t *testing.T) {
+	pod := &v1alpha1.PodPolicy{PriorityClassName: "pixie-critical"}
+
+	res := newTestPodResource(map[string]interface{}{})
+	updatePodSpec(pod, false, res)
+	podSpec := testPodSpec(res)
+	assert.Equal(t, "pixie-critical", podSpec["priorityClassName"])
+
+	res = newTestPodResource(map[string]interface{}{"priorityClassName": "system-node-critical"})
+	updatePodSpec(pod, false, res)
+	podSpec = testPodSpec(res)
+	assert.Equal(t, "system-node-critical", podSpec["priorityClassName"])
+}
+
+func TestUpdatePodSpec_DNS(t *testing.T) {
+	ndots := "2"
+	pod := &v1alpha1.PodPolicy{
+		DNSPolicy: v1.DNSClusterFirst,
+		DNSConfig: &v1.PodDNSConfig{
+			Nameservers: []string{"169.254.20.10"},
+			Options:     []v1.PodDNSConfigOption{{Name: "ndots", Value: &ndots}},
+		},
+	}
+
+	res := newTestPodResource(map[string]interface{}{})
+	updatePodSpec(pod, false, res)
+	podSpec := testPodSpec(res)
+	assert.Equal(t, "ClusterFirst", podSpec["dnsPolicy"])
+	assert.Equal(t, map[string]interface{}{
+		"nameservers": []interface{}{"169.254.20.10"},
+		"options":     []interface{}{map[string]interface{}{"name": "ndots", "value": "2"}},
+	}, podSpec["dnsConfig"])
+
+	// Pods in the host network must keep resolving cluster services.
+	res = newTestPodResource(map[string]interface{}{"hostNetwork": true})
+	updatePodSpec(pod, true, res)
+	podSpec = testPodSpec(res)
+	assert.Equal(t, "ClusterFirstWithHostNet", podSpec["dnsPolicy"])
+}
+
+func TestUpdateImageRegistry(t *testing.T) {
+	res := newTestPodResource(map[string]interface{}{
+		"initContainers": []interface{}{
+			map[string]interface{}{"name": "wait", "image": "gcr.io/pixie-oss/pixie-dev-public/curl:1.0"},
+		},
+		"containers": []interface{}{
+			map[string]interface{}{"name": "pem", "image": "gcr.io/pixie-oss/pixie-prod/vizier-pem_image:0.10.0"},
+			map[string]interface{}{"name": "nats", "image": "nats:2.8.1"},
+			map[string]interface{}{"name": "local", "image": "localhost:5000/etcd:3.5"},
+		},
+	})
+
+	updateImageRegistry("registry.internal/mirror/", res)
+
+	var images []string
+	for _, field := range []string{"initContainers", "containers"} {
+		for _, c := range testPodSpec(res)[field].([]interface{}) {
+			images = append(images, c.(map[string]interface{})["image"].(string))
+		}
+	}
+	assert.Equal(t, []string{
+		"registry.internal/mirror/pixie-oss/pixie-dev-public/curl:1.0",
+		"registry.internal/mirror/pixie-oss/pixie-prod/vizier-pem_image:0.10.0",
+		"registry.internal/mirror/nats:2.8.1",
+		"registry.internal/mirror/etcd:3.5",
+	}, images)
+}
+
+func TestGetVizierYAMLsFromCluster(t *testing.T) {
+	yamls := map[string]string{
+		"secrets":           "secrets yaml",
+		"nats":              "nats yaml",
+		"vizier_persistent": "vizier yaml",
+	}
+	secretData := make(map[string][]byte)
+	for k, v := range yamls {
+		secretData[k] = []byte(v)
+	}
+
+	tests := []struct {
+		name            string
+		objs            []runtime.Object
+		useEtcdOperator bool
+		expectError     bool
+	}{
+		{
+			name: "configmap",
+			objs: []runtime.Object{
+				&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "vizier-yamls", Namespace: "pl"}, Data: yamls},
+			},
+		},
+		{
+			name: "secret",
+			objs: []runtime.Object{
+				&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "vizier-yamls", Namespace: "pl"}, Data: secretData},
+			},
+		},
+		{
+			name: "missing yaml",
+			objs: []runtime.Object{
+				&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "vizier-yamls", Namespace: "pl"}, Data: yamls},
+			},
+			useEtcdOperator: true,
+			expectError:     true,
+		},
+		{
+			name:        "not found",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vz := &v1alpha1.Vizier{
+				Spec: v1alpha1.VizierSpec{YAMLConfigMapName: "vizier-yamls", UseEtcdOperator: test.useEtcdOperator},
+			}
+			yamlMap, err := getVizierYAMLsFromCluster(context.Background(), fake.NewSimpleClientset(test.objs...), "pl", vz)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, yamls, yamlMap)
+		})
+	}
+}
+
+func TestUpdateProxyEnv(t *testing.T) {
+	res := newTestPodResource(map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{
+				"name": "app",
+				"env": []interface{}{
+					map[string]interface{}{"name": "PL_POD_NAME", "value": "app"},
+					map[string]interface{}{"name": "NO_PROXY", "value": "custom"},
+				},
+			},
+		},
+	})
+
+	updateProxyEnv(&v1alpha1.ProxyParams{
+		HTTPSProxy:   "http://proxy:3128",
+		NoProxy:      "internal.example.com",
+		PodCIDRs:     []string{"10.244.0.0/24", "10.244.1.0/24"},
+		ServiceCIDRs: []string{"10.96.0.1/32"},
+	}, res)
+
+	container := testPodSpec(res)["containers"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "PL_POD_NAME", "value": "app"},
+		map[string]interface{}{"name": "NO_PROXY", "value": "custom"},
+		map[string]interface{}{"name": "HTTPS_PROXY", "value": "http://proxy:3128"},
+		map[string]interface{}{"name": "https_proxy", "value": "http://proxy:3128"},
+		map[string]interface{}{
+			"name":  "no_proxy",
+			"value": "internal.example.com,localhost,127.0.0.1,.svc,.cluster.local,10.96.0.1/32,10.244.0.0/24,10.244.1.0/24",
+		},
+	}, container["env"])
+}
+
+func TestUpdateMetadataPVC(t *testing.T) {
+	res := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"accessModes": []interface{}{"ReadWriteOnce"},
+		},
+	}
+
+	require.NoError(t, updateMetadataPVC(&v1alpha1.Vizier{}, res))
+	assert.NotContains(t, res["spec"], "storageClassName")
+
+	require.NoError(t, updateMetadataPVC(&v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{StorageClassName: "fast"}}, res))
+	assert.Equal(t, "fast", res["spec"].(map[string]interface{})["storageClassName"])
+
+	require.NoError(t, updateMetadataPVC(&v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{MetadataStorageSize: "64Gi"}}, res))
+	assert.Equal(t, map[string]interface{}{"storage": "64Gi"}, res["spec"].(map[string]interface{})["resources"].(map[string]interface{})["requests"])
+
+	assert.Error(t, updateMetadataPVC(&v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{MetadataStorageSize: "lots"}}, res))
+}
+
+func TestUpdateExternalNATS(t *testing.T) {
+	res := newTestPodResource(map[string]interface{}{
+		"initContainers": []interface{}{
+			map[string]interface{}{"name": "nats-wait"},
+			map[string]interface{}{"name": "etcd-wait"},
+		},
+		"containers": []interface{}{
+			map[string]interface{}{
+				"name": "app",
+				"env": []interface{}{
+					map[string]interface{}{"name": "PL_NATS_URL", "value": "pl-nats"},
+				},
+			},
+		},
+	})
+
+	updateExternalNATS(&v1alpha1.ExternalNATSParams{
+		URL:                   "tls://nats.nats-system.svc:4222",
+		TLSSecretName:         "nats-client-certs",
+		CredentialsSecretName: "nats-creds",
+		STANClusterID:         "nats-streaming",
+	}, res)
+
+	podSpec := testPodSpec(res)
+	initContainers := podSpec["initContainers"].([]interface{})
+	require.Len(t, initContainers, 1)
+	assert.Equal(t, "etcd-wait", initContainers[0].(map[string]interface{})["name"])
+
+	app := podSpec["containers"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "PL_NATS_URL", "value": "tls://nats.nats-system.svc:4222"},
+		map[string]interface{}{"name": "PL_NATS_TLS_CA_CERT", "value": "/nats-certs/ca.crt"},
+		map[string]interface{}{"name": "PL_NATS_TLS_CERT", "value": "/nats-certs/client.crt"},
+		map[string]interface{}{"name": "PL_NATS_TLS_KEY", "value": "/nats-certs/client.key"},
+		map[string]interface{}{"name": "PL_NATS_CREDS", "value": "/nats-creds/nats.creds"},
+		map[string]interface{}{"name": "PL_STAN_CLUSTER", "value": "nats-streaming"},
+	}, app["env"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "nats-certs", "mountPath": "/nats-certs", "readOnly": true},
+		map[string]interface{}{"name": "nats-creds", "mountPath": "/nats-creds", "readOnly": true},
+	}, app["volumeMounts"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "nats-certs", "secret": map[string]interface{}{"secretName": "nats-client-certs"}},
+		map[string]interface{}{"name": "nats-creds", "secret": map[string]interface{}{"secretName": "nats-creds"}},
+	}, podSpec["volumes"])
+}
+
+func TestUpdateExternalNATS_JetStream(t *testing.T) {
+	res := newTestPodResource(map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "app"},
+		},
+	})
+
+	updateExternalNATS(&v1alpha1.ExternalNATSParams{
+		URL:       "nats://nats.nats-system.svc:4222",
+		Streaming: v1alpha1.MessageBusStreamingJetStream,
+	}, res)
+
+	podSpec := testPodSpec(res)
+	app := podSpec["containers"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "PL_NATS_URL", "value": "nats://nats.nats-system.svc:4222"},
+		map[string]interface{}{"name": "PL_JETSTREAM", "value": "true"},
+	}, app["env"])
+	assert.Nil(t, app["volumeMounts"])
+	assert.Nil(t, podSpec["volumes"])
+}
+
+func TestUpdateNATSConfiguration(t *testing.T) {
+	replicas := int32(3)
+	nats := &v1alpha1.NATSParams{
+		Replicas: &replicas,
+		Resources: &v1.ResourceRequirements{
+			Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+		},
+	}
+
+	ss := &unstructured.Unstructured{Object: newTestPodResource(map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "pl-nats"},
+		},
+	})}
+	ss.SetName("pl-nats")
+	require.NoError(t, updateNATSConfiguration(nats, &k8s.Resource{
+		Object: ss,
+		GVK:    &schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"},
+	}))
+	assert.Equal(t, int64(3), ss.Object["spec"].(map[string]interface{})["replicas"])
+	container := testPodSpec(ss.Object)["containers"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"limits": map[string]interface{}{"memory": "1Gi"},
+	}, container["resources"])
+
+	cm := &unstructured.Unstructured{Object: map[string]interface{}{
+		"data": map[string]interface{}{"nats.conf": "http: 8222\n"},
+	}}
+	cm.SetName("nats-config")
+	require.NoError(t, updateNATSConfiguration(nats, &k8s.Resource{
+		Object: cm,
+		GVK:    &schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+	}))
+	assert.Contains(t, cm.Object["data"].(map[string]interface{})["nats.conf"], "routes: [")
+}
+
+func TestIsReconcilePaused(t *testing.T) {
+	vz := &v1alpha1.Vizier{}
+	assert.False(t, isReconcilePaused(vz))
+
+	vz.SetAnnotations(map[string]string{reconcileAnnotation: "enabled"})
+	assert.False(t, isReconcilePaused(vz))
+
+	vz.SetAnnotations(map[string]string{reconcileAnnotation: reconcilePaused})
+	assert.True(t, isReconcilePaused(vz))
+}
+
+func TestUpdateLoggingEnv(t *testing.T) {
+	res := newTestPodResource(map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{
+				"name": "app",
+				"env": []interface{}{
+					map[string]interface{}{"name": "PL_POD_NAME", "value": "app"},
+					map[string]interface{}{"name": "PL_LOG_LEVEL", "value": "info"},
+				},
+			},
+		},
+	})
+
+	updateLoggingEnv(&v1alpha1.LoggingParams{Level: v1alpha1.LogLevelDebug, Format: v1alpha1.LogFormatJSON}, res)
+
+	container := testPodSpec(res)["containers"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "PL_POD_NAME", "value": "app"},
+		map[string]interface{}{"name": "PL_LOG_LEVEL", "value": "debug"},
+		map[string]interface{}{"name": "GLOG_v", "value": "1"},
+		map[string]interface{}{"name": "GLOG_minloglevel", "value": "0"},
+		map[string]interface{}{"name": "PL_LOG_FORMAT", "value": "json"},
+	}, container["env"])
+}
+
+func TestGetLoggingEnv(t *testing.T) {
+	assert.Equal(t, []v1.EnvVar{
+		{Name: "PL_LOG_LEVEL", Value: "error"},
+		{Name: "GLOG_minloglevel", Value: "2"},
+	}, getLoggingEnv(&v1alpha1.LoggingParams{Level: v1alpha1.LogLevelError}))
+	assert.Empty(t, getLoggingEnv(&v1alpha1.LoggingParams{}))
+}
+
+func TestGetCustomVizierCertYAMLs(t *testing.T) {
+	certData := map[string][]byte{
+		"ca.crt":     []byte("ca"),
+		"server.crt": []byte("server cert"),
+		"server.key": []byte("server key"),
+		"client.crt": []byte("client cert"),
+		"client.key": []byte("client key"),
+	}
+
+	clientset := fake.NewSimpleClientset(
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "internal-certs", Namespace: "pl"}, Data: certData},
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "partial-certs", Namespace: "pl"}, Data: map[string][]byte{
+			"ca.crt": []byte("ca"),
+		}},
+	)
+
+	yamls, err := getCustomVizierCertYAMLs(context.Background(), clientset, "pl", "internal-certs")
+	require.NoError(t, err)
+	resources, err := k8s.GetResourcesFromYAML(strings.NewReader(yamls))
+	require.NoError(t, err)
+
+	var serviceCerts *k8s.Resource
+	for _, r := range resources {
+		if r.Object.GetName() == "service-tls-certs" {
+			serviceCerts = r
+		}
+	}
+	require.NotNil(t, serviceCerts)
+	data, _, err := unstructured.NestedStringMap(serviceCerts.Object.Object, "data")
+	require.NoError(t, err)
+	assert.Len(t, data, len(certData))
+
+	_, err = getCustomVizierCertYAMLs(context.Background(), clientset, "pl", "partial-certs")
+	assert.Error(t, err)
+	_, err = getCustomVizierCertYAMLs(context.Background(), clientset, "pl", "missing-certs")
+	assert.Error(t, err)
+}
+
+func TestGetSpecChecksum(t *testing.T) {
+	vz := &v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{Version: "0.10.0"}}
+	checksum, err := getSpecChecksum(vz)
+	require.NoError(t, err)
+
+	same, err := getSpecChecksum(vz.DeepCopy())
+	require.NoError(t, err)
+	assert.Equal(t, checksum, same)
+
+	vz.Spec.ForceRedeployGeneration = 1
+	redeployed, err := getSpecChecksum(vz)
+	require.NoError(t, err)
+	assert.NotEqual(t, checksum, redeployed)
+}
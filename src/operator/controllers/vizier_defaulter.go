@@ -0,0 +1,187 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const (
+	// The memory limit for PEMs when none is specified. This should match the default in the Vizier YAML templates.
+	defaultPEMMemoryLimit = "2Gi"
+	// The smallest memory limit that will be defaulted for PEMs, regardless of the size of the nodes.
+	minDefaultPEMMemoryLimit = "1Gi"
+	// By default, PEMs may use at most 1/pemNodeMemoryDivisor of the smallest node's allocatable memory.
+	pemNodeMemoryDivisor = 4
+	// How long to wait on Pixie Cloud for the latest Vizier version. This must be well below the webhook timeout.
+	defaulterCloudTimeout = 5 * time.Second
+	// The path which the defaulting webhook is served on, which must match the webhook definition in the OLM bundle.
+	vizierDefaulterPath = "/mutate-px-dev-v1alpha1-vizier"
+)
+
+// VizierDefaulter is a mutating admission webhook which fills in defaults for the Vizier spec, so
+// that the persisted spec reflects what will actually be deployed.
+type VizierDefaulter struct {
+	Clientset kubernetes.Interface
+	// CloudConn configures the connection to Pixie Cloud.
+	CloudConn CloudConnConfig
+
+	// The connections to Pixie Cloud, which are shared across admission requests.
+	cloudConns cloudConnPool
+}
+
+// SetupWebhookWithManager registers the defaulting webhook with the manager's webhook server.
+func (d *VizierDefaulter) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	wh := admission.WithCustomDefaulter(&v1alpha1.Vizier{}, d)
+	// Some defaults are only set on create, so the defaulter needs the request's operation, which this version of
+	// controller-runtime does not pass to it.
+	wh.Handler = &admissionRequestHandler{Handler: wh.Handler}
+	mgr.GetWebhookServer().Register(vizierDefaulterPath, wh)
+	return nil
+}
+
+type admissionRequestKey struct{}
+
+// admissionRequestHandler adds the admission request to the context of the handler which it wraps.
+type admissionRequestHandler struct {
+	admission.Handler
+}
+
+func (h *admissionRequestHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	return h.Handler.Handle(context.WithValue(ctx, admissionRequestKey{}, req), req)
+}
+
+// InjectDecoder passes the webhook's decoder on to the wrapped handler.
+func (h *admissionRequestHandler) InjectDecoder(decoder *admission.Decoder) error {
+	_, err := admission.InjectDecoderInto(decoder, h.Handler)
+	return err
+}
+
+// isAdmissionCreate returns whether the admission request in the context creates the object. Objects which are
+// defaulted outside of an admission request are treated as being created.
+func isAdmissionCreate(ctx context.Context) bool {
+	req, ok := ctx.Value(admissionRequestKey{}).(admission.Request)
+	return !ok || req.Operation == admissionv1.Create
+}
+
+// Default sets the defaults for the given Vizier. Defaults that cannot be determined are left unset,
+// so that the reconciler can fill them in at deploy time instead.
+func (d *VizierDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	vz, ok := obj.(*v1alpha1.Vizier)
+	if !ok {
+		return fmt.Errorf("expected a Vizier but got a %T", obj)
+	}
+
+	// The version and PEM memory limit need a round trip to Pixie Cloud and a list of all nodes, which are too costly
+	// for every update, so they are only defaulted on create. Updates keep the values that were defaulted then.
+	create := isAdmissionCreate(ctx)
+
+	if create && vz.Spec.Version == "" && vz.Spec.YAMLConfigMapName == "" {
+		version, err := d.getLatestVersion(ctx, vz)
+		if err != nil {
+			log.WithError(err).Warn("Failed to default Vizier version")
+		} else {
+			vz.Spec.Version = version
+		}
+	}
+
+	// Auto-sized PEMs are sized at deploy time instead.
+	if create && vz.Spec.PEMMemoryAutoSize == nil && vz.Spec.PemMemoryLimit == "" && vz.Spec.PemMemoryRequest == "" {
+		limit, err := getDefaultPEMMemoryLimit(ctx, d.Clientset)
+		if err != nil {
+			log.WithError(err).Warn("Failed to default PEM memory limit")
+		} else {
+			vz.Spec.PemMemoryLimit = limit
+		}
+	}
+
+	if !vz.Spec.UseEtcdOperator {
+		// Clusters without PVC support must use the etcd operator, which does not require PVCs.
+		storageClassExists, err := hasMetadataStorageClass(ctx, d.Clientset, vz)
+		if err != nil {
+			log.WithError(err).Warn("Failed to check storage classes")
+		} else if !storageClassExists {
+			vz.Spec.UseEtcdOperator = true
+		}
+	}
+
+	if !vz.Spec.OpenShift {
+		openShift, err := isOpenShift(d.Clientset)
+		if err != nil {
+			log.WithError(err).Warn("Failed to check for OpenShift")
+		} else {
+			vz.Spec.OpenShift = openShift
+		}
+	}
+
+	return nil
+}
+
+func (d *VizierDefaulter) getLatestVersion(ctx context.Context, vz *v1alpha1.Vizier) (string, error) {
+	cloudClient, err := d.cloudConns.get(d.CloudConn, vz.Spec.CloudAddr, vz.Spec.DevCloudNamespace)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaulterCloudTimeout)
+	defer cancel()
+	return getLatestVizierVersion(ctx, cloudpb.NewArtifactTrackerClient(cloudClient))
+}
+
+// getDefaultPEMMemoryLimit returns the default memory limit for PEMs, which is scaled down
+// on clusters whose nodes are too small to comfortably fit the usual default.
+func getDefaultPEMMemoryLimit(ctx context.Context, clientset kubernetes.Interface) (string, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	limit := resource.MustParse(defaultPEMMemoryLimit)
+	for _, n := range nodes.Items {
+		allocatable, ok := n.Status.Allocatable[v1.ResourceMemory]
+		if !ok {
+			continue
+		}
+		nodeLimit := resource.NewQuantity(allocatable.Value()/pemNodeMemoryDivisor, resource.BinarySI)
+		if nodeLimit.Cmp(limit) < 0 {
+			limit = *nodeLimit
+		}
+	}
+
+	minLimit := resource.MustParse(minDefaultPEMMemoryLimit)
+	if limit.Cmp(minLimit) < 0 {
+		limit = minLimit
+	}
+	return limit.String(), nil
+}
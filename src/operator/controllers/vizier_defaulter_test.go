@@ -0,0 +1,146 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestVizierDefaulter_Default(t *testing.T) {
+	tests := []struct {
+		name                string
+		nodeMemory          []string
+		defaultStorageClass bool
+		spec                v1alpha1.VizierSpec
+		expectedSpec        v1alpha1.VizierSpec
+	}{
+		{
+			name:                "large nodes",
+			nodeMemory:          []string{"16Gi", "32Gi"},
+			defaultStorageClass: true,
+			spec:                v1alpha1.VizierSpec{Version: "0.10.0"},
+			expectedSpec:        v1alpha1.VizierSpec{Version: "0.10.0", PemMemoryLimit: "2Gi"},
+		},
+		{
+			name:                "small nodes",
+			nodeMemory:          []string{"6Gi", "32Gi"},
+			defaultStorageClass: true,
+			spec:                v1alpha1.VizierSpec{Version: "0.10.0"},
+			expectedSpec:        v1alpha1.VizierSpec{Version: "0.10.0", PemMemoryLimit: "1536Mi"},
+		},
+		{
+			name:                "tiny nodes",
+			nodeMemory:          []string{"2Gi"},
+			defaultStorageClass: true,
+			spec:                v1alpha1.VizierSpec{Version: "0.10.0"},
+			expectedSpec:        v1alpha1.VizierSpec{Version: "0.10.0", PemMemoryLimit: "1Gi"},
+		},
+		{
+			name:                "memory already specified",
+			nodeMemory:          []string{"2Gi"},
+			defaultStorageClass: true,
+			spec:                v1alpha1.VizierSpec{Version: "0.10.0", PemMemoryRequest: "4Gi"},
+			expectedSpec:        v1alpha1.VizierSpec{Version: "0.10.0", PemMemoryRequest: "4Gi"},
+		},
+		{
+			name:                "no default storage class",
+			nodeMemory:          []string{"16Gi"},
+			defaultStorageClass: false,
+			spec:                v1alpha1.VizierSpec{Version: "0.10.0"},
+			expectedSpec:        v1alpha1.VizierSpec{Version: "0.10.0", PemMemoryLimit: "2Gi", UseEtcdOperator: true},
+		},
+		{
+			name:                "specified storage class",
+			nodeMemory:          []string{"16Gi"},
+			defaultStorageClass: false,
+			spec:                v1alpha1.VizierSpec{Version: "0.10.0", StorageClassName: "standard"},
+			expectedSpec:        v1alpha1.VizierSpec{Version: "0.10.0", PemMemoryLimit: "2Gi", StorageClassName: "standard"},
+		},
+		{
+			name:                "missing storage class",
+			nodeMemory:          []string{"16Gi"},
+			defaultStorageClass: true,
+			spec:                v1alpha1.VizierSpec{Version: "0.10.0", StorageClassName: "fast"},
+			expectedSpec:        v1alpha1.VizierSpec{Version: "0.10.0", PemMemoryLimit: "2Gi", StorageClassName: "fast", UseEtcdOperator: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var objs []runtime.Object
+			for i, mem := range test.nodeMemory {
+				objs = append(objs, &v1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: string(rune('a' + i))},
+					Status: v1.NodeStatus{
+						Allocatable: v1.ResourceList{v1.ResourceMemory: resource.MustParse(mem)},
+					},
+				})
+			}
+			sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "standard"}}
+			if test.defaultStorageClass {
+				sc.Annotations = map[string]string{"storageclass.kubernetes.io/is-default-class": "true"}
+			}
+			objs = append(objs, sc)
+
+			d := &VizierDefaulter{Clientset: fake.NewSimpleClientset(objs...)}
+			vz := &v1alpha1.Vizier{Spec: test.spec}
+			require.NoError(t, d.Default(context.Background(), vz))
+			assert.Equal(t, test.expectedSpec, vz.Spec)
+		})
+	}
+}
+
+func TestVizierDefaulter_DefaultOnUpdate(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "a"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{v1.ResourceMemory: resource.MustParse("2Gi")},
+		},
+	}
+	sc := &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "standard",
+			Annotations: map[string]string{"storageclass.kubernetes.io/is-default-class": "true"},
+		},
+	}
+	d := &VizierDefaulter{Clientset: fake.NewSimpleClientset(node, sc)}
+
+	// The version and PEM memory limit are only defaulted on create, so neither Pixie Cloud nor the nodes are
+	// consulted on update.
+	ctx := context.WithValue(context.Background(), admissionRequestKey{}, admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Update},
+	})
+	vz := &v1alpha1.Vizier{}
+	require.NoError(t, d.Default(ctx, vz))
+	assert.Equal(t, v1alpha1.VizierSpec{}, vz.Spec)
+}
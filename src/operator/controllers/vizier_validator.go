@@ -0,0 +1,169 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+// VizierValidator is a validating admission webhook which rejects changes to Viziers that the operator would not
+// be able to carry out safely.
+type VizierValidator struct{}
+
+// SetupWebhookWithManager registers the validating webhook with the manager's webhook server.
+func (v *VizierValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.Vizier{}).
+		WithValidator(v).
+		Complete()
+}
+
+// ValidateCreate validates a new Vizier.
+func (v *VizierValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	vz, ok := obj.(*v1alpha1.Vizier)
+	if !ok {
+		return fmt.Errorf("expected a Vizier but got a %T", obj)
+	}
+	return joinValidationErrors(validateVizierSpec(vz))
+}
+
+// ValidateUpdate validates an update to a Vizier.
+func (v *VizierValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	vz, ok := newObj.(*v1alpha1.Vizier)
+	if !ok {
+		return fmt.Errorf("expected a Vizier but got a %T", newObj)
+	}
+	return joinValidationErrors(validateVizierSpec(vz))
+}
+
+// ValidateDelete rejects the deletion of Viziers which are protected from deletion. If the webhook is unavailable,
+// the Vizier's finalizer still keeps its resources until the protection is disabled.
+func (v *VizierValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	vz, ok := obj.(*v1alpha1.Vizier)
+	if !ok {
+		return fmt.Errorf("expected a Vizier but got a %T", obj)
+	}
+	if vz.Spec.PreventDeletion {
+		return fmt.Errorf("vizier %s is protected from deletion, set spec.preventDeletion to false before deleting it", vz.Name)
+	}
+	return nil
+}
+
+// validateVizierSpec checks the fields of the Vizier spec which the CRD schema cannot fully validate, and returns a
+// human-readable error for each invalid field. Catching these before deploying avoids failing part way through a
+// deploy, with an error from Pixie Cloud which does not point at the field that caused it.
+func validateVizierSpec(vz *v1alpha1.Vizier) []error {
+	var errs []error
+	if vz.Spec.Version != "" {
+		if _, err := semver.Make(vz.Spec.Version); err != nil {
+			errs = append(errs, fmt.Errorf("spec.version %q is not a valid semantic version, for example 0.12.3: %v", vz.Spec.Version, err))
+		}
+	}
+
+	var limit, request *resource.Quantity
+	if vz.Spec.PemMemoryLimit != "" {
+		q, err := resource.ParseQuantity(vz.Spec.PemMemoryLimit)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("spec.pemMemoryLimit %q is not a valid memory quantity, for example 2Gi", vz.Spec.PemMemoryLimit))
+		} else {
+			limit = &q
+		}
+	}
+	if vz.Spec.PemMemoryRequest != "" {
+		q, err := resource.ParseQuantity(vz.Spec.PemMemoryRequest)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("spec.pemMemoryRequest %q is not a valid memory quantity, for example 2Gi", vz.Spec.PemMemoryRequest))
+		} else {
+			request = &q
+		}
+	}
+	if limit != nil && request != nil && request.Cmp(*limit) > 0 {
+		errs = append(errs, fmt.Errorf("spec.pemMemoryRequest %s must not be greater than spec.pemMemoryLimit %s", request, limit))
+	}
+
+	switch vz.Spec.ClockConverter {
+	case "", v1alpha1.ClockConverterDefault, v1alpha1.ClockConverterGrpc:
+	default:
+		errs = append(errs, fmt.Errorf("spec.clockConverter %q is not supported, must be one of %q or %q", vz.Spec.ClockConverter,
+			v1alpha1.ClockConverterDefault, v1alpha1.ClockConverterGrpc))
+	}
+
+	switch vz.Spec.DataAccess {
+	case v1alpha1.DataAccessUnknown, v1alpha1.DataAccessFull, v1alpha1.DataAccessRestricted, v1alpha1.DataAccessPIIRestricted:
+	default:
+		errs = append(errs, fmt.Errorf("spec.dataAccess %q is not supported, must be one of %q, %q or %q", vz.Spec.DataAccess,
+			v1alpha1.DataAccessFull, v1alpha1.DataAccessRestricted, v1alpha1.DataAccessPIIRestricted))
+	}
+
+	if err := validateDeployKeySource(vz.Spec.DeployKeySource); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, validateExternalNATS(vz.Spec.ExternalNATS)...)
+	if err := validateIPFamilies(vz.Spec.IPFamilies); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateMonitor(vz.Spec.Monitor); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, validateWebhooks(vz.Spec.Webhooks)...)
+	return errs
+}
+
+// joinValidationErrors combines the errors returned by validateVizierSpec into a single error.
+func joinValidationErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// validateDeployKeySource checks that exactly one secret manager is specified as the source of the deploy key.
+func validateDeployKeySource(src *v1alpha1.DeployKeySource) error {
+	if src == nil {
+		return nil
+	}
+	numSources := 0
+	if src.Vault != nil {
+		numSources++
+	}
+	if src.AWSSecretsManager != nil {
+		numSources++
+	}
+	if src.GCPSecretManager != nil {
+		numSources++
+	}
+	if numSources != 1 {
+		return fmt.Errorf("spec.deployKeySource must specify exactly one secret manager, but specifies %d", numSources)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestVizierValidator_ValidateDelete(t *testing.T) {
+	v := &VizierValidator{}
+
+	vz := &v1alpha1.Vizier{}
+	assert.NoError(t, v.ValidateDelete(context.Background(), vz))
+
+	vz.Spec.PreventDeletion = true
+	assert.Error(t, v.ValidateDelete(context.Background(), vz))
+}
+
+func TestVizierValidator_ValidateDeployKeySource(t *testing.T) {
+	v := &VizierValidator{}
+
+	vz := &v1alpha1.Vizier{}
+	assert.NoError(t, v.ValidateCreate(context.Background(), vz))
+
+	vz.Spec.DeployKeySource = &v1alpha1.DeployKeySource{}
+	assert.Error(t, v.ValidateCreate(context.Background(), vz))
+
+	vz.Spec.DeployKeySource.GCPSecretManager = &v1alpha1.GCPSecretManagerDeployKeySource{Name: "projects/p/secrets/s/versions/1"}
+	assert.NoError(t, v.ValidateUpdate(context.Background(), &v1alpha1.Vizier{}, vz))
+
+	vz.Spec.DeployKeySource.Vault = &v1alpha1.VaultDeployKeySource{Address: "https://vault:8200", Path: "secret/pixie", Role: "pixie"}
+	assert.Error(t, v.ValidateUpdate(context.Background(), &v1alpha1.Vizier{}, vz))
+}
+
+func TestValidateVizierSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    v1alpha1.VizierSpec
+		numErrs int
+	}{
+		{
+			name:    "empty",
+			spec:    v1alpha1.VizierSpec{},
+			numErrs: 0,
+		},
+		{
+			name: "valid",
+			spec: v1alpha1.VizierSpec{
+				Version:          "0.12.3",
+				PemMemoryLimit:   "2Gi",
+				PemMemoryRequest: "1Gi",
+				ClockConverter:   v1alpha1.ClockConverterGrpc,
+				DataAccess:       v1alpha1.DataAccessPIIRestricted,
+			},
+			numErrs: 0,
+		},
+		{
+			name:    "invalid version",
+			spec:    v1alpha1.VizierSpec{Version: "latest"},
+			numErrs: 1,
+		},
+		{
+			name:    "invalid memory",
+			spec:    v1alpha1.VizierSpec{PemMemoryLimit: "2GB!", PemMemoryRequest: "lots"},
+			numErrs: 2,
+		},
+		{
+			name:    "request above limit",
+			spec:    v1alpha1.VizierSpec{PemMemoryLimit: "1Gi", PemMemoryRequest: "2Gi"},
+			numErrs: 1,
+		},
+		{
+			name:    "invalid enums",
+			spec:    v1alpha1.VizierSpec{ClockConverter: "ntp", DataAccess: "None"},
+			numErrs: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errs := validateVizierSpec(&v1alpha1.Vizier{Spec: test.spec})
+			assert.Len(t, errs, test.numErrs)
+		})
+	}
+}
+
+func TestVizierValidator_ValidateSpec(t *testing.T) {
+	v := &VizierValidator{}
+
+	vz := &v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{Version: "latest", ClockConverter: "ntp"}}
+	err := v.ValidateCreate(context.Background(), vz)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "spec.version")
+	assert.Contains(t, err.Error(), "spec.clockConverter")
+}
@@ -0,0 +1,206 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const (
+	// How long a webhook may take to accept a notification.
+	webhookTimeout = 10 * time.Second
+	// The default URL of PagerDuty webhooks.
+	pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+)
+
+// phaseTransition is the payload of generic webhooks, describing a change of the Vizier's phase.
+type phaseTransition struct {
+	Namespace     string               `json:"namespace"`
+	Name          string               `json:"name"`
+	Phase         v1alpha1.VizierPhase `json:"phase"`
+	PreviousPhase v1alpha1.VizierPhase `json:"previousPhase"`
+	Reason        string               `json:"reason,omitempty"`
+	Message       string               `json:"message,omitempty"`
+	UnhealthyPod  string               `json:"unhealthyPod,omitempty"`
+	Time          metav1.Time          `json:"time"`
+}
+
+func newPhaseTransition(vz *v1alpha1.Vizier, prevPhase v1alpha1.VizierPhase, now time.Time) *phaseTransition {
+	return &phaseTransition{
+		Namespace:     vz.Namespace,
+		Name:          vz.Name,
+		Phase:         vz.Status.VizierPhase,
+		PreviousPhase: prevPhase,
+		Reason:        vz.Status.VizierReason,
+		Message:       vz.Status.Message,
+		UnhealthyPod:  vz.Status.UnhealthyPod,
+		Time:          metav1.NewTime(now),
+	}
+}
+
+// summary describes the transition in a single line.
+func (p *phaseTransition) summary() string {
+	s := fmt.Sprintf("Vizier %s/%s is %s (was %s)", p.Namespace, p.Name, p.Phase, p.PreviousPhase)
+	if p.Message != "" {
+		s += ": " + p.Message
+	}
+	return s
+}
+
+// getPagerDutySeverity maps the Vizier's phase to the severity of a PagerDuty alert.
+func getPagerDutySeverity(phase v1alpha1.VizierPhase) string {
+	switch phase {
+	case v1alpha1.VizierPhaseDegraded:
+		return "warning"
+	case v1alpha1.VizierPhaseDisconnected:
+		return "error"
+	}
+	return "critical"
+}
+
+// getWebhookPayload formats the transition for the webhook. The routing key is only used by PagerDuty.
+func getWebhookPayload(format v1alpha1.WebhookFormat, p *phaseTransition, routingKey string) ([]byte, error) {
+	switch format {
+	case v1alpha1.WebhookFormatSlack:
+		return json.Marshal(map[string]string{"text": p.summary()})
+	case v1alpha1.WebhookFormatPagerDuty:
+		action := "trigger"
+		if p.Phase == v1alpha1.VizierPhaseHealthy {
+			action = "resolve"
+		}
+		source := fmt.Sprintf("%s/%s", p.Namespace, p.Name)
+		return json.Marshal(map[string]interface{}{
+			"routing_key":  routingKey,
+			"event_action": action,
+			// Resolving the alert requires the same key as triggering it.
+			"dedup_key": "vizier/" + source,
+			"payload": map[string]interface{}{
+				"summary":        p.summary(),
+				"source":         source,
+				"severity":       getPagerDutySeverity(p.Phase),
+				"timestamp":      p.Time.UTC().Format(time.RFC3339),
+				"custom_details": p,
+			},
+		})
+	}
+	return json.Marshal(p)
+}
+
+// validateWebhooks checks that each webhook has a URL, and that PagerDuty webhooks have a routing key.
+func validateWebhooks(webhooks []v1alpha1.WebhookParams) []error {
+	var errs []error
+	for i, w := range webhooks {
+		if w.URL == "" && w.SecretName == "" {
+			errs = append(errs, fmt.Errorf("spec.webhooks[%d] must specify a url or secretName", i))
+		}
+		if w.Format == v1alpha1.WebhookFormatPagerDuty && w.SecretName == "" {
+			errs = append(errs, fmt.Errorf("spec.webhooks[%d].secretName must contain the routingKey of the PagerDuty integration", i))
+		}
+		if w.URL != "" {
+			if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, fmt.Errorf("spec.webhooks[%d].url %q must be an http or https URL", i, w.URL))
+			}
+		}
+	}
+	return errs
+}
+
+// sendWebhook POSTs the transition to the webhook, reading its URL or routing key from its secret if specified.
+func sendWebhook(ctx context.Context, clientset kubernetes.Interface, client *http.Client, namespace string, w v1alpha1.WebhookParams, p *phaseTransition) error {
+	addr, routingKey := w.URL, ""
+	if w.SecretName != "" {
+		s, err := clientset.CoreV1().Secrets(namespace).Get(ctx, w.SecretName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get webhook secret %s: %w", w.SecretName, err)
+		}
+		if w.Format == v1alpha1.WebhookFormatPagerDuty {
+			routingKey = string(s.Data["routingKey"])
+		} else if addr == "" {
+			addr = string(s.Data["url"])
+		}
+	}
+	if addr == "" && w.Format == v1alpha1.WebhookFormatPagerDuty {
+		addr = pagerDutyEventsURL
+	}
+	if addr == "" {
+		return errors.New("webhook has no URL")
+	}
+
+	body, err := getWebhookPayload(w.Format, p, routingKey)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, addr, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		// The error includes the URL, which may be a credential.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// notifyWebhooks notifies the Vizier's webhooks if its phase changed. The first phase of a new Vizier is not a
+// transition.
+func (m *VizierMonitor) notifyWebhooks(vz *v1alpha1.Vizier, prevPhase v1alpha1.VizierPhase, now time.Time) {
+	if len(vz.Spec.Webhooks) == 0 || prevPhase == v1alpha1.VizierPhaseNone || prevPhase == vz.Status.VizierPhase {
+		return
+	}
+	client := m.webhookClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	p := newPhaseTransition(vz, prevPhase, now)
+	for i, w := range vz.Spec.Webhooks {
+		err := sendWebhook(m.ctx, m.clientset, client, m.namespace, w, p)
+		if err != nil {
+			log.WithError(err).WithField("webhook", i).Error("Failed to notify webhook of Vizier phase transition")
+			if m.recorder != nil {
+				m.recorder.Eventf(vz, v1.EventTypeWarning, "WebhookFailed", "Failed to notify spec.webhooks[%d]: %v", i, err)
+			}
+		}
+	}
+}
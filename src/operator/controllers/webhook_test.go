@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestGetWebhookPayload(t *testing.T) {
+	p := &phaseTransition{
+		Namespace:     "pl",
+		Name:          "vizier",
+		Phase:         v1alpha1.VizierPhaseUnhealthy,
+		PreviousPhase: v1alpha1.VizierPhaseHealthy,
+		Message:       "NATS message bus is unreachable",
+		Time:          metav1.NewTime(time.Unix(0, 0)),
+	}
+
+	body, err := getWebhookPayload(v1alpha1.WebhookFormatSlack, p, "")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"text": "Vizier pl/vizier is Unhealthy (was Healthy): NATS message bus is unreachable"}`, string(body))
+
+	body, err = getWebhookPayload(v1alpha1.WebhookFormatGeneric, p, "")
+	require.NoError(t, err)
+	var generic phaseTransition
+	require.NoError(t, json.Unmarshal(body, &generic))
+	assert.Equal(t, v1alpha1.VizierPhaseUnhealthy, generic.Phase)
+	assert.Equal(t, v1alpha1.VizierPhaseHealthy, generic.PreviousPhase)
+
+	body, err = getWebhookPayload(v1alpha1.WebhookFormatPagerDuty, p, "key")
+	require.NoError(t, err)
+	var event struct {
+		RoutingKey  string `json:"routing_key"`
+		EventAction string `json:"event_action"`
+		DedupKey    string `json:"dedup_key"`
+		Payload     struct {
+			Severity string `json:"severity"`
+			Source   string `json:"source"`
+		} `json:"payload"`
+	}
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, "key", event.RoutingKey)
+	assert.Equal(t, "trigger", event.EventAction)
+	assert.Equal(t, "vizier/pl/vizier", event.DedupKey)
+	assert.Equal(t, "critical", event.Payload.Severity)
+	assert.Equal(t, "pl/vizier", event.Payload.Source)
+
+	p.Phase, p.PreviousPhase = v1alpha1.VizierPhaseHealthy, v1alpha1.VizierPhaseUnhealthy
+	body, err = getWebhookPayload(v1alpha1.WebhookFormatPagerDuty, p, "key")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, "resolve", event.EventAction)
+	assert.Equal(t, "vizier/pl/vizier", event.DedupKey)
+}
+
+func TestValidateWebhooks(t *testing.T) {
+	assert.Empty(t, validateWebhooks([]v1alpha1.WebhookParams{
+		{URL: "https://hooks.example.com/a"},
+		{SecretName: "slack-webhook", Format: v1alpha1.WebhookFormatSlack},
+		{SecretName: "pagerduty", Format: v1alpha1.WebhookFormatPagerDuty},
+	}))
+	assert.Len(t, validateWebhooks([]v1alpha1.WebhookParams{
+		{},
+		{URL: "https://events.pagerduty.com/v2/enqueue", Format: v1alpha1.WebhookFormatPagerDuty},
+		{URL: "ftp://example.com"},
+	}), 3)
+}
+
+func TestMonitor_notifyWebhooks(t *testing.T) {
+	var received []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r.URL.Path+" "+string(body))
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	clientset := fake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "slack-webhook", Namespace: "pl"},
+		Data:       map[string][]byte{"url": []byte(ts.URL + "/slack")},
+	})
+	recorder := record.NewFakeRecorder(10)
+	m := &VizierMonitor{
+		ctx:           context.Background(),
+		clientset:     clientset,
+		namespace:     "pl",
+		recorder:      recorder,
+		webhookClient: ts.Client(),
+	}
+
+	vz := &v1alpha1.Vizier{
+		ObjectMeta: metav1.ObjectMeta{Name: "vizier", Namespace: "pl"},
+		Spec: v1alpha1.VizierSpec{
+			Webhooks: []v1alpha1.WebhookParams{
+				{SecretName: "slack-webhook", Format: v1alpha1.WebhookFormatSlack},
+				{URL: ts.URL + "/fail"},
+			},
+		},
+		Status: v1alpha1.VizierStatus{VizierPhase: v1alpha1.VizierPhaseDegraded},
+	}
+
+	// Neither the first phase nor an unchanged phase are transitions.
+	m.notifyWebhooks(vz, v1alpha1.VizierPhaseNone, time.Now())
+	m.notifyWebhooks(vz, v1alpha1.VizierPhaseDegraded, time.Now())
+	assert.Empty(t, received)
+
+	m.notifyWebhooks(vz, v1alpha1.VizierPhaseHealthy, time.Now())
+	require.Len(t, received, 2)
+	assert.Equal(t, `/slack {"text":"Vizier pl/vizier is Degraded (was Healthy)"}`, received[0])
+	assert.Contains(t, received[1], `"previousPhase":"Healthy"`)
+	require.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "spec.webhooks[1]")
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// mapWorkloadToVizier maps a workload deployed by the operator to the Vizier which it belongs to, so that changes to
+// the workload trigger a reconcile of the Vizier.
+func mapWorkloadToVizier(obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()[operatorAnnotation]
+	if !ok || name == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}}}
+}
+
+// workloadPredicate filters the workload events which should trigger a reconcile to those for workloads deployed by
+// the operator, whose spec was changed or which were deleted. Status updates, such as pods becoming ready, are
+// ignored.
+func workloadPredicate() predicate.Predicate {
+	return predicate.And(
+		predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			_, ok := obj.GetLabels()[operatorAnnotation]
+			return ok
+		}),
+		predicate.GenerationChangedPredicate{},
+	)
+}
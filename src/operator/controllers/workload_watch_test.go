@@ -0,0 +1,59 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestMapWorkloadToVizier(t *testing.T) {
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name:      "kelvin",
+		Namespace: "pl",
+		Labels:    map[string]string{operatorAnnotation: "vizier"},
+	}}
+	assert.Equal(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: "pl", Name: "vizier"}},
+	}, mapWorkloadToVizier(deployment))
+
+	assert.Empty(t, mapWorkloadToVizier(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "pl"}}))
+}
+
+func TestWorkloadPredicate(t *testing.T) {
+	p := workloadPredicate()
+	labels := map[string]string{operatorAnnotation: "vizier"}
+
+	owned := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "kelvin", Labels: labels, Generation: 1}}
+	updated := owned.DeepCopy()
+	updated.Generation = 2
+	statusOnly := owned.DeepCopy()
+	statusOnly.Status.ReadyReplicas = 1
+	other := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+
+	assert.True(t, p.Delete(event.DeleteEvent{Object: owned}))
+	assert.True(t, p.Update(event.UpdateEvent{ObjectOld: owned, ObjectNew: updated}))
+	assert.False(t, p.Update(event.UpdateEvent{ObjectOld: owned, ObjectNew: statusOnly}))
+	assert.False(t, p.Delete(event.DeleteEvent{Object: other}))
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// The YAML which lists the sha256 digest of every other YAML in the bundle, one "<digest>  <name>" line per YAML,
+	// in the format of sha256sum.
+	yamlManifestName = "manifest.sha256"
+	// The YAML which holds the base64 encoded signature of the manifest, for example as produced by
+	// "cosign sign-blob".
+	yamlSignatureName = "manifest.sha256.sig"
+)
+
+// loadYAMLVerificationKey reads the PEM encoded public key which Vizier YAMLs from Pixie Cloud must be signed with.
+func loadYAMLVerificationKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read YAML verification key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in YAML verification key %s", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse YAML verification key: %w", err)
+	}
+	return key, nil
+}
+
+// verifySignature checks the signature of the data. ECDSA and RSA signatures are over the sha256 digest of the data.
+func verifySignature(key crypto.PublicKey, data []byte, sig []byte) error {
+	digest := sha256.Sum256(data)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, digest[:], sig) {
+			return errors.New("invalid ECDSA signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, data, sig) {
+			return errors.New("invalid Ed25519 signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// verifyVizierYAMLs checks that the manifest of the YAMLs is signed by the key, and that the manifest lists exactly
+// the YAMLs in the bundle with their digests. It returns the YAMLs without the manifest and its signature.
+func verifyVizierYAMLs(key crypto.PublicKey, yamlMap map[string]string) (map[string]string, error) {
+	manifest, ok := yamlMap[yamlManifestName]
+	if !ok {
+		return nil, fmt.Errorf("unsigned Vizier YAMLs: missing %s", yamlManifestName)
+	}
+	encodedSig, ok := yamlMap[yamlSignatureName]
+	if !ok {
+		return nil, fmt.Errorf("unsigned Vizier YAMLs: missing %s", yamlSignatureName)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedSig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signature of Vizier YAMLs: %w", err)
+	}
+	if err := verifySignature(key, []byte(manifest), sig); err != nil {
+		return nil, fmt.Errorf("failed to verify signature of Vizier YAMLs: %w", err)
+	}
+
+	digests := make(map[string]string)
+	for _, line := range strings.Split(manifest, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("malformed line in Vizier YAML manifest: %q", line)
+		}
+		// sha256sum marks files read in binary mode with a leading "*".
+		digests[strings.TrimPrefix(fields[1], "*")] = fields[0]
+	}
+
+	verified := make(map[string]string, len(yamlMap))
+	for name, content := range yamlMap {
+		if name == yamlManifestName || name == yamlSignatureName {
+			continue
+		}
+		expected, ok := digests[name]
+		if !ok {
+			return nil, fmt.Errorf("YAML %s is not listed in the signed manifest", name)
+		}
+		digest := sha256.Sum256([]byte(content))
+		if !strings.EqualFold(hex.EncodeToString(digest[:]), expected) {
+			return nil, fmt.Errorf("YAML %s does not match the digest in the signed manifest", name)
+		}
+		verified[name] = content
+	}
+	for name := range digests {
+		if _, ok := verified[name]; !ok {
+			return nil, fmt.Errorf("YAML %s in the signed manifest is missing", name)
+		}
+	}
+	return verified, nil
+}
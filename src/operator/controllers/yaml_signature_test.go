@@ -0,0 +1,172 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signTestYAMLs adds a sha256 manifest of the YAMLs, and its signature, to the YAMLs.
+func signTestYAMLs(t *testing.T, sign func([]byte) []byte, yamlMap map[string]string) map[string]string {
+	var names []string
+	for name := range yamlMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var manifest strings.Builder
+	signed := make(map[string]string)
+	for _, name := range names {
+		digest := sha256.Sum256([]byte(yamlMap[name]))
+		fmt.Fprintf(&manifest, "%s  %s\n", hex.EncodeToString(digest[:]), name)
+		signed[name] = yamlMap[name]
+	}
+	signed[yamlManifestName] = manifest.String()
+	signed[yamlSignatureName] = base64.StdEncoding.EncodeToString(sign([]byte(manifest.String())))
+	return signed
+}
+
+func TestVerifyVizierYAMLs(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keys := []struct {
+		name string
+		pub  crypto.PublicKey
+		sign func([]byte) []byte
+	}{
+		{
+			name: "ECDSA",
+			pub:  &ecKey.PublicKey,
+			sign: func(data []byte) []byte {
+				digest := sha256.Sum256(data)
+				sig, err := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+				require.NoError(t, err)
+				return sig
+			},
+		},
+		{
+			name: "RSA",
+			pub:  &rsaKey.PublicKey,
+			sign: func(data []byte) []byte {
+				digest := sha256.Sum256(data)
+				sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+				require.NoError(t, err)
+				return sig
+			},
+		},
+		{
+			name: "Ed25519",
+			pub:  edPub,
+			sign: func(data []byte) []byte { return ed25519.Sign(edKey, data) },
+		},
+	}
+
+	yamlMap := map[string]string{
+		"vizier_persistent": "kind: Deployment\n",
+		"nats":              "kind: StatefulSet\n",
+	}
+	for _, k := range keys {
+		t.Run(k.name, func(t *testing.T) {
+			verified, err := verifyVizierYAMLs(k.pub, signTestYAMLs(t, k.sign, yamlMap))
+			require.NoError(t, err)
+			assert.Equal(t, yamlMap, verified)
+		})
+	}
+
+	ecSign := keys[0].sign
+	t.Run("unsigned", func(t *testing.T) {
+		_, err := verifyVizierYAMLs(&ecKey.PublicKey, yamlMap)
+		assert.Error(t, err)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		_, err = verifyVizierYAMLs(&otherKey.PublicKey, signTestYAMLs(t, ecSign, yamlMap))
+		assert.Error(t, err)
+	})
+
+	t.Run("modified YAML", func(t *testing.T) {
+		signed := signTestYAMLs(t, ecSign, yamlMap)
+		signed["nats"] = "kind: DaemonSet\n"
+		_, err := verifyVizierYAMLs(&ecKey.PublicKey, signed)
+		assert.Error(t, err)
+	})
+
+	t.Run("added YAML", func(t *testing.T) {
+		signed := signTestYAMLs(t, ecSign, yamlMap)
+		signed["etcd"] = "kind: StatefulSet\n"
+		_, err := verifyVizierYAMLs(&ecKey.PublicKey, signed)
+		assert.Error(t, err)
+	})
+
+	t.Run("removed YAML", func(t *testing.T) {
+		signed := signTestYAMLs(t, ecSign, yamlMap)
+		delete(signed, "nats")
+		_, err := verifyVizierYAMLs(&ecKey.PublicKey, signed)
+		assert.Error(t, err)
+	})
+}
+
+func TestLoadYAMLVerificationKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cosign.pub")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+
+	key, err := loadYAMLVerificationKey(path)
+	require.NoError(t, err)
+	assert.True(t, ecKey.PublicKey.Equal(key))
+
+	notPEM := filepath.Join(dir, "key.txt")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a key"), 0600))
+	_, err = loadYAMLVerificationKey(notPEM)
+	assert.Error(t, err)
+
+	_, err = loadYAMLVerificationKey(filepath.Join(dir, "missing.pub"))
+	assert.Error(t, err)
+}
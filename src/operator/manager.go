@@ -21,6 +21,8 @@ package main
 import (
 	"flag"
 	"os"
+	"path/filepath"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -52,10 +54,43 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var webhookCertDir string
+	var resyncInterval time.Duration
+	var deployRetry controllers.DeployRetryConfig
+	var cloudConn controllers.CloudConnConfig
+	var specDefaultsConfigMap string
+	var yamlVerificationKeyFile string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
-	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
+	flag.BoolVar(&enableLeaderElection, "enable-leader-election", true,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs",
+		"The directory containing the serving certs for the webhook server. "+
+			"The Vizier webhooks are only enabled if certs are present.")
+	flag.DurationVar(&resyncInterval, "resync-interval", 0,
+		"How often each Vizier is fully reconciled, even if its spec has not changed. "+
+			"Disabled if 0. This may be overridden in the Vizier spec.")
+	flag.DurationVar(&deployRetry.InitialInterval, "deploy-retry-initial-interval", 15*time.Second,
+		"How long to wait before retrying to apply Vizier resources.")
+	flag.DurationVar(&deployRetry.MaxInterval, "deploy-retry-max-interval", time.Minute,
+		"The longest to wait between retries to apply Vizier resources.")
+	flag.DurationVar(&deployRetry.MaxElapsedTime, "deploy-retry-max-elapsed-time", 5*time.Minute,
+		"How long to retry applying Vizier resources before failing the deploy. "+
+			"Increase this for clusters with slow admission webhooks.")
+	flag.Float64Var(&deployRetry.RandomizationFactor, "deploy-retry-jitter", 0.5,
+		"The jitter applied to each retry interval, as a fraction of the interval.")
+	flag.StringVar(&cloudConn.ProxyURL, "cloud-proxy", "",
+		"The URL of the HTTP proxy through which Pixie Cloud is reached, for example http://proxy:3128. "+
+			"If empty, the proxy is taken from the HTTPS_PROXY and NO_PROXY environment variables.")
+	flag.StringVar(&cloudConn.CACertFile, "cloud-ca-cert", "",
+		"The path to a PEM file of CA certificates which are trusted to sign the certificate of Pixie Cloud, "+
+			"in addition to the system's trusted CAs. Use this for self-hosted clouds behind an internal CA.")
+	flag.StringVar(&specDefaultsConfigMap, "vizier-defaults-configmap", "",
+		"The ConfigMap, as <namespace>/<name>, whose \"spec.yaml\" key holds a default Vizier spec which is merged into "+
+			"the spec of every Vizier when it is deployed. Fields set in a Vizier take precedence over the defaults.")
+	flag.StringVar(&yamlVerificationKeyFile, "vizier-yaml-public-key", "",
+		"The path to a PEM encoded ECDSA, RSA or Ed25519 public key. If set, the Vizier YAMLs from Pixie Cloud are only "+
+			"applied if they include a sha256 manifest of the YAMLs which is signed by this key, for example with cosign sign-blob.")
 	flag.Parse()
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
@@ -64,6 +99,10 @@ func main() {
 		Port:               9443,
 		LeaderElection:     enableLeaderElection,
 		LeaderElectionID:   leaderElectionID,
+		// The leader steps down immediately on shutdown, so that another replica can take over without waiting
+		// for the lease to expire. This is safe since the manager exits right after.
+		LeaderElectionReleaseOnCancel: true,
+		CertDir:                       webhookCertDir,
 	})
 	if err != nil {
 		log.WithError(err).Error("Unable to start manager")
@@ -78,14 +117,46 @@ func main() {
 	clientset := k8s.GetClientset(kubeConfig)
 
 	if err = (&controllers.VizierReconciler{
-		Client:     mgr.GetClient(),
-		Scheme:     mgr.GetScheme(),
-		Clientset:  clientset,
-		RestConfig: kubeConfig,
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		Clientset:               clientset,
+		RestConfig:              kubeConfig,
+		ResyncInterval:          resyncInterval,
+		DeployRetry:             deployRetry,
+		CloudConn:               cloudConn,
+		SpecDefaultsConfigMap:   specDefaultsConfigMap,
+		YAMLVerificationKeyFile: yamlVerificationKeyFile,
+		Recorder:                mgr.GetEventRecorderFor("vizier-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		log.WithError(err).Error("Unable to create controller")
 		os.Exit(1)
 	}
+
+	// The status of each Vizier is served alongside the metrics, for external automation.
+	if err = mgr.AddMetricsExtraHandler("/vizier-status", &controllers.StatusHandler{
+		Client: mgr.GetClient(),
+	}); err != nil {
+		log.WithError(err).Error("Unable to add status handler")
+		os.Exit(1)
+	}
+
+	// The webhook server fails to start without serving certs, which are only mounted when the webhook
+	// is registered with the API server, for example by OLM.
+	if _, err := os.Stat(filepath.Join(webhookCertDir, "tls.crt")); err == nil {
+		if err = (&controllers.VizierDefaulter{
+			Clientset: clientset,
+			CloudConn: cloudConn,
+		}).SetupWebhookWithManager(mgr); err != nil {
+			log.WithError(err).Error("Unable to create webhook")
+			os.Exit(1)
+		}
+		if err = (&controllers.VizierValidator{}).SetupWebhookWithManager(mgr); err != nil {
+			log.WithError(err).Error("Unable to create webhook")
+			os.Exit(1)
+		}
+	} else {
+		log.Info("No webhook serving certs found, disabling the Vizier webhooks")
+	}
 	// +kubebuilder:scaffold:builder
 
 	log.Info("Starting manager")
@@ -37,6 +37,8 @@ import (
 	v1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
@@ -548,25 +550,25 @@ func deploy(cloudConn *grpc.ClientConn, clientset *kubernetes.Clientset, vzClien
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 		defer cancel()
 
-		t := time.NewTicker(2 * time.Second)
-		defer t.Stop()
-		clusterIDExists := false
-		for !clusterIDExists { // Wait for secret to be updated with clusterID.
-			select {
-			case <-ctx.Done():
-				// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
-				log.Fatal("Timed out waiting for cluster ID assignment")
-			case <-t.C:
-				s := k8s.GetSecret(clientset, namespace, "pl-cluster-secrets")
-				if s == nil {
-					continue
-				}
-				if cID, ok := s.Data["cluster-id"]; ok {
-					clusterID = uuid.FromStringOrNil(string(cID))
-					clusterIDExists = true
-				}
-			}
+		dynamicClient, err := dynamic.NewForConfig(kubeConfig)
+		if err != nil {
+			return err
+		}
+		// Wait for secret to be updated with clusterID.
+		err = k8s.WaitForCondition(ctx, dynamicClient, schema.GroupVersionResource{Version: "v1", Resource: "secrets"},
+			namespace, "pl-cluster-secrets", &k8s.Condition{JSONPath: "{.data['cluster-id']}"}, 5*time.Minute)
+		if errors.Is(err, k8s.ErrConditionTimeout) {
+			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+			log.WithError(err).Fatal("Timed out waiting for cluster ID assignment")
+		}
+		if err != nil {
+			log.WithError(err).Fatal("Failed to wait for cluster ID assignment")
+		}
+		s := k8s.GetSecret(clientset, namespace, "pl-cluster-secrets")
+		if s == nil {
+			return errors.New("missing cluster secrets")
 		}
+		clusterID = uuid.FromStringOrNil(string(s.Data["cluster-id"]))
 
 		return waitForCluster(ctx, cloudConn, clusterID)
 	})
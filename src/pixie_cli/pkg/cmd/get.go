@@ -119,7 +119,7 @@ var GetViziersCmd = &cobra.Command{
 		for _, vz := range vzs {
 			var lastHeartbeat interface{}
 			lastHeartbeat = vz.LastHeartbeatNs
-			if format == "" || format == "table" {
+			if format == "" || format == "table" || format == "wide" {
 				if vz.LastHeartbeatNs >= 0 {
 					lastHeartbeat = humanize.Time(
 						time.Unix(0,
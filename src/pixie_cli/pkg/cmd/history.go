@@ -0,0 +1,140 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/history"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+func init() {
+	HistoryCmd.AddCommand(HistoryListCmd)
+	HistoryCmd.AddCommand(HistoryRerunCmd)
+
+	HistoryListCmd.Flags().StringP("output", "o", "", "Output format: one of: json|table|wide|csv")
+}
+
+func mustCreateHistoryStore() *history.Store {
+	historyPath, err := utils.EnsureDefaultHistoryFilePath()
+	if err != nil {
+		utils.WithError(err).Fatal("Failed to load/create history file path")
+	}
+	return history.NewStore(historyPath)
+}
+
+// recordRunHistory adds the script execution to the local run history. Failing to record
+// the history should never fail the run itself, so errors are only logged.
+func recordRunHistory(entry *history.Entry) {
+	historyPath, err := utils.EnsureDefaultHistoryFilePath()
+	if err != nil {
+		log.WithError(err).Debug("Failed to load/create history file path")
+		return
+	}
+	if err := history.NewStore(historyPath).Add(entry); err != nil {
+		log.WithError(err).Debug("Failed to record run history")
+	}
+}
+
+// HistoryCmd is the "history" command.
+var HistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show and re-run previously executed scripts",
+}
+
+// HistoryListCmd is the "history list" command.
+var HistoryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List previously executed scripts",
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("output")
+		entries, err := mustCreateHistoryStore().List()
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to read run history")
+		}
+
+		w := components.CreateStreamWriter(format, os.Stdout)
+		defer w.Finish()
+		w.SetHeader("history", []string{"ID", "Time", "Script", "Args", "Cluster", "Duration", "Outcome"})
+		for _, e := range entries {
+			cluster := e.ClusterID
+			if e.AllClusters {
+				cluster = "all"
+			}
+			err := w.Write([]interface{}{e.ID, e.Timestamp.Local().Format(time.RFC3339), e.Script(),
+				strings.Join(e.ScriptArgs, " "), cluster, e.Duration.Round(time.Millisecond), e.Outcome})
+			if err != nil {
+				log.WithError(err).Error("Failed to write to stream")
+			}
+		}
+	},
+}
+
+// HistoryRerunCmd is the "history rerun" command.
+var HistoryRerunCmd = &cobra.Command{
+	Use:   "rerun <id>",
+	Short: "Re-run a previously executed script with the same arguments and cluster",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			utils.Fatalf("Invalid history ID: %s", args[0])
+		}
+		e, err := mustCreateHistoryStore().Get(id)
+		if err != nil {
+			utils.WithError(err).Fatalf("Failed to find history entry %d", id)
+		}
+
+		if e.ScriptFile == "-" {
+			utils.Fatal("Scripts read from STDIN cannot be re-run.")
+		}
+
+		runArgs := e.ScriptArgs
+		if e.ScriptFile == "" {
+			runArgs = append([]string{e.ScriptName}, e.ScriptArgs...)
+		}
+
+		flags := map[string]string{
+			"file":           e.ScriptFile,
+			"bundle":         e.Bundle,
+			"cluster":        e.ClusterID,
+			"all-clusters":   strconv.FormatBool(e.AllClusters),
+			"output":         e.OutputFormat,
+			"e2e_encryption": strconv.FormatBool(e.E2EEncryption),
+		}
+		for name, val := range flags {
+			if err := RunCmd.Flags().Set(name, val); err != nil {
+				utils.WithError(err).Fatalf("Failed to set flag %s", name)
+			}
+		}
+		viper.BindPFlag("bundle", RunCmd.Flags().Lookup("bundle"))
+
+		utils.Infof("Re-running: %s %s", e.Script(), strings.Join(e.ScriptArgs, " "))
+		RunCmd.Run(RunCmd, runArgs)
+	},
+}
@@ -50,6 +50,9 @@ func init() {
 	RootCmd.PersistentFlags().BoolP("quiet", "q", false, "quiet mode")
 	viper.BindPFlag("quiet", RootCmd.PersistentFlags().Lookup("quiet"))
 
+	RootCmd.PersistentFlags().Bool("no-truncate", false, "Wrap long values in table output, rather than truncating them to fit the terminal")
+	viper.BindPFlag("no_truncate", RootCmd.PersistentFlags().Lookup("no-truncate"))
+
 	RootCmd.PersistentFlags().Bool("do_not_track", false, "do_not_track")
 	viper.BindPFlag("do_not_track", RootCmd.PersistentFlags().Lookup("do_not_track"))
 
@@ -69,6 +72,7 @@ func init() {
 	RootCmd.AddCommand(DeployKeyCmd)
 	RootCmd.AddCommand(APIKeyCmd)
 	RootCmd.AddCommand(DebugCmd)
+	RootCmd.AddCommand(HistoryCmd)
 
 	RootCmd.PersistentFlags().MarkHidden("cloud_addr")
 	RootCmd.PersistentFlags().MarkHidden("dev_cloud_namespace")
@@ -179,7 +183,7 @@ var RootCmd = &cobra.Command{
 
 func checkAuthForCmd(c *cobra.Command) {
 	switch c {
-	case DeployCmd, UpdateCmd, RunCmd, LiveCmd, GetCmd, ScriptCmd, DeployKeyCmd, APIKeyCmd:
+	case DeployCmd, UpdateCmd, RunCmd, LiveCmd, GetCmd, ScriptCmd, DeployKeyCmd, APIKeyCmd, HistoryRerunCmd:
 		authenticated := auth.IsAuthenticated(viper.GetString("cloud_addr"))
 		if !authenticated {
 			utils.Errorf("Failed to authenticate. Please retry `px auth login`.")
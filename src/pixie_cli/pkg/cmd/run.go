@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/gofrs/uuid"
@@ -33,13 +34,14 @@ import (
 	"github.com/spf13/viper"
 
 	"px.dev/pixie/src/cloud/api/ptproxy"
+	"px.dev/pixie/src/pixie_cli/pkg/history"
 	"px.dev/pixie/src/pixie_cli/pkg/script"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
 	"px.dev/pixie/src/pixie_cli/pkg/vizier"
 )
 
 func init() {
-	RunCmd.Flags().StringP("output", "o", "", "Output format: one of: json|table|csv")
+	RunCmd.Flags().StringP("output", "o", "", "Output format: one of: json|table|wide|csv")
 	RunCmd.Flags().StringP("file", "f", "", "Script file, specify - for STDIN")
 	RunCmd.Flags().BoolP("list", "l", false, "List available scripts")
 	RunCmd.Flags().BoolP("e2e_encryption", "e", true, "Enable E2E encryption")
@@ -138,13 +140,14 @@ func createNewCobraCommand() *cobra.Command {
 
 			var execScript *script.ExecutableScript
 			scriptFile, _ := cmd.Flags().GetString("file")
+			var scriptName string
 			var scriptArgs []string
 
 			if scriptFile == "" {
 				if len(args) == 0 {
 					utils.Fatal("Expected script_name with script args.")
 				}
-				scriptName := args[0]
+				scriptName = args[0]
 				execScript = br.MustGetScript(scriptName)
 				scriptArgs = args[1:]
 			} else {
@@ -191,8 +194,33 @@ func createNewCobraCommand() *cobra.Command {
 			// Support Ctrl+C to cancel a query.
 			ctx, cleanup := utils.WithSignalCancellable(context.Background())
 			defer cleanup()
+			startTime := time.Now()
 			err = vizier.RunScriptAndOutputResults(ctx, conns, execScript, format, useEncryption)
 
+			historyEntry := &history.Entry{
+				Timestamp:     startTime,
+				ScriptName:    scriptName,
+				ScriptFile:    scriptFile,
+				ScriptArgs:    scriptArgs,
+				Bundle:        viper.GetString("bundle"),
+				AllClusters:   allClusters,
+				OutputFormat:  format,
+				E2EEncryption: useEncryption,
+				Duration:      time.Since(startTime),
+				Outcome:       history.OutcomeSuccess,
+			}
+			if !allClusters {
+				historyEntry.ClusterID = clusterID.String()
+			}
+			if err != nil {
+				historyEntry.Outcome = history.OutcomeFailed
+				historyEntry.Error = err.Error()
+				if vzErr, ok := err.(*vizier.ScriptExecutionError); ok && vzErr.Code() == vizier.CodeCanceled {
+					historyEntry.Outcome = history.OutcomeCanceled
+				}
+			}
+			recordRunHistory(historyEntry)
+
 			if err != nil {
 				vzErr, ok := err.(*vizier.ScriptExecutionError)
 				switch {
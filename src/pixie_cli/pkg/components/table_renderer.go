@@ -24,12 +24,26 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/mattn/go-runewidth"
 	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
+const (
+	// Columns are never shrunk below this width when fitting a table to the terminal.
+	minTableColWidth = 8
+	// The suffix appended to values which were truncated to fit the terminal.
+	truncationSuffix = "…"
+)
+
+var ansiEscapeRegexp = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
 // OutputStreamWriter is the default interface for all output writers.
 type OutputStreamWriter interface {
 	SetHeader(id string, headerValues []string)
@@ -51,6 +65,8 @@ func CreateStreamWriter(format string, w io.Writer) OutputStreamWriter {
 		return NewJSONStreamWriter(w)
 	case "table":
 		return NewTableStreamWriter(w)
+	case "wide":
+		return NewWideTableStreamWriter(w)
 	case "csv":
 		return NewCSVStreamWriter(w)
 	case "null":
@@ -68,6 +84,10 @@ type TableStreamWriter struct {
 	id           string
 	headerValues []string
 	data         [][]interface{}
+	// maxWidth is the width the table is fit to. A maxWidth of 0 renders all values in full.
+	maxWidth int
+	// wrap determines whether values which don't fit are wrapped onto multiple lines, rather than truncated.
+	wrap bool
 }
 
 type stringer interface {
@@ -87,14 +107,38 @@ func stringifyValue(val interface{}) string {
 	}
 }
 
-// NewTableStreamWriter creates a table writer based on input stream.
+// NewTableStreamWriter creates a table writer based on input stream. When writing to a terminal, the table is
+// fit to the width of the terminal by truncating long values, or by wrapping them if no_truncate is set.
 func NewTableStreamWriter(w io.Writer) *TableStreamWriter {
+	return &TableStreamWriter{
+		w:        w,
+		data:     make([][]interface{}, 0),
+		maxWidth: terminalWidth(w),
+		wrap:     viper.GetBool("no_truncate"),
+	}
+}
+
+// NewWideTableStreamWriter creates a table writer which renders all values in full, regardless of the terminal width.
+func NewWideTableStreamWriter(w io.Writer) *TableStreamWriter {
 	return &TableStreamWriter{
 		w:    w,
 		data: make([][]interface{}, 0),
 	}
 }
 
+// terminalWidth returns the width of the terminal the writer outputs to, or 0 if it is not a terminal.
+func terminalWidth(w io.Writer) int {
+	f, ok := w.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return 0
+	}
+	width, _, err := term.GetSize(int(f.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
 // SetHeader is called to set the key values for each of the data values. Must be called before Write is.
 func (t *TableStreamWriter) SetHeader(id string, headerValues []string) {
 	t.id = id
@@ -121,8 +165,21 @@ func (t *TableStreamWriter) stringifyRow(row []interface{}) []string {
 // Finish is called when all the data has been sent. In the case of the table we can now render all the values.
 func (t *TableStreamWriter) Finish() {
 	fmt.Printf("Table ID: %s\n", t.id)
+	header := t.headerValues
+	rows := make([][]string, len(t.data))
+	for i, row := range t.data {
+		rows[i] = t.stringifyRow(row)
+	}
+	if t.maxWidth > 0 {
+		widths := fitColumnWidths(header, rows, t.maxWidth)
+		header = fitRow(header, widths, false)
+		for i, row := range rows {
+			rows[i] = fitRow(row, widths, t.wrap)
+		}
+	}
+
 	table := tablewriter.NewWriter(t.w)
-	table.SetHeader(t.headerValues)
+	table.SetHeader(header)
 
 	table.SetAutoFormatHeaders(true)
 	table.SetAutoWrapText(false)
@@ -138,11 +195,93 @@ func (t *TableStreamWriter) Finish() {
 	table.SetTablePadding("\t")
 	table.SetNoWhiteSpace(false)
 
-	for _, row := range t.data {
-		table.Append(t.stringifyRow(row))
+	table.AppendBulk(rows)
+	table.Render()
+}
+
+func cellWidth(val string) int {
+	width := 0
+	for _, line := range strings.Split(val, "\n") {
+		if w := tablewriter.DisplayWidth(line); w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+// fitColumnWidths returns the width of each column, such that the rendered table is at most maxWidth wide.
+// The widest columns are shrunk first, so that narrow columns are left intact.
+func fitColumnWidths(header []string, rows [][]string, maxWidth int) []int {
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = cellWidth(h)
+	}
+	for _, row := range rows {
+		for i, val := range row {
+			if w := cellWidth(val); w > widths[i] {
+				widths[i] = w
+			}
+		}
 	}
 
-	table.Render()
+	// Each column is padded by a space on either side, and each line ends with an extra space.
+	available := maxWidth - 2*len(widths) - 1
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+	for total > available {
+		widest := 0
+		for i, w := range widths {
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= minTableColWidth {
+			// The table can't be shrunk any further, so it will be wrapped by the terminal.
+			break
+		}
+		widths[widest]--
+		total--
+	}
+	return widths
+}
+
+// fitRow truncates or wraps each value in the row to the width of its column.
+func fitRow(row []string, widths []int, wrap bool) []string {
+	fitted := make([]string, len(row))
+	for i, val := range row {
+		var lines []string
+		for _, line := range strings.Split(val, "\n") {
+			if tablewriter.DisplayWidth(line) <= widths[i] {
+				lines = append(lines, line)
+				continue
+			}
+			// Color codes can't be split safely, so they are dropped from values that need to be shortened.
+			line = ansiEscapeRegexp.ReplaceAllLiteralString(line, "")
+			if wrap {
+				lines = append(lines, wrapLine(line, widths[i])...)
+			} else {
+				lines = append(lines, runewidth.Truncate(line, widths[i], truncationSuffix))
+			}
+		}
+		fitted[i] = strings.Join(lines, "\n")
+	}
+	return fitted
+}
+
+func wrapLine(line string, width int) []string {
+	var lines []string
+	for runewidth.StringWidth(line) > width {
+		head := runewidth.Truncate(line, width, "")
+		if head == "" {
+			// A single character is wider than the column.
+			break
+		}
+		lines = append(lines, head)
+		line = line[len(head):]
+	}
+	return append(lines, line)
 }
 
 const tableNameKey = "_tableName_"
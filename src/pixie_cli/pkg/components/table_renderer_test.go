@@ -0,0 +1,87 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package components
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFitColumnWidths(t *testing.T) {
+	header := []string{"id", "name", "value"}
+	rows := [][]string{
+		{"1", "short", strings.Repeat("a", 100)},
+		{"2", strings.Repeat("b", 40), "c"},
+	}
+
+	tests := []struct {
+		name     string
+		maxWidth int
+		expected []int
+	}{
+		{
+			name:     "fits",
+			maxWidth: 200,
+			expected: []int{2, 40, 100},
+		},
+		{
+			name:     "shrinks widest column",
+			maxWidth: 100,
+			expected: []int{2, 40, 51},
+		},
+		{
+			name:     "shrinks multiple columns",
+			maxWidth: 60,
+			expected: []int{2, 25, 26},
+		},
+		{
+			name:     "stops at minimum width",
+			maxWidth: 10,
+			expected: []int{2, minTableColWidth, minTableColWidth},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, fitColumnWidths(header, rows, test.maxWidth))
+		})
+	}
+}
+
+func TestFitRow(t *testing.T) {
+	row := []string{"abc", "abcdefghij", "ab\nabcdefgh"}
+	widths := []int{4, 4, 4}
+
+	assert.Equal(t, []string{"abc", "abc…", "ab\nabc…"}, fitRow(row, widths, false))
+	assert.Equal(t, []string{"abc", "abcd\nefgh\nij", "ab\nabcd\nefgh"}, fitRow(row, widths, true))
+}
+
+func TestTableStreamWriter_Wide(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewWideTableStreamWriter(buf)
+	w.SetHeader("test", []string{"name", "value"})
+	require.NoError(t, w.Write([]interface{}{"a", strings.Repeat("x", 200)}))
+	w.Finish()
+
+	assert.Contains(t, buf.String(), strings.Repeat("x", 200))
+}
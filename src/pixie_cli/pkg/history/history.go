@@ -0,0 +1,164 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The maximum number of entries that are kept in the history. Older entries are dropped first.
+const defaultMaxEntries = 500
+
+// ErrEntryNotFound is returned when the requested entry does not exist in the history.
+var ErrEntryNotFound = errors.New("history entry not found")
+
+// Outcome is the result of a script execution.
+type Outcome string
+
+const (
+	// OutcomeSuccess indicates that the script ran to completion.
+	OutcomeSuccess Outcome = "Success"
+	// OutcomeCanceled indicates that the script was canceled by the user.
+	OutcomeCanceled Outcome = "Canceled"
+	// OutcomeFailed indicates that the script failed to execute.
+	OutcomeFailed Outcome = "Failed"
+)
+
+// Entry is a single recorded invocation of `px run`.
+type Entry struct {
+	ID        int       `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	// ScriptName is the name of the script in the bundle. It is empty if the script was loaded from a file.
+	ScriptName string `json:"scriptName,omitempty"`
+	// ScriptFile is the path to the script if it was loaded from a file.
+	ScriptFile string   `json:"scriptFile,omitempty"`
+	ScriptArgs []string `json:"scriptArgs,omitempty"`
+	// Bundle is the bundle that the script was loaded from, if not the default.
+	Bundle        string `json:"bundle,omitempty"`
+	ClusterID     string `json:"clusterID,omitempty"`
+	AllClusters   bool   `json:"allClusters,omitempty"`
+	OutputFormat  string `json:"outputFormat,omitempty"`
+	E2EEncryption bool   `json:"e2eEncryption"`
+
+	Duration time.Duration `json:"duration"`
+	Outcome  Outcome       `json:"outcome"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Script returns a printable name for the script that was run.
+func (e *Entry) Script() string {
+	if e.ScriptName != "" {
+		return e.ScriptName
+	}
+	return e.ScriptFile
+}
+
+// Store persists the run history in a local file.
+type Store struct {
+	path       string
+	maxEntries int
+}
+
+// NewStore creates a history store backed by the file at the given path.
+func NewStore(path string) *Store {
+	return &Store{
+		path:       path,
+		maxEntries: defaultMaxEntries,
+	}
+}
+
+// List returns all of the entries in the history, ordered from oldest to newest. A history file which is empty or
+// can't be decoded is treated as an empty history, so that it is replaced by the next entry that is added.
+func (s *Store) List() ([]*Entry, error) {
+	b, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return []*Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*Entry, 0)
+	if len(bytes.TrimSpace(b)) == 0 {
+		return entries, nil
+	}
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return []*Entry{}, nil
+	}
+	return entries, nil
+}
+
+// Get returns the entry with the given ID.
+func (s *Store) Get(id int) (*Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return nil, ErrEntryNotFound
+}
+
+// Add assigns the next available ID to the entry and appends it to the history.
+func (s *Store) Add(entry *Entry) error {
+	entries, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	entry.ID = 1
+	if len(entries) > 0 {
+		entry.ID = entries[len(entries)-1].ID + 1
+	}
+	entries = append(entries, entry)
+	if len(entries) > s.maxEntries {
+		entries = entries[len(entries)-s.maxEntries:]
+	}
+
+	return s.write(entries)
+}
+
+// write replaces the history file with the given entries. The entries are written to a temporary file which is then
+// renamed over the history file, so that concurrent or interrupted writes never leave a partially written history.
+func (s *Store) write(entries []*Entry) error {
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = json.NewEncoder(f).Encode(entries)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
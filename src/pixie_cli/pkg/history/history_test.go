@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package history_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/history"
+)
+
+func TestStore_AddAndGet(t *testing.T) {
+	s := history.NewStore(filepath.Join(t.TempDir(), "history.json"))
+
+	entries, err := s.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	ts := time.Date(2022, time.March, 7, 15, 0, 0, 0, time.UTC)
+	require.NoError(t, s.Add(&history.Entry{
+		Timestamp:  ts,
+		ScriptName: "px/namespace",
+		ScriptArgs: []string{"--namespace", "default"},
+		ClusterID:  "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		Duration:   time.Second,
+		Outcome:    history.OutcomeSuccess,
+	}))
+	require.NoError(t, s.Add(&history.Entry{
+		Timestamp:  ts.Add(time.Minute),
+		ScriptFile: "./test.pxl",
+		Outcome:    history.OutcomeFailed,
+		Error:      "compilation failed",
+	}))
+
+	entries, err = s.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, 1, entries[0].ID)
+	assert.Equal(t, 2, entries[1].ID)
+
+	e, err := s.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, "px/namespace", e.Script())
+	assert.Equal(t, []string{"--namespace", "default"}, e.ScriptArgs)
+	assert.Equal(t, ts, e.Timestamp)
+	assert.Equal(t, time.Second, e.Duration)
+
+	e, err = s.Get(2)
+	require.NoError(t, err)
+	assert.Equal(t, "./test.pxl", e.Script())
+	assert.Equal(t, history.OutcomeFailed, e.Outcome)
+
+	_, err = s.Get(3)
+	assert.Equal(t, history.ErrEntryNotFound, err)
+}
+
+func TestStore_CorruptHistory(t *testing.T) {
+	for _, contents := range []string{"", "[{\"id\": 1, \"timest"} {
+		path := filepath.Join(t.TempDir(), "history.json")
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+		s := history.NewStore(path)
+
+		entries, err := s.List()
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+
+		// The unreadable history is replaced by the new entry.
+		require.NoError(t, s.Add(&history.Entry{ScriptName: "px/namespace", Outcome: history.OutcomeSuccess}))
+		entries, err = s.List()
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, 1, entries[0].ID)
+
+		// Only the history file is left behind.
+		files, err := os.ReadDir(filepath.Dir(path))
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		assert.Equal(t, "history.json", files[0].Name())
+	}
+}
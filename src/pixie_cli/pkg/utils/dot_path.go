@@ -24,9 +24,10 @@ import (
 )
 
 const (
-	pixieDotPath    = ".pixie"
-	pixieConfigFile = "config.json"
-	pixieAuthFile   = "auth.json"
+	pixieDotPath     = ".pixie"
+	pixieConfigFile  = "config.json"
+	pixieAuthFile    = "auth.json"
+	pixieHistoryFile = "history.json"
 )
 
 // ensureDotFolderPath returns and creates the dot folder for cli config/auth.
@@ -68,3 +69,14 @@ func EnsureDefaultAuthFilePath() (string, error) {
 	pixieAuthFilePath := filepath.Join(pixieDirPath, pixieAuthFile)
 	return pixieAuthFilePath, nil
 }
+
+// EnsureDefaultHistoryFilePath returns the file path for the run history file.
+func EnsureDefaultHistoryFilePath() (string, error) {
+	pixieDirPath, err := ensureDotFolderPath()
+	if err != nil {
+		return "", err
+	}
+
+	pixieHistoryFilePath := filepath.Join(pixieDirPath, pixieHistoryFile)
+	return pixieHistoryFilePath, nil
+}
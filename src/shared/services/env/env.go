@@ -25,21 +25,24 @@ import (
 // Env is the interface that all sub-environments should implement.
 type Env interface {
 	JWTSigningKey() string
+	PreviousJWTSigningKey() string
 	Audience() string
 }
 
 // BaseEnv is the struct containing server state that is valid across multiple sessions
 // for example, database connections and config information.
 type BaseEnv struct {
-	jwtSigningKey string
-	audience      string
+	jwtSigningKey         string
+	previousJWTSigningKey string
+	audience              string
 }
 
 // New creates a new base environment use by all our services.
 func New(audience string) *BaseEnv {
 	return &BaseEnv{
-		jwtSigningKey: viper.GetString("jwt_signing_key"),
-		audience:      audience,
+		jwtSigningKey:         viper.GetString("jwt_signing_key"),
+		previousJWTSigningKey: viper.GetString("previous_jwt_signing_key"),
+		audience:              audience,
 	}
 }
 
@@ -48,6 +51,12 @@ func (e *BaseEnv) JWTSigningKey() string {
 	return e.jwtSigningKey
 }
 
+// PreviousJWTSigningKey returns the JWT key which was in use before the key was last rotated, if any. Tokens signed
+// with this key are still accepted.
+func (e *BaseEnv) PreviousJWTSigningKey() string {
+	return e.previousJWTSigningKey
+}
+
 // Audience returns the audience that should be associated with any JWT keys.
 func (e *BaseEnv) Audience() string {
 	return e.audience
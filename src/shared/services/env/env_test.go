@@ -29,8 +29,10 @@ import (
 
 func TestNew(t *testing.T) {
 	viper.Set("jwt_signing_key", "the-jwt-key")
+	viper.Set("previous_jwt_signing_key", "the-previous-jwt-key")
 
 	env := env.New("audience")
 	assert.Equal(t, "the-jwt-key", env.JWTSigningKey())
+	assert.Equal(t, "the-previous-jwt-key", env.PreviousJWTSigningKey())
 	assert.Equal(t, "audience", env.Audience())
 }
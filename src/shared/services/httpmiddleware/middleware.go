@@ -88,6 +88,10 @@ func WithBearerAuthMiddleware(env env.Env, next http.Handler) http.Handler {
 
 		aCtx := authcontext.New()
 		err := aCtx.UseJWTAuth(env.JWTSigningKey(), token, env.Audience())
+		// Tokens signed before the JWT signing key was rotated remain valid.
+		if err != nil && env.PreviousJWTSigningKey() != "" {
+			err = aCtx.UseJWTAuth(env.PreviousJWTSigningKey(), token, env.Audience())
+		}
 		if err != nil {
 			http.Error(w, "Failed to parse token", http.StatusUnauthorized)
 			return
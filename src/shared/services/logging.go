@@ -24,6 +24,7 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"github.com/zenazn/goji/web/mutil"
 
 	version "px.dev/pixie/src/shared/goversion"
@@ -35,11 +36,24 @@ func init() {
 	}
 }
 
-// SetupServiceLogging sets up a consistent logging env for all services.
+// SetupServiceLogging sets up a consistent logging env for all services. The log level and format may be set
+// with the PL_LOG_LEVEL and PL_LOG_FORMAT environment variables, which must be read after the flags are parsed.
 func SetupServiceLogging() {
 	// Setup logging.
 	log.SetOutput(os.Stdout)
 	log.SetLevel(log.InfoLevel)
+
+	if level := viper.GetString("log_level"); level != "" {
+		parsed, err := log.ParseLevel(level)
+		if err != nil {
+			log.WithError(err).Warn("Invalid log level, using info")
+		} else {
+			log.SetLevel(parsed)
+		}
+	}
+	if viper.GetString("log_format") == "json" {
+		log.SetFormatter(&log.JSONFormatter{})
+	}
 }
 
 // HTTPLoggingMiddleware is a middleware function used for logging HTTP requests.
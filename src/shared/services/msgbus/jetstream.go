@@ -0,0 +1,174 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package msgbus
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// jetStreamMessage implements msgbus.Msg interface for JetStream messages.
+type jetStreamMessage struct {
+	m *nats.Msg
+}
+
+func (m *jetStreamMessage) Data() []byte {
+	return m.m.Data
+}
+func (m *jetStreamMessage) Ack() error {
+	return m.m.Ack()
+}
+
+func wrapJetStreamMsgHandler(cb MsgHandler) nats.MsgHandler {
+	return func(m *nats.Msg) {
+		cb(&jetStreamMessage{m: m})
+	}
+}
+
+// persistentJetStreamSub implements msgbus.PersistentSub for JetStream durable consumers.
+type persistentJetStreamSub struct {
+	js      nats.JetStreamContext
+	stream  string
+	durable string
+	sub     *nats.Subscription
+}
+
+func (u *persistentJetStreamSub) Close() error {
+	// The consumer is created before subscribing and bound to, so unsubscribing keeps the durable consumer.
+	return u.sub.Unsubscribe()
+}
+
+func (u *persistentJetStreamSub) Destroy() error {
+	err := u.sub.Unsubscribe()
+	if err != nil {
+		return err
+	}
+	return u.js.DeleteConsumer(u.stream, u.durable)
+}
+
+// jetStreamStreamer implements the msgbus.Streamer interface.
+type jetStreamStreamer struct {
+	js          nats.JetStreamContext
+	stream      string
+	ackWait     time.Duration
+	maxInflight int
+}
+
+// durableNameReplacer replaces the characters which are not allowed in the name of a durable consumer.
+var durableNameReplacer = strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_")
+
+// jetStreamDurableName returns the name of the durable consumer for the subscription. Durable consumers are named
+// per stream rather than per subject, so the subject is part of the name to track each subject's position separately.
+func jetStreamDurableName(subject, persistentName string) string {
+	return durableNameReplacer.Replace(persistentName + "_" + subject)
+}
+
+func (s *jetStreamStreamer) PersistentSubscribe(subject, persistentName string, cb MsgHandler) (PersistentSub, error) {
+	durable := jetStreamDurableName(subject, persistentName)
+	_, err := s.js.ConsumerInfo(s.stream, durable)
+	if errors.Is(err, nats.ErrConsumerNotFound) {
+		_, err = s.js.AddConsumer(s.stream, &nats.ConsumerConfig{
+			Durable:        durable,
+			DeliverSubject: nats.NewInbox(),
+			// Parallel subscribers with the same subject + persistentName pair share the consumer as a queue group.
+			DeliverGroup:  durable,
+			DeliverPolicy: nats.DeliverAllPolicy,
+			AckPolicy:     nats.AckExplicitPolicy,
+			AckWait:       s.ackWait,
+			MaxAckPending: s.maxInflight,
+			FilterSubject: subject,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := s.js.QueueSubscribe(subject, durable, wrapJetStreamMsgHandler(cb), nats.Bind(s.stream, durable), nats.ManualAck())
+	if err != nil {
+		return nil, err
+	}
+
+	return &persistentJetStreamSub{js: s.js, stream: s.stream, durable: durable, sub: sub}, nil
+}
+
+func (s *jetStreamStreamer) Publish(subject string, data []byte) error {
+	_, err := s.js.Publish(subject, data)
+	return err
+}
+
+func (s *jetStreamStreamer) PeekLatestMessage(subject string) (Msg, error) {
+	sub, err := s.js.SubscribeSync(subject, nats.BindStream(s.stream), nats.DeliverLast(), nats.AckNone())
+	if err != nil {
+		return nil, err
+	}
+
+	defer sub.Unsubscribe()
+
+	m, err := sub.NextMsg(emptyQueueTimeout)
+	if errors.Is(err, nats.ErrTimeout) {
+		// This means the queue is considered empty, and we return no error but no element.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &jetStreamMessage{m: m}, nil
+}
+
+// JetStreamStreamerConfig contains options that can be set for a JetStream Streamer.
+type JetStreamStreamerConfig struct {
+	// Stream is the name of the JetStream stream which stores the subjects that are published and subscribed to.
+	Stream string
+	// AckWait is the duration to wait before Ack() is considered failed and JetStream knows to resend the value.
+	AckWait time.Duration
+	// MaxInflight is the number of unacked messages which are delivered to a subscription at a time.
+	MaxInflight int
+}
+
+// DefaultJetStreamStreamerConfig are the default settings for the JetStream streamer, apart from the stream
+// which must be specified.
+var DefaultJetStreamStreamerConfig = JetStreamStreamerConfig{
+	AckWait:     30 * time.Second,
+	MaxInflight: 50,
+}
+
+// NewJetStreamStreamerWithConfig creates a new Streamer implemented using JetStream with specific configuration.
+// The stream is expected to already exist.
+func NewJetStreamStreamerWithConfig(nc *nats.Conn, cfg JetStreamStreamerConfig) (Streamer, error) {
+	if cfg.Stream == "" {
+		return nil, errors.New("a JetStream stream must be specified")
+	}
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, err
+	}
+	_, err = js.StreamInfo(cfg.Stream)
+	if err != nil {
+		return nil, err
+	}
+	return &jetStreamStreamer{
+		js:          js,
+		stream:      cfg.Stream,
+		ackWait:     cfg.AckWait,
+		maxInflight: cfg.MaxInflight,
+	}, nil
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package msgbus_test
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/shared/services/msgbus"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+func mustStartTestJetStreamStreamer(t *testing.T) (msgbus.Streamer, func()) {
+	nc, cleanup := testingutils.MustStartTestNATSWithJetStream(t)
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name:     "test",
+		Subjects: []string{"abc", "def"},
+	})
+	require.NoError(t, err)
+
+	cfg := msgbus.DefaultJetStreamStreamerConfig
+	cfg.Stream = "test"
+	s, err := msgbus.NewJetStreamStreamerWithConfig(nc, cfg)
+	require.NoError(t, err)
+	return s, cleanup
+}
+
+func TestNewJetStreamStreamer_MissingStream(t *testing.T) {
+	nc, cleanup := testingutils.MustStartTestNATSWithJetStream(t)
+	defer cleanup()
+
+	_, err := msgbus.NewJetStreamStreamerWithConfig(nc, msgbus.DefaultJetStreamStreamerConfig)
+	assert.Error(t, err)
+
+	cfg := msgbus.DefaultJetStreamStreamerConfig
+	cfg.Stream = "missing"
+	_, err = msgbus.NewJetStreamStreamerWithConfig(nc, cfg)
+	assert.Error(t, err)
+}
+
+func TestJetStreamPersistentSubscribeInterface(t *testing.T) {
+	s, cleanup := mustStartTestJetStreamStreamer(t)
+	defer cleanup()
+
+	sub := "abc"
+	data := [][]byte{[]byte("123"), []byte("abc"), []byte("asdf")}
+
+	// Publish data to the subject.
+	for _, d := range data {
+		require.NoError(t, s.Publish(sub, d))
+	}
+
+	ch1 := make(chan msgbus.Msg)
+	pSub, err := s.PersistentSubscribe(sub, "indexer", func(m msgbus.Msg) {
+		ch1 <- m
+		require.NoError(t, m.Ack())
+	})
+	require.NoError(t, err)
+
+	// Should receive all messages that were published.
+	require.NoError(t, receiveExpectedUpdates(ch1, data))
+	require.NoError(t, pSub.Close())
+
+	// Make sure when we recreate the subscription, we don't receive new messages (all old ack messages should be ignored).
+	ch2 := make(chan msgbus.Msg)
+	pSub, err = s.PersistentSubscribe(sub, "indexer", func(m msgbus.Msg) {
+		ch2 <- m
+		require.NoError(t, m.Ack())
+	})
+	require.NoError(t, err)
+
+	// Should receive no messages.
+	require.NoError(t, receiveExpectedUpdates(ch2, [][]byte{}))
+	require.NoError(t, pSub.Close())
+
+	// The same persistent name on a different subject should not receive the updates of the first subject.
+	ch3 := make(chan msgbus.Msg)
+	pSub, err = s.PersistentSubscribe("def", "indexer", func(m msgbus.Msg) {
+		ch3 <- m
+		require.NoError(t, m.Ack())
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, receiveExpectedUpdates(ch3, [][]byte{}))
+	require.NoError(t, pSub.Close())
+
+	// New durable subscribe with a different name should receive all of the old updates.
+	ch4 := make(chan msgbus.Msg)
+	pSub, err = s.PersistentSubscribe(sub, "new_indexer", func(m msgbus.Msg) {
+		ch4 <- m
+		require.NoError(t, m.Ack())
+	})
+	require.NoError(t, err)
+
+	// Should receive all messages on this channel.
+	require.NoError(t, receiveExpectedUpdates(ch4, data))
+	require.NoError(t, pSub.Close())
+}
+
+func TestJetStreamPersistentSubscribeDestroy(t *testing.T) {
+	s, cleanup := mustStartTestJetStreamStreamer(t)
+	defer cleanup()
+
+	sub := "abc"
+	data := [][]byte{[]byte("123"), []byte("abc"), []byte("asdf")}
+
+	for _, d := range data {
+		require.NoError(t, s.Publish(sub, d))
+	}
+
+	ch1 := make(chan msgbus.Msg)
+	pSub, err := s.PersistentSubscribe(sub, "indexer", func(m msgbus.Msg) {
+		ch1 <- m
+		require.NoError(t, m.Ack())
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, receiveExpectedUpdates(ch1, data))
+	require.NoError(t, pSub.Destroy())
+
+	// Recreating a destroyed subscription should receive all of the old updates again.
+	ch2 := make(chan msgbus.Msg)
+	pSub, err = s.PersistentSubscribe(sub, "indexer", func(m msgbus.Msg) {
+		ch2 <- m
+		require.NoError(t, m.Ack())
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, receiveExpectedUpdates(ch2, data))
+	require.NoError(t, pSub.Close())
+}
+
+func TestJetStreamPeekLatestMessage(t *testing.T) {
+	s, cleanup := mustStartTestJetStreamStreamer(t)
+	defer cleanup()
+
+	sub := "abc"
+	m, err := s.PeekLatestMessage(sub)
+	require.NoError(t, err)
+	// Expect bottom of queue to be nil because no elements found.
+	require.Nil(t, m)
+
+	data := [][]byte{[]byte("123"), []byte("abc"), []byte("asdf")}
+	for _, d := range data {
+		require.NoError(t, s.Publish(sub, d))
+	}
+
+	m, err = s.PeekLatestMessage(sub)
+	require.NoError(t, err)
+	// Expect bottom of queue to be the last element we pushed.
+	assert.Equal(t, data[2], m.Data())
+}
@@ -27,6 +27,7 @@ import (
 
 func init() {
 	pflag.String("nats_url", "pl-nats", "The url of the nats message bus")
+	pflag.String("nats_creds", "", "The path to the user credentials file used to authenticate to the nats message bus")
 }
 
 // MustConnectNATS attempts to connect to the NATS message bus.
@@ -34,12 +35,17 @@ func MustConnectNATS() *nats.Conn {
 	var nc *nats.Conn
 	var err error
 	natsURL := viper.GetString("nats_url")
+	var opts []nats.Option
+	if creds := viper.GetString("nats_creds"); creds != "" {
+		opts = append(opts, nats.UserCredentials(creds))
+	}
 	if viper.GetBool("disable_ssl") {
-		nc, err = nats.Connect(natsURL)
+		nc, err = nats.Connect(natsURL, opts...)
 	} else {
-		nc, err = nats.Connect(natsURL,
+		opts = append(opts,
 			nats.ClientCert(viper.GetString("client_tls_cert"), viper.GetString("client_tls_key")),
 			nats.RootCAs(viper.GetString("tls_ca_cert")))
+		nc, err = nats.Connect(natsURL, opts...)
 	}
 
 	if err != nil && !viper.GetBool("disable_ssl") {
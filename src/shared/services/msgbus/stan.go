@@ -62,6 +62,10 @@ func (u *persistentSTANSub) Close() error {
 	return u.sub.Close()
 }
 
+func (u *persistentSTANSub) Destroy() error {
+	return u.sub.Unsubscribe()
+}
+
 // stanMessage implements msgbus.Msg interface for STAN messages.
 type stanMessage struct {
 	sm *stan.Msg
@@ -160,6 +160,42 @@ func TestSTANPersistentSubscribeInterface(t *testing.T) {
 	require.NoError(t, pSub.Close())
 }
 
+func TestSTANPersistentSubscribeDestroy(t *testing.T) {
+	_, sc, cleanup := testingutils.MustStartTestStan(t, "stan", "test-client")
+	defer cleanup()
+	s, err := msgbus.NewSTANStreamer(sc)
+	require.NoError(t, err)
+
+	sub := "abc"
+	data := [][]byte{[]byte("123"), []byte("abc"), []byte("asdf")}
+
+	// Publish data to the subject.
+	for _, d := range data {
+		require.NoError(t, s.Publish(sub, d))
+	}
+
+	ch1 := make(chan msgbus.Msg)
+	pSub, err := s.PersistentSubscribe(sub, "indexer", func(m msgbus.Msg) {
+		ch1 <- m
+		require.NoError(t, m.Ack())
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, receiveExpectedUpdates(ch1, data))
+	require.NoError(t, pSub.Destroy())
+
+	// Recreating a destroyed subscription should receive all of the old updates again.
+	ch2 := make(chan msgbus.Msg)
+	pSub, err = s.PersistentSubscribe(sub, "indexer", func(m msgbus.Msg) {
+		ch2 <- m
+		require.NoError(t, m.Ack())
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, receiveExpectedUpdates(ch2, data))
+	require.NoError(t, pSub.Close())
+}
+
 func TestSTANPublishAfterSubscribe(t *testing.T) {
 	_, sc, cleanup := testingutils.MustStartTestStan(t, "stan", "test-client")
 	defer cleanup()
@@ -34,6 +34,8 @@ type PersistentSub interface {
 	// Close the subscription, but allow future PersistentSubs to read from the sub starting after
 	// the last acked message.
 	Close() error
+	// Destroy the subscription, so that future PersistentSubs with the same name read from the start of the sub.
+	Destroy() error
 }
 
 // Streamer is an interface for any streaming handler.
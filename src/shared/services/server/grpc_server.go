@@ -97,6 +97,10 @@ func createGRPCAuthFunc(env env.Env, opts *GRPCServerOptions) func(context.Conte
 		}
 
 		err = sCtx.UseJWTAuth(env.JWTSigningKey(), token, env.Audience())
+		// Tokens signed before the JWT signing key was rotated remain valid.
+		if err != nil && env.PreviousJWTSigningKey() != "" {
+			err = sCtx.UseJWTAuth(env.PreviousJWTSigningKey(), token, env.Audience())
+		}
 		if err != nil {
 			return nil, status.Errorf(codes.Unauthenticated, "invalid auth token: %v", err)
 		}
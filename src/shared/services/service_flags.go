@@ -119,6 +119,26 @@ func SetupSSLClientFlags() {
 	commonSetup.Do(setupCommonFlags)
 	pflag.String("client_tls_key", "../certs/client.key", "The TLS key to use.")
 	pflag.String("client_tls_cert", "../certs/client.crt", "The TLS certificate to use.")
+	pflag.String("nats_tls_ca_cert", "", "The CA cert to verify NATS with. Defaults to the tls_ca_cert.")
+	pflag.String("nats_tls_cert", "", "The TLS certificate to use for NATS. Defaults to the client_tls_cert.")
+	pflag.String("nats_tls_key", "", "The TLS key to use for NATS. Defaults to the client_tls_key.")
+}
+
+// GetNATSClientCert returns the TLS certificate and key to use when connecting to NATS. These are the client
+// TLS certificate and key, unless NATS specific ones are specified, such as for an externally managed NATS.
+func GetNATSClientCert() (string, string) {
+	if viper.GetString("nats_tls_cert") != "" && viper.GetString("nats_tls_key") != "" {
+		return viper.GetString("nats_tls_cert"), viper.GetString("nats_tls_key")
+	}
+	return viper.GetString("client_tls_cert"), viper.GetString("client_tls_key")
+}
+
+// GetNATSCACert returns the CA cert to use to verify NATS.
+func GetNATSCACert() string {
+	if viper.GetString("nats_tls_ca_cert") != "" {
+		return viper.GetString("nats_tls_ca_cert")
+	}
+	return viper.GetString("tls_ca_cert")
 }
 
 // CheckSSLClientFlags checks SSL client specific flags.
@@ -33,11 +33,19 @@ var reasonToMessageMap = map[VizierReason]string{
 	CloudConnectorPodFailed:        "Cloud connector pod failed to start. If this status persists, investigate failures on the vizier-cloud-connector pod using `kubectl describe` and `kubectl logs`.",
 	CloudConnectorMissing: "Cloud connector pod not found. Something is preventing the vizier-operator from deploying Pixie. " +
 		"If this status persists, clobber and re-deploy your Pixie instance.",
+	CloudConnectorHeartbeatFailed: "Cloud connector has not sent a heartbeat to Pixie Cloud in over a minute, so the Vizier will not show up as connected in the UI. " +
+		"Check the vizier-cloud-connector logs, and that the cloud address is reachable within your firewall and network configurations.",
 	MetadataPVCMissing: "The PVC requested by Pixie cannot be found. The vizier-metadata service is unable to start without the PVC. " +
 		"If this status persists, ensure that PersistentVolumes may be mounted in your cluster and clobber and re-deploy your Pixie instance.",
 	MetadataPVCStorageClassUnavailable: "The PVC requested by Pixie cannot be created successfully: cluster lacks PersistentVolumes or dynamic storage provisioning. " +
 		"See https://kubernetes.io/docs/concepts/storage/persistent-volumes/#lifecycle-of-a-volume-and-claim for info on setting up this feature on the cluster.",
 	MetadataPVCPendingBinding: "The PVC requested by Pixie is still Pending. If stuck in this status, investigate the status of PVC in the Vizier namespace (default `pl`) using `kubectl describe`.",
+	MetadataPVCNearlyFull: "The PVC used by the vizier-metadata service is more than 90% full. Once it is full, the metadata service fails and queries break. " +
+		"Increase the size of the PVC with the `metadataStorageSize` field of the Vizier spec if the storage class allows volume expansion.",
+	EtcdMembersUnhealthy: "Some of the members of the etcd cluster storing the Vizier metadata are unhealthy. Investigate the `pl-etcd` pods in the Vizier namespace (default `pl`) " +
+		"before more members fail and the cluster loses quorum.",
+	EtcdQuorumLost: "The etcd cluster storing the Vizier metadata has lost quorum, so the metadata service cannot read or write metadata. " +
+		"Investigate the `pl-etcd` pods in the Vizier namespace (default `pl`).",
 	ControlPlaneFailedToScheduleBecauseOfTaints: "The Vizier control plane could not be scheduled because taints exist on " +
 		"every node. Consider removing taints from some nodes or manually adding tolerations to each deployment in Vizier using the `patches` or `nodeSelector` flags.",
 	ControlPlaneFailedToSchedule: "Vizier control plane pods failed to schedule. Investigate the failures of non-Ready pods in the Vizier namespace (default `pl`) using `kubectl describe`. Refer to https://docs.px.dev/troubleshooting/ for troubleshooting recommendations.",
@@ -46,6 +54,11 @@ var reasonToMessageMap = map[VizierReason]string{
 	NATSPodPending:               "NATS message bus pods are still pending. If this status persists, investigate failures on the Pending NATS pods in the Vizier namespace (default `pl`).",
 	NATSPodMissing:               "NATS message bus pods are missing. If this status persists, clobber and redeploy this Pixie instance.",
 	NATSPodFailed:                "NATS message bus pods have failed. Investigate failures on the Pending NATS pods in the Vizier namespace (default `pl`).",
+	NATSConnectivityFailed:       "Unable to round-trip a message through the NATS message bus. Check the NATS logs for cluster or network partitions, and that NATS accepts the Vizier's certs and credentials.",
+	ExternalNATSUnreachable: "None of the servers of the external NATS message bus accept connections. Check that the URL in the Vizier's externalNATS spec is correct " +
+		"and reachable from the Vizier namespace.",
+	ExternalNATSSecretInvalid: "A secret referenced by the Vizier's externalNATS spec is missing, or does not contain the expected files. The TLS secret must contain " +
+		"ca.crt, client.crt and client.key, and the credentials secret must contain nats.creds.",
 	PEMsSomeInsufficientMemory: "Some PEMs are failing to schedule due to insufficient memory available on the nodes. You will not be able to receive data from those failing nodes. " +
 		"Free up memory on those nodes to start scraping Pixie data from those nodes.",
 	PEMsAllInsufficientMemory: "None of the PEMs can schedule due to insufficient memory available on the nodes. " +
@@ -89,6 +102,8 @@ const (
 	CloudConnectorMissing VizierReason = "CloudConnectorMissing"
 	// CloudConnectorRegistering occurs when the cloud connector is still registering with Pixie Cloud.
 	CloudConnectorRegistering VizierReason = "CloudConnectorRegistering"
+	// CloudConnectorHeartbeatFailed occurs when a registered cloud connector has stopped sending heartbeats to Pixie Cloud.
+	CloudConnectorHeartbeatFailed VizierReason = "CloudConnectorHeartbeatFailed"
 
 	// MetadataPVCMissing occurs when the operator cannot find the metadata PVC.
 	MetadataPVCMissing VizierReason = "MetadataPVCMissing"
@@ -96,6 +111,12 @@ const (
 	MetadataPVCStorageClassUnavailable VizierReason = "MetadataPVCStorageClassUnavailable"
 	// MetadataPVCPendingBinding occurs when the Metadata PVC is still pending, but the spec is requesting a valid Storage class.
 	MetadataPVCPendingBinding VizierReason = "MetadataPVCPendingBinding"
+	// MetadataPVCNearlyFull occurs when the metadata PVC is bound, but nearly out of space.
+	MetadataPVCNearlyFull VizierReason = "MetadataPVCNearlyFull"
+	// EtcdMembersUnhealthy occurs when some members of the metadata etcd cluster are unhealthy, but it still has quorum.
+	EtcdMembersUnhealthy VizierReason = "EtcdMembersUnhealthy"
+	// EtcdQuorumLost occurs when too few members of the metadata etcd cluster are healthy for it to have quorum.
+	EtcdQuorumLost VizierReason = "EtcdQuorumLost"
 
 	// ControlPlanePodsPending occurs when one or more control plane pods are pending.
 	ControlPlanePodsPending VizierReason = "ControlPlanePodsPending"
@@ -112,6 +133,12 @@ const (
 	NATSPodMissing VizierReason = "NATSPodMissing"
 	// NATSPodFailed occurs when the nats pod failed to start up.
 	NATSPodFailed VizierReason = "NATSPodFailed"
+	// NATSConnectivityFailed occurs when a message cannot be round-tripped through NATS, even if its pods are running.
+	NATSConnectivityFailed VizierReason = "NATSConnectivityFailed"
+	// ExternalNATSUnreachable occurs when none of the servers of an external NATS cluster accept connections.
+	ExternalNATSUnreachable VizierReason = "ExternalNATSUnreachable"
+	// ExternalNATSSecretInvalid occurs when a secret used to connect to an external NATS cluster is missing or incomplete.
+	ExternalNATSSecretInvalid VizierReason = "ExternalNATSSecretInvalid"
 
 	// PEMsSomeInsufficientMemory occurs when some PEMs (strictly not all) fail to schedule due to insufficient memory. If all PEMs experience
 	// insufficient memory, then the Reason should be PEMsAllInsufficientMemory.
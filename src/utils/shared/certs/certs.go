@@ -115,7 +115,8 @@ func (cg *certGenerator) signCertAndKey(cert *x509.Certificate, privateKey *rsa.
 	return certData, keyData, nil
 }
 
-func getVizierDNSNamesForNamespace(namespace string) []string {
+// GetVizierDNSNamesForNamespace returns the DNS names which the Vizier service certs must be valid for.
+func GetVizierDNSNamesForNamespace(namespace string) []string {
 	// Localhost must be here because etcd relies on it.
 	return []string{
 		fmt.Sprintf("*.%s.svc", namespace),
@@ -187,11 +188,11 @@ func GenerateVizierCertYAMLs(namespace string) (string, error) {
 		return "", err
 	}
 
-	clientCert, clientKey, err := cg.generateSignedCertAndKey(getVizierDNSNamesForNamespace(namespace))
+	clientCert, clientKey, err := cg.generateSignedCertAndKey(GetVizierDNSNamesForNamespace(namespace))
 	if err != nil {
 		return "", err
 	}
-	serverCert, serverKey, err := cg.generateSignedCertAndKey(getVizierDNSNamesForNamespace(namespace))
+	serverCert, serverKey, err := cg.generateSignedCertAndKey(GetVizierDNSNamesForNamespace(namespace))
 	if err != nil {
 		return "", err
 	}
@@ -200,6 +201,11 @@ func GenerateVizierCertYAMLs(namespace string) (string, error) {
 		return "", err
 	}
 
+	return VizierCertYAMLs(namespace, caCert, serverCert, serverKey, clientCert, clientKey)
+}
+
+// VizierCertYAMLs generates the yamls for the vizier cert secrets, containing the given PEM-encoded certs and keys.
+func VizierCertYAMLs(namespace string, caCert, serverCert, serverKey, clientCert, clientKey []byte) (string, error) {
 	var yamls []string
 
 	proxyCert, err := k8s.CreateGenericSecretFromLiterals(namespace, "proxy-tls-certs", map[string]string{
@@ -31,6 +31,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -197,6 +198,12 @@ func ApplyResources(clientset kubernetes.Interface, config *rest.Config, resourc
 	}
 	rm := restmapper.NewDiscoveryRESTMapper(apiGroupResources)
 
+	return ApplyResourcesWithMapper(config, rm, resources, namespace, allowedResources, allowUpdate)
+}
+
+// ApplyResourcesWithMapper applies the resources like ApplyResources, but maps them to API resources with the given
+// mapper instead of discovering the API resources on each call. It is safe to call concurrently.
+func ApplyResourcesWithMapper(config *rest.Config, rm meta.RESTMapper, resources []*Resource, namespace string, allowedResources []string, allowUpdate bool) error {
 	for _, resource := range resources {
 		mapping, err := rm.RESTMapping(resource.GVK.GroupKind(), resource.GVK.Version)
 		if err != nil {
@@ -216,7 +223,7 @@ func ApplyResources(clientset kubernetes.Interface, config *rest.Config, resourc
 			}
 		}
 
-		restconfig := config
+		restconfig := rest.CopyConfig(config)
 		restconfig.GroupVersion = &schema.GroupVersion{
 			Group:   mapping.GroupVersionKind.Group,
 			Version: mapping.GroupVersionKind.Version,
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// How often the resource is fetched when waiting for a condition.
+const waitForConditionPollInterval = 2 * time.Second
+
+// ErrConditionTimeout is returned when the condition is not met before the timeout elapses or the context is canceled.
+var ErrConditionTimeout = errors.New("timed out waiting for condition")
+
+// Condition is a JSONPath based condition on a K8s resource, similar to `kubectl wait --for=jsonpath=...`.
+type Condition struct {
+	// JSONPath is the expression to evaluate against the resource, for example: {.status.phase}.
+	// The surrounding braces are optional.
+	JSONPath string
+	// Value is the value that the expression should evaluate to. If empty, the condition is met
+	// once the expression evaluates to any non-empty value.
+	Value string
+}
+
+func (c *Condition) String() string {
+	if c.Value == "" {
+		return c.JSONPath
+	}
+	return fmt.Sprintf("%s=%s", c.JSONPath, c.Value)
+}
+
+// WaitForCondition waits until the named resource satisfies the given condition. Resources which do not
+// exist yet are treated as not satisfying the condition. It returns an error if the timeout elapses or
+// the context is canceled before the condition is met.
+func WaitForCondition(ctx context.Context, client dynamic.Interface, gvr schema.GroupVersionResource, namespace, name string, cond *Condition, timeout time.Duration) error {
+	expr := cond.JSONPath
+	if !strings.HasPrefix(expr, "{") {
+		expr = fmt.Sprintf("{%s}", expr)
+	}
+	j := jsonpath.New("condition").AllowMissingKeys(true)
+	if err := j.Parse(expr); err != nil {
+		return fmt.Errorf("invalid JSONPath expression %s: %w", cond.JSONPath, err)
+	}
+
+	err := wait.PollImmediateWithContext(ctx, waitForConditionPollInterval, timeout, func(ctx context.Context) (bool, error) {
+		obj, err := client.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		buf := &bytes.Buffer{}
+		if err := j.Execute(buf, obj.UnstructuredContent()); err != nil {
+			return false, err
+		}
+		if cond.Value == "" {
+			return buf.Len() > 0, nil
+		}
+		return buf.String() == cond.Value, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("%w: %s %s/%s did not satisfy %s", ErrConditionTimeout, gvr.Resource, namespace, name, cond.String())
+	}
+	return err
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func TestWaitForCondition(t *testing.T) {
+	podGVR := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	secretGVR := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+
+	objs := []runtime.Object{
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "vizier-pem-abcd", Namespace: "pl"},
+			Status:     v1.PodStatus{Phase: v1.PodRunning},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pl-cluster-secrets", Namespace: "pl"},
+			Data:       map[string][]byte{"cluster-id": []byte("abcd")},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		gvr         schema.GroupVersionResource
+		objName     string
+		cond        *k8s.Condition
+		expectError bool
+		timeout     bool
+	}{
+		{
+			name:    "matching value",
+			gvr:     podGVR,
+			objName: "vizier-pem-abcd",
+			cond:    &k8s.Condition{JSONPath: "{.status.phase}", Value: "Running"},
+		},
+		{
+			name:    "relaxed expression",
+			gvr:     podGVR,
+			objName: "vizier-pem-abcd",
+			cond:    &k8s.Condition{JSONPath: ".status.phase", Value: "Running"},
+		},
+		{
+			name:    "key exists",
+			gvr:     secretGVR,
+			objName: "pl-cluster-secrets",
+			cond:    &k8s.Condition{JSONPath: "{.data['cluster-id']}"},
+		},
+		{
+			name:        "mismatched value",
+			gvr:         podGVR,
+			objName:     "vizier-pem-abcd",
+			cond:        &k8s.Condition{JSONPath: "{.status.phase}", Value: "Failed"},
+			expectError: true,
+			timeout:     true,
+		},
+		{
+			name:        "missing key",
+			gvr:         secretGVR,
+			objName:     "pl-cluster-secrets",
+			cond:        &k8s.Condition{JSONPath: "{.data['cluster-name']}"},
+			expectError: true,
+			timeout:     true,
+		},
+		{
+			name:        "missing resource",
+			gvr:         secretGVR,
+			objName:     "pl-other-secrets",
+			cond:        &k8s.Condition{JSONPath: "{.data['cluster-id']}"},
+			expectError: true,
+			timeout:     true,
+		},
+		{
+			name:        "invalid expression",
+			gvr:         podGVR,
+			objName:     "vizier-pem-abcd",
+			cond:        &k8s.Condition{JSONPath: "{.status[}"},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleDynamicClient(scheme.Scheme, objs...)
+			err := k8s.WaitForCondition(context.Background(), client, test.gvr, "pl", test.objName, test.cond, 100*time.Millisecond)
+			if test.expectError {
+				assert.Error(t, err)
+				assert.Equal(t, test.timeout, errors.Is(err, k8s.ErrConditionTimeout))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
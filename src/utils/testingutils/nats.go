@@ -31,7 +31,8 @@ import (
 	"github.com/phayes/freeport"
 )
 
-func startNATS() (*server.Server, *nats.Conn, error) {
+// startNATS starts a NATS server, which has JetStream enabled if a JetStream store directory is given.
+func startNATS(jetStreamStoreDir string) (*server.Server, *nats.Conn, error) {
 	var err error
 	defer func() {
 		if r := recover(); r != nil {
@@ -46,6 +47,10 @@ func startNATS() (*server.Server, *nats.Conn, error) {
 
 	opts := test.DefaultTestOptions
 	opts.Port = port
+	if jetStreamStoreDir != "" {
+		opts.JetStream = true
+		opts.StoreDir = jetStreamStoreDir
+	}
 	gnatsd := test.RunServer(&opts)
 	if gnatsd == nil {
 		return nil, nil, errors.New("Could not run NATS server")
@@ -63,12 +68,21 @@ func startNATS() (*server.Server, *nats.Conn, error) {
 
 // MustStartTestNATS starts up a NATS server at an open port.
 func MustStartTestNATS(t *testing.T) (*nats.Conn, func()) {
+	return mustStartTestNATS(t, "")
+}
+
+// MustStartTestNATSWithJetStream starts up a NATS server with JetStream enabled at an open port.
+func MustStartTestNATSWithJetStream(t *testing.T) (*nats.Conn, func()) {
+	return mustStartTestNATS(t, t.TempDir())
+}
+
+func mustStartTestNATS(t *testing.T, jetStreamStoreDir string) (*nats.Conn, func()) {
 	var gnatsd *server.Server
 	var conn *nats.Conn
 
 	natsConnectFn := func() error {
 		var err error
-		gnatsd, conn, err = startNATS()
+		gnatsd, conn, err = startNATS(jetStreamStoreDir)
 		if err != nil {
 			return err
 		}
@@ -115,7 +115,10 @@ int main(int argc, char** argv) {
     LOG(FATAL) << "The HOST_IP must be specified";
   }
 
-  std::string addr = absl::Substitute("$0:$1", FLAGS_pod_ip, FLAGS_rpc_port);
+  // IPv6 pod IPs must be bracketed when joined with the port.
+  std::string addr = FLAGS_pod_ip.find(':') == std::string::npos
+                         ? absl::Substitute("$0:$1", FLAGS_pod_ip, FLAGS_rpc_port)
+                         : absl::Substitute("[$0]:$1", FLAGS_pod_ip, FLAGS_rpc_port);
 
   std::string mds_addr =
       absl::Substitute("$0.$1.svc:$2", FLAGS_mds_addr, FLAGS_namespace, FLAGS_mds_port);
@@ -32,6 +32,15 @@ DEFINE_string(client_tls_key,
 DEFINE_string(tls_ca_crt, gflags::StringFromEnv("PL_TLS_CA_CERT", "../../services/certs/ca.crt"),
               "The GRPC CA cert");
 
+DEFINE_string(nats_tls_ca_crt, gflags::StringFromEnv("PL_NATS_TLS_CA_CERT", ""),
+              "The NATS CA cert. Defaults to the GRPC CA cert");
+
+DEFINE_string(nats_tls_cert, gflags::StringFromEnv("PL_NATS_TLS_CERT", ""),
+              "The NATS client TLS cert. Defaults to the GRPC client TLS cert");
+
+DEFINE_string(nats_tls_key, gflags::StringFromEnv("PL_NATS_TLS_KEY", ""),
+              "The NATS client TLS key. Defaults to the GRPC client TLS key");
+
 namespace px {
 namespace vizier {
 namespace agent {
@@ -58,9 +67,14 @@ std::unique_ptr<NATSTLSConfig> SSL::DefaultNATSCreds() {
   if (!SSL::Enabled()) {
     return tls_config;
   }
-  tls_config->ca_cert = FLAGS_tls_ca_crt;
-  tls_config->tls_cert = FLAGS_client_tls_cert;
-  tls_config->tls_key = FLAGS_client_tls_key;
+  tls_config->ca_cert = FLAGS_nats_tls_ca_crt.empty() ? FLAGS_tls_ca_crt : FLAGS_nats_tls_ca_crt;
+  if (!FLAGS_nats_tls_cert.empty() && !FLAGS_nats_tls_key.empty()) {
+    tls_config->tls_cert = FLAGS_nats_tls_cert;
+    tls_config->tls_key = FLAGS_nats_tls_key;
+  } else {
+    tls_config->tls_cert = FLAGS_client_tls_cert;
+    tls_config->tls_key = FLAGS_client_tls_key;
+  }
   return tls_config;
 }
 
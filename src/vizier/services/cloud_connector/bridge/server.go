@@ -47,6 +47,7 @@ import (
 	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
 	"px.dev/pixie/src/shared/cvmsgs"
 	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/shared/services"
 	vzstatus "px.dev/pixie/src/shared/status"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/vizier/messages/messagespb"
@@ -126,6 +127,8 @@ spec:
 
 const (
 	heartbeatIntervalS = 5 * time.Second
+	// How long the bridge may go without sending a heartbeat to Pixie Cloud before it reports that it is disconnected.
+	heartbeatStaleTimeout = 1 * time.Minute
 	// HeartbeatTopic is the topic that heartbeats are written to.
 	HeartbeatTopic                = "heartbeat"
 	registrationTimeout           = 30 * time.Second
@@ -181,6 +184,9 @@ type Bridge struct {
 	vizChecker   VizierHealthChecker
 
 	hbSeqNum int64
+	// The time at which the last heartbeat was sent to Pixie Cloud, in nanoseconds.
+	lastHeartbeatNs int64
+	startTime       time.Time
 
 	nc     *nats.Conn
 	natsCh chan *nats.Msg
@@ -227,6 +233,7 @@ func New(vizierID uuid.UUID, assignedClusterName string, jwtSigningKey string, d
 		vzInfo:              vzInfo,
 		vzOperator:          vzOperator,
 		hbSeqNum:            0,
+		startTime:           time.Now(),
 		nc:                  nc,
 		// Buffer NATS channels to make sure we don't back-pressure NATS
 		natsCh:            make(chan *nats.Msg, 5000),
@@ -342,8 +349,8 @@ func (s *Bridge) RunStream() {
 		connectNats := func() error {
 			log.Info("Connecting to NATS...")
 			nc, err = nats.Connect(viper.GetString("nats_url"),
-				nats.ClientCert(viper.GetString("client_tls_cert"), viper.GetString("client_tls_key")),
-				nats.RootCAs(viper.GetString("tls_ca_cert")))
+				nats.ClientCert(services.GetNATSClientCert()),
+				nats.RootCAs(services.GetNATSCACert()))
 			return err
 		}
 
@@ -813,6 +820,7 @@ func (s *Bridge) startStreamGRPCWriter(stream vzconnpb.VZConnService_NATSBridgeC
 				log.WithError(err).Error("Error sending GRPC message")
 				return
 			}
+			s.recordSent(s.pendingGRPCOutMsg)
 			s.pendingGRPCOutMsg = nil
 		}
 
@@ -825,6 +833,7 @@ func (s *Bridge) startStreamGRPCWriter(stream vzconnpb.VZConnService_NATSBridgeC
 				s.pendingGRPCOutMsg = m
 				return
 			}
+			s.recordSent(m)
 		}
 	}
 
@@ -1263,5 +1272,22 @@ func (s *Bridge) GetStatus() vzstatus.VizierReason {
 		return vzstatus.CloudConnectorRegistering
 	}
 	// TODO(michellenguyen): Add status reasons for whether the bridge stream has started/stopped successfully.
+
+	// Heartbeats are sent every few seconds once the bridge stream is established, so a registered cloud connector
+	// which has stopped sending them is no longer connected to Pixie Cloud.
+	lastHeartbeat := s.startTime
+	if ns := atomic.LoadInt64(&s.lastHeartbeatNs); ns != 0 {
+		lastHeartbeat = time.Unix(0, ns)
+	}
+	if time.Since(lastHeartbeat) > heartbeatStaleTimeout {
+		return vzstatus.CloudConnectorHeartbeatFailed
+	}
 	return ""
 }
+
+// recordSent tracks when heartbeats are sent to Pixie Cloud.
+func (s *Bridge) recordSent(m *vzconnpb.V2CBridgeMessage) {
+	if m.Topic == HeartbeatTopic {
+		atomic.StoreInt64(&s.lastHeartbeatNs, time.Now().UnixNano())
+	}
+}
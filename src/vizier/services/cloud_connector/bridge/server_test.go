@@ -44,6 +44,7 @@ import (
 	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
 	"px.dev/pixie/src/shared/cvmsgspb"
 	"px.dev/pixie/src/shared/k8s/metadatapb"
+	"px.dev/pixie/src/shared/status"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/utils/testingutils"
 	"px.dev/pixie/src/vizier/services/cloud_connector/bridge"
@@ -494,3 +495,18 @@ func TestNATSGRPCBridgeTest_TestRegisterDeployment(t *testing.T) {
 		assert.Equal(t, "fakeName", vzInfo.lastClusterName)
 	}()
 }
+
+func TestBridge_GetStatus(t *testing.T) {
+	ts, cleanup := makeTestState(t)
+	defer cleanup(t)
+
+	b := bridge.New(ts.vzID, "", ts.jwt, "", time.Now().UnixNano(), nil, &FakeVZInfo{}, &FakeVZOperatorInfo{}, ts.nats, &FakeVZChecker{}, nil)
+	assert.Equal(t, status.CloudConnectorFailedToConnect, b.GetStatus())
+
+	b = bridge.New(uuid.Nil, "", ts.jwt, "", time.Now().UnixNano(), ts.vzClient, &FakeVZInfo{}, &FakeVZOperatorInfo{}, ts.nats, &FakeVZChecker{}, nil)
+	assert.Equal(t, status.CloudConnectorRegistering, b.GetStatus())
+
+	// A bridge which just started has not missed any heartbeats yet.
+	b = bridge.New(ts.vzID, "", ts.jwt, "", time.Now().UnixNano(), ts.vzClient, &FakeVZInfo{}, &FakeVZOperatorInfo{}, ts.nats, &FakeVZChecker{}, nil)
+	assert.Equal(t, status.VizierReason(""), b.GetStatus())
+}
@@ -194,8 +194,8 @@ func main() {
 	connectNats := func() error {
 		log.Info("Connecting to NATS...")
 		nc, err = nats.Connect(viper.GetString("nats_url"),
-			nats.ClientCert(viper.GetString("client_tls_cert"), viper.GetString("client_tls_key")),
-			nats.RootCAs(viper.GetString("tls_ca_cert")))
+			nats.ClientCert(services.GetNATSClientCert()),
+			nats.RootCAs(services.GetNATSCACert()))
 		if err != nil {
 			log.WithError(err).Error("Failed to connect to NATS")
 		}
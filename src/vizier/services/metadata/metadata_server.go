@@ -172,8 +172,8 @@ func main() {
 		nc, err = nats.Connect(viper.GetString("nats_url"))
 	} else {
 		nc, err = nats.Connect(viper.GetString("nats_url"),
-			nats.ClientCert(viper.GetString("client_tls_cert"), viper.GetString("client_tls_key")),
-			nats.RootCAs(viper.GetString("tls_ca_cert")))
+			nats.ClientCert(services.GetNATSClientCert()),
+			nats.RootCAs(services.GetNATSCACert()))
 	}
 
 	if err != nil {
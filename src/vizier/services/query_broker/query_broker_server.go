@@ -58,6 +58,7 @@ func init() {
 	pflag.String("mds_service", "vizier-metadata-svc", "The metadata service name")
 	pflag.String("mds_port", "50400", "The querybroker service port")
 	pflag.String("pod_namespace", "pl", "The namespace this pod runs in.")
+	pflag.String("nats_url", "pl-nats", "The URL of NATS")
 }
 
 // NewVizierServiceClient creates a new vz RPC client stub.
@@ -146,11 +147,11 @@ func main() {
 	// Connect to NATS.
 	var natsConn *nats.Conn
 	if viper.GetBool("disable_ssl") {
-		natsConn, err = nats.Connect("pl-nats")
+		natsConn, err = nats.Connect(viper.GetString("nats_url"))
 	} else {
-		natsConn, err = nats.Connect("pl-nats",
-			nats.ClientCert(viper.GetString("client_tls_cert"), viper.GetString("client_tls_key")),
-			nats.RootCAs(viper.GetString("tls_ca_cert")))
+		natsConn, err = nats.Connect(viper.GetString("nats_url"),
+			nats.ClientCert(services.GetNATSClientCert()),
+			nats.RootCAs(services.GetNATSCACert()))
 	}
 	if err != nil {
 		log.WithError(err).Fatal("Failed to connect to NATS.")
@@ -27,6 +27,7 @@ import (
 	"github.com/gogo/protobuf/types"
 	"github.com/nats-io/nats.go"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
 	"px.dev/pixie/src/shared/cvmsgspb"
@@ -35,7 +36,7 @@ import (
 )
 
 func connectNATS() *nats.Conn {
-	natsURL := "pl-nats"
+	natsURL := viper.GetString("nats_url")
 
 	var nc *nats.Conn
 	var err error
@@ -43,8 +44,8 @@ func connectNATS() *nats.Conn {
 		nc, err = nats.Connect(natsURL)
 	} else {
 		nc, err = nats.Connect(natsURL,
-			nats.ClientCert(viper.GetString("client_tls_cert"), viper.GetString("client_tls_key")),
-			nats.RootCAs(viper.GetString("tls_ca_cert")))
+			nats.ClientCert(services.GetNATSClientCert()),
+			nats.RootCAs(services.GetNATSCACert()))
 	}
 
 	if err != nil && viper.GetBool("disable_ssl") {
@@ -107,6 +108,7 @@ func handleNATSMessage(m *nats.Msg) {
 func main() {
 	services.SetupCommonFlags()
 	services.SetupSSLClientFlags()
+	pflag.String("nats_url", "pl-nats", "The URL of NATS")
 	services.PostFlagSetupAndParse()
 	services.CheckSSLClientFlags()
 